@@ -8,15 +8,24 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 	"crypto-trading-bot/internal/binance"
 	"crypto-trading-bot/internal/bot"
+	"crypto-trading-bot/internal/broadcast"
 	"crypto-trading-bot/internal/config"
 	"crypto-trading-bot/internal/indicators"
+	"crypto-trading-bot/internal/performance"
+	"crypto-trading-bot/internal/risk"
 	"crypto-trading-bot/internal/sentiment"
 	"crypto-trading-bot/internal/signals"
 	"crypto-trading-bot/internal/strategies/interval"
+	"crypto-trading-bot/internal/strategies/rules"
 	"crypto-trading-bot/internal/trading"
+	"crypto-trading-bot/internal/webhook"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -28,11 +37,23 @@ type App struct {
 	binanceClient    *binance.Client              // REST API client for Binance
 	binanceWS        *binance.WSClient            // WebSocket client for real-time market data
 	indicatorManager *indicators.IndicatorManager // Technical indicator calculator
+	indicatorSpecs   map[string][]indicators.Spec // Indicators added to the default preset by symbol ("" = all symbols)
 	tradingEngine    *trading.TradingEngine       // Core trading execution engine
-	autonomousBot    *bot.AutonomousBot          // Autonomous trading bot
+	autonomousBot    atomic.Pointer[bot.AutonomousBot] // Autonomous trading bot; read by webhook and risk goroutines
 	signalHandler    *signals.SignalHandler       // Trading signal processor
 	sentimentManager *sentiment.SentimentManager  // Sentiment analysis manager
 	intervalStrategy *interval.IntervalStrategy   // Interval trading strategy
+	ruleRunner       *rules.Runner                // Declarative rule strategies trading on the main engine
+	signalStore      *signals.SignalStore         // Persistent history of generated signals
+	signalModels     *signals.ModelRegistry       // Signal models shared by all bots
+	webhook          *webhook.Server              // External alerts receiver; nil when disabled
+	broadcaster      *broadcast.Broadcaster       // Publishes signals and trades to external consumers; nil when disabled
+	signalLabeler    *signals.OutcomeLabeler      // Fills in forward returns of stored signals
+	breaker          *risk.CircuitBreaker         // Kill switch shared by all engines and strategies
+	portfolio        *risk.PortfolioRisk          // Portfolio exposure limits shared by all engines
+	klineCache       *binance.KlineCache          // Cached klines for risk analytics and indicator warmup
+	klineFeeds       sync.Map                     // Kline streams feeding indicatorManager, keyed by "symbol:timeframe"
+	riskJournal      *risk.DecisionJournal        // Log of pre-trade risk decisions of all accounts
 	cfg              *config.Config               // Application configuration
 }
 
@@ -42,6 +63,11 @@ func NewApp() *App {
 	return &App{}
 }
 
+// currentBot returns the autonomous bot, nil before the first StartBot.
+func (a *App) currentBot() *bot.AutonomousBot {
+	return a.autonomousBot.Load()
+}
+
 // setupLogging configures the logging system to write to both file and stdout.
 // Logs are written to bot.log with timestamps and debug level enabled.
 func setupLogging() error {
@@ -93,6 +119,7 @@ func (a *App) startup(ctx context.Context) {
 
 	// Initialize Binance client
 	a.binanceClient = binance.NewClient()
+	a.klineCache = binance.NewKlineCache(a.binanceClient, 5*time.Minute)
 	log.Info("Binance REST client initialized")
 
 	// Initialize WebSocket client
@@ -105,16 +132,75 @@ func (a *App) startup(ctx context.Context) {
 
 	// Initialize indicator manager
 	a.indicatorManager = indicators.NewIndicatorManager()
+	if err := a.indicatorManager.SetRegimeThresholds(*a.regimeThresholds()); err != nil {
+		log.Errorf("Invalid regime thresholds, using defaults: %v", err)
+	}
+	a.indicatorSpecs = a.loadIndicatorSpecs()
+	for symbol, specs := range a.indicatorSpecs {
+		if err := a.indicatorManager.SetSpecs(symbol, specs); err != nil {
+			log.Errorf("Invalid indicator specs for %q, ignoring: %v", symbol, err)
+			delete(a.indicatorSpecs, symbol)
+		}
+	}
 	log.Info("Indicator manager initialized")
 
 	// Initialize signal handler
 	a.signalHandler = signals.NewSignalHandler()
-	log.Info("Signal handler initialized")
+	signalStore, err := signals.NewSignalStore(a.cfg.SignalStorePath, 0)
+	if err != nil {
+		log.Errorf("Failed to open signal store: %v", err)
+		signalStore, _ = signals.NewSignalStore("", 0)
+	}
+	a.signalStore = signalStore
+	a.signalHandler.SetStore(a.signalStore)
+	a.signalHandler.SetValidity(a.cfg.SignalTTLCandles, time.Duration(a.cfg.SignalTTLSeconds)*time.Second)
+	a.signalModels = signals.NewModelRegistry()
+	if a.cfg.SignalModelsFile != "" {
+		if err := a.signalModels.LoadFile(a.cfg.SignalModelsFile); err != nil {
+			log.Errorf("Failed to load signal models from %s: %v", a.cfg.SignalModelsFile, err)
+		}
+	}
+	if err := a.signalModels.SetActive(a.cfg.SignalModel); err != nil {
+		log.Errorf("Failed to activate signal model: %v", err)
+	}
+	log.Infof("Signal handler initialized (model %s)", a.signalModels.Active().ID())
+
+	a.signalLabeler = signals.NewOutcomeLabeler(a.signalStore, a.klineCache, a.cfg.LabelHorizons())
+	a.signalLabeler.Start(time.Minute)
 
 	// Initialize sentiment manager
 	a.sentimentManager = sentiment.NewSentimentManager()
 	log.Info("Sentiment manager initialized")
 
+	// Initialize circuit breaker shared by all engines
+	a.breaker = risk.NewCircuitBreaker(&risk.CircuitBreakerConfig{
+		MaxDailyLoss:         a.cfg.BreakerMaxDailyLoss,
+		MaxDrawdown:          a.cfg.BreakerMaxDrawdown,
+		MaxConsecutiveLosses: a.cfg.BreakerMaxConsecutiveLosses,
+		StaleDataTimeout:     time.Duration(a.cfg.BreakerStaleDataSeconds) * time.Second,
+		MaxOrderErrors:       a.cfg.BreakerMaxOrderErrors,
+		FlattenOnTrip:        a.cfg.BreakerFlattenOnTrip,
+	})
+	log.Info("Circuit breaker initialized")
+
+	// Initialize portfolio limits shared by all engines
+	portfolioLimits := risk.DefaultPortfolioLimits()
+	portfolioLimits.MaxGrossExposure = a.cfg.PortfolioMaxGross
+	portfolioLimits.MaxNetExposure = a.cfg.PortfolioMaxNet
+	portfolioLimits.MaxAssetExposure = a.cfg.PortfolioMaxAsset
+	portfolioLimits.MaxSectorExposure = a.cfg.PortfolioMaxSector
+	portfolioLimits.MaxClusterExposure = a.cfg.PortfolioMaxCluster
+	portfolioLimits.CorrelationThreshold = a.cfg.PortfolioCorrelationThreshold
+	a.portfolio = risk.NewPortfolioRisk(portfolioLimits)
+	log.Info("Portfolio risk limits initialized")
+
+	riskJournal, err := risk.NewDecisionJournal(a.cfg.RiskJournalPath, 0)
+	if err != nil {
+		log.Errorf("Failed to open risk decision journal: %v", err)
+		riskJournal, _ = risk.NewDecisionJournal("", 0)
+	}
+	a.riskJournal = riskJournal
+
 	// Initialize trading engine
 	engineConfig := &trading.EngineConfig{
 		Symbol:            "BTCUSDT",
@@ -126,22 +212,401 @@ func (a *App) startup(ctx context.Context) {
 		MinConfidence:     a.cfg.MinConfidence,
 		MaxDailyTrades:    a.cfg.MaxDailyTrades,
 		CooldownMinutes:   a.cfg.CooldownMinutes,
+		Account:           "main",
+		Session:           a.sessionConfig(),
+		CircuitBreaker:    a.breaker,
+		Sizing:            a.sizingConfig(),
+		Portfolio:         a.portfolio,
+		PortfolioHoldings: a.portfolioHoldings,
+		VaRLimit:          a.varLimit(),
+		RiskRules:         a.cfg.RiskRulesFor("main"),
+		MaxOpenPositions:  a.cfg.RiskMaxOpenPositions,
+		DecisionJournal:   a.riskJournal,
+		ExitPolicies:      a.exitPolicies(),
 	}
 	a.tradingEngine = trading.NewTradingEngine(engineConfig)
+	a.tradingEngine.StartEquitySampler()
 	log.Info("Trading engine initialized")
 
+	a.checkRuinTolerance()
+	a.startWebhook()
+	a.startBroadcast()
+	a.startRuleStrategies()
+
 	log.Info("Application started successfully")
 }
 
+// startWebhook starts the external alerts receiver when an address is
+// configured.
+func (a *App) startWebhook() {
+	if a.cfg.WebhookAddr == "" {
+		return
+	}
+	server, err := webhook.NewServer(webhook.Config{
+		Addr:             a.cfg.WebhookAddr,
+		Path:             a.cfg.WebhookPath,
+		Secret:           a.cfg.WebhookSecret,
+		ModelTag:         a.cfg.WebhookModelTag,
+		DefaultTimeframe: a.cfg.WebhookTimeframe,
+		RatePerMinute:    a.cfg.WebhookRatePerMinute,
+		Burst:            a.cfg.WebhookBurst,
+		MaxSkew:          time.Duration(a.cfg.WebhookMaxSkewSeconds) * time.Second,
+		RequireTimestamp: a.cfg.WebhookRequireTimestamp,
+	}, a.ingestExternalSignal)
+	if err != nil {
+		log.Errorf("Webhook receiver disabled: %v", err)
+		return
+	}
+	if err := server.Start(); err != nil {
+		log.Errorf("Failed to start webhook receiver: %v", err)
+		return
+	}
+	a.webhook = server
+}
+
+// ingestExternalSignal routes a webhook signal to the account configured to
+// trade alerts. Signals the bot cannot trade are only recorded; the main
+// account rejects signals for other symbols than its own.
+func (a *App) ingestExternalSignal(signal *signals.Signal) error {
+	switch a.cfg.WebhookAccount {
+	case "bot":
+		if autonomousBot := a.currentBot(); autonomousBot != nil {
+			err := autonomousBot.IngestSignal(signal)
+			if err == nil {
+				return nil
+			}
+			log.Infof("External signal %s recorded without trading: %v", signal.ID, err)
+		}
+		a.signalHandler.UpdateSignal(signal)
+	case "main":
+		if symbol := a.tradingEngine.GetSymbol(); signal.Symbol != symbol {
+			return fmt.Errorf("symbol %s is not traded by the main account (%s)", signal.Symbol, symbol)
+		}
+		a.signalHandler.UpdateSignal(signal)
+		a.tradingEngine.ProcessSignal(signal)
+	default:
+		a.signalHandler.UpdateSignal(signal)
+	}
+	return nil
+}
+
+// startBroadcast starts publishing signals and trades when outbound
+// webhooks or the WebSocket feed are configured, and follows the main
+// account. The bot is attached when it starts.
+func (a *App) startBroadcast() {
+	urls := splitList(a.cfg.BroadcastWebhookURLs)
+	if len(urls) == 0 && a.cfg.BroadcastWSAddr == "" {
+		return
+	}
+
+	filter := broadcast.Filter{
+		Events:        splitList(a.cfg.BroadcastEvents),
+		MinConfidence: a.cfg.BroadcastMinConfidence,
+	}
+	broadcastConfig := broadcast.Config{}
+	for _, url := range urls {
+		broadcastConfig.Targets = append(broadcastConfig.Targets, broadcast.Target{
+			URL:        url,
+			Secret:     a.cfg.BroadcastWebhookSecret,
+			Filter:     filter,
+			MaxRetries: a.cfg.BroadcastMaxRetries,
+			Timeout:    time.Duration(a.cfg.BroadcastTimeoutSeconds) * time.Second,
+		})
+	}
+	if a.cfg.BroadcastWSAddr != "" {
+		broadcastConfig.Feed = &broadcast.FeedConfig{
+			Addr:  a.cfg.BroadcastWSAddr,
+			Path:  a.cfg.BroadcastWSPath,
+			Token: a.cfg.BroadcastWSToken,
+		}
+	}
+
+	broadcaster, err := broadcast.NewBroadcaster(broadcastConfig)
+	if err != nil {
+		log.Errorf("Broadcasting disabled: %v", err)
+		return
+	}
+	if err := broadcaster.Start(); err != nil {
+		log.Errorf("Failed to start broadcaster: %v", err)
+		return
+	}
+	a.broadcaster = broadcaster
+
+	broadcaster.FollowSignals("main", "main", a.signalHandler.Subscribe(a.broadcastSubscription("broadcast:main")))
+	a.tradingEngine.OnTradeEvent("broadcast", broadcaster.PublishTrade)
+}
+
+// broadcastSubscription selects the signals worth broadcasting: HOLD
+// signals are produced for every candle and are skipped unless enabled.
+func (a *App) broadcastSubscription(name string) signals.SubscriptionOptions {
+	options := signals.SubscriptionOptions{
+		Name:   name,
+		Filter: signals.SubscriptionFilter{MinConfidence: a.cfg.BroadcastMinConfidence},
+		Buffer: 256,
+	}
+	if !a.cfg.BroadcastIncludeHold {
+		options.Filter.Directions = []string{"LONG", "SHORT"}
+	}
+	return options
+}
+
+// attachBroadcast publishes the signals and trades of the bot.
+func (a *App) attachBroadcast(autonomousBot *bot.AutonomousBot) {
+	if a.broadcaster == nil {
+		return
+	}
+	a.broadcaster.FollowSignals("bot", "bot", autonomousBot.SubscribeSignals(a.broadcastSubscription("broadcast:bot")))
+	autonomousBot.OnTradeEvent("broadcast", a.broadcaster.PublishTrade)
+}
+
+// startRuleStrategies creates the runner of rule strategies and starts the
+// definitions found in the configured directory.
+func (a *App) startRuleStrategies() {
+	a.ruleRunner = rules.NewRunner(a.tradingEngine, a.binanceClient, a.binanceWS)
+	a.ruleRunner.SetSignalHandler(a.signalHandler)
+
+	if a.cfg.RulesDir == "" {
+		return
+	}
+	defs, err := rules.LoadDefinitions(a.cfg.RulesDir)
+	if err != nil {
+		log.Errorf("Failed to load rule strategies: %v", err)
+		return
+	}
+	for _, def := range defs {
+		if err := a.ruleRunner.Start(def); err != nil {
+			log.Errorf("Failed to start rule strategy %s: %v", def.Name, err)
+		}
+	}
+	log.Infof("Loaded %d rule strategies from %s", len(defs), a.cfg.RulesDir)
+}
+
+// GetBroadcastStats returns the delivery counters of the outbound webhooks
+// and the WebSocket feed
+func (a *App) GetBroadcastStats() broadcast.Stats {
+	if a.broadcaster == nil {
+		return broadcast.Stats{Following: []string{}, Targets: []broadcast.TargetStats{}}
+	}
+	return a.broadcaster.Stats()
+}
+
+// GetWebhookStats returns the request counters of the webhook receiver
+func (a *App) GetWebhookStats() webhook.Stats {
+	if a.webhook == nil {
+		return webhook.Stats{Rejected: make(map[string]int)}
+	}
+	return a.webhook.Stats()
+}
+
+// sessionConfig builds the trading calendar configuration from the app config.
+// A session config file, if set, takes precedence over the environment values.
+func (a *App) sessionConfig() *trading.SessionConfig {
+	if a.cfg.SessionConfigFile != "" {
+		sessionCfg, err := trading.LoadSessionConfig(a.cfg.SessionConfigFile)
+		if err == nil {
+			return sessionCfg
+		}
+		log.Errorf("Failed to load session config from %s: %v", a.cfg.SessionConfigFile, err)
+	}
+
+	return &trading.SessionConfig{
+		Timezone:     a.cfg.SessionTimezone,
+		RolloverHour: a.cfg.SessionRolloverHour,
+	}
+}
+
+// sizingConfig builds the position sizing model from the configuration.
+// It returns nil when no sizing method is set, keeping fixed fractional sizing.
+func (a *App) sizingConfig() *risk.SizingConfig {
+	if a.cfg.SizingMethod == "" {
+		return nil
+	}
+	return &risk.SizingConfig{
+		Method:          a.cfg.SizingMethod,
+		Notional:        a.cfg.SizingNotional,
+		RiskPerTrade:    a.cfg.RiskPerTrade,
+		TargetRisk:      a.cfg.SizingTargetRisk,
+		KellyFraction:   a.cfg.SizingKellyFraction,
+		TotalRisk:       a.cfg.SizingTotalRisk,
+		MaxPositions:    a.cfg.SizingMaxPositions,
+		MaxPositionSize: a.cfg.MaxPositionSize,
+	}
+}
+
+// exitPolicies loads the per-strategy exit policies. It returns nil, which
+// selects the built-in policies, when no file is configured or it is invalid.
+func (a *App) exitPolicies() map[string][]trading.ExitPolicyConfig {
+	if a.cfg.ExitPoliciesFile == "" {
+		return nil
+	}
+	policies, err := trading.LoadExitPolicies(a.cfg.ExitPoliciesFile)
+	if err != nil {
+		log.Errorf("Failed to load exit policies from %s: %v", a.cfg.ExitPoliciesFile, err)
+		return nil
+	}
+	return policies
+}
+
+// confluenceConfig returns the multi-timeframe confluence settings, nil
+// when disabled or invalid.
+func (a *App) confluenceConfig() *signals.ConfluenceConfig {
+	if a.cfg.ConfluenceMode == "" {
+		return nil
+	}
+	weights, err := signals.ParseConfluenceWeights(a.cfg.ConfluenceWeights)
+	if err != nil {
+		log.Errorf("Invalid confluence weights, using timeframe rank: %v", err)
+		weights = nil
+	}
+	cfg := &signals.ConfluenceConfig{
+		Mode:         a.cfg.ConfluenceMode,
+		Weights:      weights,
+		MinAgreement: a.cfg.ConfluenceMinAgreement,
+		RequireTrend: a.cfg.ConfluenceRequireTrend,
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Errorf("Multi-timeframe confluence disabled: %v", err)
+		return nil
+	}
+	return cfg
+}
+
+// regimeThresholds returns the market regime classifier settings.
+func (a *App) regimeThresholds() *indicators.RegimeThresholds {
+	return &indicators.RegimeThresholds{
+		TrendADX:          a.cfg.RegimeTrendADX,
+		RangeADX:          a.cfg.RegimeRangeADX,
+		HighVolPercentile: a.cfg.RegimeHighVolPercentile,
+		LowVolPercentile:  a.cfg.RegimeLowVolPercentile,
+		Lookback:          a.cfg.RegimeLookback,
+		VolatilityWindow:  a.cfg.RegimeVolatilityWindow,
+	}
+}
+
+// loadIndicatorSpecs parses the additional indicators of all symbols and
+// the per-symbol ones of the form "BTCUSDT:rsi(21),ema(100)|ETHUSDT:rsi(10)".
+// Invalid entries are logged and skipped.
+func (a *App) loadIndicatorSpecs() map[string][]indicators.Spec {
+	result := make(map[string][]indicators.Spec)
+	if a.cfg.IndicatorSpecs != "" {
+		specs, err := indicators.ParseSpecs(a.cfg.IndicatorSpecs)
+		if err != nil {
+			log.Errorf("Invalid INDICATOR_SPECS: %v", err)
+		} else {
+			result[""] = specs
+		}
+	}
+	for _, entry := range strings.Split(a.cfg.IndicatorSymbolSpecs, "|") {
+		symbol, list, ok := strings.Cut(entry, ":")
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if !ok || symbol == "" {
+			if strings.TrimSpace(entry) != "" {
+				log.Errorf("Invalid INDICATOR_SYMBOL_SPECS entry %q", entry)
+			}
+			continue
+		}
+		specs, err := indicators.ParseSpecs(list)
+		if err != nil {
+			log.Errorf("Invalid INDICATOR_SYMBOL_SPECS for %s: %v", symbol, err)
+			continue
+		}
+		result[symbol] = specs
+	}
+	return result
+}
+
+// regimeGate returns the regimes the bot trades in, nil when not
+// restricted or invalid.
+func (a *App) regimeGate() *indicators.RegimeGate {
+	gate := &indicators.RegimeGate{
+		Allow: splitList(a.cfg.BotRegimeAllow),
+		Block: splitList(a.cfg.BotRegimeBlock),
+	}
+	if len(gate.Allow) == 0 && len(gate.Block) == 0 {
+		return nil
+	}
+	if err := gate.Validate(); err != nil {
+		log.Errorf("Bot regime gate disabled: %v", err)
+		return nil
+	}
+	return gate
+}
+
+// splitList splits a comma-separated config value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// varLimit returns the pre-trade VaR check, nil when disabled.
+func (a *App) varLimit() *risk.VaRLimit {
+	if a.cfg.VaRMaxPercent <= 0 {
+		return nil
+	}
+	return &risk.VaRLimit{
+		MaxVaRPercent: a.cfg.VaRMaxPercent,
+		Confidence:    a.cfg.VaRConfidence,
+		Method:        risk.VaRHistorical,
+		Series: func(symbols []string) []risk.PriceSeries {
+			return a.varSeries(a.cfg.VaRInterval, symbols)
+		},
+	}
+}
+
 // shutdown gracefully shuts down all application components.
 // It closes WebSocket connections and cleans up resources.
 func (a *App) shutdown(ctx context.Context) {
 	log.Info("Application shutting down...")
 
+	if a.tradingEngine != nil {
+		a.tradingEngine.StopEquitySampler()
+	}
+
 	if a.binanceWS != nil {
 		a.binanceWS.Close()
 	}
 
+	if a.signalLabeler != nil {
+		a.signalLabeler.Stop()
+	}
+
+	if a.ruleRunner != nil {
+		a.ruleRunner.StopAll()
+	}
+
+	if a.webhook != nil {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := a.webhook.Stop(stopCtx); err != nil {
+			log.Errorf("Failed to stop webhook receiver: %v", err)
+		}
+		cancel()
+	}
+
+	if a.broadcaster != nil {
+		stopCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := a.broadcaster.Stop(stopCtx); err != nil {
+			log.Errorf("Failed to stop broadcaster: %v", err)
+		}
+		cancel()
+	}
+
+	if a.signalStore != nil {
+		if err := a.signalStore.Close(); err != nil {
+			log.Errorf("Failed to close signal store: %v", err)
+		}
+	}
+
+	if a.riskJournal != nil {
+		if err := a.riskJournal.Close(); err != nil {
+			log.Errorf("Failed to close risk decision journal: %v", err)
+		}
+	}
+
 	log.Info("Application shutdown complete")
 }
 
@@ -160,32 +625,202 @@ func (a *App) GetAllTickers() ([]binance.Ticker, error) {
 	return a.binanceClient.GetAllTickers()
 }
 
-// SubscribeKline subscribes to real-time kline updates
+// SubscribeKline subscribes to real-time kline updates; the closed candles
+// feed the indicators of the symbol/timeframe
 func (a *App) SubscribeKline(symbol, interval string) error {
 	if a.binanceWS == nil {
 		return fmt.Errorf("WebSocket client not initialized")
 	}
-	_, err := a.binanceWS.SubscribeKline(symbol, interval)
-	return err
+	symbol = strings.ToUpper(symbol)
+	key := symbol + ":" + interval
+	// Отписаться от потока нельзя, поэтому на каждый символ/таймфрейм подписываемся один раз
+	if _, subscribed := a.klineFeeds.LoadOrStore(key, true); subscribed {
+		return nil
+	}
+	ch, err := a.binanceWS.SubscribeKline(symbol, interval)
+	if err != nil {
+		a.klineFeeds.Delete(key)
+		return err
+	}
+	go a.feedIndicators(symbol, interval, ch)
+	return nil
 }
 
-// CalculateIndicators calculates technical indicators for given candle data
-func (a *App) CalculateIndicators(symbol, timeframe string, high, low, close, volume float64) *indicators.IndicatorValues {
+// feedIndicators feeds the closed candles of a kline stream to the
+// persistent indicator set of the symbol/timeframe
+func (a *App) feedIndicators(symbol, interval string, ch chan *binance.KlineWSMessage) {
+	for msg := range ch {
+		if !msg.Kline.IsFinal {
+			continue
+		}
+		high, _ := strconv.ParseFloat(msg.Kline.High, 64)
+		low, _ := strconv.ParseFloat(msg.Kline.Low, 64)
+		close, _ := strconv.ParseFloat(msg.Kline.Close, 64)
+		volume, _ := strconv.ParseFloat(msg.Kline.Volume, 64)
+		if close <= 0 {
+			continue
+		}
+		// Сначала догоняем свечи, пропущенные потоком, из кеша
+		set := a.liveIndicatorSet(symbol, interval)
+		set.UpdateClosedAt(msg.Kline.StartTime, high, low, close, volume)
+	}
+	a.klineFeeds.Delete(symbol + ":" + interval)
+	log.Warnf("Indicators lost the %s %s kline stream", symbol, interval)
+}
+
+// liveIndicatorSet returns the persistent indicator set of a symbol/timeframe
+// after feeding it the closed candles of the kline cache it has not seen
+// yet, which warms up new sets and fills in candles the stream missed
+func (a *App) liveIndicatorSet(symbol, timeframe string) *indicators.IndicatorSet {
+	symbol = strings.ToUpper(symbol)
 	set := a.indicatorManager.GetOrCreate(symbol, timeframe)
-	return set.UpdateAll(high, low, close, volume)
+	klines, err := a.klineCache.GetKlines(symbol, timeframe, 500)
+	if err != nil {
+		log.Warnf("Failed to load %s %s klines for the indicators: %v", symbol, timeframe, err)
+		return set
+	}
+	// Последняя свеча ответа еще формировалась в момент загрузки
+	if len(klines) > 0 {
+		klines = klines[:len(klines)-1]
+	}
+	last := set.LastOpenTime()
+	for _, k := range klines {
+		if k.OpenTime > last {
+			set.UpdateClosedAt(k.OpenTime, k.High, k.Low, k.Close, k.Volume)
+		}
+	}
+	return set
+}
+
+// CalculateIndicators calculates technical indicators for the given,
+// still forming candle of a symbol. The candle is fed to a copy of the
+// persistent indicator set, which only closed candles update
+func (a *App) CalculateIndicators(symbol, timeframe string, high, low, close, volume float64) *indicators.IndicatorValues {
+	set := a.liveIndicatorSet(symbol, timeframe)
+	var openTime int64
+	if last := set.LastOpenTime(); last > 0 {
+		openTime = last + signals.TimeframeDuration(timeframe).Milliseconds()
+	}
+	return set.Clone().UpdateAllAt(openTime, high, low, close, volume)
+}
+
+// CalculateIndicatorSeries calculates indicator series over the given
+// candles, oldest first, e.g. for chart overlays and backtests. Specs such
+// as "rsi(21), bb(20,2.5)" select the indicators, empty specs the default
+// preset; the live indicator state is not touched
+func (a *App) CalculateIndicatorSeries(klines []binance.Kline, specs string) (*indicators.SeriesResult, error) {
+	parsed, err := indicators.ParseSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+	return indicators.ComputeSeries(klines, parsed)
+}
+
+// GetIndicatorSeries loads the last limit candles of a symbol and
+// calculates indicator series over them like CalculateIndicatorSeries
+func (a *App) GetIndicatorSeries(symbol, interval string, limit int, specs string) (*indicators.SeriesResult, error) {
+	parsed, err := indicators.ParseSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+	klines, err := a.binanceClient.GetKlines(symbol, interval, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s %s klines: %w", symbol, interval, err)
+	}
+	return indicators.ComputeSeries(klines, parsed)
+}
+
+// SetIndicatorSpecs selects the indicators added to the default preset for
+// a symbol, e.g. "rsi(21), bb(20,2.5)"; an empty symbol applies to all
+// symbols without their own selection and empty specs remove it
+func (a *App) SetIndicatorSpecs(symbol, specs string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	var parsed []indicators.Spec
+	if strings.TrimSpace(specs) != "" {
+		var err error
+		if parsed, err = indicators.ParseSpecs(specs); err != nil {
+			return err
+		}
+	}
+	if err := a.indicatorManager.SetSpecs(symbol, parsed); err != nil {
+		return err
+	}
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		if err := autonomousBot.SetIndicatorSpecs(symbol, parsed); err != nil {
+			return err
+		}
+	}
+
+	updated := make(map[string][]indicators.Spec, len(a.indicatorSpecs)+1)
+	for s, list := range a.indicatorSpecs {
+		updated[s] = list
+	}
+	if parsed == nil {
+		delete(updated, symbol)
+	} else {
+		updated[symbol] = parsed
+	}
+	a.indicatorSpecs = updated
+	return nil
+}
+
+// GetIndicatorSpecs returns the additional indicators by symbol ("" = all
+// symbols without their own selection)
+func (a *App) GetIndicatorSpecs() map[string][]string {
+	result := make(map[string][]string, len(a.indicatorSpecs))
+	for symbol, specs := range a.indicatorSpecs {
+		list := make([]string, len(specs))
+		for i, spec := range specs {
+			list[i] = spec.String()
+		}
+		result[symbol] = list
+	}
+	return result
+}
+
+// GetIndicatorDefinitions returns the indicators specs can select
+func (a *App) GetIndicatorDefinitions() []indicators.Definition {
+	return indicators.DefaultRegistry().List()
 }
 
 // GetSignals returns trading signals based on current indicators
 func (a *App) GetSignals(symbol, timeframe string, price float64) []indicators.Signal {
-	set := a.indicatorManager.GetOrCreate(symbol, timeframe)
+	set := a.liveIndicatorSet(symbol, timeframe)
 	return set.GetSignals(price)
 }
 
+// GetMarketRegime returns the market regime of a symbol/timeframe, from
+// the bot's live indicators when it runs and from the closed candles of
+// the symbol otherwise
+func (a *App) GetMarketRegime(symbol, timeframe string) (indicators.Regime, error) {
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		if regime, ok := autonomousBot.GetRegime(symbol, timeframe); ok {
+			return regime, nil
+		}
+	}
+	if set := a.liveIndicatorSet(symbol, timeframe); set.LastOpenTime() > 0 {
+		return set.Regime(), nil
+	}
+	return indicators.Regime{}, fmt.Errorf("no candles for %s %s", symbol, timeframe)
+}
+
+// GetMarketRegimes returns the market regime of every symbol/timeframe
+// keyed by "symbol:timeframe"
+func (a *App) GetMarketRegimes() map[string]indicators.Regime {
+	regimes := a.indicatorManager.Regimes()
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		for key, regime := range autonomousBot.GetRegimes() {
+			regimes[key] = regime
+		}
+	}
+	return regimes
+}
+
 // StartBot starts the autonomous trading bot
 func (a *App) StartBot(symbols []string, timeframes []string) error {
-	if a.autonomousBot != nil && a.autonomousBot.IsRunning() {
+	if autonomousBot := a.currentBot(); autonomousBot != nil && autonomousBot.IsRunning() {
 		// Если бот уже запущен, останавливаем его перед перезапуском с новыми настройками
-		a.autonomousBot.Stop()
+		autonomousBot.Stop()
 		time.Sleep(500 * time.Millisecond)
 	}
 
@@ -207,14 +842,38 @@ func (a *App) StartBot(symbols []string, timeframes []string) error {
 		CooldownMinutes:  a.cfg.CooldownMinutes,
 		MLServiceAddr:   a.cfg.MLServiceAddr,
 		EnableSentiment: false,
+		Session:         a.sessionConfig(),
+		CircuitBreaker:  a.breaker,
+		Sizing:          a.sizingConfig(),
+		Portfolio:       a.portfolio,
+		PortfolioHoldings: a.portfolioHoldings,
+		VaRLimit:        a.varLimit(),
+		RiskRules:       a.cfg.RiskRulesFor("bot"),
+		MaxOpenPositions: a.cfg.RiskMaxOpenPositions,
+		DecisionJournal: a.riskJournal,
+		ExitPolicies:    a.exitPolicies(),
+		Confluence:      a.confluenceConfig(),
+		RegimeThresholds: a.regimeThresholds(),
+		RegimeGate:      a.regimeGate(),
+		IndicatorSpecs:  a.indicatorSpecs,
 	}
 
 	log.Infof("Starting bot with config: symbols=%v, timeframes=%v, riskPerTrade=%.2f, minConfidence=%.2f, maxDailyTrades=%d, cooldownMinutes=%d",
 		symbols, timeframes, a.cfg.RiskPerTrade, a.cfg.MinConfidence, a.cfg.MaxDailyTrades, a.cfg.CooldownMinutes)
 
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		// Отписываем старый движок: события сделок шлёт только текущий бот
+		autonomousBot.OnTradeEvent("broadcast", nil)
+	}
+
 	// Используем существующий WebSocket клиент из App
-	a.autonomousBot = bot.NewAutonomousBotWithWS(botConfig, a.binanceWS)
-	return a.autonomousBot.Start(a.ctx)
+	autonomousBot := bot.NewAutonomousBotWithWS(botConfig, a.binanceWS)
+	autonomousBot.SetSignalStore(a.signalStore)
+	autonomousBot.SetSignalModels(a.signalModels)
+	autonomousBot.SetSignalValidity(a.cfg.SignalTTLCandles, time.Duration(a.cfg.SignalTTLSeconds)*time.Second)
+	a.attachBroadcast(autonomousBot)
+	a.autonomousBot.Store(autonomousBot)
+	return autonomousBot.Start(a.ctx)
 }
 
 // UpdateBotConfig updates bot configuration
@@ -227,9 +886,9 @@ func (a *App) UpdateBotConfig(riskPerTrade, maxPositionSize, minConfidence float
 	a.cfg.CooldownMinutes = cooldownMinutes
 
 	// Если бот запущен, обновляем его конфигурацию
-	if a.autonomousBot != nil && a.autonomousBot.IsRunning() {
+	if autonomousBot := a.currentBot(); autonomousBot != nil && autonomousBot.IsRunning() {
 		// Получаем текущие символы и таймфреймы
-		botConfig := a.autonomousBot.GetConfig()
+		botConfig := autonomousBot.GetConfig()
 		if botConfig != nil {
 			// Обновляем конфигурацию бота
 			newConfig := &bot.BotConfig{
@@ -243,8 +902,23 @@ func (a *App) UpdateBotConfig(riskPerTrade, maxPositionSize, minConfidence float
 				CooldownMinutes: cooldownMinutes,
 				MLServiceAddr:   botConfig.MLServiceAddr,
 				EnableSentiment: botConfig.EnableSentiment,
+				Session:         botConfig.Session,
+				CircuitBreaker:  botConfig.CircuitBreaker,
+				Sizing:          botConfig.Sizing,
+				Portfolio:       botConfig.Portfolio,
+				PortfolioHoldings: botConfig.PortfolioHoldings,
+				VaRLimit:        botConfig.VaRLimit,
+				RiskRules:       botConfig.RiskRules,
+				MaxOpenPositions: botConfig.MaxOpenPositions,
+				DecisionJournal: botConfig.DecisionJournal,
+				ExitPolicies:    botConfig.ExitPolicies,
+				Confluence:      botConfig.Confluence,
+				RegimeThresholds: botConfig.RegimeThresholds,
+				RegimeGate:      botConfig.RegimeGate,
+				IndicatorSpecs:  botConfig.IndicatorSpecs,
 			}
-			a.autonomousBot.UpdateConfig(newConfig)
+			autonomousBot.UpdateConfig(newConfig)
+			a.checkRuinTolerance()
 		}
 	}
 
@@ -253,15 +927,15 @@ func (a *App) UpdateBotConfig(riskPerTrade, maxPositionSize, minConfidence float
 
 // StopBot stops the autonomous trading bot
 func (a *App) StopBot() {
-	if a.autonomousBot != nil {
-		a.autonomousBot.Stop()
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		autonomousBot.Stop()
 	}
 }
 
 // GetBotStats returns bot trading statistics
 func (a *App) GetBotStats() trading.TradingStats {
-	if a.autonomousBot != nil {
-		return a.autonomousBot.GetStats()
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		return autonomousBot.GetStats()
 	}
 	if a.tradingEngine == nil {
 		return trading.TradingStats{}
@@ -271,8 +945,8 @@ func (a *App) GetBotStats() trading.TradingStats {
 
 // GetPositions returns all open positions
 func (a *App) GetPositions() []trading.Position {
-	if a.autonomousBot != nil {
-		return a.autonomousBot.GetPositions()
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		return autonomousBot.GetPositions()
 	}
 	if a.tradingEngine == nil {
 		return []trading.Position{}
@@ -282,8 +956,8 @@ func (a *App) GetPositions() []trading.Position {
 
 // GetTradeHistory returns trade history
 func (a *App) GetTradeHistory() []trading.Trade {
-	if a.autonomousBot != nil {
-		return a.autonomousBot.GetTradeHistory()
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		return autonomousBot.GetTradeHistory()
 	}
 	if a.tradingEngine == nil {
 		return []trading.Trade{}
@@ -291,6 +965,358 @@ func (a *App) GetTradeHistory() []trading.Trade {
 	return a.tradingEngine.GetTradeHistory()
 }
 
+// GetEquityCurve returns mark-to-market equity snapshots between from and to
+// (Unix milliseconds, 0 = unbounded), downsampled to at most maxPoints entries
+func (a *App) GetEquityCurve(from, to int64, maxPoints int) []trading.EquityPoint {
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		return autonomousBot.GetEquityCurve(from, to, maxPoints)
+	}
+	if a.tradingEngine == nil {
+		return []trading.EquityPoint{}
+	}
+	return a.tradingEngine.GetEquityCurve(from, to, maxPoints)
+}
+
+// GetPerformanceReport returns risk-adjusted performance analytics computed
+// from the trade history and the equity curve
+func (a *App) GetPerformanceReport() performance.Report {
+	trades := performance.FromTrades(a.GetTradeHistory())
+	equity := performance.FromEquityPoints(a.GetEquityCurve(0, 0, 0))
+	return performance.Analyze(trades, equity, performance.Config{InitialCapital: a.cfg.InitialBalance})
+}
+
+// GetExcursionAnalysis returns MAE/MFE statistics of closed trades and the
+// stop/target distances that would have performed best
+func (a *App) GetExcursionAnalysis() trading.ExcursionReport {
+	return trading.AnalyzeExcursions(a.GetTradeHistory())
+}
+
+// GetAttributionReport breaks the closed trades of the main engine and the
+// bot down by strategy, account, signal model and contributing indicator
+func (a *App) GetAttributionReport() performance.AttributionReport {
+	var trades []trading.Trade
+	if a.tradingEngine != nil {
+		trades = append(trades, a.tradingEngine.GetTradeHistory()...)
+	}
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		trades = append(trades, autonomousBot.GetTradeHistory()...)
+	}
+	return performance.Attribute(trades, a.signalStore)
+}
+
+// GetSignalHistory returns stored signals for a symbol ("" = all) between
+// from and to (Unix milliseconds, 0 = unbounded), newest first
+func (a *App) GetSignalHistory(symbol string, from, to int64, limit int) []signals.Signal {
+	if a.signalStore == nil {
+		return []signals.Signal{}
+	}
+	var fromTime, toTime time.Time
+	if from > 0 {
+		fromTime = time.UnixMilli(from)
+	}
+	if to > 0 {
+		toTime = time.UnixMilli(to)
+	}
+	return a.signalStore.Query(symbol, fromTime, toTime, limit)
+}
+
+// GetSignalEdge reports hit rate and average forward return of labeled
+// signals for a symbol ("" = all) between from and to (Unix milliseconds,
+// 0 = unbounded), by direction, confidence bucket, indicator and model
+func (a *App) GetSignalEdge(symbol string, from, to int64) signals.EdgeReport {
+	var horizons []int
+	if a.signalLabeler != nil {
+		horizons = a.signalLabeler.Horizons()
+	}
+	return signals.AnalyzeEdge(a.GetSignalHistory(symbol, from, to, 0), horizons)
+}
+
+// GetSignalModels returns all registered signal models
+func (a *App) GetSignalModels() []signals.SignalModel {
+	if a.signalModels == nil {
+		return []signals.SignalModel{}
+	}
+	return a.signalModels.List()
+}
+
+// GetActiveSignalModel returns the model new signals are generated with
+func (a *App) GetActiveSignalModel() (signals.SignalModel, error) {
+	if a.signalModels == nil {
+		return signals.SignalModel{}, fmt.Errorf("signal models not initialized")
+	}
+	return *a.signalModels.Active(), nil
+}
+
+// SetSignalModel switches the signal model by "name" or "name@version".
+// Running bots use the new model from their next signal.
+func (a *App) SetSignalModel(id string) error {
+	if a.signalModels == nil {
+		return fmt.Errorf("signal models not initialized")
+	}
+	if err := a.signalModels.SetActive(id); err != nil {
+		return err
+	}
+	a.cfg.SignalModel = id
+	log.Infof("Signal model switched to %s", a.signalModels.Active().ID())
+	return nil
+}
+
+// LoadSignalModels registers the models of a JSON file ("" reloads the
+// configured file). Changed models must carry a new version.
+func (a *App) LoadSignalModels(path string) error {
+	if a.signalModels == nil {
+		return fmt.Errorf("signal models not initialized")
+	}
+	if path == "" {
+		path = a.cfg.SignalModelsFile
+	}
+	if path == "" {
+		return fmt.Errorf("no signal models file configured")
+	}
+	return a.signalModels.LoadFile(path)
+}
+
+// EmergencyStop halts new entries in every engine and strategy and closes
+// all open positions. Trading stays halted until ResetCircuitBreaker is called.
+func (a *App) EmergencyStop() error {
+	if a.breaker == nil {
+		return fmt.Errorf("circuit breaker not initialized")
+	}
+	log.Warn("=== EMERGENCY STOP REQUESTED ===")
+	a.breaker.Trip(risk.TripManual, "Emergency stop", true)
+	return nil
+}
+
+// ResetCircuitBreaker re-enables trading after a trip or an emergency stop
+func (a *App) ResetCircuitBreaker() error {
+	if a.breaker == nil {
+		return fmt.Errorf("circuit breaker not initialized")
+	}
+	a.breaker.Reset()
+	return nil
+}
+
+// GetCircuitBreakerState returns whether trading is halted and why
+func (a *App) GetCircuitBreakerState() risk.BreakerState {
+	if a.breaker == nil {
+		return risk.BreakerState{}
+	}
+	return a.breaker.State()
+}
+
+// GetPortfolioExposure returns gross, net, per-asset, per-sector and
+// correlated-cluster exposure of the main engine and the bot together, with
+// the correlation matrix of held symbols
+func (a *App) GetPortfolioExposure() risk.PortfolioExposure {
+	if a.portfolio == nil {
+		return risk.PortfolioExposure{}
+	}
+	var symbols []string
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		symbols = autonomousBot.GetConfig().Symbols
+	}
+	holdings, equity := a.portfolioHoldings()
+	return a.portfolio.Exposure(holdings, equity, symbols...)
+}
+
+// portfolioHoldings returns the open positions and the combined equity of
+// every engine sharing the portfolio limits
+func (a *App) portfolioHoldings() ([]risk.Holding, float64) {
+	var holdings []risk.Holding
+	var equity float64
+	if a.tradingEngine != nil {
+		holdings = append(holdings, a.tradingEngine.GetHoldings()...)
+		equity += a.tradingEngine.GetEquity()
+	}
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		holdings = append(holdings, autonomousBot.GetHoldings()...)
+		equity += autonomousBot.GetEquity()
+	}
+	return holdings, equity
+}
+
+// GetRiskRules returns the pre-trade rule order of an account ("main" or "bot")
+func (a *App) GetRiskRules(account string) ([]string, error) {
+	switch account {
+	case "main":
+		if a.tradingEngine == nil {
+			return nil, fmt.Errorf("trading engine not initialized")
+		}
+		return a.tradingEngine.GetRiskRules(), nil
+	case "bot":
+		autonomousBot := a.currentBot()
+		if autonomousBot == nil {
+			rules := a.cfg.RiskRulesFor("bot")
+			if len(rules) == 0 {
+				rules = risk.DefaultRiskRules()
+			}
+			return rules, nil
+		}
+		return autonomousBot.GetRiskRules(), nil
+	default:
+		return nil, fmt.Errorf("unknown account: %s", account)
+	}
+}
+
+// SetRiskRules sets the pre-trade rule order of an account ("main" or
+// "bot"). An empty list restores the built-in order.
+func (a *App) SetRiskRules(account string, rules []string) error {
+	switch account {
+	case "main":
+		if a.tradingEngine == nil {
+			return fmt.Errorf("trading engine not initialized")
+		}
+		if err := a.tradingEngine.SetRiskRules(rules); err != nil {
+			return err
+		}
+	case "bot":
+		if _, err := risk.NewRuleChain(rules, risk.RuleParams{Breaker: a.breaker, Portfolio: a.portfolio}, nil); err != nil {
+			return err
+		}
+		if autonomousBot := a.currentBot(); autonomousBot != nil {
+			if err := autonomousBot.SetRiskRules(rules); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown account: %s", account)
+	}
+
+	// Сохраняем порядок, чтобы перезапущенный бот получил те же правила
+	a.cfg.SetRiskRulesFor(account, rules)
+	return nil
+}
+
+// GetRiskDecisions returns the most recent pre-trade risk decisions, newest
+// first. An empty account returns decisions of all accounts.
+func (a *App) GetRiskDecisions(account string, limit int) []risk.RiskDecision {
+	if a.riskJournal == nil {
+		return []risk.RiskDecision{}
+	}
+	return a.riskJournal.Query(account, limit)
+}
+
+// ruinTolerance returns the configured risk of ruin tolerance.
+func (a *App) ruinTolerance() risk.RuinTolerance {
+	return risk.RuinTolerance{
+		MaxRiskPerTrade:        a.cfg.RuinMaxRiskPerTrade,
+		MaxRuinProbability:     a.cfg.RuinMaxProbability,
+		DrawdownLevel:          a.cfg.RuinDrawdownLevel,
+		MaxDrawdownProbability: a.cfg.RuinMaxDrawdownProbability,
+	}
+}
+
+// checkRuinTolerance logs a warning when the current risk settings exceed
+// the risk of ruin tolerance.
+func (a *App) checkRuinTolerance() {
+	report, err := a.GetRiskOfRuin(0, 0, 0, 0)
+	if err != nil || !report.ExceedsTolerance {
+		return
+	}
+	for _, warning := range report.Warnings {
+		log.Warnf("Risk of ruin: %s", warning)
+	}
+	if report.MaxSafeRiskPerTrade > 0 {
+		log.Warnf("Risk of ruin: risk per trade within tolerance is at most %.2f%%", report.MaxSafeRiskPerTrade*100)
+	}
+}
+
+// GetRiskOfRuin projects the probability of drawdowns and ruin over the
+// next trades. Zero arguments use the configured horizon and the rolling
+// statistics of the bot (or the main account when the bot is not running);
+// non-zero values override them for what-if analysis.
+func (a *App) GetRiskOfRuin(trades int, winRate, payoffRatio, riskPerTrade float64) (risk.RuinReport, error) {
+	if a.tradingEngine == nil {
+		return risk.RuinReport{}, fmt.Errorf("trading engine not initialized")
+	}
+
+	input := a.tradingEngine.RuinInput(a.cfg.RuinWindowTrades)
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		input = autonomousBot.RuinInput(a.cfg.RuinWindowTrades)
+	}
+	input.Trades = a.cfg.RuinHorizonTrades
+	input.RuinLevel = a.cfg.RuinLevel
+	if trades > 0 {
+		input.Trades = trades
+	}
+	if winRate > 0 || payoffRatio > 0 {
+		// Реализованные R-множители не соответствуют гипотетической статистике
+		input.RMultiples = nil
+	}
+	if winRate > 0 {
+		input.WinRate = winRate
+	}
+	if payoffRatio > 0 {
+		input.PayoffRatio = payoffRatio
+	}
+	if riskPerTrade > 0 {
+		input.RiskPerTrade = riskPerTrade
+	}
+	return risk.ProjectRuin(input, a.ruinTolerance()), nil
+}
+
+// GetPortfolioVaR returns historical, parametric and Monte Carlo VaR and
+// CVaR of the open positions of all engines. interval is the kline
+// timeframe ("" uses the configured one), horizonBars the holding period in
+// bars and confidenceLevels e.g. [0.95, 0.99] (empty uses 95% and 99%)
+func (a *App) GetPortfolioVaR(interval string, horizonBars int, confidenceLevels []float64) (risk.VaRReport, error) {
+	if a.tradingEngine == nil {
+		return risk.VaRReport{}, fmt.Errorf("trading engine not initialized")
+	}
+	if interval == "" {
+		interval = a.cfg.VaRInterval
+	}
+
+	holdings, equity := a.portfolioHoldings()
+	symbols := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		symbols = append(symbols, h.Symbol)
+	}
+	series := a.varSeries(interval, symbols)
+
+	return risk.ComputeVaR(holdings, series, equity, risk.VaRConfig{
+		ConfidenceLevels: confidenceLevels,
+		HorizonBars:      horizonBars,
+	}), nil
+}
+
+// varSeries loads kline closes of symbols at interval for VaR. Both the
+// VaR report and the pre-trade VaR check use it.
+func (a *App) varSeries(interval string, symbols []string) []risk.PriceSeries {
+	seen := make(map[string]bool)
+	series := make([]risk.PriceSeries, 0, len(symbols))
+	for _, symbol := range symbols {
+		if seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+
+		klines, err := a.klineCache.GetKlines(symbol, interval, a.cfg.VaRLookback)
+		if err != nil {
+			log.Warnf("VaR: failed to load klines for %s: %v", symbol, err)
+			continue
+		}
+		s := risk.PriceSeries{Symbol: symbol, Times: make([]int64, len(klines)), Closes: make([]float64, len(klines))}
+		for i, k := range klines {
+			s.Times[i] = k.OpenTime
+			s.Closes[i] = k.Close
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+// GetDailyHistory returns archived per-day trading statistics
+func (a *App) GetDailyHistory() []trading.DailySummary {
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		return autonomousBot.GetDailyHistory()
+	}
+	if a.tradingEngine == nil {
+		return []trading.DailySummary{}
+	}
+	return a.tradingEngine.GetDailyHistory()
+}
+
 // GetBalance returns current balance
 func (a *App) GetBalance() float64 {
 	if a.tradingEngine == nil {
@@ -301,9 +1327,33 @@ func (a *App) GetBalance() float64 {
 
 // GetSignalsList returns all current signals
 func (a *App) GetSignalsList() []*signals.Signal {
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		return autonomousBot.GetSignals()
+	}
 	return a.signalHandler.GetAllSignals()
 }
 
+// GetSignalSubscriptions returns delivered and dropped counts of every
+// signal stream subscriber
+func (a *App) GetSignalSubscriptions() []signals.SubscriptionStats {
+	stats := a.signalHandler.GetSubscriptions()
+	if autonomousBot := a.currentBot(); autonomousBot != nil {
+		stats = append(stats, autonomousBot.GetSignalSubscriptions()...)
+	}
+	return stats
+}
+
+// GetSignalDigests returns a one-line explanation of the latest signal of
+// every symbol and timeframe, suitable for notifications
+func (a *App) GetSignalDigests() []string {
+	sigs := a.GetSignalsList()
+	digests := make([]string, 0, len(sigs))
+	for _, sig := range sigs {
+		digests = append(digests, sig.Digest())
+	}
+	return digests
+}
+
 // GetSentimentScore returns current sentiment score
 func (a *App) GetSentimentScore() sentiment.SentimentScore {
 	return a.sentimentManager.GetScore()
@@ -713,6 +1763,13 @@ func (a *App) StartIntervalStrategy(config interval.IntervalConfig) error {
 		return err
 	}
 	
+	if config.RegimeGate != nil {
+		if err := config.RegimeGate.Validate(); err != nil {
+			log.Errorf("Failed to start interval strategy: %v", err)
+			return err
+		}
+	}
+
 	// Устанавливаем символ в конфигурацию для обратной совместимости
 	if len(config.Symbols) == 0 {
 		config.Symbols = []string{symbol}
@@ -773,11 +1830,52 @@ func (a *App) StopIntervalStrategy() {
 	}
 }
 
+// StartRuleStrategy starts a rule strategy from its JSON or YAML definition,
+// replacing a running strategy with the same name
+func (a *App) StartRuleStrategy(definition string) error {
+	if a.ruleRunner == nil {
+		return fmt.Errorf("rule strategies are not initialized")
+	}
+	def, err := rules.ParseDefinitionText([]byte(definition))
+	if err != nil {
+		return err
+	}
+	return a.ruleRunner.Start(def)
+}
+
+// StopRuleStrategy stops a rule strategy; its open positions stay open
+func (a *App) StopRuleStrategy(name string) error {
+	if a.ruleRunner == nil || !a.ruleRunner.Stop(name) {
+		return fmt.Errorf("rule strategy %s is not running", name)
+	}
+	return nil
+}
+
+// GetRuleStrategies returns the status of the running rule strategies
+func (a *App) GetRuleStrategies() []rules.Status {
+	if a.ruleRunner == nil {
+		return []rules.Status{}
+	}
+	return a.ruleRunner.Statuses()
+}
+
+// ValidateRuleStrategy checks a JSON or YAML definition without starting it
+func (a *App) ValidateRuleStrategy(definition string) error {
+	_, err := rules.ParseDefinitionText([]byte(definition))
+	return err
+}
+
+// GetRuleIdentifiers returns the identifiers rule expressions can use
+func (a *App) GetRuleIdentifiers() []string {
+	return rules.Identifiers()
+}
+
 // GetIntervalStats возвращает статистику интервальной торговли
 func (a *App) GetIntervalStats() interval.IntervalStats {
 	if a.intervalStrategy == nil {
 		return interval.IntervalStats{
 			ActiveIntervals: make(map[string]interval.PriceInterval),
+			Regimes:         make(map[string]indicators.Regime),
 		}
 	}
 	return a.intervalStrategy.GetStats()
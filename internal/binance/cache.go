@@ -0,0 +1,68 @@
+package binance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// KlineCache caches REST kline responses per symbol and interval so that
+// analytics such as VaR do not hit the API on every request.
+type KlineCache struct {
+	client  *Client
+	ttl     time.Duration
+	entries map[string]klineCacheEntry
+	mu      sync.Mutex
+}
+
+type klineCacheEntry struct {
+	klines    []Kline
+	fetchedAt time.Time
+}
+
+// NewKlineCache creates a cache in front of client. Entries older than ttl
+// are refetched.
+func NewKlineCache(client *Client, ttl time.Duration) *KlineCache {
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	return &KlineCache{
+		client:  client,
+		ttl:     ttl,
+		entries: make(map[string]klineCacheEntry),
+	}
+}
+
+// GetKlines returns at least limit klines (if available) for symbol and
+// interval, from the cache when fresh.
+func (kc *KlineCache) GetKlines(symbol, interval string, limit int) ([]Kline, error) {
+	key := fmt.Sprintf("%s:%s", symbol, interval)
+
+	kc.mu.Lock()
+	entry, ok := kc.entries[key]
+	kc.mu.Unlock()
+
+	if ok && time.Since(entry.fetchedAt) < kc.ttl && len(entry.klines) >= limit {
+		return tailKlines(entry.klines, limit), nil
+	}
+
+	klines, err := kc.client.GetKlines(symbol, interval, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	kc.mu.Lock()
+	kc.entries[key] = klineCacheEntry{klines: klines, fetchedAt: time.Now()}
+	kc.mu.Unlock()
+
+	return tailKlines(klines, limit), nil
+}
+
+func tailKlines(klines []Kline, limit int) []Kline {
+	if limit > 0 && len(klines) > limit {
+		klines = klines[len(klines)-limit:]
+	}
+	result := make([]Kline, len(klines))
+	copy(result, klines)
+	return result
+}
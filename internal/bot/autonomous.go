@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"fmt"
 	"strconv"
 	"strings"
 	"sync"
@@ -12,6 +13,7 @@ import (
 	"crypto-trading-bot/internal/binance"
 	"crypto-trading-bot/internal/indicators"
 	"crypto-trading-bot/internal/signals"
+	"crypto-trading-bot/internal/risk"
 	"crypto-trading-bot/internal/trading"
 )
 
@@ -21,11 +23,13 @@ type AutonomousBot struct {
 	binanceWS      *binance.WSClient
 	indicatorMgr   *indicators.IndicatorManager
 	signalHandler  *signals.SignalHandler
+	signalModels   *signals.ModelRegistry
 	tradingEngine  *trading.TradingEngine
 
 	isRunning bool
 	stopChan  chan struct{}
 	mu        sync.RWMutex
+	tradeMu   sync.Mutex // Serializes symbol switches and entries of the trading engine
 
 	lastPrices    map[string]float64
 	candleBuffers map[string][]binance.Kline
@@ -42,6 +46,20 @@ type BotConfig struct {
 	CooldownMinutes int
 	MLServiceAddr   string
 	EnableSentiment bool
+	Session         *trading.SessionConfig
+	CircuitBreaker  *risk.CircuitBreaker // Shared kill switch; nil gives the bot its own
+	Sizing          *risk.SizingConfig   // Position sizing model; nil means fixed fractional
+	Portfolio       *risk.PortfolioRisk  // Shared portfolio exposure limits; nil gives the bot its own
+	PortfolioHoldings func() ([]risk.Holding, float64) // Holdings and equity of all accounts sharing Portfolio; nil covers the bot only
+	VaRLimit        *risk.VaRLimit       // Optional pre-trade VaR check
+	RiskRules       []string             // Pre-trade rule order for the bot account; empty uses the defaults
+	MaxOpenPositions int                 // Max simultaneous open positions (0 = no limit)
+	DecisionJournal *risk.DecisionJournal // Shared log of risk decisions
+	ExitPolicies    map[string][]trading.ExitPolicyConfig // Exit policies per strategy; nil uses the defaults
+	Confluence      *signals.ConfluenceConfig // Multi-timeframe consolidation; nil trades the newest signal of any timeframe
+	RegimeThresholds *indicators.RegimeThresholds // Market regime classifier; nil uses the defaults
+	RegimeGate      *indicators.RegimeGate    // Regimes the bot trades in; nil trades in any regime
+	IndicatorSpecs  map[string][]indicators.Spec // Indicators added to the default preset by symbol ("" = all symbols)
 }
 
 func NewAutonomousBot(config *BotConfig) *AutonomousBot {
@@ -59,6 +77,17 @@ func NewAutonomousBotWithWS(config *BotConfig, wsClient *binance.WSClient) *Auto
 		MinConfidence:     config.MinConfidence,
 		MaxDailyTrades:    config.MaxDailyTrades,
 		CooldownMinutes:   config.CooldownMinutes,
+		Account:           "bot",
+		Session:           config.Session,
+		CircuitBreaker:    config.CircuitBreaker,
+		Sizing:            config.Sizing,
+		Portfolio:         config.Portfolio,
+		PortfolioHoldings: config.PortfolioHoldings,
+		VaRLimit:          config.VaRLimit,
+		RiskRules:         config.RiskRules,
+		MaxOpenPositions:  config.MaxOpenPositions,
+		DecisionJournal:   config.DecisionJournal,
+		ExitPolicies:      config.ExitPolicies,
 	}
 
 	// Используем переданный WebSocket клиент или создаем новый
@@ -66,12 +95,25 @@ func NewAutonomousBotWithWS(config *BotConfig, wsClient *binance.WSClient) *Auto
 		wsClient = binance.NewWSClient()
 	}
 
+	indicatorMgr := indicators.NewIndicatorManager()
+	if config.RegimeThresholds != nil {
+		if err := indicatorMgr.SetRegimeThresholds(*config.RegimeThresholds); err != nil {
+			log.Warnf("Invalid regime thresholds, using defaults: %v", err)
+		}
+	}
+	for symbol, specs := range config.IndicatorSpecs {
+		if err := indicatorMgr.SetSpecs(symbol, specs); err != nil {
+			log.Warnf("Invalid indicator specs for %q, ignoring: %v", symbol, err)
+		}
+	}
+
 	return &AutonomousBot{
 		config:         config,
 		binanceClient:  binance.NewClient(),
 		binanceWS:      wsClient,
-		indicatorMgr:   indicators.NewIndicatorManager(),
+		indicatorMgr:   indicatorMgr,
 		signalHandler:  signals.NewSignalHandler(),
+		signalModels:   signals.NewModelRegistry(),
 		tradingEngine:  trading.NewTradingEngine(engineConfig),
 		lastPrices:     make(map[string]float64),
 		candleBuffers:  make(map[string][]binance.Kline),
@@ -160,10 +202,19 @@ func (bot *AutonomousBot) loadHistoricalData() error {
 			key := symbol + ":" + tf
 			bot.candleBuffers[key] = klines
 
+			// Прогреваем ряды доходностей для оценки корреляций между символами
+			closeTimes := make([]time.Time, len(klines))
+			closes := make([]float64, len(klines))
+			for i, k := range klines {
+				closeTimes[i] = time.UnixMilli(k.CloseTime)
+				closes[i] = k.Close
+			}
+			bot.tradingEngine.LoadPriceHistory(symbol, closeTimes, closes)
+
 			indicatorSet := bot.indicatorMgr.GetOrCreate(symbol, tf)
 			var lastValues *indicators.IndicatorValues
 			for _, k := range klines {
-				lastValues = indicatorSet.UpdateAll(k.High, k.Low, k.Close, k.Volume)
+				lastValues = indicatorSet.UpdateAllAt(k.OpenTime, k.High, k.Low, k.Close, k.Volume)
 				// Update last price
 				bot.lastPrices[symbol] = k.Close
 			}
@@ -224,6 +275,7 @@ func (bot *AutonomousBot) processKline(symbol, timeframe string, msg *binance.Kl
 
 	// ВСЕГДА обновляем цену, даже для промежуточных свечей
 	bot.signalHandler.UpdatePrice(symbol, close)
+	bot.tradingEngine.UpdatePrice(symbol, close)
 	bot.lastPrices[symbol] = close
 
 	log.Infof("📊 PROCESSING KLINE: %s %s | IsFinal=%v | OHLCV: O=%.8f H=%.8f L=%.8f C=%.8f V=%.2f | Price updated: %.8f",
@@ -239,7 +291,7 @@ func (bot *AutonomousBot) processKline(symbol, timeframe string, msg *binance.Kl
 	log.Infof("✅ FINAL CANDLE received for %s %s", symbol, timeframe)
 
 	indicatorSet := bot.indicatorMgr.GetOrCreate(symbol, timeframe)
-	values := indicatorSet.UpdateAll(high, low, close, volume)
+	values := indicatorSet.UpdateAllAt(msg.Kline.StartTime, high, low, close, volume)
 
 	if values != nil {
 		log.Debugf("Indicators updated: RSI=%.2f, MACD_Line=%.2f, MACD_Signal=%.2f, ATR14=%.2f, BB_Upper=%.2f, BB_Lower=%.2f",
@@ -301,16 +353,14 @@ func (bot *AutonomousBot) generateSignal(symbol, timeframe string, indicatorValu
 		log.Debugf("No technical signals generated for %s %s", symbol, timeframe)
 	}
 	
-	techScore := signals.CalculateTechnicalScore(techSignals)
-	log.Debugf("Technical score for %s %s: %.4f", symbol, timeframe, techScore)
-
 	mlScore := 0.0
 	sentimentScore := 0.0
 
-	combinedSignal := signals.CombineSignals(techScore, mlScore, sentimentScore)
-	combinedSignal.Symbol = symbol
-	combinedSignal.Timeframe = timeframe
-	combinedSignal.Price = currentPrice
+	regime := indicatorSet.Regime()
+	model := bot.signalModels.Active()
+	combinedSignal := model.Generate(symbol, timeframe, currentPrice, techSignals, mlScore, sentimentScore, &regime)
+	techScore := combinedSignal.TechnicalSignal
+	log.Debugf("Technical score for %s %s: %.4f (model %s)", symbol, timeframe, techScore, model.ID())
 
 	if indicatorValues != nil {
 		combinedSignal.ATR = indicatorValues.ATR14
@@ -321,7 +371,9 @@ func (bot *AutonomousBot) generateSignal(symbol, timeframe string, indicatorValu
 	log.Infof("Direction: %s, Confidence: %.2f", combinedSignal.Direction, combinedSignal.Confidence)
 	log.Infof("Technical Score: %.2f, ML Score: %.2f, Sentiment Score: %.2f", techScore, mlScore, sentimentScore)
 	log.Infof("Price: %.8f, ATR: %.8f", currentPrice, combinedSignal.ATR)
+	log.Infof("Regime: %s", regime.String())
 	log.Infof("Signal ID: %s", combinedSignal.ID)
+	log.Infof("Why: %s", combinedSignal.Explanation.Digest)
 	
 	// Log technical signals details
 	if len(techSignals) > 0 {
@@ -335,6 +387,7 @@ func (bot *AutonomousBot) generateSignal(symbol, timeframe string, indicatorValu
 	
 	// Always update signal, even if HOLD - this ensures signal handler has latest data
 	bot.signalHandler.UpdateSignal(combinedSignal)
+	bot.updateConfluence(symbol)
 	
 	// Verify signal was saved
 	savedSignal := bot.signalHandler.GetSignal(symbol, timeframe)
@@ -355,6 +408,42 @@ func (bot *AutonomousBot) generateSignal(symbol, timeframe string, indicatorValu
 	log.Info("=== SIGNAL GENERATION COMPLETE ===")
 }
 
+// updateConfluence consolidates the symbol's latest signals of all
+// timeframes into one multi-timeframe signal, if confluence is enabled.
+func (bot *AutonomousBot) updateConfluence(symbol string) {
+	// Без bot.mu: вызывается и из Start, который держит блокировку
+	cfg := bot.config.Confluence
+	timeframes := bot.config.Timeframes
+	if cfg == nil {
+		return
+	}
+
+	byTimeframe := make(map[string]*signals.Signal, len(timeframes))
+	for _, tf := range timeframes {
+		if sig := bot.signalHandler.GetSignal(symbol, tf); sig != nil {
+			byTimeframe[tf] = sig
+		}
+	}
+
+	consolidated := signals.Confluence(symbol, byTimeframe, cfg, time.Now())
+	if consolidated == nil {
+		return
+	}
+	bot.signalHandler.UpdateSignal(consolidated)
+	log.Infof("Confluence signal for %s: %s (confidence %.2f%%) - %s",
+		symbol, consolidated.Direction, consolidated.Confidence*100, strings.Join(consolidated.Reasons, "; "))
+}
+
+// latestSignal returns the signal to trade for a symbol: the consolidated
+// multi-timeframe one when confluence is enabled, otherwise the newest
+// signal of any timeframe.
+func (bot *AutonomousBot) latestSignal(symbol string) *signals.Signal {
+	if bot.config.Confluence != nil {
+		return bot.signalHandler.GetSignal(symbol, signals.ConfluenceTimeframe)
+	}
+	return bot.signalHandler.GetLatestSignalForSymbol(symbol)
+}
+
 func (bot *AutonomousBot) mainLoop(ctx context.Context) {
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
@@ -394,6 +483,7 @@ func (bot *AutonomousBot) updatePricesViaREST() {
 		// Обновляем цену только если она изменилась
 		if oldPrice == 0 || currentPrice != oldPrice {
 			bot.signalHandler.UpdatePrice(symbol, currentPrice)
+			bot.tradingEngine.UpdatePrice(symbol, currentPrice)
 			bot.lastPrices[symbol] = currentPrice
 			if oldPrice != 0 {
 				log.Debugf("💰 Price updated via REST API: %s %.8f -> %.8f (change: %.2f%%)",
@@ -407,7 +497,7 @@ func (bot *AutonomousBot) processSignals() {
 	// Обрабатываем сигналы для всех символов из конфигурации
 	for _, symbol := range bot.config.Symbols {
 		// Получаем последний сигнал для символа
-		latestSignal := bot.signalHandler.GetLatestSignalForSymbol(symbol)
+		latestSignal := bot.latestSignal(symbol)
 		if latestSignal == nil {
 			log.Debugf("No signal found for symbol %s - generating signal now", symbol)
 			// Try to generate signal immediately if we have enough data
@@ -421,11 +511,9 @@ func (bot *AutonomousBot) processSignals() {
 			if candleCount >= 60 && currentPrice > 0 {
 				indicatorSet := bot.indicatorMgr.GetOrCreate(symbol, timeframe)
 				techSignals := indicatorSet.GetSignals(currentPrice)
-				techScore := signals.CalculateTechnicalScore(techSignals)
-				combinedSignal := signals.CombineSignals(techScore, 0.0, 0.0)
-				combinedSignal.Symbol = symbol
-				combinedSignal.Timeframe = timeframe
-				combinedSignal.Price = currentPrice
+				regime := indicatorSet.Regime()
+				combinedSignal := bot.signalModels.Active().Generate(symbol, timeframe, currentPrice, techSignals, 0.0, 0.0, &regime)
+				techScore := combinedSignal.TechnicalSignal
 				
 				// Get ATR from indicators
 				indicatorValues := indicatorSet.UpdateAll(
@@ -439,7 +527,8 @@ func (bot *AutonomousBot) processSignals() {
 				}
 				
 				bot.signalHandler.UpdateSignal(combinedSignal)
-				latestSignal = combinedSignal
+				bot.updateConfluence(symbol)
+				latestSignal = bot.latestSignal(symbol)
 				log.Infof("✅ Generated signal on-demand: %s with confidence %.2f%% (techScore=%.4f)", 
 					combinedSignal.Direction, combinedSignal.Confidence*100, techScore)
 			} else {
@@ -451,65 +540,80 @@ func (bot *AutonomousBot) processSignals() {
 			}
 		}
 
-		// Проверяем уверенность сигнала (MinConfidence может быть 0.0 для скальпинга)
-		// Логируем детально для диагностики
-		if bot.config.MinConfidence > 0 && latestSignal.Confidence < bot.config.MinConfidence {
-			log.Warnf("⚠️ Signal confidence %.2f below minimum %.2f for %s - SKIPPING", 
-				latestSignal.Confidence, bot.config.MinConfidence, symbol)
-			log.Warnf("   Signal details: Direction=%s, TechnicalScore=%.4f, Price=%.2f", 
-				latestSignal.Direction, latestSignal.TechnicalSignal, latestSignal.Price)
-			continue
-		}
-		
-		// Логируем успешную проверку уверенности
-		log.Infof("✅ Signal passed confidence check: %.2f >= %.2f for %s", 
-			latestSignal.Confidence, bot.config.MinConfidence, symbol)
-		
-		// Skip HOLD signals
-		if latestSignal.Direction == "HOLD" {
-			log.Debugf("Signal is HOLD for %s, skipping", symbol)
-			continue
-		}
+		bot.tradeSignal(symbol, latestSignal)
+	}
+}
 
-		// Обновляем символ в trading engine если нужно
-		bot.mu.RLock()
-		currentSymbol := bot.tradingEngine.GetSymbol()
-		bot.mu.RUnlock()
-
-		if currentSymbol != symbol {
-			// Обновляем конфигурацию trading engine для нового символа
-			engineConfig := &trading.EngineConfig{
-				Symbol:            symbol,
-				InitialBalance:    bot.config.InitialBalance,
-				MaxPositionSize:   bot.config.MaxPositionSize,
-				RiskPerTrade:      bot.config.RiskPerTrade,
-				DefaultStopLoss:   0.01,   // Tighter stop for scalping (1% instead of 2%)
-				DefaultTakeProfit: 0.02,   // Smaller target for scalping (2% instead of 4%)
-				MinConfidence:     bot.config.MinConfidence,
-				MaxDailyTrades:    bot.config.MaxDailyTrades,
-				CooldownMinutes:   bot.config.CooldownMinutes,
-			}
-			bot.tradingEngine.UpdateConfig(engineConfig)
-			log.Infof("Updated trading engine config for symbol %s", symbol)
+// tradeSignal hands a symbol's signal to the trading engine, switching the
+// engine to the symbol first.
+func (bot *AutonomousBot) tradeSignal(symbol string, latestSignal *signals.Signal) {
+	bot.tradeMu.Lock()
+	defer bot.tradeMu.Unlock()
+
+	// MinConfidence и остальные лимиты проверяет цепочка риск-правил trading engine
+
+	// Skip HOLD signals
+	if latestSignal.Direction == "HOLD" {
+		log.Debugf("Signal is HOLD for %s, skipping", symbol)
+		return
+	}
+
+	if latestSignal.Regime != nil {
+		if ok, reason := bot.config.RegimeGate.Permits(*latestSignal.Regime); !ok {
+			log.Infof("Signal %s for %s skipped: %s", latestSignal.Direction, symbol, reason)
+			return
 		}
+	}
 
-		// Trading engine обработает сигнал в своем mainLoop
-		// Но мы можем также обработать его здесь для более быстрой реакции
-		log.Infof("=== PROCESSING SIGNAL FOR TRADING ===")
-		log.Infof("Symbol: %s, Direction: %s, Confidence: %.2f, Price: %.2f", 
-			symbol, latestSignal.Direction, latestSignal.Confidence, latestSignal.Price)
-		log.Infof("Min Confidence Required: %.2f", bot.config.MinConfidence)
-		
-		// Проверяем текущий символ в trading engine
-		bot.mu.RLock()
-		engineSymbol := bot.tradingEngine.GetSymbol()
-		bot.mu.RUnlock()
-		log.Infof("Trading engine symbol: %s, Signal symbol: %s", engineSymbol, symbol)
-		
-		log.Infof("Calling bot.tradingEngine.ProcessSignal()...")
-		bot.tradingEngine.ProcessSignal(latestSignal)
-		log.Info("=== SIGNAL PROCESSING COMPLETE ===")
+	// Обновляем символ в trading engine если нужно
+	bot.mu.RLock()
+	currentSymbol := bot.tradingEngine.GetSymbol()
+	bot.mu.RUnlock()
+
+	if currentSymbol != symbol {
+		// Обновляем конфигурацию trading engine для нового символа
+		engineConfig := &trading.EngineConfig{
+			Symbol:            symbol,
+			InitialBalance:    bot.config.InitialBalance,
+			MaxPositionSize:   bot.config.MaxPositionSize,
+			RiskPerTrade:      bot.config.RiskPerTrade,
+			DefaultStopLoss:   0.01,   // Tighter stop for scalping (1% instead of 2%)
+			DefaultTakeProfit: 0.02,   // Smaller target for scalping (2% instead of 4%)
+			MinConfidence:     bot.config.MinConfidence,
+			MaxDailyTrades:    bot.config.MaxDailyTrades,
+			CooldownMinutes:   bot.config.CooldownMinutes,
+			Account:           "bot",
+			Session:           bot.config.Session,
+			CircuitBreaker:    bot.config.CircuitBreaker,
+			Sizing:            bot.config.Sizing,
+			Portfolio:         bot.config.Portfolio,
+			PortfolioHoldings: bot.config.PortfolioHoldings,
+			VaRLimit:          bot.config.VaRLimit,
+			RiskRules:         bot.config.RiskRules,
+			MaxOpenPositions:  bot.config.MaxOpenPositions,
+			DecisionJournal:   bot.config.DecisionJournal,
+			ExitPolicies:      bot.config.ExitPolicies,
+		}
+		bot.tradingEngine.UpdateConfig(engineConfig)
+		log.Infof("Updated trading engine config for symbol %s", symbol)
 	}
+
+	// Trading engine обработает сигнал в своем mainLoop
+	// Но мы можем также обработать его здесь для более быстрой реакции
+	log.Infof("=== PROCESSING SIGNAL FOR TRADING ===")
+	log.Infof("Symbol: %s, Direction: %s, Confidence: %.2f, Price: %.2f", 
+		symbol, latestSignal.Direction, latestSignal.Confidence, latestSignal.Price)
+	log.Infof("Min Confidence Required: %.2f", bot.config.MinConfidence)
+	
+	// Проверяем текущий символ в trading engine
+	bot.mu.RLock()
+	engineSymbol := bot.tradingEngine.GetSymbol()
+	bot.mu.RUnlock()
+	log.Infof("Trading engine symbol: %s, Signal symbol: %s", engineSymbol, symbol)
+	
+	log.Infof("Calling bot.tradingEngine.ProcessSignal()...")
+	bot.tradingEngine.ProcessSignal(latestSignal)
+	log.Info("=== SIGNAL PROCESSING COMPLETE ===")
 }
 
 func (bot *AutonomousBot) updatePositions() {
@@ -545,6 +649,159 @@ func (bot *AutonomousBot) GetTradeHistory() []trading.Trade {
 	return bot.tradingEngine.GetTradeHistory()
 }
 
+func (bot *AutonomousBot) GetEquityCurve(from, to int64, maxPoints int) []trading.EquityPoint {
+	return bot.tradingEngine.GetEquityCurve(from, to, maxPoints)
+}
+
+// SetSignalStore persists every signal generated by the bot to store.
+func (bot *AutonomousBot) SetSignalStore(store *signals.SignalStore) {
+	bot.signalHandler.SetStore(store)
+}
+
+// SetSignalValidity sets how long the bot's signals stay actionable, in
+// candles of their timeframe or fallback for unknown timeframes.
+func (bot *AutonomousBot) SetSignalValidity(candles float64, fallback time.Duration) {
+	bot.signalHandler.SetValidity(candles, fallback)
+}
+
+// SubscribeSignals returns a filtered stream of the bot's signals.
+func (bot *AutonomousBot) SubscribeSignals(options signals.SubscriptionOptions) *signals.Subscription {
+	return bot.signalHandler.Subscribe(options)
+}
+
+// OnTradeEvent registers a callback for the positions the bot opens,
+// reduces and closes, see trading.PaperTrader.OnTradeEvent.
+func (bot *AutonomousBot) OnTradeEvent(key string, fn func(trading.TradeEvent)) {
+	bot.tradingEngine.OnTradeEvent(key, fn)
+}
+
+// GetSignalSubscriptions returns delivery counters of the bot's signal
+// subscriptions.
+func (bot *AutonomousBot) GetSignalSubscriptions() []signals.SubscriptionStats {
+	return bot.signalHandler.GetSubscriptions()
+}
+
+// GetSignals returns the latest signal of every symbol and timeframe.
+func (bot *AutonomousBot) GetSignals() []*signals.Signal {
+	return bot.signalHandler.GetAllSignals()
+}
+
+// IngestSignal records an external signal, e.g. a webhook alert, and
+// trades it right away under the bot's risk rules. External signals bypass
+// confluence and regime gating and are kept apart from the bot's own
+// timeframe signals.
+func (bot *AutonomousBot) IngestSignal(signal *signals.Signal) error {
+	bot.mu.RLock()
+	running := bot.isRunning
+	symbols := bot.config.Symbols
+	bot.mu.RUnlock()
+
+	if !running {
+		return fmt.Errorf("bot is not running")
+	}
+	traded := false
+	for _, symbol := range symbols {
+		if symbol == signal.Symbol {
+			traded = true
+			break
+		}
+	}
+	if !traded {
+		return fmt.Errorf("symbol %s is not traded by the bot", signal.Symbol)
+	}
+
+	bot.signalHandler.UpdateExternalSignal(signal)
+	bot.tradeSignal(signal.Symbol, signal)
+	return nil
+}
+
+// GetRegime returns the market regime of a symbol/timeframe; false if the
+// bot has no candles for it.
+func (bot *AutonomousBot) GetRegime(symbol, timeframe string) (indicators.Regime, bool) {
+	return bot.indicatorMgr.Regime(symbol, timeframe)
+}
+
+// GetRegimes returns the market regime of every symbol/timeframe keyed by
+// "symbol:timeframe".
+func (bot *AutonomousBot) GetRegimes() map[string]indicators.Regime {
+	return bot.indicatorMgr.Regimes()
+}
+
+// SetIndicatorSpecs selects the indicators added to the default preset for
+// symbol ("" = all symbols without their own); nil removes the selection.
+func (bot *AutonomousBot) SetIndicatorSpecs(symbol string, specs []indicators.Spec) error {
+	if err := bot.indicatorMgr.SetSpecs(symbol, specs); err != nil {
+		return err
+	}
+
+	bot.mu.Lock()
+	defer bot.mu.Unlock()
+	updated := make(map[string][]indicators.Spec, len(bot.config.IndicatorSpecs)+1)
+	for s, list := range bot.config.IndicatorSpecs {
+		updated[s] = list
+	}
+	if specs == nil {
+		delete(updated, symbol)
+	} else {
+		updated[symbol] = specs
+	}
+	bot.config.IndicatorSpecs = updated
+	return nil
+}
+
+// SetSignalModels makes the bot generate signals with the active model of
+// registry.
+func (bot *AutonomousBot) SetSignalModels(registry *signals.ModelRegistry) {
+	bot.signalModels = registry
+}
+
+// GetCircuitBreaker returns the circuit breaker guarding the bot's engine.
+func (bot *AutonomousBot) GetCircuitBreaker() *risk.CircuitBreaker {
+	return bot.tradingEngine.GetCircuitBreaker()
+}
+
+// GetPortfolioExposure returns the exposure of the bot's positions against
+// the portfolio limits.
+func (bot *AutonomousBot) GetPortfolioExposure() risk.PortfolioExposure {
+	return bot.tradingEngine.GetPortfolioExposure(bot.config.Symbols...)
+}
+
+// SetRiskRules replaces the pre-trade rule order of the bot account.
+func (bot *AutonomousBot) SetRiskRules(names []string) error {
+	if err := bot.tradingEngine.SetRiskRules(names); err != nil {
+		return err
+	}
+	bot.mu.Lock()
+	bot.config.RiskRules = append([]string(nil), names...)
+	bot.mu.Unlock()
+	return nil
+}
+
+// GetRiskRules returns the bot's rule names in evaluation order.
+func (bot *AutonomousBot) GetRiskRules() []string {
+	return bot.tradingEngine.GetRiskRules()
+}
+
+// GetHoldings returns the bot's open positions as signed notionals.
+func (bot *AutonomousBot) GetHoldings() []risk.Holding {
+	return bot.tradingEngine.GetHoldings()
+}
+
+// GetEquity returns the marked equity of the bot's account.
+func (bot *AutonomousBot) GetEquity() float64 {
+	return bot.tradingEngine.GetEquity()
+}
+
+// RuinInput returns the bot's realized statistics for a risk of ruin
+// projection over the last window trades (0 = all).
+func (bot *AutonomousBot) RuinInput(window int) risk.RuinInput {
+	return bot.tradingEngine.RuinInput(window)
+}
+
+func (bot *AutonomousBot) GetDailyHistory() []trading.DailySummary {
+	return bot.tradingEngine.GetDailyHistory()
+}
+
 func (bot *AutonomousBot) GetConfig() *BotConfig {
 	bot.mu.RLock()
 	defer bot.mu.RUnlock()
@@ -555,6 +812,23 @@ func (bot *AutonomousBot) UpdateConfig(newConfig *BotConfig) {
 	bot.mu.Lock()
 	defer bot.mu.Unlock()
 	bot.config = newConfig
+	thresholds := indicators.DefaultRegimeThresholds()
+	if newConfig.RegimeThresholds != nil {
+		thresholds = *newConfig.RegimeThresholds
+	}
+	if err := bot.indicatorMgr.SetRegimeThresholds(thresholds); err != nil {
+		log.Warnf("Invalid regime thresholds, keeping previous: %v", err)
+	}
+	for symbol := range bot.indicatorMgr.Specs() {
+		if _, ok := newConfig.IndicatorSpecs[symbol]; !ok {
+			bot.indicatorMgr.SetSpecs(symbol, nil)
+		}
+	}
+	for symbol, specs := range newConfig.IndicatorSpecs {
+		if err := bot.indicatorMgr.SetSpecs(symbol, specs); err != nil {
+			log.Warnf("Invalid indicator specs for %q, keeping previous: %v", symbol, err)
+		}
+	}
 	// Обновляем конфигурацию trading engine
 	engineConfig := &trading.EngineConfig{
 		Symbol:            newConfig.Symbols[0],
@@ -566,6 +840,17 @@ func (bot *AutonomousBot) UpdateConfig(newConfig *BotConfig) {
 		MinConfidence:     newConfig.MinConfidence,
 		MaxDailyTrades:    newConfig.MaxDailyTrades,
 		CooldownMinutes:   newConfig.CooldownMinutes,
+		Account:           "bot",
+		Session:           newConfig.Session,
+		CircuitBreaker:    newConfig.CircuitBreaker,
+		Sizing:            newConfig.Sizing,
+		Portfolio:         newConfig.Portfolio,
+		PortfolioHoldings: newConfig.PortfolioHoldings,
+		VaRLimit:          newConfig.VaRLimit,
+		RiskRules:         newConfig.RiskRules,
+		MaxOpenPositions:  newConfig.MaxOpenPositions,
+		DecisionJournal:   newConfig.DecisionJournal,
+		ExitPolicies:      newConfig.ExitPolicies,
 	}
 	bot.tradingEngine.UpdateConfig(engineConfig)
 }
@@ -0,0 +1,193 @@
+package broadcast
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"crypto-trading-bot/internal/signals"
+	"crypto-trading-bot/internal/trading"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Config configures the broadcaster. Without targets and a feed nothing is
+// published.
+type Config struct {
+	Targets []Target    `json:"targets"`
+	Feed    *FeedConfig `json:"feed"` // nil disables the WebSocket feed
+}
+
+// Stats reports the broadcaster's counters.
+type Stats struct {
+	Running   bool          `json:"running"`
+	Published int64         `json:"published"`
+	Following []string      `json:"following"` // Keys of the followed signal streams
+	Targets   []TargetStats `json:"targets"`
+	Feed      *FeedStats    `json:"feed"`
+}
+
+// Broadcaster fans signals and trade events out to the webhook targets and
+// the feed. Publishing never blocks the trading code: each target has its
+// own queue and slow feed clients are disconnected.
+type Broadcaster struct {
+	senders   []*webhookSender
+	feed      *Feed
+	follows   map[string]*signals.Subscription
+	published atomic.Int64
+	running   bool
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	mu        sync.Mutex
+}
+
+// NewBroadcaster creates a broadcaster; call Start to begin delivery.
+func NewBroadcaster(config Config) (*Broadcaster, error) {
+	b := &Broadcaster{follows: make(map[string]*signals.Subscription)}
+	for i := range config.Targets {
+		target := config.Targets[i]
+		if err := target.Validate(); err != nil {
+			return nil, err
+		}
+		b.senders = append(b.senders, newWebhookSender(target))
+	}
+	if config.Feed != nil {
+		feed, err := NewFeed(*config.Feed)
+		if err != nil {
+			return nil, err
+		}
+		b.feed = feed
+	}
+	return b, nil
+}
+
+// Start starts the webhook workers and the feed server.
+func (b *Broadcaster) Start() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.running {
+		return nil
+	}
+	if b.feed != nil {
+		if err := b.feed.Start(); err != nil {
+			return fmt.Errorf("failed to start broadcast feed: %w", err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	for _, sender := range b.senders {
+		b.wg.Add(1)
+		go func(sender *webhookSender) {
+			defer b.wg.Done()
+			sender.run(ctx)
+		}(sender)
+	}
+	b.running = true
+
+	log.Infof("Broadcaster started: %d webhook target(s), feed enabled: %v", len(b.senders), b.feed != nil)
+	return nil
+}
+
+// Stop closes the followed signal streams, stops the feed and abandons
+// events still queued or being retried.
+func (b *Broadcaster) Stop(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.running {
+		b.mu.Unlock()
+		return nil
+	}
+	b.running = false
+	for key, sub := range b.follows {
+		sub.Close()
+		delete(b.follows, key)
+	}
+	b.cancel()
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	if b.feed != nil {
+		return b.feed.Stop(ctx)
+	}
+	return nil
+}
+
+// Publish hands the event to every target and the feed.
+func (b *Broadcaster) Publish(event Event) {
+	b.mu.Lock()
+	running := b.running
+	b.mu.Unlock()
+	if !running {
+		return
+	}
+
+	b.published.Add(1)
+	for _, sender := range b.senders {
+		sender.enqueue(event)
+	}
+	if b.feed != nil {
+		b.feed.Publish(event)
+	}
+}
+
+// PublishTrade publishes a position change; it has the signature of a
+// trading.TradingEngine.OnTradeEvent callback.
+func (b *Broadcaster) PublishTrade(event trading.TradeEvent) {
+	b.Publish(NewTradeEvent(event))
+}
+
+// FollowSignals publishes every signal delivered on sub as an event of
+// account. Following again with the same key closes the previous
+// subscription, so that a restarted bot does not leave a stale stream
+// behind.
+func (b *Broadcaster) FollowSignals(key, account string, sub *signals.Subscription) {
+	b.mu.Lock()
+	if previous, ok := b.follows[key]; ok {
+		previous.Close()
+	}
+	b.follows[key] = sub
+	b.mu.Unlock()
+
+	go func() {
+		for sig := range sub.C() {
+			b.Publish(NewSignalEvent(account, sig))
+		}
+	}()
+}
+
+// Unfollow closes the signal stream followed under key.
+func (b *Broadcaster) Unfollow(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sub, ok := b.follows[key]; ok {
+		sub.Close()
+		delete(b.follows, key)
+	}
+}
+
+// Stats returns the broadcaster's counters.
+func (b *Broadcaster) Stats() Stats {
+	b.mu.Lock()
+	stats := Stats{
+		Running:   b.running,
+		Published: b.published.Load(),
+		Following: make([]string, 0, len(b.follows)),
+		Targets:   make([]TargetStats, 0, len(b.senders)),
+	}
+	for key := range b.follows {
+		stats.Following = append(stats.Following, key)
+	}
+	b.mu.Unlock()
+	sort.Strings(stats.Following)
+
+	for _, sender := range b.senders {
+		stats.Targets = append(stats.Targets, sender.Stats())
+	}
+	if b.feed != nil {
+		feedStats := b.feed.Stats()
+		stats.Feed = &feedStats
+	}
+	return stats
+}
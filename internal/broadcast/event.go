@@ -0,0 +1,199 @@
+// Package broadcast publishes generated signals and trade events to
+// external consumers: outbound webhooks with retries and a local WebSocket
+// feed. Both carry the same versioned JSON schema, Event.
+package broadcast
+
+import (
+	"crypto-trading-bot/internal/signals"
+	"crypto-trading-bot/internal/trading"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is the version of the Event JSON schema. Within a version
+// fields are only added, never renamed or removed; consumers should ignore
+// fields they do not know.
+const SchemaVersion = 1
+
+// Event types.
+const (
+	EventSignal          = "signal"
+	EventPositionOpened  = trading.TradeEventOpened
+	EventPositionReduced = trading.TradeEventReduced
+	EventPositionClosed  = trading.TradeEventClosed
+)
+
+// IsEventType reports whether eventType is one of the Event* constants.
+func IsEventType(eventType string) bool {
+	switch eventType {
+	case EventSignal, EventPositionOpened, EventPositionReduced, EventPositionClosed:
+		return true
+	}
+	return false
+}
+
+// Event is the message sent to webhooks and feed clients, e.g.
+//
+//	{"version": 1, "id": "...", "type": "position.closed", "account": "bot",
+//	 "time": 1735689600000, "position": {...}, "trade": {...}}
+//
+// A signal event carries Signal; position.opened carries Position;
+// position.reduced and position.closed carry the position and the Trade
+// that closed (part of) it. The ID is stable across retries, so consumers
+// can de-duplicate deliveries.
+type Event struct {
+	Version  int              `json:"version"`
+	ID       string           `json:"id"`
+	Type     string           `json:"type"`
+	Account  string           `json:"account"`
+	Time     int64            `json:"time"` // Unix ms
+	Signal   *SignalPayload   `json:"signal,omitempty"`
+	Position *PositionPayload `json:"position,omitempty"`
+	Trade    *TradePayload    `json:"trade,omitempty"`
+}
+
+// SignalPayload is the signal of a signal event.
+type SignalPayload struct {
+	ID          string   `json:"id"`
+	RootID      string   `json:"rootId"` // First signal of a run of duplicates
+	Symbol      string   `json:"symbol"`
+	Timeframe   string   `json:"timeframe"`
+	Direction   string   `json:"direction"` // "LONG", "SHORT" or "HOLD"
+	Confidence  float64  `json:"confidence"`
+	Price       float64  `json:"price"`
+	StopLoss    float64  `json:"stopLoss"`   // 0 if the source set none
+	TakeProfit  float64  `json:"takeProfit"` // 0 if the source set none
+	Model       string   `json:"model"`
+	Regime      string   `json:"regime"`     // e.g. "trend/high_vol"; empty if unknown
+	Timestamp   int64    `json:"timestamp"`  // Unix ms
+	ValidUntil  int64    `json:"validUntil"` // Unix ms, 0 = no expiry
+	DuplicateOf string   `json:"duplicateOf"`
+	Digest      string   `json:"digest"` // One-line summary
+	Reasons     []string `json:"reasons"`
+}
+
+// PositionPayload is the position of a position event.
+type PositionPayload struct {
+	ID         string  `json:"id"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"` // "LONG" or "SHORT"
+	EntryPrice float64 `json:"entryPrice"`
+	Quantity   float64 `json:"quantity"` // Remaining quantity
+	StopLoss   float64 `json:"stopLoss"`
+	TakeProfit float64 `json:"takeProfit"`
+	OpenedAt   int64   `json:"openedAt"` // Unix ms
+	SignalID   string  `json:"signalId"`
+	Strategy   string  `json:"strategy"`
+}
+
+// TradePayload is the closed part of a position.
+type TradePayload struct {
+	ID         string  `json:"id"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	EntryPrice float64 `json:"entryPrice"`
+	ExitPrice  float64 `json:"exitPrice"`
+	Quantity   float64 `json:"quantity"`
+	PnL        float64 `json:"pnl"`
+	PnLPercent float64 `json:"pnlPercent"`
+	OpenedAt   int64   `json:"openedAt"` // Unix ms
+	ClosedAt   int64   `json:"closedAt"` // Unix ms
+	Reason     string  `json:"reason"`
+	SignalID   string  `json:"signalId"`
+	Strategy   string  `json:"strategy"`
+	MAER       float64 `json:"maeR"` // Maximum adverse excursion in R, 0 without a stop
+	MFER       float64 `json:"mfeR"` // Maximum favorable excursion in R, 0 without a stop
+}
+
+// NewSignalEvent builds the event of a signal generated for account.
+func NewSignalEvent(account string, sig *signals.Signal) Event {
+	payload := &SignalPayload{
+		ID:          sig.ID,
+		RootID:      sig.RootID(),
+		Symbol:      sig.Symbol,
+		Timeframe:   sig.Timeframe,
+		Direction:   sig.Direction,
+		Confidence:  sig.Confidence,
+		Price:       sig.Price,
+		StopLoss:    sig.StopLoss,
+		TakeProfit:  sig.TakeProfit,
+		Model:       sig.Model,
+		Timestamp:   sig.Timestamp.UnixMilli(),
+		ValidUntil:  sig.ValidUntil,
+		DuplicateOf: sig.DuplicateOf,
+		Digest:      sig.Digest(),
+		Reasons:     append([]string{}, sig.Reasons...),
+	}
+	if sig.Regime != nil {
+		payload.Regime = sig.Regime.Name()
+	}
+	return Event{
+		Version: SchemaVersion,
+		ID:      uuid.New().String(),
+		Type:    EventSignal,
+		Account: account,
+		Time:    sig.Timestamp.UnixMilli(),
+		Signal:  payload,
+	}
+}
+
+// NewTradeEvent builds the event of a position change.
+func NewTradeEvent(te trading.TradeEvent) Event {
+	event := Event{
+		Version: SchemaVersion,
+		ID:      uuid.New().String(),
+		Type:    te.Type,
+		Account: te.Account,
+		Time:    te.Time.UnixMilli(),
+	}
+	if pos := te.Position; pos != nil {
+		event.Position = &PositionPayload{
+			ID:         pos.ID,
+			Symbol:     pos.Symbol,
+			Side:       pos.Side,
+			EntryPrice: pos.EntryPrice,
+			Quantity:   pos.Quantity,
+			StopLoss:   pos.StopLoss,
+			TakeProfit: pos.TakeProfit,
+			OpenedAt:   pos.OpenedAt.UnixMilli(),
+			SignalID:   pos.SignalID,
+			Strategy:   pos.Strategy,
+		}
+		if te.Type == EventPositionClosed {
+			event.Position.Quantity = 0
+		}
+	}
+	if trade := te.Trade; trade != nil {
+		event.Trade = &TradePayload{
+			ID:         trade.ID,
+			Symbol:     trade.Symbol,
+			Side:       trade.Side,
+			EntryPrice: trade.EntryPrice,
+			ExitPrice:  trade.ExitPrice,
+			Quantity:   trade.Quantity,
+			PnL:        trade.PnL,
+			PnLPercent: trade.PnLPercent,
+			OpenedAt:   trade.OpenedAt.UnixMilli(),
+			ClosedAt:   trade.ClosedAt.UnixMilli(),
+			Reason:     trade.Reason,
+			SignalID:   trade.SignalID,
+			Strategy:   trade.Strategy,
+			MAER:       trade.MAER,
+			MFER:       trade.MFER,
+		}
+	}
+	return event
+}
+
+// symbol returns the symbol the event is about.
+func (e Event) symbol() string {
+	switch {
+	case e.Signal != nil:
+		return e.Signal.Symbol
+	case e.Position != nil:
+		return e.Position.Symbol
+	case e.Trade != nil:
+		return e.Trade.Symbol
+	}
+	return ""
+}
@@ -0,0 +1,356 @@
+package broadcast
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+// Feed connection timings.
+const (
+	feedWriteTimeout = 10 * time.Second
+	feedPongTimeout  = 60 * time.Second
+	feedPingInterval = 30 * time.Second
+)
+
+// FeedConfig configures the local WebSocket feed.
+type FeedConfig struct {
+	Addr         string `json:"addr"`         // Listen address, e.g. "127.0.0.1:8788"
+	Path         string `json:"path"`         // Endpoint path (default "/feed")
+	Token        string `json:"-"`            // Required from clients when set; without it only local origins may connect
+	ClientBuffer int    `json:"clientBuffer"` // Events queued per client before it is disconnected as too slow (default 64)
+}
+
+// Validate fills defaults and checks the configuration.
+func (c *FeedConfig) Validate() error {
+	if c.Addr == "" {
+		return fmt.Errorf("feed listen address is required")
+	}
+	if c.Path == "" {
+		c.Path = "/feed"
+	}
+	if c.ClientBuffer <= 0 {
+		c.ClientBuffer = 64
+	}
+	return nil
+}
+
+// FeedStats counts the feed's clients and messages.
+type FeedStats struct {
+	Running     bool   `json:"running"`
+	Addr        string `json:"addr"`
+	Clients     int    `json:"clients"`
+	Connections int64  `json:"connections"` // Clients connected since start
+	Sent        int64  `json:"sent"`
+	SlowClients int64  `json:"slowClients"` // Clients disconnected because their buffer was full
+	LastError   string `json:"lastError"`
+}
+
+// Feed streams events to WebSocket clients as JSON text messages, one
+// Event per message. Clients choose events with query parameters, e.g.
+// /feed?events=signal,position.closed&symbols=BTCUSDT&accounts=bot&minConfidence=0.7
+type Feed struct {
+	config   FeedConfig
+	upgrader websocket.Upgrader
+	clients  map[*feedClient]struct{}
+	server   *http.Server
+	stats    FeedStats
+	mu       sync.Mutex
+}
+
+type feedClient struct {
+	conn   *websocket.Conn
+	filter Filter
+	send   chan []byte
+}
+
+// NewFeed creates a WebSocket feed.
+func NewFeed(config FeedConfig) (*Feed, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	f := &Feed{
+		config:  config,
+		clients: make(map[*feedClient]struct{}),
+		stats:   FeedStats{Addr: config.Addr},
+	}
+	f.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		CheckOrigin:     f.checkOrigin,
+	}
+	return f, nil
+}
+
+// Start listens on the configured address in the background.
+func (f *Feed) Start() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", f.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", f.config.Addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(f.config.Path, f)
+	f.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	f.stats.Running = true
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Broadcast feed stopped: %v", err)
+			f.mu.Lock()
+			f.stats.Running = false
+			f.stats.LastError = err.Error()
+			f.mu.Unlock()
+		}
+	}(f.server)
+
+	log.Infof("Broadcast feed listening on ws://%s%s", f.config.Addr, f.config.Path)
+	return nil
+}
+
+// Stop disconnects all clients and shuts the server down.
+func (f *Feed) Stop(ctx context.Context) error {
+	f.mu.Lock()
+	server := f.server
+	f.server = nil
+	f.stats.Running = false
+	for client := range f.clients {
+		f.removeLocked(client)
+	}
+	f.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	// Shutdown не ждёт соединений, перехваченных websocket, их закрывают клиентские горутины
+	return server.Shutdown(ctx)
+}
+
+// Publish sends the event to every client whose filter it matches. Clients
+// that cannot keep up are disconnected rather than slowing down the others.
+func (f *Feed) Publish(event Event) {
+	var message []byte
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for client := range f.clients {
+		if !client.filter.Matches(event) {
+			continue
+		}
+		if message == nil {
+			var err error
+			if message, err = json.Marshal(event); err != nil {
+				f.stats.LastError = err.Error()
+				return
+			}
+		}
+		select {
+		case client.send <- message:
+			f.stats.Sent++
+		default:
+			f.stats.SlowClients++
+			log.Warnf("Broadcast feed client %s is too slow, disconnecting", client.conn.RemoteAddr())
+			f.removeLocked(client)
+		}
+	}
+}
+
+// Stats returns a copy of the feed counters.
+func (f *Feed) Stats() FeedStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := f.stats
+	stats.Clients = len(f.clients)
+	return stats
+}
+
+// ServeHTTP authenticates a client and upgrades the connection.
+func (f *Feed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.authorized(r) {
+		http.Error(w, "invalid feed token", http.StatusUnauthorized)
+		return
+	}
+	filter, err := parseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	conn, err := f.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade уже ответил клиенту
+		log.Debugf("Broadcast feed upgrade failed: %v", err)
+		return
+	}
+
+	client := &feedClient{
+		conn:   conn,
+		filter: filter,
+		send:   make(chan []byte, f.config.ClientBuffer),
+	}
+	f.mu.Lock()
+	if f.server == nil {
+		f.mu.Unlock()
+		conn.Close()
+		return
+	}
+	f.clients[client] = struct{}{}
+	f.stats.Connections++
+	f.mu.Unlock()
+
+	log.Infof("Broadcast feed client connected: %s", conn.RemoteAddr())
+	go f.writeLoop(client)
+	go f.readLoop(client)
+}
+
+// removeLocked unregisters the client; its write loop then closes the
+// connection. f.mu must be held.
+func (f *Feed) removeLocked(client *feedClient) {
+	if _, ok := f.clients[client]; !ok {
+		return
+	}
+	delete(f.clients, client)
+	close(client.send)
+}
+
+func (f *Feed) remove(client *feedClient) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.removeLocked(client)
+}
+
+// writeLoop writes queued messages and pings until the client is removed.
+func (f *Feed) writeLoop(client *feedClient) {
+	ticker := time.NewTicker(feedPingInterval)
+	defer func() {
+		ticker.Stop()
+		client.conn.Close()
+		log.Infof("Broadcast feed client disconnected: %s", client.conn.RemoteAddr())
+	}()
+
+	for {
+		select {
+		case message, ok := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+			if !ok {
+				client.conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				f.remove(client)
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(feedWriteTimeout))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				f.remove(client)
+				return
+			}
+		}
+	}
+}
+
+// readLoop discards client messages and notices disconnects and missed
+// pongs.
+func (f *Feed) readLoop(client *feedClient) {
+	defer f.remove(client)
+
+	client.conn.SetReadLimit(1 << 10)
+	client.conn.SetReadDeadline(time.Now().Add(feedPongTimeout))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(feedPongTimeout))
+	})
+	for {
+		if _, _, err := client.conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+// authorized checks the token from the "token" query parameter or a bearer
+// Authorization header.
+func (f *Feed) authorized(r *http.Request) bool {
+	if f.config.Token == "" {
+		return true
+	}
+	token := r.URL.Query().Get("token")
+	if header := r.Header.Get("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(f.config.Token)) == 1
+}
+
+// checkOrigin lets any page connect when a token is required. Without a
+// token only non-browser clients and local pages may connect, so that a
+// foreign web page cannot read the feed through the user's browser.
+func (f *Feed) checkOrigin(r *http.Request) bool {
+	if f.config.Token != "" {
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch parsed.Hostname() {
+	case "localhost", "127.0.0.1", "::1", "wails.localhost":
+		return true
+	}
+	return parsed.Scheme == "wails"
+}
+
+// parseFilter reads a client filter from comma-separated query parameters.
+func parseFilter(query url.Values) (Filter, error) {
+	split := func(key string) []string {
+		var values []string
+		for _, value := range strings.Split(query.Get(key), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+		return values
+	}
+	filter := Filter{
+		Events:   split("events"),
+		Accounts: split("accounts"),
+		Symbols:  split("symbols"),
+	}
+	for i, symbol := range filter.Symbols {
+		filter.Symbols[i] = strings.ToUpper(symbol)
+	}
+	if value := query.Get("minConfidence"); value != "" {
+		confidence, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid minConfidence %q", value)
+		}
+		filter.MinConfidence = confidence
+	}
+	return filter, filter.Validate()
+}
@@ -0,0 +1,45 @@
+package broadcast
+
+import "fmt"
+
+// Filter selects the events sent to a webhook target or feed client. Empty
+// fields match everything.
+type Filter struct {
+	Events        []string `json:"events"` // Event* types
+	Accounts      []string `json:"accounts"`
+	Symbols       []string `json:"symbols"`
+	MinConfidence float64  `json:"minConfidence"` // Applies to signal events only
+}
+
+// Validate checks that the filter only names known event types.
+func (f Filter) Validate() error {
+	for _, eventType := range f.Events {
+		if !IsEventType(eventType) {
+			return fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	if f.MinConfidence < 0 || f.MinConfidence > 1 {
+		return fmt.Errorf("min confidence must be within [0, 1]")
+	}
+	return nil
+}
+
+// Matches reports whether the event passes the filter.
+func (f Filter) Matches(e Event) bool {
+	if !matchesAny(f.Events, e.Type) || !matchesAny(f.Accounts, e.Account) || !matchesAny(f.Symbols, e.symbol()) {
+		return false
+	}
+	return e.Signal == nil || e.Signal.Confidence >= f.MinConfidence
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,241 @@
+package broadcast
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers of outbound webhook requests.
+const (
+	EventTypeHeader = "X-Broadcast-Event"
+	EventIDHeader   = "X-Broadcast-Id"
+	SignatureHeader = "X-Broadcast-Signature" // "sha256=" + hex HMAC-SHA256 of the body, if the target has a secret
+)
+
+// Target is an outbound webhook.
+type Target struct {
+	Name       string        `json:"name"` // Shown in stats; the URL host by default
+	URL        string        `json:"url"`
+	Secret     string        `json:"-"` // Signs request bodies when set
+	Filter     Filter        `json:"filter"`
+	MaxRetries int           `json:"maxRetries"` // Retries after the first attempt (default 3, -1 = none)
+	Timeout    time.Duration `json:"timeout"`    // Per request (default 5s)
+	Backoff    time.Duration `json:"backoff"`    // Delay before the first retry, doubled on each retry (default 1s)
+	MaxBackoff time.Duration `json:"maxBackoff"` // Upper bound of the delay (default 1m)
+	QueueSize  int           `json:"queueSize"`  // Events waiting for delivery before new ones are dropped (default 256)
+}
+
+// Validate fills defaults and checks the target.
+func (t *Target) Validate() error {
+	parsed, err := url.Parse(t.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q", t.URL)
+	}
+	if t.Name == "" {
+		t.Name = parsed.Host
+	}
+	if err := t.Filter.Validate(); err != nil {
+		return fmt.Errorf("webhook %s: %w", t.Name, err)
+	}
+	if t.MaxRetries == 0 {
+		t.MaxRetries = 3
+	} else if t.MaxRetries < 0 {
+		t.MaxRetries = 0
+	}
+	if t.Timeout <= 0 {
+		t.Timeout = 5 * time.Second
+	}
+	if t.Backoff <= 0 {
+		t.Backoff = time.Second
+	}
+	if t.MaxBackoff < t.Backoff {
+		t.MaxBackoff = time.Minute
+		if t.MaxBackoff < t.Backoff {
+			t.MaxBackoff = t.Backoff
+		}
+	}
+	if t.QueueSize <= 0 {
+		t.QueueSize = 256
+	}
+	return nil
+}
+
+// TargetStats counts the deliveries of a webhook target.
+type TargetStats struct {
+	Name            string `json:"name"`
+	Host            string `json:"host"`
+	Queued          int    `json:"queued"`
+	Delivered       int64  `json:"delivered"`
+	Failed          int64  `json:"failed"`  // Events given up after the last retry
+	Dropped         int64  `json:"dropped"` // Events dropped because the queue was full
+	Retries         int64  `json:"retries"`
+	LastStatus      int    `json:"lastStatus"`      // HTTP status of the last attempt, 0 on network errors
+	LastDeliveredAt int64  `json:"lastDeliveredAt"` // Unix ms
+	LastError       string `json:"lastError"`
+}
+
+// webhookSender delivers events to one target from its own queue, so that
+// a slow or failing target does not hold up the others.
+type webhookSender struct {
+	target Target
+	client *http.Client
+	queue  chan Event
+	stats  TargetStats
+	mu     sync.Mutex
+}
+
+func newWebhookSender(target Target) *webhookSender {
+	parsed, _ := url.Parse(target.URL)
+	return &webhookSender{
+		target: target,
+		client: &http.Client{Timeout: target.Timeout},
+		queue:  make(chan Event, target.QueueSize),
+		stats:  TargetStats{Name: target.Name, Host: parsed.Host},
+	}
+}
+
+// enqueue queues a matching event without blocking.
+func (s *webhookSender) enqueue(event Event) {
+	if !s.target.Filter.Matches(event) {
+		return
+	}
+	select {
+	case s.queue <- event:
+	default:
+		s.mu.Lock()
+		s.stats.Dropped++
+		s.mu.Unlock()
+		log.Warnf("Broadcast webhook %s: queue full, dropping %s event %s", s.target.Name, event.Type, event.ID)
+	}
+}
+
+// run delivers queued events until ctx is cancelled.
+func (s *webhookSender) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-s.queue:
+			s.deliver(ctx, event)
+		}
+	}
+}
+
+// deliver posts the event, retrying network errors, 429 and 5xx responses
+// with exponential backoff. Other responses are final.
+func (s *webhookSender) deliver(ctx context.Context, event Event) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		s.fail(fmt.Errorf("failed to encode event: %w", err))
+		return
+	}
+
+	backoff := s.target.Backoff
+	for attempt := 0; ; attempt++ {
+		status, retryAfter, err := s.post(ctx, event, body)
+
+		s.mu.Lock()
+		s.stats.LastStatus = status
+		if err == nil {
+			s.stats.Delivered++
+			s.stats.LastDeliveredAt = time.Now().UnixMilli()
+		}
+		s.mu.Unlock()
+		if err == nil {
+			return
+		}
+
+		retryable := status == 0 || status == http.StatusTooManyRequests || status >= 500
+		if !retryable || attempt >= s.target.MaxRetries || ctx.Err() != nil {
+			s.fail(fmt.Errorf("event %s not delivered after %d attempt(s): %w", event.ID, attempt+1, err))
+			return
+		}
+
+		wait := backoff
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		if wait > s.target.MaxBackoff {
+			wait = s.target.MaxBackoff
+		}
+		s.mu.Lock()
+		s.stats.Retries++
+		s.stats.LastError = err.Error()
+		s.mu.Unlock()
+		log.Debugf("Broadcast webhook %s: %v, retrying in %v", s.target.Name, err, wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.fail(fmt.Errorf("event %s not delivered: broadcaster stopped", event.ID))
+			return
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one attempt and returns the response status and the delay the
+// target asked for with Retry-After.
+func (s *webhookSender) post(ctx context.Context, event Event, body []byte) (int, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.target.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "crypto-trading-bot")
+	req.Header.Set(EventTypeHeader, event.Type)
+	req.Header.Set(EventIDHeader, event.ID)
+	if s.target.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.target.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("webhook responded %s", resp.Status)
+}
+
+func (s *webhookSender) fail(err error) {
+	s.mu.Lock()
+	s.stats.Failed++
+	s.stats.LastError = err.Error()
+	s.mu.Unlock()
+	log.Warnf("Broadcast webhook %s: %v", s.target.Name, err)
+}
+
+// Stats returns a copy of the delivery counters.
+func (s *webhookSender) Stats() TargetStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Queued = len(s.queue)
+	return stats
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"sync"
 )
 
 type Config struct {
@@ -18,6 +20,110 @@ type Config struct {
 	CooldownMinutes  int
 	DatabasePath     string
 	RedisAddr        string
+	SessionTimezone  string
+	SessionRolloverHour int
+	SessionConfigFile string
+	SignalStorePath   string
+	SignalModelsFile  string // JSON file with signal models
+	SignalModel       string // Active signal model, "name" or "name@version"
+	SignalLabelHorizons string // Forward-return horizons in candles, comma separated
+	SignalTTLCandles    float64 // Signal validity in candles of its timeframe
+	SignalTTLSeconds    int     // Validity of signals without a known timeframe
+
+	// Multi-timeframe confluence
+	ConfluenceMode         string // weighted, higher_trend, timing (empty = off)
+	ConfluenceWeights      string // Per-timeframe weights, e.g. "1m:1,15m:2,1h:3"
+	ConfluenceMinAgreement float64
+	ConfluenceRequireTrend bool
+
+	// Market regime detection
+	RegimeTrendADX          float64
+	RegimeRangeADX          float64
+	RegimeHighVolPercentile float64
+	RegimeLowVolPercentile  float64
+	RegimeLookback          int
+	RegimeVolatilityWindow  int
+	BotRegimeAllow          string // Regimes the bot trades in, comma separated (empty = any)
+	BotRegimeBlock          string // Regimes the bot does not trade in, comma separated
+
+	// Additional indicators with tuned periods
+	IndicatorSpecs       string // Specs added for all symbols, e.g. "rsi(21), bb(20,2.5)"
+	IndicatorSymbolSpecs string // Per-symbol specs replacing IndicatorSpecs, e.g. "BTCUSDT:rsi(21),ema(100)|ETHUSDT:rsi(10)"
+
+	// External alerts webhook
+	WebhookAddr             string // Listen address (empty = disabled)
+	WebhookPath             string
+	WebhookSecret           string
+	WebhookModelTag         string // Model recorded in webhook signals
+	WebhookTimeframe        string // Timeframe of alerts without one
+	WebhookRatePerMinute    int
+	WebhookBurst            int
+	WebhookMaxSkewSeconds   int
+	WebhookRequireTimestamp bool
+	WebhookAccount          string // Account trading alerts: bot, main or none
+
+	// Broadcasting of signals and trade events
+	BroadcastWebhookURLs     string // Outbound webhooks, comma separated (empty = none)
+	BroadcastWebhookSecret   string // Signs outbound bodies with HMAC-SHA256
+	BroadcastEvents          string // Event types to send, comma separated (empty = all)
+	BroadcastMinConfidence   float64
+	BroadcastIncludeHold     bool // Also broadcast HOLD signals
+	BroadcastMaxRetries      int
+	BroadcastTimeoutSeconds  int
+	BroadcastWSAddr          string // Local WebSocket feed address (empty = disabled)
+	BroadcastWSPath          string
+	BroadcastWSToken         string
+
+	// Declarative rule strategies
+	RulesDir string // Directory of JSON and YAML strategy definitions started at launch (empty = none)
+
+	// Circuit breaker
+	BreakerMaxDailyLoss         float64
+	BreakerMaxDrawdown          float64
+	BreakerMaxConsecutiveLosses int
+	BreakerStaleDataSeconds     int
+	BreakerMaxOrderErrors       int
+	BreakerFlattenOnTrip        bool
+
+	// Position sizing
+	SizingMethod       string
+	SizingNotional     float64
+	SizingTargetRisk   float64
+	SizingKellyFraction float64
+	SizingTotalRisk    float64
+	SizingMaxPositions int
+
+	// Portfolio limits (fractions of equity)
+	PortfolioMaxGross            float64
+	PortfolioMaxNet              float64
+	PortfolioMaxAsset            float64
+	PortfolioMaxSector           float64
+	PortfolioMaxCluster          float64
+	PortfolioCorrelationThreshold float64
+
+	// Value-at-Risk
+	VaRMaxPercent  float64
+	VaRConfidence  float64
+	VaRInterval    string
+	VaRLookback    int
+
+	// Pre-trade risk rules
+	RiskRules            string            // Default rule order, comma separated (empty = built-in order)
+	RiskRulesByAccount   map[string]string // Per-account rule order from RISK_RULES_<ACCOUNT>; guarded by riskRulesMu
+	riskRulesMu          sync.RWMutex
+	RiskMaxOpenPositions int
+	RiskJournalPath      string
+
+	ExitPoliciesFile string // JSON file with exit policies per strategy
+
+	// Risk of ruin tolerance
+	RuinLevel                  float64 // Equity loss counted as ruin
+	RuinHorizonTrades          int
+	RuinWindowTrades           int // Closed trades the statistics are taken from (0 = all)
+	RuinMaxProbability         float64
+	RuinMaxRiskPerTrade        float64
+	RuinDrawdownLevel          float64
+	RuinMaxDrawdownProbability float64
 }
 
 func Load() *Config {
@@ -34,11 +140,170 @@ func Load() *Config {
 		CooldownMinutes:   getIntEnv("COOLDOWN_MINUTES", 2),          // Уменьшено для более частых сделок
 		DatabasePath:      getEnv("DATABASE_PATH", "./trading.db"),
 		RedisAddr:         getEnv("REDIS_ADDR", "localhost:6379"),
+		SessionTimezone:   getEnv("SESSION_TIMEZONE", "UTC"),
+		SessionRolloverHour: getIntEnv("SESSION_ROLLOVER_HOUR", 0),
+		SessionConfigFile: getEnv("SESSION_CONFIG_FILE", ""),     // JSON с торговыми окнами и blackout-периодами
+		SignalStorePath:   getEnv("SIGNAL_STORE_PATH", "./signals.jsonl"), // История сигналов (пусто = только в памяти)
+		SignalModelsFile:  getEnv("SIGNAL_MODELS_FILE", ""),                // Модели сигналов (пусто = только встроенная)
+		SignalModel:       getEnv("SIGNAL_MODEL", "default"),
+		SignalLabelHorizons: getEnv("SIGNAL_LABEL_HORIZONS", "1,5,15,60"), // Горизонты разметки сигналов в свечах
+		SignalTTLCandles:    getFloatEnv("SIGNAL_TTL_CANDLES", 1),          // Сигнал действителен до закрытия следующей свечи
+		SignalTTLSeconds:    getIntEnv("SIGNAL_TTL_SECONDS", 60),
+
+		// Объединение сигналов разных таймфреймов (пусто = торгуем самый свежий сигнал)
+		ConfluenceMode:         getEnv("CONFLUENCE_MODE", ""),
+		ConfluenceWeights:      getEnv("CONFLUENCE_WEIGHTS", ""), // Пусто = вес по старшинству таймфрейма
+		ConfluenceMinAgreement: getFloatEnv("CONFLUENCE_MIN_AGREEMENT", 0.6),
+		ConfluenceRequireTrend: getEnv("CONFLUENCE_REQUIRE_TREND", "false") == "true",
+
+		// Режим рынка: тренд/флэт по ADX, высокая/низкая волатильность по перцентилям ATR и realized vol
+		RegimeTrendADX:          getFloatEnv("REGIME_TREND_ADX", 25),
+		RegimeRangeADX:          getFloatEnv("REGIME_RANGE_ADX", 20),
+		RegimeHighVolPercentile: getFloatEnv("REGIME_HIGH_VOL_PERCENTILE", 80),
+		RegimeLowVolPercentile:  getFloatEnv("REGIME_LOW_VOL_PERCENTILE", 20),
+		RegimeLookback:          getIntEnv("REGIME_LOOKBACK", 100),
+		RegimeVolatilityWindow:  getIntEnv("REGIME_VOLATILITY_WINDOW", 20),
+		BotRegimeAllow:          getEnv("BOT_REGIME_ALLOW", ""), // trend, range, high_vol, low_vol
+		BotRegimeBlock:          getEnv("BOT_REGIME_BLOCK", ""),
+
+		// Индикаторы сверх пресета по умолчанию: rsi(21), ema(100), bb(20,2.5), fast=ema(12) ...
+		IndicatorSpecs:       getEnv("INDICATOR_SPECS", ""),
+		IndicatorSymbolSpecs: getEnv("INDICATOR_SYMBOL_SPECS", ""),
+
+		// Приём внешних алертов (TradingView и т.п.); без WEBHOOK_SECRET не запускается
+		WebhookAddr:             getEnv("WEBHOOK_ADDR", ""), // Например 127.0.0.1:8787
+		WebhookPath:             getEnv("WEBHOOK_PATH", "/webhook"),
+		WebhookSecret:           getEnv("WEBHOOK_SECRET", ""),
+		WebhookModelTag:         getEnv("WEBHOOK_MODEL_TAG", "webhook"),
+		WebhookTimeframe:        getEnv("WEBHOOK_TIMEFRAME", "1m"),
+		WebhookRatePerMinute:    getIntEnv("WEBHOOK_RATE_PER_MINUTE", 30),
+		WebhookBurst:            getIntEnv("WEBHOOK_BURST", 10),
+		WebhookMaxSkewSeconds:   getIntEnv("WEBHOOK_MAX_SKEW_SECONDS", 300),
+		WebhookRequireTimestamp: getEnv("WEBHOOK_REQUIRE_TIMESTAMP", "true") == "true",
+		WebhookAccount:          getEnv("WEBHOOK_ACCOUNT", "bot"),
+
+		// Публикация сигналов и сделок наружу: исходящие вебхуки и локальный WebSocket
+		BroadcastWebhookURLs:    getEnv("BROADCAST_WEBHOOK_URLS", ""),
+		BroadcastWebhookSecret:  getEnv("BROADCAST_WEBHOOK_SECRET", ""),
+		BroadcastEvents:         getEnv("BROADCAST_EVENTS", ""), // signal, position.opened, position.reduced, position.closed
+		BroadcastMinConfidence:  getFloatEnv("BROADCAST_MIN_CONFIDENCE", 0),
+		BroadcastIncludeHold:    getEnv("BROADCAST_INCLUDE_HOLD", "false") == "true",
+		BroadcastMaxRetries:     getIntEnv("BROADCAST_MAX_RETRIES", 3),
+		BroadcastTimeoutSeconds: getIntEnv("BROADCAST_TIMEOUT_SECONDS", 5),
+		BroadcastWSAddr:         getEnv("BROADCAST_WS_ADDR", ""), // Например 127.0.0.1:8788
+		BroadcastWSPath:         getEnv("BROADCAST_WS_PATH", "/feed"),
+		BroadcastWSToken:        getEnv("BROADCAST_WS_TOKEN", ""),
+
+		RulesDir: getEnv("RULES_DIR", ""),
+
+		// Автоматические остановки торговли (0 = проверка отключена)
+		BreakerMaxDailyLoss:         getFloatEnv("BREAKER_MAX_DAILY_LOSS", 0.05),  // Доля от equity на начало дня
+		BreakerMaxDrawdown:          getFloatEnv("BREAKER_MAX_DRAWDOWN", 0.2),     // Доля от пика equity
+		BreakerMaxConsecutiveLosses: getIntEnv("BREAKER_MAX_CONSECUTIVE_LOSSES", 5),
+		BreakerStaleDataSeconds:     getIntEnv("BREAKER_STALE_DATA_SECONDS", 120),
+		BreakerMaxOrderErrors:       getIntEnv("BREAKER_MAX_ORDER_ERRORS", 5),
+		BreakerFlattenOnTrip:        getEnv("BREAKER_FLATTEN_ON_TRIP", "false") == "true",
+
+		// Модель размера позиции: fixed_notional, fixed_fractional, volatility, kelly, equal_risk
+		// (пусто = fixed fractional через RISK_PER_TRADE)
+		SizingMethod:        getEnv("SIZING_METHOD", ""),
+		SizingNotional:      getFloatEnv("SIZING_NOTIONAL", 1000),
+		SizingTargetRisk:    getFloatEnv("SIZING_TARGET_RISK", 0.01),
+		SizingKellyFraction: getFloatEnv("SIZING_KELLY_FRACTION", 0.25),
+		SizingTotalRisk:     getFloatEnv("SIZING_TOTAL_RISK", 0.06),
+		SizingMaxPositions:  getIntEnv("SIZING_MAX_POSITIONS", 3),
+
+		// Лимиты портфеля в долях от equity (0 = без ограничения)
+		PortfolioMaxGross:             getFloatEnv("PORTFOLIO_MAX_GROSS", 1.0),
+		PortfolioMaxNet:               getFloatEnv("PORTFOLIO_MAX_NET", 1.0),
+		PortfolioMaxAsset:             getFloatEnv("PORTFOLIO_MAX_ASSET", 0.5),
+		PortfolioMaxSector:            getFloatEnv("PORTFOLIO_MAX_SECTOR", 0.6),
+		PortfolioMaxCluster:           getFloatEnv("PORTFOLIO_MAX_CLUSTER", 0.6),
+		PortfolioCorrelationThreshold: getFloatEnv("PORTFOLIO_CORRELATION_THRESHOLD", 0.7),
+
+		// VaR: лимит в % от equity для проверки перед сделкой (0 = выключено)
+		VaRMaxPercent: getFloatEnv("VAR_MAX_PERCENT", 0),
+		VaRConfidence: getFloatEnv("VAR_CONFIDENCE", 0.99),
+		VaRInterval:   getEnv("VAR_INTERVAL", "1h"),  // Таймфрейм свечей для отчета VaR
+		VaRLookback:   getIntEnv("VAR_LOOKBACK", 500), // Количество свечей для отчета VaR
+
+		// Цепочка риск-правил перед сделкой: RISK_RULES для всех счетов,
+		// RISK_RULES_MAIN / RISK_RULES_BOT для отдельных счетов
+		RiskRules:            getEnv("RISK_RULES", ""),
+		RiskRulesByAccount:   getPrefixedEnv("RISK_RULES_"),
+		RiskMaxOpenPositions: getIntEnv("RISK_MAX_OPEN_POSITIONS", 0),              // 0 = без ограничения
+		RiskJournalPath:      getEnv("RISK_JOURNAL_PATH", "./risk_decisions.jsonl"), // Журнал решений (пусто = только в памяти)
+
+		// Политики выхода по стратегиям (пусто = встроенные правила скальпинга)
+		ExitPoliciesFile: getEnv("EXIT_POLICIES_FILE", ""),
+
+		// Риск разорения: допустимые вероятности на горизонте N сделок
+		RuinLevel:                  getFloatEnv("RUIN_LEVEL", 0.5),        // Потеря 50% equity = разорение
+		RuinHorizonTrades:          getIntEnv("RUIN_HORIZON_TRADES", 100),
+		RuinWindowTrades:           getIntEnv("RUIN_WINDOW_TRADES", 100),  // Скользящее окно статистики
+		RuinMaxProbability:         getFloatEnv("RUIN_MAX_PROBABILITY", 0.01),
+		RuinMaxRiskPerTrade:        getFloatEnv("RUIN_MAX_RISK_PER_TRADE", 0.02),
+		RuinDrawdownLevel:          getFloatEnv("RUIN_DRAWDOWN_LEVEL", 0.2),
+		RuinMaxDrawdownProbability: getFloatEnv("RUIN_MAX_DRAWDOWN_PROBABILITY", 0.25),
 	}
 
 	return cfg
 }
 
+// RiskRulesFor returns the rule order configured for an account, or nil for
+// the built-in order.
+func (c *Config) RiskRulesFor(account string) []string {
+	c.riskRulesMu.RLock()
+	value, ok := c.RiskRulesByAccount[strings.ToLower(account)]
+	c.riskRulesMu.RUnlock()
+	if !ok {
+		value = c.RiskRules
+	}
+	var rules []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			rules = append(rules, name)
+		}
+	}
+	return rules
+}
+
+// SetRiskRulesFor stores the rule order of an account, e.g. so that a
+// restarted bot gets the rules set at runtime.
+func (c *Config) SetRiskRulesFor(account string, rules []string) {
+	c.riskRulesMu.Lock()
+	defer c.riskRulesMu.Unlock()
+	if c.RiskRulesByAccount == nil {
+		c.RiskRulesByAccount = make(map[string]string)
+	}
+	c.RiskRulesByAccount[strings.ToLower(account)] = strings.Join(rules, ",")
+}
+
+// LabelHorizons returns the signal labeling horizons in candles.
+func (c *Config) LabelHorizons() []int {
+	var horizons []int
+	for _, value := range strings.Split(c.SignalLabelHorizons, ",") {
+		if h, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && h > 0 {
+			horizons = append(horizons, h)
+		}
+	}
+	return horizons
+}
+
+// getPrefixedEnv collects variables starting with prefix, keyed by the
+// lower-cased rest of the name.
+func getPrefixedEnv(prefix string) map[string]string {
+	values := make(map[string]string)
+	for _, kv := range os.Environ() {
+		key, value, ok := strings.Cut(kv, "=")
+		if !ok || !strings.HasPrefix(key, prefix) || value == "" {
+			continue
+		}
+		values[strings.ToLower(strings.TrimPrefix(key, prefix))] = value
+	}
+	return values
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
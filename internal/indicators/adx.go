@@ -141,6 +141,9 @@ func (a *ADX) Signal() Signal {
 			Strength:  strength,
 			Indicator: "ADX",
 			Reason:    "Strong bullish trend",
+			Value:     a.adx,
+			Threshold: 25,
+			Crossed:   "above",
 		}
 	}
 
@@ -151,6 +154,9 @@ func (a *ADX) Signal() Signal {
 			Strength:  strength,
 			Indicator: "ADX",
 			Reason:    "Strong bearish trend",
+			Value:     a.adx,
+			Threshold: 25,
+			Crossed:   "above",
 		}
 	}
 
@@ -170,3 +176,10 @@ func (a *ADX) Reset() {
 	a.count = 0
 }
 
+func (a *ADX) clone() *ADX {
+	clone := *a
+	clone.plusDM = append([]float64(nil), a.plusDM...)
+	clone.minusDM = append([]float64(nil), a.minusDM...)
+	clone.tr = append([]float64(nil), a.tr...)
+	return &clone
+}
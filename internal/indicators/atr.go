@@ -71,3 +71,9 @@ func (a *ATR) Reset() {
 	a.value = 0
 	a.count = 0
 }
+
+func (a *ATR) clone() *ATR {
+	clone := *a
+	clone.trValues = append([]float64(nil), a.trValues...)
+	return &clone
+}
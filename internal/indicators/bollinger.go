@@ -100,6 +100,9 @@ func (bb *BollingerBands) Signal(price float64) Signal {
 			Strength:  0.9 + (0.05-percentB)*2, // 0.9-1.0
 			Indicator: "BollingerBands",
 			Reason:    "Price at lower band",
+			Value:     percentB,
+			Threshold: 0.05,
+			Crossed:   "below",
 		}
 	}
 	
@@ -110,6 +113,9 @@ func (bb *BollingerBands) Signal(price float64) Signal {
 			Strength:  0.5 + (0.20-percentB)/0.15*0.4, // 0.5-0.9
 			Indicator: "BollingerBands",
 			Reason:    "Price in lower band region",
+			Value:     percentB,
+			Threshold: 0.20,
+			Crossed:   "below",
 		}
 	}
 	
@@ -120,6 +126,9 @@ func (bb *BollingerBands) Signal(price float64) Signal {
 			Strength:  0.3 + (0.35-percentB)/0.15*0.2, // 0.3-0.5
 			Indicator: "BollingerBands",
 			Reason:    "Price approaching lower band",
+			Value:     percentB,
+			Threshold: 0.35,
+			Crossed:   "below",
 		}
 	}
 
@@ -130,6 +139,9 @@ func (bb *BollingerBands) Signal(price float64) Signal {
 			Strength:  0.9 + (percentB-0.95)*2, // 0.9-1.0
 			Indicator: "BollingerBands",
 			Reason:    "Price at upper band",
+			Value:     percentB,
+			Threshold: 0.95,
+			Crossed:   "above",
 		}
 	}
 	
@@ -140,6 +152,9 @@ func (bb *BollingerBands) Signal(price float64) Signal {
 			Strength:  0.5 + (percentB-0.80)/0.15*0.4, // 0.5-0.9
 			Indicator: "BollingerBands",
 			Reason:    "Price in upper band region",
+			Value:     percentB,
+			Threshold: 0.80,
+			Crossed:   "above",
 		}
 	}
 	
@@ -150,6 +165,9 @@ func (bb *BollingerBands) Signal(price float64) Signal {
 			Strength:  0.3 + (percentB-0.65)/0.15*0.2, // 0.3-0.5
 			Indicator: "BollingerBands",
 			Reason:    "Price approaching upper band",
+			Value:     percentB,
+			Threshold: 0.65,
+			Crossed:   "above",
 		}
 	}
 
@@ -162,3 +180,9 @@ func (bb *BollingerBands) Reset() {
 	bb.middle = 0
 	bb.lower = 0
 }
+
+func (bb *BollingerBands) clone() *BollingerBands {
+	clone := *bb
+	clone.prices = append([]float64(nil), bb.prices...)
+	return &clone
+}
@@ -71,6 +71,9 @@ func (c *CCI) Signal() Signal {
 			Strength:  strength,
 			Indicator: "CCI",
 			Reason:    "Overbought condition",
+			Value:     c.value,
+			Threshold: 100,
+			Crossed:   "above",
 		}
 	}
 
@@ -81,6 +84,9 @@ func (c *CCI) Signal() Signal {
 			Strength:  strength,
 			Indicator: "CCI",
 			Reason:    "Oversold condition",
+			Value:     c.value,
+			Threshold: -100,
+			Crossed:   "below",
 		}
 	}
 
@@ -91,6 +97,9 @@ func (c *CCI) Signal() Signal {
 			Strength:  0.3,
 			Indicator: "CCI",
 			Reason:    "Moderate overbought",
+			Value:     c.value,
+			Threshold: 50,
+			Crossed:   "above",
 		}
 	}
 
@@ -100,6 +109,9 @@ func (c *CCI) Signal() Signal {
 			Strength:  0.3,
 			Indicator: "CCI",
 			Reason:    "Moderate oversold",
+			Value:     c.value,
+			Threshold: -50,
+			Crossed:   "below",
 		}
 	}
 
@@ -113,3 +125,8 @@ func (c *CCI) Reset() {
 	c.value = 0
 }
 
+func (c *CCI) clone() *CCI {
+	clone := *c
+	clone.prices = append([]float64(nil), c.prices...)
+	return &clone
+}
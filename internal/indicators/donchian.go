@@ -0,0 +1,110 @@
+package indicators
+
+// DonchianChannels are the highest high and lowest low of the last period
+// candles. A close beyond the channel of the preceding candles is a
+// breakout.
+type DonchianChannels struct {
+	period    int
+	highs     []float64
+	lows      []float64
+	upper     float64
+	middle    float64
+	lower     float64
+	prevUpper float64 // Channel of the period candles before the last one
+	prevLower float64
+}
+
+func NewDonchianChannels(period int) *DonchianChannels {
+	return &DonchianChannels{
+		period: period,
+		highs:  make([]float64, 0, period+1),
+		lows:   make([]float64, 0, period+1),
+	}
+}
+
+// DefaultDonchianChannels creates 20-candle channels
+func DefaultDonchianChannels() *DonchianChannels {
+	return NewDonchianChannels(20)
+}
+
+func (dc *DonchianChannels) Update(high, low, close float64) (upper, middle, lower float64) {
+	// Храним period+1 свечей: канал без последней свечи нужен для пробоя
+	dc.highs = pushWindow(dc.highs, high, dc.period+1)
+	dc.lows = pushWindow(dc.lows, low, dc.period+1)
+
+	n := len(dc.highs)
+	current := max(n-dc.period, 0)
+	dc.upper = maxSlice(dc.highs[current:])
+	dc.lower = minSlice(dc.lows[current:])
+	dc.middle = (dc.upper + dc.lower) / 2
+
+	if n > dc.period {
+		dc.prevUpper = maxSlice(dc.highs[:n-1])
+		dc.prevLower = minSlice(dc.lows[:n-1])
+	}
+	return dc.upper, dc.middle, dc.lower
+}
+
+func (dc *DonchianChannels) Values() (upper, middle, lower float64) {
+	return dc.upper, dc.middle, dc.lower
+}
+
+func (dc *DonchianChannels) Upper() float64 {
+	return dc.upper
+}
+
+func (dc *DonchianChannels) Middle() float64 {
+	return dc.middle
+}
+
+func (dc *DonchianChannels) Lower() float64 {
+	return dc.lower
+}
+
+func (dc *DonchianChannels) Signal(price float64) Signal {
+	if len(dc.highs) <= dc.period {
+		return Signal{Type: "HOLD", Indicator: "Donchian"}
+	}
+
+	if price > dc.prevUpper {
+		return Signal{
+			Type:      "BUY",
+			Strength:  0.8,
+			Indicator: "Donchian",
+			Reason:    "Breakout above Donchian Channel",
+			Value:     price,
+			Threshold: dc.prevUpper,
+			Crossed:   "above",
+		}
+	}
+	if price < dc.prevLower {
+		return Signal{
+			Type:      "SELL",
+			Strength:  0.8,
+			Indicator: "Donchian",
+			Reason:    "Breakdown below Donchian Channel",
+			Value:     price,
+			Threshold: dc.prevLower,
+			Crossed:   "below",
+		}
+	}
+
+	return Signal{Type: "HOLD", Indicator: "Donchian"}
+}
+
+func (dc *DonchianChannels) Reset() {
+	dc.highs = dc.highs[:0]
+	dc.lows = dc.lows[:0]
+	dc.upper = 0
+	dc.middle = 0
+	dc.lower = 0
+	dc.prevUpper = 0
+	dc.prevLower = 0
+}
+
+func (dc *DonchianChannels) clone() *DonchianChannels {
+	clone := *dc
+	clone.highs = append([]float64(nil), dc.highs...)
+	clone.lows = append([]float64(nil), dc.lows...)
+	return &clone
+}
@@ -35,6 +35,11 @@ func (e *EMA) Reset() {
 	e.count = 0
 }
 
+func (e *EMA) clone() *EMA {
+	clone := *e
+	return &clone
+}
+
 func (e *EMA) IsReady() bool {
 	return e.count >= e.period
 }
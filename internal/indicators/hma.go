@@ -0,0 +1,139 @@
+package indicators
+
+import "math"
+
+// HullMA (Hull moving average) is WMA(2*WMA(n/2) - WMA(n), sqrt(n)): a
+// smooth average with little lag, read by the direction of its slope.
+type HullMA struct {
+	half  *wma
+	full  *wma
+	hull  *wma
+	value float64
+	prev  float64 // Value of the candle before the last one
+	slope float64 // Last change of the value
+	prior float64 // Change before the last one
+	count int
+}
+
+func NewHullMA(period int) *HullMA {
+	return &HullMA{
+		half: newWMA(max(period/2, 1)),
+		full: newWMA(period),
+		hull: newWMA(max(int(math.Round(math.Sqrt(float64(period)))), 1)),
+	}
+}
+
+// DefaultHullMA creates a 20-candle Hull MA
+func DefaultHullMA() *HullMA {
+	return NewHullMA(20)
+}
+
+func (h *HullMA) Update(price float64) float64 {
+	raw := 2*h.half.update(price) - h.full.update(price)
+	value := h.hull.update(raw)
+
+	h.count++
+	if h.count > 1 {
+		h.prior = h.slope
+		h.slope = value - h.value
+	}
+	h.prev = h.value
+	h.value = value
+	return h.value
+}
+
+func (h *HullMA) Value() float64 {
+	return h.value
+}
+
+// Slope returns the change of the value on the last candle
+func (h *HullMA) Slope() float64 {
+	return h.slope
+}
+
+func (h *HullMA) Signal() Signal {
+	if h.count < 3 {
+		return Signal{Type: "HOLD", Indicator: "HullMA"}
+	}
+
+	// Разворот наклона - основной сигнал Hull MA
+	if h.slope > 0 && h.prior <= 0 {
+		return Signal{
+			Type:      "BUY",
+			Strength:  0.7,
+			Indicator: "HullMA",
+			Reason:    "Hull MA turned up",
+			Value:     h.value,
+			Threshold: h.prev,
+			Crossed:   "above",
+		}
+	}
+	if h.slope < 0 && h.prior >= 0 {
+		return Signal{
+			Type:      "SELL",
+			Strength:  0.7,
+			Indicator: "HullMA",
+			Reason:    "Hull MA turned down",
+			Value:     h.value,
+			Threshold: h.prev,
+			Crossed:   "below",
+		}
+	}
+	if h.slope > 0 {
+		return Signal{Type: "BUY", Strength: 0.4, Indicator: "HullMA", Reason: "Hull MA rising", Value: h.value}
+	}
+	if h.slope < 0 {
+		return Signal{Type: "SELL", Strength: 0.4, Indicator: "HullMA", Reason: "Hull MA falling", Value: h.value}
+	}
+
+	return Signal{Type: "HOLD", Indicator: "HullMA"}
+}
+
+func (h *HullMA) Reset() {
+	h.half.reset()
+	h.full.reset()
+	h.hull.reset()
+	h.value = 0
+	h.prev = 0
+	h.slope = 0
+	h.prior = 0
+	h.count = 0
+}
+
+func (h *HullMA) clone() *HullMA {
+	clone := *h
+	clone.half = h.half.clone()
+	clone.full = h.full.clone()
+	clone.hull = h.hull.clone()
+	return &clone
+}
+
+// wma is a linearly weighted moving average; until the window fills it
+// averages the prices seen so far.
+type wma struct {
+	period int
+	prices []float64
+}
+
+func newWMA(period int) *wma {
+	return &wma{period: period, prices: make([]float64, 0, period)}
+}
+
+func (w *wma) update(price float64) float64 {
+	w.prices = pushWindow(w.prices, price, w.period)
+	var weighted, weights float64
+	for i, p := range w.prices {
+		weight := float64(i + 1)
+		weighted += p * weight
+		weights += weight
+	}
+	return weighted / weights
+}
+
+func (w *wma) reset() {
+	w.prices = w.prices[:0]
+}
+
+func (w *wma) clone() *wma {
+	return &wma{period: w.period, prices: append([]float64(nil), w.prices...)}
+}
@@ -0,0 +1,154 @@
+package indicators
+
+import "math"
+
+// Ichimoku Cloud (Ichimoku Kinko Hyo). The leading spans are projected
+// kijun candles ahead; SpanA/SpanB are the cloud under the current candle,
+// LeadA/LeadB the cloud being drawn ahead of it.
+type Ichimoku struct {
+	tenkanPeriod int
+	kijunPeriod  int
+	senkouPeriod int
+	highs        []float64
+	lows         []float64
+	leadsA       []float64 // Projected span A of the last kijun+1 candles
+	leadsB       []float64
+	tenkan       float64
+	kijun        float64
+	close        float64
+}
+
+func NewIchimoku(tenkanPeriod, kijunPeriod, senkouPeriod int) *Ichimoku {
+	window := max(tenkanPeriod, kijunPeriod, senkouPeriod)
+	return &Ichimoku{
+		tenkanPeriod: tenkanPeriod,
+		kijunPeriod:  kijunPeriod,
+		senkouPeriod: senkouPeriod,
+		highs:        make([]float64, 0, window),
+		lows:         make([]float64, 0, window),
+	}
+}
+
+// DefaultIchimoku creates the cloud with the standard 9, 26, 52 periods
+func DefaultIchimoku() *Ichimoku {
+	return NewIchimoku(9, 26, 52)
+}
+
+func (ic *Ichimoku) Update(high, low, close float64) (tenkan, kijun, spanA, spanB float64) {
+	window := max(ic.tenkanPeriod, ic.kijunPeriod, ic.senkouPeriod)
+	ic.highs = pushWindow(ic.highs, high, window)
+	ic.lows = pushWindow(ic.lows, low, window)
+	ic.close = close
+
+	ic.tenkan = ic.midpoint(ic.tenkanPeriod)
+	ic.kijun = ic.midpoint(ic.kijunPeriod)
+	ic.leadsA = pushWindow(ic.leadsA, (ic.tenkan+ic.kijun)/2, ic.kijunPeriod+1)
+	ic.leadsB = pushWindow(ic.leadsB, ic.midpoint(ic.senkouPeriod), ic.kijunPeriod+1)
+
+	spanA, spanB = ic.Cloud()
+	return ic.tenkan, ic.kijun, spanA, spanB
+}
+
+// midpoint returns the middle of the range of the last period candles.
+func (ic *Ichimoku) midpoint(period int) float64 {
+	n := min(period, len(ic.highs))
+	return (maxSlice(ic.highs[len(ic.highs)-n:]) + minSlice(ic.lows[len(ic.lows)-n:])) / 2
+}
+
+func (ic *Ichimoku) Values() (tenkan, kijun, spanA, spanB float64) {
+	spanA, spanB = ic.Cloud()
+	return ic.tenkan, ic.kijun, spanA, spanB
+}
+
+func (ic *Ichimoku) Tenkan() float64 {
+	return ic.tenkan
+}
+
+func (ic *Ichimoku) Kijun() float64 {
+	return ic.kijun
+}
+
+// Cloud returns the spans projected onto the current candle. Until kijun
+// candles have passed the oldest projection stands in.
+func (ic *Ichimoku) Cloud() (spanA, spanB float64) {
+	if len(ic.leadsA) == 0 {
+		return 0, 0
+	}
+	return ic.leadsA[0], ic.leadsB[0]
+}
+
+// Lead returns the spans computed on the current candle, which form the
+// cloud kijun candles ahead.
+func (ic *Ichimoku) Lead() (leadA, leadB float64) {
+	if len(ic.leadsA) == 0 {
+		return 0, 0
+	}
+	return ic.leadsA[len(ic.leadsA)-1], ic.leadsB[len(ic.leadsB)-1]
+}
+
+func (ic *Ichimoku) Signal() Signal {
+	spanA, spanB := ic.Cloud()
+	top, bottom := math.Max(spanA, spanB), math.Min(spanA, spanB)
+	leadA, leadB := ic.Lead()
+
+	// Price above the cloud: bullish, confirmed by tenkan over kijun and a bullish cloud ahead
+	if ic.close > top {
+		strength := 0.4
+		if ic.tenkan > ic.kijun {
+			strength += 0.3
+		}
+		if leadA > leadB {
+			strength += 0.2
+		}
+		return Signal{
+			Type:      "BUY",
+			Strength:  strength,
+			Indicator: "Ichimoku",
+			Reason:    "Price above the cloud",
+			Value:     ic.close,
+			Threshold: top,
+			Crossed:   "above",
+		}
+	}
+
+	if ic.close < bottom {
+		strength := 0.4
+		if ic.tenkan < ic.kijun {
+			strength += 0.3
+		}
+		if leadA < leadB {
+			strength += 0.2
+		}
+		return Signal{
+			Type:      "SELL",
+			Strength:  strength,
+			Indicator: "Ichimoku",
+			Reason:    "Price below the cloud",
+			Value:     ic.close,
+			Threshold: bottom,
+			Crossed:   "below",
+		}
+	}
+
+	// Price inside the cloud: no trend
+	return Signal{Type: "HOLD", Indicator: "Ichimoku"}
+}
+
+func (ic *Ichimoku) Reset() {
+	ic.highs = ic.highs[:0]
+	ic.lows = ic.lows[:0]
+	ic.leadsA = ic.leadsA[:0]
+	ic.leadsB = ic.leadsB[:0]
+	ic.tenkan = 0
+	ic.kijun = 0
+	ic.close = 0
+}
+
+func (ic *Ichimoku) clone() *Ichimoku {
+	clone := *ic
+	clone.highs = append([]float64(nil), ic.highs...)
+	clone.lows = append([]float64(nil), ic.lows...)
+	clone.leadsA = append([]float64(nil), ic.leadsA...)
+	clone.leadsB = append([]float64(nil), ic.leadsB...)
+	return &clone
+}
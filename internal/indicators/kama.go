@@ -0,0 +1,109 @@
+package indicators
+
+import "math"
+
+// KAMA (Kaufman adaptive moving average) follows the price quickly in
+// efficient, trending markets and barely moves in noisy ones. The
+// efficiency ratio (0-1) is the net change over the period divided by the
+// sum of the absolute changes.
+type KAMA struct {
+	period int
+	fastSC float64
+	slowSC float64
+	prices []float64
+	value  float64
+	prev   float64
+	er     float64
+	close  float64
+}
+
+func NewKAMA(period, fastPeriod, slowPeriod int) *KAMA {
+	return &KAMA{
+		period: period,
+		fastSC: 2.0 / float64(fastPeriod+1),
+		slowSC: 2.0 / float64(slowPeriod+1),
+		prices: make([]float64, 0, period+1),
+	}
+}
+
+// DefaultKAMA creates KAMA with the standard 10, 2, 30 parameters
+func DefaultKAMA() *KAMA {
+	return NewKAMA(10, 2, 30)
+}
+
+func (k *KAMA) Update(price float64) float64 {
+	k.prices = pushWindow(k.prices, price, k.period+1)
+	k.close = price
+	k.prev = k.value
+
+	if len(k.prices) <= k.period {
+		k.value = price
+		return k.value
+	}
+
+	change := math.Abs(price - k.prices[0])
+	var volatility float64
+	for i := 1; i < len(k.prices); i++ {
+		volatility += math.Abs(k.prices[i] - k.prices[i-1])
+	}
+	k.er = 0
+	if volatility > 0 {
+		k.er = change / volatility
+	}
+
+	sc := math.Pow(k.er*(k.fastSC-k.slowSC)+k.slowSC, 2)
+	k.value += sc * (price - k.value)
+	return k.value
+}
+
+func (k *KAMA) Value() float64 {
+	return k.value
+}
+
+// EfficiencyRatio returns the efficiency ratio of the last period candles
+func (k *KAMA) EfficiencyRatio() float64 {
+	return k.er
+}
+
+func (k *KAMA) Signal() Signal {
+	if len(k.prices) <= k.period {
+		return Signal{Type: "HOLD", Indicator: "KAMA"}
+	}
+
+	// Сила сигнала растет с эффективностью движения
+	strength := 0.3 + k.er*0.6 // 0.3-0.9
+	if k.close > k.value && k.value > k.prev {
+		return Signal{
+			Type:      "BUY",
+			Strength:  strength,
+			Indicator: "KAMA",
+			Reason:    "Price above rising KAMA",
+			Value:     k.er,
+		}
+	}
+	if k.close < k.value && k.value < k.prev {
+		return Signal{
+			Type:      "SELL",
+			Strength:  strength,
+			Indicator: "KAMA",
+			Reason:    "Price below falling KAMA",
+			Value:     k.er,
+		}
+	}
+
+	return Signal{Type: "HOLD", Indicator: "KAMA"}
+}
+
+func (k *KAMA) Reset() {
+	k.prices = k.prices[:0]
+	k.value = 0
+	k.prev = 0
+	k.er = 0
+	k.close = 0
+}
+
+func (k *KAMA) clone() *KAMA {
+	clone := *k
+	clone.prices = append([]float64(nil), k.prices...)
+	return &clone
+}
@@ -0,0 +1,110 @@
+package indicators
+
+import "math"
+
+// KeltnerChannels are an EMA of the close with bands a multiple of the ATR
+// above and below it. Unlike Bollinger Bands they are used for breakouts:
+// a close outside the channel signals a move in its direction.
+type KeltnerChannels struct {
+	multiplier float64
+	ema        *EMA
+	atr        *ATR
+	upper      float64
+	middle     float64
+	lower      float64
+}
+
+func NewKeltnerChannels(period, atrPeriod int, multiplier float64) *KeltnerChannels {
+	return &KeltnerChannels{
+		multiplier: multiplier,
+		ema:        NewEMA(period),
+		atr:        NewATR(atrPeriod),
+	}
+}
+
+// DefaultKeltnerChannels creates channels with the standard 20, 10, 2.0
+// parameters
+func DefaultKeltnerChannels() *KeltnerChannels {
+	return NewKeltnerChannels(20, 10, 2.0)
+}
+
+func (kc *KeltnerChannels) Update(high, low, close float64) (upper, middle, lower float64) {
+	kc.middle = kc.ema.Update(close)
+	atr := kc.atr.Update(high, low, close)
+	kc.upper = kc.middle + kc.multiplier*atr
+	kc.lower = kc.middle - kc.multiplier*atr
+	return kc.upper, kc.middle, kc.lower
+}
+
+func (kc *KeltnerChannels) Values() (upper, middle, lower float64) {
+	return kc.upper, kc.middle, kc.lower
+}
+
+func (kc *KeltnerChannels) Upper() float64 {
+	return kc.upper
+}
+
+func (kc *KeltnerChannels) Middle() float64 {
+	return kc.middle
+}
+
+func (kc *KeltnerChannels) Lower() float64 {
+	return kc.lower
+}
+
+// Position returns where price is relative to the channel (0 = lower,
+// 1 = upper)
+func (kc *KeltnerChannels) Position(price float64) float64 {
+	if kc.upper == kc.lower {
+		return 0.5
+	}
+	return (price - kc.lower) / (kc.upper - kc.lower)
+}
+
+func (kc *KeltnerChannels) Signal(price float64) Signal {
+	width := kc.upper - kc.lower
+	if width <= 0 {
+		return Signal{Type: "HOLD", Indicator: "Keltner"}
+	}
+
+	// Пробой канала: сила растет с выходом за полосу на половину ширины канала
+	if price > kc.upper {
+		return Signal{
+			Type:      "BUY",
+			Strength:  0.5 + math.Min((price-kc.upper)/(width/2), 1)*0.4, // 0.5-0.9
+			Indicator: "Keltner",
+			Reason:    "Close above upper Keltner Channel",
+			Value:     price,
+			Threshold: kc.upper,
+			Crossed:   "above",
+		}
+	}
+	if price < kc.lower {
+		return Signal{
+			Type:      "SELL",
+			Strength:  0.5 + math.Min((kc.lower-price)/(width/2), 1)*0.4,
+			Indicator: "Keltner",
+			Reason:    "Close below lower Keltner Channel",
+			Value:     price,
+			Threshold: kc.lower,
+			Crossed:   "below",
+		}
+	}
+
+	return Signal{Type: "HOLD", Indicator: "Keltner"}
+}
+
+func (kc *KeltnerChannels) Reset() {
+	kc.ema.Reset()
+	kc.atr.Reset()
+	kc.upper = 0
+	kc.middle = 0
+	kc.lower = 0
+}
+
+func (kc *KeltnerChannels) clone() *KeltnerChannels {
+	clone := *kc
+	clone.ema = kc.ema.clone()
+	clone.atr = kc.atr.clone()
+	return &clone
+}
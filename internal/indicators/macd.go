@@ -70,6 +70,9 @@ func (m *MACD) Signal() Signal {
 			Strength:  strength,
 			Indicator: "MACD",
 			Reason:    "Bullish MACD",
+			Value:     m.histogram,
+			Threshold: 0,
+			Crossed:   "above",
 		}
 	}
 
@@ -85,6 +88,9 @@ func (m *MACD) Signal() Signal {
 			Strength:  strength,
 			Indicator: "MACD",
 			Reason:    "Bearish MACD",
+			Value:     m.histogram,
+			Threshold: 0,
+			Crossed:   "below",
 		}
 	}
 	
@@ -95,6 +101,9 @@ func (m *MACD) Signal() Signal {
 			Strength:  0.25,
 			Indicator: "MACD",
 			Reason:    "MACD momentum building",
+			Value:     m.histogram,
+			Threshold: -0.0001,
+			Crossed:   "above",
 		}
 	}
 	
@@ -105,6 +114,9 @@ func (m *MACD) Signal() Signal {
 			Strength:  0.25,
 			Indicator: "MACD",
 			Reason:    "MACD momentum weakening",
+			Value:     m.histogram,
+			Threshold: 0.0001,
+			Crossed:   "below",
 		}
 	}
 
@@ -120,3 +132,11 @@ func (m *MACD) Reset() {
 	m.histogram = 0
 	m.count = 0
 }
+
+func (m *MACD) clone() *MACD {
+	clone := *m
+	clone.fastEMA = m.fastEMA.clone()
+	clone.slowEMA = m.slowEMA.clone()
+	clone.signalEMA = m.signalEMA.clone()
+	return &clone
+}
@@ -3,6 +3,9 @@
 package indicators
 
 import (
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -10,49 +13,205 @@ import (
 // It provides a convenient way to calculate and update multiple indicators
 // simultaneously with new price data.
 type IndicatorSet struct {
-	EMA9     *EMA
-	EMA21    *EMA
-	EMA50    *EMA
-	EMA200   *EMA
-	RSI14    *RSI
-	RSI7     *RSI
-	MACD     *MACD
-	BB       *BollingerBands
-	ATR14    *ATR
-	StochRSI *StochRSI
-	OBV      *OBV
-	ADX      *ADX
-	CCI      *CCI
-	Williams *Williams
-	Momentum *Momentum
-	mu       sync.RWMutex
+	EMA9           *EMA
+	EMA21          *EMA
+	EMA50          *EMA
+	EMA200         *EMA
+	RSI14          *RSI
+	RSI7           *RSI
+	MACD           *MACD
+	BB             *BollingerBands
+	ATR14          *ATR
+	StochRSI       *StochRSI
+	OBV            *OBV
+	ADX            *ADX
+	CCI            *CCI
+	Williams       *Williams
+	Momentum       *Momentum
+	RegimeDetector *RegimeDetector
+	custom         []*SpecIndicator // Additional instances built from specs, see SetSpecs
+	lastOpenTime   int64            // Open time of the last candle fed by UpdateClosedAt
+	mu             sync.RWMutex
 }
 
 func NewIndicatorSet() *IndicatorSet {
 	return &IndicatorSet{
-		EMA9:     NewEMA(9),
-		EMA21:    NewEMA(21),
-		EMA50:    NewEMA(50),
-		EMA200:   NewEMA(200),
-		RSI14:    NewRSI(14),
-		RSI7:     NewRSI(7),
-		MACD:     DefaultMACD(),
-		BB:       DefaultBollingerBands(),
-		ATR14:    NewATR(14),
-		StochRSI: DefaultStochRSI(),
-		OBV:      NewOBV(),
-		ADX:      DefaultADX(),
-		CCI:      DefaultCCI(),
-		Williams: DefaultWilliams(),
-		Momentum: DefaultMomentum(),
+		EMA9:           NewEMA(9),
+		EMA21:          NewEMA(21),
+		EMA50:          NewEMA(50),
+		EMA200:         NewEMA(200),
+		RSI14:          NewRSI(14),
+		RSI7:           NewRSI(7),
+		MACD:           DefaultMACD(),
+		BB:             DefaultBollingerBands(),
+		ATR14:          NewATR(14),
+		StochRSI:       DefaultStochRSI(),
+		OBV:            NewOBV(),
+		ADX:            DefaultADX(),
+		CCI:            DefaultCCI(),
+		Williams:       DefaultWilliams(),
+		Momentum:       DefaultMomentum(),
+		RegimeDetector: NewRegimeDetector(DefaultRegimeThresholds()),
 	}
 }
 
+// NewIndicatorSetWithSpecs creates the default preset plus the indicators
+// selected by specs, e.g. rsi(21) or bb(20,2.5).
+func NewIndicatorSetWithSpecs(specs []Spec) (*IndicatorSet, error) {
+	set := NewIndicatorSet()
+	if err := set.SetSpecs(specs); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// SetSpecs replaces the additional indicators of the set. Instances whose
+// spec is unchanged keep their state; new ones start from scratch.
+func (is *IndicatorSet) SetSpecs(specs []Spec) error {
+	custom, err := buildSpecIndicators(specs)
+	if err != nil {
+		return err
+	}
+
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	existing := make(map[string]*SpecIndicator, len(is.custom))
+	for _, si := range is.custom {
+		existing[si.spec.String()] = si
+	}
+	for i, si := range custom {
+		if old, ok := existing[si.spec.String()]; ok {
+			custom[i] = old
+		}
+	}
+	is.custom = custom
+	return nil
+}
+
+// Specs returns the resolved specs of the additional indicators.
+func (is *IndicatorSet) Specs() []Spec {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+
+	specs := make([]Spec, len(is.custom))
+	for i, si := range is.custom {
+		specs[i] = si.spec
+	}
+	return specs
+}
+
+// Keys returns the keys of the set's values in IndicatorValues.Map: the
+// preset names followed by the keys of the spec indicators.
+func (is *IndicatorSet) Keys() []string {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+
+	keys := make([]string, 0, len(presetKeys)+len(is.custom))
+	for key := range presetKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, si := range is.custom {
+		keys = append(keys, si.keys...)
+	}
+	return keys
+}
+
+// buildSpecIndicators creates an instance per spec and rejects specs whose
+// values would share keys with each other or with the default preset.
+func buildSpecIndicators(specs []Spec) ([]*SpecIndicator, error) {
+	custom := make([]*SpecIndicator, 0, len(specs))
+	keys := make(map[string]string)
+	for _, spec := range specs {
+		si, err := defaultRegistry.New(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range si.keys {
+			if presetKeys[key] {
+				return nil, fmt.Errorf("indicator key %q is already provided by the default preset", key)
+			}
+			if other, ok := keys[key]; ok {
+				return nil, fmt.Errorf("indicator specs %s and %s both define %q", other, si.spec, key)
+			}
+			keys[key] = si.spec.String()
+		}
+		custom = append(custom, si)
+	}
+	return custom, nil
+}
+
 // UpdateAll updates all indicators with new candle data
 func (is *IndicatorSet) UpdateAll(high, low, close, volume float64) *IndicatorValues {
+	return is.UpdateAllAt(0, high, low, close, volume)
+}
+
+// UpdateAllAt is UpdateAll for a candle opening at openTime (Unix ms), which
+// time-dependent indicators such as session VWAP need; 0 means unknown
+func (is *IndicatorSet) UpdateAllAt(openTime int64, high, low, close, volume float64) *IndicatorValues {
 	is.mu.Lock()
 	defer is.mu.Unlock()
+	return is.update(openTime, high, low, close, volume)
+}
+
+// UpdateClosedAt feeds a closed candle unless the set has already seen a
+// candle opening at or after openTime, so that overlapping sources such as
+// cached history and the kline stream count each candle once. ok is false
+// for skipped candles.
+func (is *IndicatorSet) UpdateClosedAt(openTime int64, high, low, close, volume float64) (values *IndicatorValues, ok bool) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+
+	if openTime <= is.lastOpenTime {
+		return nil, false
+	}
+	is.lastOpenTime = openTime
+	return is.update(openTime, high, low, close, volume), true
+}
+
+// LastOpenTime returns the open time of the last candle fed by
+// UpdateClosedAt; 0 if there was none
+func (is *IndicatorSet) LastOpenTime() int64 {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	return is.lastOpenTime
+}
+
+// Clone returns an independent copy of the set, e.g. to preview a forming
+// candle without touching the closed-candle state
+func (is *IndicatorSet) Clone() *IndicatorSet {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
 
+	clone := &IndicatorSet{
+		EMA9:           is.EMA9.clone(),
+		EMA21:          is.EMA21.clone(),
+		EMA50:          is.EMA50.clone(),
+		EMA200:         is.EMA200.clone(),
+		RSI14:          is.RSI14.clone(),
+		RSI7:           is.RSI7.clone(),
+		MACD:           is.MACD.clone(),
+		BB:             is.BB.clone(),
+		ATR14:          is.ATR14.clone(),
+		StochRSI:       is.StochRSI.clone(),
+		OBV:            is.OBV.clone(),
+		ADX:            is.ADX.clone(),
+		CCI:            is.CCI.clone(),
+		Williams:       is.Williams.clone(),
+		Momentum:       is.Momentum.clone(),
+		RegimeDetector: is.RegimeDetector.clone(),
+		custom:         make([]*SpecIndicator, len(is.custom)),
+		lastOpenTime:   is.lastOpenTime,
+	}
+	for i, si := range is.custom {
+		clone.custom[i] = si.clone()
+	}
+	return clone
+}
+
+// update feeds a candle to every indicator. The caller must hold the lock.
+func (is *IndicatorSet) update(openTime int64, high, low, close, volume float64) *IndicatorValues {
 	// Update all indicators
 	ema9 := is.EMA9.Update(close)
 	ema21 := is.EMA21.Update(close)
@@ -78,6 +237,17 @@ func (is *IndicatorSet) UpdateAll(high, low, close, volume float64) *IndicatorVa
 	williams := is.Williams.Update(high, low, close)
 	momentum := is.Momentum.Update(close)
 
+	regime := is.RegimeDetector.Update(close, atr, is.BB.Bandwidth(), adx, is.ADX.PlusDI(), is.ADX.MinusDI())
+
+	var custom map[string]float64
+	if len(is.custom) > 0 {
+		custom = make(map[string]float64)
+		for _, si := range is.custom {
+			si.UpdateAt(openTime, high, low, close, volume)
+			si.fill(custom)
+		}
+	}
+
 	return &IndicatorValues{
 		EMA9:       ema9,
 		EMA21:      ema21,
@@ -100,9 +270,18 @@ func (is *IndicatorSet) UpdateAll(high, low, close, volume float64) *IndicatorVa
 		CCI:        cci,
 		Williams:   williams,
 		Momentum:   momentum,
+		Regime:     regime,
+		Custom:     custom,
 	}
 }
 
+// Regime returns the market regime at the last candle
+func (is *IndicatorSet) Regime() Regime {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	return is.RegimeDetector.Regime()
+}
+
 // GetSignals returns all current signals
 func (is *IndicatorSet) GetSignals(price float64) []Signal {
 	is.mu.RLock()
@@ -120,6 +299,13 @@ func (is *IndicatorSet) GetSignals(price float64) []Signal {
 		is.Momentum.Signal(),
 	}
 
+	// Голоса дополнительных индикаторов названы ключом спецификации, например "rsi(21)"
+	for _, si := range is.custom {
+		if signal, ok := si.Signal(price); ok {
+			signals = append(signals, signal)
+		}
+	}
+
 	// EMA crossover signals - more sensitive
 	ema9 := is.EMA9.Value()
 	ema21 := is.EMA21.Value()
@@ -238,43 +424,198 @@ func (is *IndicatorSet) Reset() {
 	is.CCI.Reset()
 	is.Williams.Reset()
 	is.Momentum.Reset()
+	is.RegimeDetector.Reset()
+	for _, si := range is.custom {
+		si.Reset()
+	}
 }
 
 // IndicatorValues holds all calculated values
 type IndicatorValues struct {
-	EMA9       float64 `json:"ema9"`
-	EMA21      float64 `json:"ema21"`
-	EMA50      float64 `json:"ema50"`
-	EMA200     float64 `json:"ema200"`
-	RSI14      float64 `json:"rsi14"`
-	RSI7       float64 `json:"rsi7"`
-	MACDLine   float64 `json:"macdLine"`
-	MACDSignal float64 `json:"macdSignal"`
-	MACDHist   float64 `json:"macdHist"`
-	BBUpper    float64 `json:"bbUpper"`
-	BBMiddle   float64 `json:"bbMiddle"`
-	BBLower    float64 `json:"bbLower"`
-	BBPercentB float64 `json:"bbPercentB"`
-	ATR14      float64 `json:"atr14"`
-	StochRSI_K float64 `json:"stochRsiK"`
-	StochRSI_D float64 `json:"stochRsiD"`
-	OBV        float64 `json:"obv"`
-	ADX        float64 `json:"adx"`
-	CCI        float64 `json:"cci"`
-	Williams   float64 `json:"williams"`
-	Momentum   float64 `json:"momentum"`
+	EMA9       float64            `json:"ema9"`
+	EMA21      float64            `json:"ema21"`
+	EMA50      float64            `json:"ema50"`
+	EMA200     float64            `json:"ema200"`
+	RSI14      float64            `json:"rsi14"`
+	RSI7       float64            `json:"rsi7"`
+	MACDLine   float64            `json:"macdLine"`
+	MACDSignal float64            `json:"macdSignal"`
+	MACDHist   float64            `json:"macdHist"`
+	BBUpper    float64            `json:"bbUpper"`
+	BBMiddle   float64            `json:"bbMiddle"`
+	BBLower    float64            `json:"bbLower"`
+	BBPercentB float64            `json:"bbPercentB"`
+	ATR14      float64            `json:"atr14"`
+	StochRSI_K float64            `json:"stochRsiK"`
+	StochRSI_D float64            `json:"stochRsiD"`
+	OBV        float64            `json:"obv"`
+	ADX        float64            `json:"adx"`
+	CCI        float64            `json:"cci"`
+	Williams   float64            `json:"williams"`
+	Momentum   float64            `json:"momentum"`
+	Regime     Regime             `json:"regime"`
+	Custom     map[string]float64 `json:"custom,omitempty"` // Values of the indicators added by specs, by key
+}
+
+// presetKeys are the keys of the default preset in IndicatorValues.Map.
+var presetKeys = map[string]bool{
+	"ema9": true, "ema21": true, "ema50": true, "ema200": true, "rsi14": true, "rsi7": true,
+	"macdLine": true, "macdSignal": true, "macdHist": true, "bbUpper": true, "bbMiddle": true,
+	"bbLower": true, "bbPercentB": true, "atr14": true, "stochRsiK": true, "stochRsiD": true,
+	"obv": true, "adx": true, "cci": true, "williams": true, "momentum": true,
+}
+
+// Map returns all values keyed by name: the default preset under its JSON
+// names (ema9, macdHist, ...) and the spec indicators under their keys
+// (rsi(21), bb(20,2.5).upper, ...).
+func (v *IndicatorValues) Map() map[string]float64 {
+	values := map[string]float64{
+		"ema9":       v.EMA9,
+		"ema21":      v.EMA21,
+		"ema50":      v.EMA50,
+		"ema200":     v.EMA200,
+		"rsi14":      v.RSI14,
+		"rsi7":       v.RSI7,
+		"macdLine":   v.MACDLine,
+		"macdSignal": v.MACDSignal,
+		"macdHist":   v.MACDHist,
+		"bbUpper":    v.BBUpper,
+		"bbMiddle":   v.BBMiddle,
+		"bbLower":    v.BBLower,
+		"bbPercentB": v.BBPercentB,
+		"atr14":      v.ATR14,
+		"stochRsiK":  v.StochRSI_K,
+		"stochRsiD":  v.StochRSI_D,
+		"obv":        v.OBV,
+		"adx":        v.ADX,
+		"cci":        v.CCI,
+		"williams":   v.Williams,
+		"momentum":   v.Momentum,
+	}
+	for key, value := range v.Custom {
+		values[key] = value
+	}
+	return values
+}
+
+// Get returns a value by its key in Map.
+func (v *IndicatorValues) Get(key string) (float64, bool) {
+	if value, ok := v.Custom[key]; ok {
+		return value, true
+	}
+	value, ok := v.Map()[key]
+	return value, ok
 }
 
 // IndicatorManager manages indicators for multiple symbols/timeframes
 type IndicatorManager struct {
-	sets map[string]*IndicatorSet // key: "symbol:timeframe"
-	mu   sync.RWMutex
+	sets             map[string]*IndicatorSet // key: "symbol:timeframe"
+	regimeThresholds RegimeThresholds
+	specs            map[string][]Spec // Additional indicators by symbol; "" applies to symbols without their own
+	mu               sync.RWMutex
 }
 
 func NewIndicatorManager() *IndicatorManager {
 	return &IndicatorManager{
-		sets: make(map[string]*IndicatorSet),
+		sets:             make(map[string]*IndicatorSet),
+		regimeThresholds: DefaultRegimeThresholds(),
+		specs:            make(map[string][]Spec),
+	}
+}
+
+// SetSpecs selects the additional indicators of symbol, or of all symbols
+// without their own selection when symbol is empty, and applies them to
+// the existing sets. nil specs remove the selection.
+func (im *IndicatorManager) SetSpecs(symbol string, specs []Spec) error {
+	if _, err := buildSpecIndicators(specs); err != nil {
+		return err
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	if specs == nil {
+		delete(im.specs, symbol)
+	} else {
+		im.specs[symbol] = append([]Spec(nil), specs...)
+	}
+	for key, set := range im.sets {
+		setSymbol, _, _ := strings.Cut(key, ":")
+		if symbol == "" || setSymbol == symbol {
+			if err := set.SetSpecs(im.specsFor(setSymbol)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Specs returns the selected additional indicators by symbol; "" holds the
+// selection of all other symbols.
+func (im *IndicatorManager) Specs() map[string][]Spec {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+
+	specs := make(map[string][]Spec, len(im.specs))
+	for symbol, list := range im.specs {
+		specs[symbol] = append([]Spec(nil), list...)
+	}
+	return specs
+}
+
+func (im *IndicatorManager) specsFor(symbol string) []Spec {
+	if specs, ok := im.specs[symbol]; ok {
+		return specs
 	}
+	return im.specs[""]
+}
+
+// SetRegimeThresholds configures regime detection of all existing and new
+// indicator sets
+func (im *IndicatorManager) SetRegimeThresholds(thresholds RegimeThresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return err
+	}
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.regimeThresholds = thresholds
+	for _, set := range im.sets {
+		set.mu.Lock()
+		set.RegimeDetector.SetThresholds(thresholds)
+		set.mu.Unlock()
+	}
+	return nil
+}
+
+// Regime returns the market regime of a symbol/timeframe; false if no
+// candles were seen for it
+func (im *IndicatorManager) Regime(symbol, timeframe string) (Regime, bool) {
+	im.mu.RLock()
+	set, ok := im.sets[symbol+":"+timeframe]
+	im.mu.RUnlock()
+	if !ok {
+		return Regime{}, false
+	}
+	return set.Regime(), true
+}
+
+// Regimes returns the market regime of every symbol/timeframe keyed by
+// "symbol:timeframe"
+func (im *IndicatorManager) Regimes() map[string]Regime {
+	im.mu.RLock()
+	sets := make(map[string]*IndicatorSet, len(im.sets))
+	for key, set := range im.sets {
+		sets[key] = set
+	}
+	im.mu.RUnlock()
+
+	result := make(map[string]Regime, len(sets))
+	for key, set := range sets {
+		result[key] = set.Regime()
+	}
+	return result
 }
 
 func (im *IndicatorManager) GetOrCreate(symbol, timeframe string) *IndicatorSet {
@@ -295,7 +636,25 @@ func (im *IndicatorManager) GetOrCreate(symbol, timeframe string) *IndicatorSet
 		return set
 	}
 
-	set := NewIndicatorSet()
+	set := im.newSet(symbol)
 	im.sets[key] = set
 	return set
 }
+
+// NewSet creates a set configured like the managed sets of symbol that the
+// manager does not keep, e.g. for one-off calculations.
+func (im *IndicatorManager) NewSet(symbol string) *IndicatorSet {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return im.newSet(symbol)
+}
+
+// newSet builds a set with the regime thresholds and specs of symbol. The
+// caller must hold the lock.
+func (im *IndicatorManager) newSet(symbol string) *IndicatorSet {
+	set := NewIndicatorSet()
+	set.RegimeDetector = NewRegimeDetector(im.regimeThresholds)
+	// Спецификации проверены в SetSpecs; при ошибке остается только пресет по умолчанию
+	set.SetSpecs(im.specsFor(symbol))
+	return set
+}
@@ -0,0 +1,153 @@
+package indicators
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+type testCandle struct {
+	openTime                 int64
+	high, low, close, volume float64
+}
+
+// testCandles returns n one-minute candles of a wave with a drift.
+func testCandles(n int) []testCandle {
+	candles := make([]testCandle, n)
+	for i := range candles {
+		c := 100 + 10*math.Sin(float64(i)/7) + float64(i)/10
+		candles[i] = testCandle{
+			openTime: int64(i) * 60000,
+			high:     c + 1 + math.Cos(float64(i)/3),
+			low:      c - 1 - math.Sin(float64(i)/5)/2,
+			close:    c,
+			volume:   1000 + 100*math.Cos(float64(i)/4),
+		}
+	}
+	return candles
+}
+
+func feedCandles(set *IndicatorSet, candles []testCandle) *IndicatorValues {
+	var values *IndicatorValues
+	for _, c := range candles {
+		values = set.UpdateAllAt(c.openTime, c.high, c.low, c.close, c.volume)
+	}
+	return values
+}
+
+// sumCalculator sums the closes and is not a Cloner.
+type sumCalculator struct{ sum float64 }
+
+func (c *sumCalculator) Update(_, _, close, _ float64) []float64 {
+	c.sum += close
+	return []float64{c.sum}
+}
+
+func (c *sumCalculator) Reset() { c.sum = 0 }
+
+func TestIndicatorSetClone(t *testing.T) {
+	tests := []struct {
+		name  string
+		specs string
+	}{
+		{"default preset", ""},
+		{"spec indicators", "ema(5), rsi(21), macd(5,10,3), band=bb(10,2.5), atr(7), stochrsi, volume=obv, adx(7,7), cci, williams, momentum(5)"},
+		{"time and window indicators", "vwap, avwap(600000), ichimoku(5,10,20), supertrend, psar, keltner, donchian, hma(9), kama"},
+	}
+	candles := testCandles(120)
+	history, forming, next := candles[:100], candles[100], candles[101:]
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			specs, err := ParseSpecs(tt.specs)
+			if err != nil {
+				t.Fatalf("ParseSpecs() = %v", err)
+			}
+			newSet := func() *IndicatorSet {
+				set, err := NewIndicatorSetWithSpecs(specs)
+				if err != nil {
+					t.Fatalf("NewIndicatorSetWithSpecs() = %v", err)
+				}
+				return set
+			}
+
+			set := newSet()
+			feedCandles(set, history)
+
+			// Копия считает формирующуюся свечу так же, как набор, получивший ее напрямую
+			preview := set.Clone().UpdateAllAt(forming.openTime, forming.high, forming.low, forming.close, forming.volume)
+			reference := newSet()
+			want := feedCandles(reference, append(append([]testCandle(nil), history...), forming))
+			if !reflect.DeepEqual(preview, want) {
+				t.Errorf("clone preview = %+v, want %+v", preview, want)
+			}
+
+			// Исходный набор не видел формирующуюся свечу
+			unchanged := newSet()
+			feedCandles(unchanged, history)
+			if got, want := feedCandles(set, next), feedCandles(unchanged, next); !reflect.DeepEqual(got, want) {
+				t.Errorf("set after a clone preview = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestIndicatorSetCloneFreezesUncloneable(t *testing.T) {
+	set := NewIndicatorSet()
+	set.custom = []*SpecIndicator{{spec: Spec{Name: "sum"}, keys: []string{"sum"}, calc: &sumCalculator{}}}
+	candles := testCandles(3)
+	feedCandles(set, candles)
+	var sum float64
+	for _, c := range candles {
+		sum += c.close
+	}
+
+	tests := []struct {
+		name    string
+		set     *IndicatorSet
+		wantSum float64
+	}{
+		// Копия не может обновить калькулятор без Clone и оставляет последнее значение
+		{"clone", set.Clone(), sum},
+		{"original", set, sum + 105},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := tt.set.UpdateAllAt(0, 110, 100, 105, 1)
+			if got := values.Custom["sum"]; math.Abs(got-tt.wantSum) > 1e-9 {
+				t.Errorf("sum = %.4f, want %.4f", got, tt.wantSum)
+			}
+		})
+	}
+}
+
+func TestUpdateClosedAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		openTimes []int64
+		wantOK    []bool
+		wantLast  int64
+	}{
+		{"in order", []int64{60000, 120000, 180000}, []bool{true, true, true}, 180000},
+		{"repeated candle", []int64{60000, 120000, 120000}, []bool{true, true, false}, 120000},
+		{"older candle", []int64{120000, 60000, 180000}, []bool{true, false, true}, 180000},
+		{"gap", []int64{60000, 300000}, []bool{true, true}, 300000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := NewIndicatorSet()
+			if got := set.LastOpenTime(); got != 0 {
+				t.Fatalf("LastOpenTime() of a new set = %d, want 0", got)
+			}
+			for i, openTime := range tt.openTimes {
+				values, ok := set.UpdateClosedAt(openTime, 101, 99, 100, 10)
+				if ok != tt.wantOK[i] || (values != nil) != ok {
+					t.Errorf("UpdateClosedAt(%d) = %v, %v; want ok %v", openTime, values, ok, tt.wantOK[i])
+				}
+			}
+			if got := set.LastOpenTime(); got != tt.wantLast {
+				t.Errorf("LastOpenTime() = %d, want %d", got, tt.wantLast)
+			}
+		})
+	}
+}
@@ -64,6 +64,9 @@ func (m *Momentum) Signal() Signal {
 			Strength:  strength,
 			Indicator: "Momentum",
 			Reason:    "Positive momentum",
+			Value:     m.value,
+			Threshold: 1.0,
+			Crossed:   "above",
 		}
 	}
 
@@ -77,6 +80,9 @@ func (m *Momentum) Signal() Signal {
 			Strength:  strength,
 			Indicator: "Momentum",
 			Reason:    "Negative momentum",
+			Value:     m.value,
+			Threshold: -1.0,
+			Crossed:   "below",
 		}
 	}
 
@@ -87,6 +93,9 @@ func (m *Momentum) Signal() Signal {
 			Strength:  0.25,
 			Indicator: "Momentum",
 			Reason:    "Weak positive momentum",
+			Value:     m.value,
+			Threshold: 0.3,
+			Crossed:   "above",
 		}
 	}
 
@@ -96,6 +105,9 @@ func (m *Momentum) Signal() Signal {
 			Strength:  0.25,
 			Indicator: "Momentum",
 			Reason:    "Weak negative momentum",
+			Value:     m.value,
+			Threshold: -0.3,
+			Crossed:   "below",
 		}
 	}
 
@@ -107,3 +119,8 @@ func (m *Momentum) Reset() {
 	m.value = 0
 }
 
+func (m *Momentum) clone() *Momentum {
+	clone := *m
+	clone.prices = append([]float64(nil), m.prices...)
+	return &clone
+}
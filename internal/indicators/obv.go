@@ -54,3 +54,8 @@ func (o *OBV) Reset() {
 	o.prevOBV = 0
 	o.count = 0
 }
+
+func (o *OBV) clone() *OBV {
+	clone := *o
+	return &clone
+}
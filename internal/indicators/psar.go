@@ -0,0 +1,164 @@
+package indicators
+
+import "math"
+
+// ParabolicSAR (stop and reverse) trails the price with a stop that
+// accelerates towards it as the trend makes new extremes.
+type ParabolicSAR struct {
+	step      float64 // Acceleration factor increment
+	maxStep   float64 // Acceleration factor cap
+	af        float64
+	sar       float64
+	ep        float64 // Extreme point of the current trend
+	up        bool
+	reversed  bool // The trend reversed on the last candle
+	highs     []float64
+	lows      []float64
+	close     float64
+	prevClose float64
+	count     int
+}
+
+func NewParabolicSAR(step, maxStep float64) *ParabolicSAR {
+	return &ParabolicSAR{
+		step:    step,
+		maxStep: maxStep,
+		highs:   make([]float64, 0, 2),
+		lows:    make([]float64, 0, 2),
+	}
+}
+
+// DefaultParabolicSAR creates the SAR with the standard 0.02, 0.2 factors
+func DefaultParabolicSAR() *ParabolicSAR {
+	return NewParabolicSAR(0.02, 0.2)
+}
+
+func (p *ParabolicSAR) Update(high, low, close float64) float64 {
+	p.count++
+	p.close = close
+	p.reversed = false
+
+	switch p.count {
+	case 1:
+		p.sar = low
+	case 2:
+		// Направление первого тренда задает вторая свеча
+		p.up = close >= p.prevClose
+		p.af = p.step
+		if p.up {
+			p.sar = math.Min(p.lows[0], low)
+			p.ep = math.Max(p.highs[0], high)
+		} else {
+			p.sar = math.Max(p.highs[0], high)
+			p.ep = math.Min(p.lows[0], low)
+		}
+	default:
+		sar := p.sar + p.af*(p.ep-p.sar)
+		if p.up {
+			// SAR не может заходить за минимумы двух предыдущих свечей
+			sar = math.Min(sar, minSlice(p.lows))
+			if low < sar {
+				p.up, p.reversed = false, true
+				sar, p.ep, p.af = p.ep, low, p.step
+			} else if high > p.ep {
+				p.ep = high
+				p.af = math.Min(p.af+p.step, p.maxStep)
+			}
+		} else {
+			sar = math.Max(sar, maxSlice(p.highs))
+			if high > sar {
+				p.up, p.reversed = true, true
+				sar, p.ep, p.af = p.ep, high, p.step
+			} else if low < p.ep {
+				p.ep = low
+				p.af = math.Min(p.af+p.step, p.maxStep)
+			}
+		}
+		p.sar = sar
+	}
+
+	p.highs = pushWindow(p.highs, high, 2)
+	p.lows = pushWindow(p.lows, low, 2)
+	p.prevClose = close
+	return p.sar
+}
+
+func (p *ParabolicSAR) Value() float64 {
+	return p.sar
+}
+
+// Direction returns 1 while the SAR trails an uptrend and -1 in a downtrend
+func (p *ParabolicSAR) Direction() float64 {
+	if p.up {
+		return 1
+	}
+	return -1
+}
+
+func (p *ParabolicSAR) Signal() Signal {
+	if p.count < 3 {
+		return Signal{Type: "HOLD", Indicator: "ParabolicSAR"}
+	}
+
+	if p.reversed {
+		if p.up {
+			return Signal{
+				Type:      "BUY",
+				Strength:  0.8,
+				Indicator: "ParabolicSAR",
+				Reason:    "Parabolic SAR reversed below price",
+				Value:     p.close,
+				Threshold: p.sar,
+				Crossed:   "above",
+			}
+		}
+		return Signal{
+			Type:      "SELL",
+			Strength:  0.8,
+			Indicator: "ParabolicSAR",
+			Reason:    "Parabolic SAR reversed above price",
+			Value:     p.close,
+			Threshold: p.sar,
+			Crossed:   "below",
+		}
+	}
+
+	// Чем больше ускорение, тем зрелее тренд
+	strength := 0.3 + (p.af-p.step)/math.Max(p.maxStep-p.step, p.step)*0.3 // 0.3-0.6
+	if p.up {
+		return Signal{
+			Type:      "BUY",
+			Strength:  strength,
+			Indicator: "ParabolicSAR",
+			Reason:    "Price above Parabolic SAR",
+			Value:     p.close,
+		}
+	}
+	return Signal{
+		Type:      "SELL",
+		Strength:  strength,
+		Indicator: "ParabolicSAR",
+		Reason:    "Price below Parabolic SAR",
+		Value:     p.close,
+	}
+}
+
+func (p *ParabolicSAR) Reset() {
+	p.af = 0
+	p.sar = 0
+	p.ep = 0
+	p.up = false
+	p.reversed = false
+	p.highs = p.highs[:0]
+	p.lows = p.lows[:0]
+	p.close = 0
+	p.prevClose = 0
+	p.count = 0
+}
+
+func (p *ParabolicSAR) clone() *ParabolicSAR {
+	clone := *p
+	clone.highs = append([]float64(nil), p.highs...)
+	clone.lows = append([]float64(nil), p.lows...)
+	return &clone
+}
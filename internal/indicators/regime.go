@@ -0,0 +1,327 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Market regime labels. A regime carries at most one trend label and one
+// volatility label.
+const (
+	RegimeTrend   = "trend"    // ADX shows a directional market
+	RegimeRange   = "range"    // Weak ADX, price oscillates in a band
+	RegimeHighVol = "high_vol" // ATR, realized volatility or bandwidth in the top percentiles
+	RegimeLowVol  = "low_vol"  // ATR and realized volatility in the bottom percentiles
+)
+
+// IsRegimeLabel reports whether label is one of the Regime* constants.
+func IsRegimeLabel(label string) bool {
+	switch label {
+	case RegimeTrend, RegimeRange, RegimeHighVol, RegimeLowVol:
+		return true
+	}
+	return false
+}
+
+// RegimeThresholds configures the regime classifier.
+type RegimeThresholds struct {
+	TrendADX          float64 `json:"trendAdx"`          // ADX at or above which the market trends (default 25)
+	RangeADX          float64 `json:"rangeAdx"`          // ADX below which the market ranges (default 20)
+	HighVolPercentile float64 `json:"highVolPercentile"` // Percentile at or above which volatility is high (default 80)
+	LowVolPercentile  float64 `json:"lowVolPercentile"`  // Percentile at or below which volatility is low (default 20)
+	Lookback          int     `json:"lookback"`          // Candles the percentiles are ranked against (default 100)
+	VolatilityWindow  int     `json:"volatilityWindow"`  // Candles of returns in the realized volatility (default 20)
+}
+
+// DefaultRegimeThresholds returns the classifier defaults.
+func DefaultRegimeThresholds() RegimeThresholds {
+	return RegimeThresholds{
+		TrendADX:          25,
+		RangeADX:          20,
+		HighVolPercentile: 80,
+		LowVolPercentile:  20,
+		Lookback:          100,
+		VolatilityWindow:  20,
+	}
+}
+
+// Validate fills defaults and checks the thresholds.
+func (t *RegimeThresholds) Validate() error {
+	defaults := DefaultRegimeThresholds()
+	if t.TrendADX <= 0 {
+		t.TrendADX = defaults.TrendADX
+	}
+	if t.RangeADX <= 0 {
+		t.RangeADX = defaults.RangeADX
+	}
+	if t.HighVolPercentile <= 0 {
+		t.HighVolPercentile = defaults.HighVolPercentile
+	}
+	if t.LowVolPercentile <= 0 {
+		t.LowVolPercentile = defaults.LowVolPercentile
+	}
+	if t.Lookback <= 0 {
+		t.Lookback = defaults.Lookback
+	}
+	if t.VolatilityWindow <= 1 {
+		t.VolatilityWindow = defaults.VolatilityWindow
+	}
+	if t.RangeADX > t.TrendADX {
+		return fmt.Errorf("regime range ADX %.1f must not exceed trend ADX %.1f", t.RangeADX, t.TrendADX)
+	}
+	if t.LowVolPercentile >= t.HighVolPercentile || t.HighVolPercentile > 100 {
+		return fmt.Errorf("regime volatility percentiles must satisfy 0 < low < high <= 100")
+	}
+	return nil
+}
+
+// Regime is the market regime of a symbol/timeframe at the last candle.
+type Regime struct {
+	Trend                 string  `json:"trend"`      // RegimeTrend, RegimeRange or empty between the ADX thresholds
+	Volatility            string  `json:"volatility"` // RegimeHighVol, RegimeLowVol or empty for normal volatility
+	Direction             string  `json:"direction"`  // "up" or "down" by +DI/-DI, empty without a trend
+	ADX                   float64 `json:"adx"`
+	Bandwidth             float64 `json:"bandwidth"`             // Bollinger bandwidth
+	BandwidthPercentile   float64 `json:"bandwidthPercentile"`   // 0-100 within the lookback
+	ATRPercent            float64 `json:"atrPercent"`            // ATR relative to the close
+	ATRPercentile         float64 `json:"atrPercentile"`         // 0-100 within the lookback
+	RealizedVol           float64 `json:"realizedVol"`           // Standard deviation of log returns per candle
+	RealizedVolPercentile float64 `json:"realizedVolPercentile"` // 0-100 within the lookback
+	Samples               int     `json:"samples"`               // Candles seen
+	Ready                 bool    `json:"ready"`                 // Enough history to classify
+}
+
+// Labels returns the regime labels that apply, trend label first.
+func (r Regime) Labels() []string {
+	labels := make([]string, 0, 2)
+	if r.Trend != "" {
+		labels = append(labels, r.Trend)
+	}
+	if r.Volatility != "" {
+		labels = append(labels, r.Volatility)
+	}
+	return labels
+}
+
+// Is reports whether the regime carries label.
+func (r Regime) Is(label string) bool {
+	return r.Trend == label || r.Volatility == label
+}
+
+// Name joins the labels, e.g. "trend/high_vol"; "neutral" when no label
+// applies and "unknown" before the regime is ready.
+func (r Regime) Name() string {
+	if !r.Ready {
+		return "unknown"
+	}
+	labels := r.Labels()
+	if len(labels) == 0 {
+		return "neutral"
+	}
+	return strings.Join(labels, "/")
+}
+
+// String formats the regime, e.g.
+// "trend/high_vol (ADX 31.2, ATR p85, RV p90, BW p70)".
+func (r Regime) String() string {
+	if !r.Ready {
+		return fmt.Sprintf("unknown (%d candles)", r.Samples)
+	}
+	return fmt.Sprintf("%s (ADX %.1f, ATR p%.0f, RV p%.0f, BW p%.0f)",
+		r.Name(), r.ADX, r.ATRPercentile, r.RealizedVolPercentile, r.BandwidthPercentile)
+}
+
+// RegimeGate enables a strategy only in some regimes.
+type RegimeGate struct {
+	Allow []string `json:"allow"` // At least one of these labels must apply (empty allows any)
+	Block []string `json:"block"` // None of these labels may apply
+}
+
+// Validate checks that the gate only names known regime labels.
+func (g *RegimeGate) Validate() error {
+	for _, label := range append(append([]string(nil), g.Allow...), g.Block...) {
+		if !IsRegimeLabel(label) {
+			return fmt.Errorf("unknown market regime: %s", label)
+		}
+	}
+	return nil
+}
+
+// Permits reports whether trading is allowed in regime r and why not. A
+// regime that is not ready yet is permitted, so that gating never blocks a
+// strategy while the history warms up.
+func (g *RegimeGate) Permits(r Regime) (bool, string) {
+	if g == nil || !r.Ready {
+		return true, ""
+	}
+	for _, label := range g.Block {
+		if r.Is(label) {
+			return false, fmt.Sprintf("regime %s is blocked", label)
+		}
+	}
+	if len(g.Allow) == 0 {
+		return true, ""
+	}
+	for _, label := range g.Allow {
+		if r.Is(label) {
+			return true, ""
+		}
+	}
+	return false, fmt.Sprintf("regime %s is not in %v", r.String(), g.Allow)
+}
+
+// regimeWarmup is the number of candles the default ADX needs to settle.
+const regimeWarmup = 28
+
+// RegimeDetector classifies the market regime from ADX, Bollinger
+// bandwidth, the ATR percentile and realized volatility. It is fed by
+// IndicatorSet.UpdateAll once per candle.
+type RegimeDetector struct {
+	thresholds RegimeThresholds
+	prevClose  float64
+	returns    []float64
+	atrHistory []float64
+	bwHistory  []float64
+	rvHistory  []float64
+	regime     Regime
+}
+
+// NewRegimeDetector creates a detector; invalid thresholds fall back to the
+// defaults.
+func NewRegimeDetector(thresholds RegimeThresholds) *RegimeDetector {
+	if err := thresholds.Validate(); err != nil {
+		thresholds = DefaultRegimeThresholds()
+	}
+	return &RegimeDetector{thresholds: thresholds}
+}
+
+// SetThresholds replaces the thresholds; the history is kept.
+func (d *RegimeDetector) SetThresholds(thresholds RegimeThresholds) error {
+	if err := thresholds.Validate(); err != nil {
+		return err
+	}
+	d.thresholds = thresholds
+	return nil
+}
+
+// Update adds a candle's close and indicator values and returns the new
+// regime.
+func (d *RegimeDetector) Update(close, atr, bandwidth, adx, plusDI, minusDI float64) Regime {
+	t := d.thresholds
+
+	if d.prevClose > 0 && close > 0 {
+		d.returns = pushWindow(d.returns, math.Log(close/d.prevClose), t.VolatilityWindow)
+	}
+	d.prevClose = close
+
+	atrPercent := 0.0
+	if close > 0 {
+		atrPercent = atr / close
+	}
+	realizedVol := stdDev(d.returns)
+
+	d.atrHistory = pushWindow(d.atrHistory, atrPercent, t.Lookback)
+	d.bwHistory = pushWindow(d.bwHistory, bandwidth, t.Lookback)
+	d.rvHistory = pushWindow(d.rvHistory, realizedVol, t.Lookback)
+
+	r := Regime{
+		ADX:                   adx,
+		Bandwidth:             bandwidth,
+		BandwidthPercentile:   percentileRank(d.bwHistory, bandwidth),
+		ATRPercent:            atrPercent,
+		ATRPercentile:         percentileRank(d.atrHistory, atrPercent),
+		RealizedVol:           realizedVol,
+		RealizedVolPercentile: percentileRank(d.rvHistory, realizedVol),
+		Samples:               d.regime.Samples + 1,
+	}
+	// ADX сглажен дважды: до regimeWarmup свечей его значение ещё не устоялось
+	r.Ready = r.Samples >= regimeWarmup && len(d.returns) >= t.VolatilityWindow
+
+	if r.Ready {
+		switch {
+		case adx >= t.TrendADX:
+			r.Trend = RegimeTrend
+			if plusDI >= minusDI {
+				r.Direction = "up"
+			} else {
+				r.Direction = "down"
+			}
+		case adx < t.RangeADX:
+			r.Trend = RegimeRange
+		case r.BandwidthPercentile <= 50:
+			// Между порогами ADX сужающиеся полосы говорят о флэте
+			r.Trend = RegimeRange
+		}
+
+		switch {
+		case r.ATRPercentile >= t.HighVolPercentile || r.RealizedVolPercentile >= t.HighVolPercentile ||
+			r.BandwidthPercentile >= t.HighVolPercentile:
+			r.Volatility = RegimeHighVol
+		case r.ATRPercentile <= t.LowVolPercentile && r.RealizedVolPercentile <= t.LowVolPercentile:
+			r.Volatility = RegimeLowVol
+		}
+	}
+
+	d.regime = r
+	return r
+}
+
+// Regime returns the regime at the last update.
+func (d *RegimeDetector) Regime() Regime {
+	return d.regime
+}
+
+func (d *RegimeDetector) Reset() {
+	d.prevClose = 0
+	d.returns = d.returns[:0]
+	d.atrHistory = d.atrHistory[:0]
+	d.bwHistory = d.bwHistory[:0]
+	d.rvHistory = d.rvHistory[:0]
+	d.regime = Regime{}
+}
+
+func (d *RegimeDetector) clone() *RegimeDetector {
+	clone := *d
+	clone.returns = append([]float64(nil), d.returns...)
+	clone.atrHistory = append([]float64(nil), d.atrHistory...)
+	clone.bwHistory = append([]float64(nil), d.bwHistory...)
+	clone.rvHistory = append([]float64(nil), d.rvHistory...)
+	return &clone
+}
+
+// pushWindow appends value and keeps the last size values.
+func pushWindow(values []float64, value float64, size int) []float64 {
+	values = append(values, value)
+	if len(values) > size {
+		values = values[len(values)-size:]
+	}
+	return values
+}
+
+// percentileRank returns the mid-rank percentile of value in history,
+// 0-100: values equal to it count half, so a flat history ranks at 50.
+func percentileRank(history []float64, value float64) float64 {
+	if len(history) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), history...)
+	sort.Float64s(sorted)
+	below := sort.SearchFloat64s(sorted, value)
+	notAbove := sort.Search(len(sorted), func(i int) bool { return sorted[i] > value })
+	return (float64(below) + float64(notAbove-below)/2) / float64(len(sorted)) * 100
+}
+
+// stdDev returns the sample standard deviation of values.
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	mean := sum(values) / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(variance / float64(len(values)-1))
+}
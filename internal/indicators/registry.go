@@ -0,0 +1,679 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Param describes a numeric parameter of a registered indicator.
+type Param struct {
+	Name    string  `json:"name"`
+	Default float64 `json:"default"` // Used when the spec omits the parameter
+	Min     float64 `json:"min"`     // Smallest accepted value
+	Integer bool    `json:"integer"` // Periods and window lengths
+}
+
+// Calculator is a running instance of a registered indicator.
+type Calculator interface {
+	// Update feeds a candle and returns one value per output of the
+	// indicator definition.
+	Update(high, low, close, volume float64) []float64
+	Reset()
+}
+
+// TimedCalculator is implemented by calculators that depend on the candle
+// time, such as session VWAP. They receive the open time of the candle
+// when it is known.
+type TimedCalculator interface {
+	UpdateAt(openTime int64, high, low, close, volume float64) []float64
+}
+
+// Signaler is implemented by calculators that vote in signal scoring.
+type Signaler interface {
+	Signal(price float64) Signal
+}
+
+// Cloner is implemented by calculators whose state can be copied, which
+// lets an indicator set preview a forming candle on a copy of itself.
+// Calculators without it keep their last values in the copy.
+type Cloner interface {
+	Clone() Calculator
+}
+
+// Definition registers an indicator that can be built from a Spec.
+type Definition struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Params      []Param  `json:"params"`
+	Outputs     []string `json:"outputs"` // Output names; indicators with one output have a single value
+
+	// Warmup returns the candles needed before the values are meaningful.
+	Warmup func(params []float64) int `json:"-"`
+	// New creates a calculator for resolved parameters.
+	New func(params []float64) Calculator `json:"-"`
+	// Validate optionally checks resolved parameters against each other.
+	Validate func(params []float64) error `json:"-"`
+}
+
+// Registry holds the indicator definitions specs are resolved against.
+type Registry struct {
+	defs map[string]Definition
+	mu   sync.RWMutex
+}
+
+// NewRegistry creates a registry with the built-in indicators.
+func NewRegistry() *Registry {
+	r := &Registry{defs: make(map[string]Definition)}
+	for _, def := range builtinDefinitions() {
+		r.defs[def.Name] = def
+	}
+	return r
+}
+
+var defaultRegistry = NewRegistry()
+
+// DefaultRegistry returns the registry indicator sets use.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Register adds or replaces an indicator definition.
+func (r *Registry) Register(def Definition) error {
+	if !specNamePattern.MatchString(def.Name) {
+		return fmt.Errorf("invalid indicator name %q", def.Name)
+	}
+	if def.New == nil || def.Warmup == nil {
+		return fmt.Errorf("indicator %s: constructor and warmup are required", def.Name)
+	}
+	if len(def.Outputs) == 0 {
+		return fmt.Errorf("indicator %s: at least one output is required", def.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.defs[def.Name] = def
+	return nil
+}
+
+// Lookup returns the definition of an indicator.
+func (r *Registry) Lookup(name string) (Definition, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	def, ok := r.defs[name]
+	return def, ok
+}
+
+// List returns all definitions sorted by name.
+func (r *Registry) List() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	defs := make([]Definition, 0, len(r.defs))
+	for _, def := range r.defs {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Resolve checks a spec against its definition and fills in the default
+// parameters, so that "rsi" and "rsi(14)" resolve to the same spec.
+func (r *Registry) Resolve(spec Spec) (Spec, error) {
+	def, ok := r.Lookup(spec.Name)
+	if !ok {
+		return Spec{}, fmt.Errorf("unknown indicator %q", spec.Name)
+	}
+	if len(spec.Params) > len(def.Params) {
+		return Spec{}, fmt.Errorf("indicator %s takes at most %d parameters", spec.Name, len(def.Params))
+	}
+
+	params := make([]float64, len(def.Params))
+	for i, param := range def.Params {
+		value := param.Default
+		if i < len(spec.Params) {
+			value = spec.Params[i]
+		}
+		if math.IsNaN(value) || math.IsInf(value, 0) || value < param.Min {
+			return Spec{}, fmt.Errorf("indicator %s: %s must be at least %g", spec.Name, param.Name, param.Min)
+		}
+		if param.Integer && value != math.Trunc(value) {
+			return Spec{}, fmt.Errorf("indicator %s: %s must be a whole number", spec.Name, param.Name)
+		}
+		params[i] = value
+	}
+	if def.Validate != nil {
+		if err := def.Validate(params); err != nil {
+			return Spec{}, fmt.Errorf("indicator %s: %w", spec.Name, err)
+		}
+	}
+	return Spec{Alias: spec.Alias, Name: spec.Name, Params: params}, nil
+}
+
+// New builds an indicator instance from a spec.
+func (r *Registry) New(spec Spec) (*SpecIndicator, error) {
+	resolved, err := r.Resolve(spec)
+	if err != nil {
+		return nil, err
+	}
+	def, _ := r.Lookup(resolved.Name)
+
+	keys := make([]string, len(def.Outputs))
+	for i, output := range def.Outputs {
+		keys[i] = resolved.Key()
+		if len(def.Outputs) > 1 {
+			keys[i] += "." + output
+		}
+	}
+	return &SpecIndicator{
+		spec:   resolved,
+		keys:   keys,
+		calc:   def.New(resolved.Params),
+		warmup: def.Warmup(resolved.Params),
+	}, nil
+}
+
+// SpecIndicator is an indicator built from a Spec. Its values are keyed by
+// the spec key, and by "<key>.<output>" for multi-value indicators.
+type SpecIndicator struct {
+	spec   Spec
+	keys   []string
+	calc   Calculator
+	warmup int
+	count  int
+	values []float64
+}
+
+// Spec returns the resolved spec of the indicator.
+func (si *SpecIndicator) Spec() Spec {
+	return si.spec
+}
+
+// Keys returns the keys of the indicator's values in output order.
+func (si *SpecIndicator) Keys() []string {
+	return append([]string(nil), si.keys...)
+}
+
+// Update feeds a candle.
+func (si *SpecIndicator) Update(high, low, close, volume float64) {
+	si.values = si.calc.Update(high, low, close, volume)
+	si.count++
+}
+
+// UpdateAt feeds a candle opening at openTime (Unix ms); indicators that
+// do not depend on time ignore it.
+func (si *SpecIndicator) UpdateAt(openTime int64, high, low, close, volume float64) {
+	if timed, ok := si.calc.(TimedCalculator); ok {
+		si.values = timed.UpdateAt(openTime, high, low, close, volume)
+	} else {
+		si.values = si.calc.Update(high, low, close, volume)
+	}
+	si.count++
+}
+
+// Values returns the last values by key.
+func (si *SpecIndicator) Values() map[string]float64 {
+	values := make(map[string]float64, len(si.keys))
+	si.fill(values)
+	return values
+}
+
+func (si *SpecIndicator) fill(values map[string]float64) {
+	for i, key := range si.keys {
+		if i < len(si.values) {
+			values[key] = si.values[i]
+		}
+	}
+}
+
+// Ready reports whether the indicator has seen enough candles for its
+// values to be meaningful.
+func (si *SpecIndicator) Ready() bool {
+	if si.count < si.warmup {
+		return false
+	}
+	// Например, avwap не имеет значений до своей точки привязки
+	if ready, ok := si.calc.(interface{ Ready() bool }); ok {
+		return ready.Ready()
+	}
+	return true
+}
+
+// Signal returns the indicator's vote, named by its spec key so that
+// signal models can weigh tuned instances separately. ok is false for
+// indicators that do not vote and during warmup.
+func (si *SpecIndicator) Signal(price float64) (signal Signal, ok bool) {
+	signaler, isSignaler := si.calc.(Signaler)
+	if !isSignaler || !si.Ready() {
+		return Signal{}, false
+	}
+	signal = signaler.Signal(price)
+	signal.Indicator = si.spec.Key()
+	return signal, true
+}
+
+// Reset clears the indicator state.
+func (si *SpecIndicator) Reset() {
+	si.calc.Reset()
+	si.count = 0
+	si.values = nil
+}
+
+// clone copies the indicator state; a calculator that is not a Cloner is
+// frozen at its last values and does not vote in the copy.
+func (si *SpecIndicator) clone() *SpecIndicator {
+	clone := *si
+	clone.values = append([]float64(nil), si.values...)
+	if cloner, ok := si.calc.(Cloner); ok {
+		clone.calc = cloner.Clone()
+	} else {
+		clone.calc = frozenCalculator(clone.values)
+	}
+	return &clone
+}
+
+// frozenCalculator keeps returning the last values of a calculator that
+// cannot be copied.
+type frozenCalculator []float64
+
+func (c frozenCalculator) Update(_, _, _, _ float64) []float64 { return c }
+
+func (c frozenCalculator) Reset() {}
+
+// Calculators wrapping the built-in indicators.
+
+type emaCalculator struct{ *EMA }
+
+func (c emaCalculator) Update(_, _, close, _ float64) []float64 {
+	return []float64{c.EMA.Update(close)}
+}
+
+func (c emaCalculator) Clone() Calculator { return emaCalculator{c.EMA.clone()} }
+
+type rsiCalculator struct{ *RSI }
+
+func (c rsiCalculator) Update(_, _, close, _ float64) []float64 {
+	return []float64{c.RSI.Update(close)}
+}
+
+func (c rsiCalculator) Clone() Calculator { return rsiCalculator{c.RSI.clone()} }
+
+func (c rsiCalculator) Signal(float64) Signal { return c.RSI.Signal() }
+
+type macdCalculator struct{ *MACD }
+
+func (c macdCalculator) Update(_, _, close, _ float64) []float64 {
+	line, signal, hist := c.MACD.Update(close)
+	return []float64{line, signal, hist}
+}
+
+func (c macdCalculator) Clone() Calculator { return macdCalculator{c.MACD.clone()} }
+
+func (c macdCalculator) Signal(float64) Signal { return c.MACD.Signal() }
+
+type bollingerCalculator struct{ *BollingerBands }
+
+func (c bollingerCalculator) Update(_, _, close, _ float64) []float64 {
+	upper, middle, lower := c.BollingerBands.Update(close)
+	return []float64{upper, middle, lower, c.PercentB(close), c.Bandwidth()}
+}
+
+func (c bollingerCalculator) Clone() Calculator { return bollingerCalculator{c.BollingerBands.clone()} }
+
+func (c bollingerCalculator) Signal(price float64) Signal { return c.BollingerBands.Signal(price) }
+
+type atrCalculator struct{ *ATR }
+
+func (c atrCalculator) Update(high, low, close, _ float64) []float64 {
+	return []float64{c.ATR.Update(high, low, close)}
+}
+
+func (c atrCalculator) Clone() Calculator { return atrCalculator{c.ATR.clone()} }
+
+type stochRSICalculator struct{ *StochRSI }
+
+func (c stochRSICalculator) Update(_, _, close, _ float64) []float64 {
+	k, d := c.StochRSI.Update(close)
+	return []float64{k, d}
+}
+
+func (c stochRSICalculator) Clone() Calculator { return stochRSICalculator{c.StochRSI.clone()} }
+
+func (c stochRSICalculator) Signal(float64) Signal { return c.StochRSI.Signal() }
+
+type obvCalculator struct{ *OBV }
+
+func (c obvCalculator) Update(_, _, close, volume float64) []float64 {
+	return []float64{c.OBV.Update(close, volume)}
+}
+
+func (c obvCalculator) Clone() Calculator { return obvCalculator{c.OBV.clone()} }
+
+type adxCalculator struct{ *ADX }
+
+func (c adxCalculator) Update(high, low, close, _ float64) []float64 {
+	adx := c.ADX.Update(high, low, close)
+	return []float64{adx, c.PlusDI(), c.MinusDI()}
+}
+
+func (c adxCalculator) Clone() Calculator { return adxCalculator{c.ADX.clone()} }
+
+func (c adxCalculator) Signal(float64) Signal { return c.ADX.Signal() }
+
+type cciCalculator struct{ *CCI }
+
+func (c cciCalculator) Update(high, low, close, _ float64) []float64 {
+	return []float64{c.CCI.Update(high, low, close)}
+}
+
+func (c cciCalculator) Clone() Calculator { return cciCalculator{c.CCI.clone()} }
+
+func (c cciCalculator) Signal(float64) Signal { return c.CCI.Signal() }
+
+type williamsCalculator struct{ *Williams }
+
+func (c williamsCalculator) Update(high, low, close, _ float64) []float64 {
+	return []float64{c.Williams.Update(high, low, close)}
+}
+
+func (c williamsCalculator) Clone() Calculator { return williamsCalculator{c.Williams.clone()} }
+
+func (c williamsCalculator) Signal(float64) Signal { return c.Williams.Signal() }
+
+type momentumCalculator struct{ *Momentum }
+
+func (c momentumCalculator) Update(_, _, close, _ float64) []float64 {
+	return []float64{c.Momentum.Update(close)}
+}
+
+func (c momentumCalculator) Clone() Calculator { return momentumCalculator{c.Momentum.clone()} }
+
+func (c momentumCalculator) Signal(float64) Signal { return c.Momentum.Signal() }
+
+type vwapCalculator struct {
+	*VWAP
+	multiplier float64
+}
+
+func (c vwapCalculator) Update(high, low, close, volume float64) []float64 {
+	return c.UpdateAt(0, high, low, close, volume)
+}
+
+func (c vwapCalculator) UpdateAt(openTime int64, high, low, close, volume float64) []float64 {
+	value := c.VWAP.Update(openTime, high, low, close, volume)
+	upper, lower := c.Bands(c.multiplier)
+	return []float64{value, upper, lower}
+}
+
+func (c vwapCalculator) Clone() Calculator { return vwapCalculator{c.VWAP.clone(), c.multiplier} }
+
+func (c vwapCalculator) Signal(float64) Signal { return c.VWAP.Signal() }
+
+type ichimokuCalculator struct{ *Ichimoku }
+
+func (c ichimokuCalculator) Update(high, low, close, _ float64) []float64 {
+	tenkan, kijun, spanA, spanB := c.Ichimoku.Update(high, low, close)
+	leadA, leadB := c.Lead()
+	return []float64{tenkan, kijun, spanA, spanB, leadA, leadB}
+}
+
+func (c ichimokuCalculator) Clone() Calculator { return ichimokuCalculator{c.Ichimoku.clone()} }
+
+func (c ichimokuCalculator) Signal(float64) Signal { return c.Ichimoku.Signal() }
+
+type superTrendCalculator struct{ *SuperTrend }
+
+func (c superTrendCalculator) Update(high, low, close, _ float64) []float64 {
+	return []float64{c.SuperTrend.Update(high, low, close), c.Direction()}
+}
+
+func (c superTrendCalculator) Clone() Calculator { return superTrendCalculator{c.SuperTrend.clone()} }
+
+func (c superTrendCalculator) Signal(float64) Signal { return c.SuperTrend.Signal() }
+
+type psarCalculator struct{ *ParabolicSAR }
+
+func (c psarCalculator) Update(high, low, close, _ float64) []float64 {
+	return []float64{c.ParabolicSAR.Update(high, low, close), c.Direction()}
+}
+
+func (c psarCalculator) Clone() Calculator { return psarCalculator{c.ParabolicSAR.clone()} }
+
+func (c psarCalculator) Signal(float64) Signal { return c.ParabolicSAR.Signal() }
+
+type keltnerCalculator struct{ *KeltnerChannels }
+
+func (c keltnerCalculator) Update(high, low, close, _ float64) []float64 {
+	upper, middle, lower := c.KeltnerChannels.Update(high, low, close)
+	return []float64{upper, middle, lower}
+}
+
+func (c keltnerCalculator) Clone() Calculator { return keltnerCalculator{c.KeltnerChannels.clone()} }
+
+func (c keltnerCalculator) Signal(price float64) Signal { return c.KeltnerChannels.Signal(price) }
+
+type donchianCalculator struct{ *DonchianChannels }
+
+func (c donchianCalculator) Update(high, low, close, _ float64) []float64 {
+	upper, middle, lower := c.DonchianChannels.Update(high, low, close)
+	return []float64{upper, middle, lower}
+}
+
+func (c donchianCalculator) Clone() Calculator { return donchianCalculator{c.DonchianChannels.clone()} }
+
+func (c donchianCalculator) Signal(price float64) Signal { return c.DonchianChannels.Signal(price) }
+
+type hullMACalculator struct{ *HullMA }
+
+func (c hullMACalculator) Update(_, _, close, _ float64) []float64 {
+	return []float64{c.HullMA.Update(close)}
+}
+
+func (c hullMACalculator) Clone() Calculator { return hullMACalculator{c.HullMA.clone()} }
+
+func (c hullMACalculator) Signal(float64) Signal { return c.HullMA.Signal() }
+
+type kamaCalculator struct{ *KAMA }
+
+func (c kamaCalculator) Update(_, _, close, _ float64) []float64 {
+	return []float64{c.KAMA.Update(close), c.EfficiencyRatio()}
+}
+
+func (c kamaCalculator) Clone() Calculator { return kamaCalculator{c.KAMA.clone()} }
+
+func (c kamaCalculator) Signal(float64) Signal { return c.KAMA.Signal() }
+
+func period(name string, def float64) Param {
+	return Param{Name: name, Default: def, Min: 1, Integer: true}
+}
+
+func builtinDefinitions() []Definition {
+	return []Definition{
+		{
+			Name:        "ema",
+			Description: "Exponential moving average",
+			Params:      []Param{period("period", 21)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New:         func(p []float64) Calculator { return emaCalculator{NewEMA(int(p[0]))} },
+		},
+		{
+			Name:        "rsi",
+			Description: "Relative strength index",
+			Params:      []Param{period("period", 14)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New:         func(p []float64) Calculator { return rsiCalculator{NewRSI(int(p[0]))} },
+		},
+		{
+			Name:        "macd",
+			Description: "Moving average convergence divergence",
+			Params:      []Param{period("fast", 12), period("slow", 26), period("signal", 9)},
+			Outputs:     []string{"line", "signal", "hist"},
+			Warmup:      func(p []float64) int { return int(math.Max(p[0], p[1]) + p[2] - 1) },
+			New: func(p []float64) Calculator {
+				return macdCalculator{NewMACD(int(p[0]), int(p[1]), int(p[2]))}
+			},
+		},
+		{
+			Name:        "bb",
+			Description: "Bollinger Bands",
+			Params:      []Param{period("period", 20), {Name: "multiplier", Default: 2, Min: 0.1}},
+			Outputs:     []string{"upper", "middle", "lower", "percentB", "bandwidth"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New: func(p []float64) Calculator {
+				return bollingerCalculator{NewBollingerBands(int(p[0]), p[1])}
+			},
+		},
+		{
+			Name:        "atr",
+			Description: "Average true range",
+			Params:      []Param{period("period", 14)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New:         func(p []float64) Calculator { return atrCalculator{NewATR(int(p[0]))} },
+		},
+		{
+			Name:        "stochrsi",
+			Description: "Stochastic RSI",
+			Params:      []Param{period("rsi", 14), period("stoch", 14), period("k", 3), period("d", 3)},
+			Outputs:     []string{"k", "d"},
+			Warmup:      func(p []float64) int { return int(p[0] + p[1] + p[2] - 2) },
+			New: func(p []float64) Calculator {
+				return stochRSICalculator{NewStochRSI(int(p[0]), int(p[1]), int(p[2]), int(p[3]))}
+			},
+		},
+		{
+			Name:        "obv",
+			Description: "On-balance volume",
+			Outputs:     []string{"value"},
+			Warmup:      func([]float64) int { return 1 },
+			New:         func([]float64) Calculator { return obvCalculator{NewOBV()} },
+		},
+		{
+			Name:        "adx",
+			Description: "Average directional index with +DI and -DI",
+			Params:      []Param{period("period", 14), period("adxPeriod", 14)},
+			Outputs:     []string{"value", "plusDI", "minusDI"},
+			Warmup:      func(p []float64) int { return int(p[0]) + 1 },
+			New:         func(p []float64) Calculator { return adxCalculator{NewADX(int(p[0]), int(p[1]))} },
+		},
+		{
+			Name:        "cci",
+			Description: "Commodity channel index",
+			Params:      []Param{period("period", 20)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New:         func(p []float64) Calculator { return cciCalculator{NewCCI(int(p[0]))} },
+		},
+		{
+			Name:        "williams",
+			Description: "Williams %R",
+			Params:      []Param{period("period", 14)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New:         func(p []float64) Calculator { return williamsCalculator{NewWilliams(int(p[0]))} },
+		},
+		{
+			Name:        "momentum",
+			Description: "Rate of change in percent",
+			Params:      []Param{period("period", 10)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) + 1 },
+			New:         func(p []float64) Calculator { return momentumCalculator{NewMomentum(int(p[0]))} },
+		},
+		{
+			Name:        "vwap",
+			Description: "Session VWAP with standard deviation bands; session 0 never restarts",
+			Params:      []Param{{Name: "sessionHours", Default: 24, Min: 0}, {Name: "multiplier", Default: 2, Min: 0.1}},
+			Outputs:     []string{"value", "upper", "lower"},
+			Warmup:      func([]float64) int { return 1 },
+			New: func(p []float64) Calculator {
+				session := time.Duration(p[0] * float64(time.Hour))
+				return vwapCalculator{NewSessionVWAP(session), p[1]}
+			},
+		},
+		{
+			Name:        "avwap",
+			Description: "VWAP anchored at a candle open time (Unix ms) with standard deviation bands; anchor 0 is the first candle",
+			Params:      []Param{{Name: "anchor", Default: 0, Min: 0, Integer: true}, {Name: "multiplier", Default: 2, Min: 0.1}},
+			Outputs:     []string{"value", "upper", "lower"},
+			Warmup:      func([]float64) int { return 1 },
+			New: func(p []float64) Calculator {
+				return vwapCalculator{NewAnchoredVWAP(int64(p[0])), p[1]}
+			},
+		},
+		{
+			Name:        "ichimoku",
+			Description: "Ichimoku Cloud: conversion and base lines, the cloud under the candle and the cloud projected ahead",
+			Params:      []Param{period("tenkan", 9), period("kijun", 26), period("senkou", 52)},
+			Outputs:     []string{"tenkan", "kijun", "spanA", "spanB", "leadA", "leadB"},
+			Warmup:      func(p []float64) int { return int(math.Max(p[0], math.Max(p[1], p[2])) + p[1]) },
+			New: func(p []float64) Calculator {
+				return ichimokuCalculator{NewIchimoku(int(p[0]), int(p[1]), int(p[2]))}
+			},
+		},
+		{
+			Name:        "supertrend",
+			Description: "SuperTrend ATR trailing stop; direction is 1 in an uptrend and -1 in a downtrend",
+			Params:      []Param{period("period", 10), {Name: "multiplier", Default: 3, Min: 0.1}},
+			Outputs:     []string{"value", "direction"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New: func(p []float64) Calculator {
+				return superTrendCalculator{NewSuperTrend(int(p[0]), p[1])}
+			},
+		},
+		{
+			Name:        "psar",
+			Description: "Parabolic SAR; direction is 1 in an uptrend and -1 in a downtrend",
+			Params:      []Param{{Name: "step", Default: 0.02, Min: 0.001}, {Name: "max", Default: 0.2, Min: 0.001}},
+			Outputs:     []string{"value", "direction"},
+			Warmup:      func([]float64) int { return 3 },
+			New:         func(p []float64) Calculator { return psarCalculator{NewParabolicSAR(p[0], p[1])} },
+			Validate: func(p []float64) error {
+				if p[0] > p[1] {
+					return fmt.Errorf("step must not exceed max")
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "keltner",
+			Description: "Keltner Channels: EMA with ATR bands",
+			Params:      []Param{period("period", 20), period("atrPeriod", 10), {Name: "multiplier", Default: 2, Min: 0.1}},
+			Outputs:     []string{"upper", "middle", "lower"},
+			Warmup:      func(p []float64) int { return int(math.Max(p[0], p[1])) },
+			New: func(p []float64) Calculator {
+				return keltnerCalculator{NewKeltnerChannels(int(p[0]), int(p[1]), p[2])}
+			},
+		},
+		{
+			Name:        "donchian",
+			Description: "Donchian Channels: highest high and lowest low",
+			Params:      []Param{period("period", 20)},
+			Outputs:     []string{"upper", "middle", "lower"},
+			Warmup:      func(p []float64) int { return int(p[0]) },
+			New:         func(p []float64) Calculator { return donchianCalculator{NewDonchianChannels(int(p[0]))} },
+		},
+		{
+			Name:        "hma",
+			Description: "Hull moving average",
+			Params:      []Param{period("period", 20)},
+			Outputs:     []string{"value"},
+			Warmup:      func(p []float64) int { return int(p[0]) + int(math.Round(math.Sqrt(p[0]))) - 1 },
+			New:         func(p []float64) Calculator { return hullMACalculator{NewHullMA(int(p[0]))} },
+		},
+		{
+			Name:        "kama",
+			Description: "Kaufman adaptive moving average with its efficiency ratio",
+			Params:      []Param{period("period", 10), period("fast", 2), period("slow", 30)},
+			Outputs:     []string{"value", "er"},
+			Warmup:      func(p []float64) int { return int(p[0]) + 1 },
+			New: func(p []float64) Calculator {
+				return kamaCalculator{NewKAMA(int(p[0]), int(p[1]), int(p[2]))}
+			},
+		},
+	}
+}
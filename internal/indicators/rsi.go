@@ -89,6 +89,9 @@ func (r *RSI) Signal() Signal {
 			Strength:  0.9 + (30-r.value)/30*0.1, // 0.9-1.0
 			Indicator: "RSI",
 			Reason:    "Strong oversold condition",
+			Value:     r.value,
+			Threshold: 30,
+			Crossed:   "below",
 		}
 	}
 	// Moderate oversold - for scalping
@@ -98,6 +101,9 @@ func (r *RSI) Signal() Signal {
 			Strength:  0.5 + (40-r.value)/10*0.4, // 0.5-0.9
 			Indicator: "RSI",
 			Reason:    "Moderate oversold condition",
+			Value:     r.value,
+			Threshold: 40,
+			Crossed:   "below",
 		}
 	}
 	// Weak oversold - for quick scalps
@@ -107,6 +113,9 @@ func (r *RSI) Signal() Signal {
 			Strength:  0.3 + (45-r.value)/5*0.2, // 0.3-0.5
 			Indicator: "RSI",
 			Reason:    "Weak oversold condition",
+			Value:     r.value,
+			Threshold: 45,
+			Crossed:   "below",
 		}
 	}
 	
@@ -117,6 +126,9 @@ func (r *RSI) Signal() Signal {
 			Strength:  0.9 + (r.value-70)/30*0.1, // 0.9-1.0
 			Indicator: "RSI",
 			Reason:    "Strong overbought condition",
+			Value:     r.value,
+			Threshold: 70,
+			Crossed:   "above",
 		}
 	}
 	// Moderate overbought - for scalping
@@ -126,6 +138,9 @@ func (r *RSI) Signal() Signal {
 			Strength:  0.5 + (r.value-60)/10*0.4, // 0.5-0.9
 			Indicator: "RSI",
 			Reason:    "Moderate overbought condition",
+			Value:     r.value,
+			Threshold: 60,
+			Crossed:   "above",
 		}
 	}
 	// Weak overbought - for quick scalps
@@ -135,6 +150,9 @@ func (r *RSI) Signal() Signal {
 			Strength:  0.3 + (r.value-55)/5*0.2, // 0.3-0.5
 			Indicator: "RSI",
 			Reason:    "Weak overbought condition",
+			Value:     r.value,
+			Threshold: 55,
+			Crossed:   "above",
 		}
 	}
 	
@@ -151,6 +169,13 @@ func (r *RSI) Reset() {
 	r.value = 50
 }
 
+func (r *RSI) clone() *RSI {
+	clone := *r
+	clone.gains = append([]float64(nil), r.gains...)
+	clone.losses = append([]float64(nil), r.losses...)
+	return &clone
+}
+
 func sum(arr []float64) float64 {
 	var s float64
 	for _, v := range arr {
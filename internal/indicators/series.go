@@ -0,0 +1,123 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"crypto-trading-bot/internal/binance"
+)
+
+// Series is one indicator output over a candle series, aligned with the
+// candles.
+type Series struct {
+	Key       string    `json:"key"`       // Value key, e.g. "ema9" or "macd.hist"
+	Indicator string    `json:"indicator"` // Resolved spec, e.g. "macd=macd(12,26,9)"
+	Warmup    int       `json:"warmup"`    // Leading candles without a valid value
+	Values    []float64 `json:"values"`    // One per candle; 0 where not valid
+	Valid     []bool    `json:"valid"`     // False during warmup and for undefined values
+}
+
+// SeriesResult holds the indicator series of a candle series.
+type SeriesResult struct {
+	Times  []int64  `json:"times"` // Open time of each candle, Unix ms
+	Series []Series `json:"series"`
+}
+
+// DefaultPresetSpecs returns specs reproducing the default preset of
+// IndicatorSet, aliased like the IndicatorValues fields. Computed without
+// other specs, their series are keyed like IndicatorValues.Map: ema9,
+// rsi14, macdHist, bbUpper, stochRsiK and so on, plus bbBandwidth,
+// adxPlusDI and adxMinusDI.
+func DefaultPresetSpecs() []Spec {
+	return []Spec{
+		{Alias: "ema9", Name: "ema", Params: []float64{9}},
+		{Alias: "ema21", Name: "ema", Params: []float64{21}},
+		{Alias: "ema50", Name: "ema", Params: []float64{50}},
+		{Alias: "ema200", Name: "ema", Params: []float64{200}},
+		{Alias: "rsi14", Name: "rsi", Params: []float64{14}},
+		{Alias: "rsi7", Name: "rsi", Params: []float64{7}},
+		{Alias: "macd", Name: "macd", Params: []float64{12, 26, 9}},
+		{Alias: "bb", Name: "bb", Params: []float64{20, 2}},
+		{Alias: "atr14", Name: "atr", Params: []float64{14}},
+		{Alias: "stochRsi", Name: "stochrsi", Params: []float64{14, 14, 3, 3}},
+		{Alias: "obv", Name: "obv"},
+		{Alias: "adx", Name: "adx", Params: []float64{14, 14}},
+		{Alias: "cci", Name: "cci", Params: []float64{20}},
+		{Alias: "williams", Name: "williams", Params: []float64{14}},
+		{Alias: "momentum", Name: "momentum", Params: []float64{10}},
+	}
+}
+
+// ComputeSeries computes the indicators selected by specs over klines,
+// oldest first, with fresh indicator instances; no live state is read or
+// changed. Empty specs compute the default preset.
+func ComputeSeries(klines []binance.Kline, specs []Spec) (*SeriesResult, error) {
+	preset := len(specs) == 0
+	if preset {
+		specs = DefaultPresetSpecs()
+	}
+	for i := 1; i < len(klines); i++ {
+		if klines[i].OpenTime <= klines[i-1].OpenTime {
+			return nil, fmt.Errorf("klines must be in ascending time order (candle %d)", i)
+		}
+	}
+
+	instances := make([]*SpecIndicator, 0, len(specs))
+	keys := make(map[string]string)
+	result := &SeriesResult{Times: make([]int64, len(klines))}
+	for _, spec := range specs {
+		si, err := defaultRegistry.New(spec)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range si.keys {
+			if preset {
+				key = presetSeriesKey(key)
+			}
+			if other, ok := keys[key]; ok {
+				return nil, fmt.Errorf("indicator specs %s and %s both define %q", other, si.spec, key)
+			}
+			keys[key] = si.spec.String()
+			result.Series = append(result.Series, Series{
+				Key:       key,
+				Indicator: si.spec.String(),
+				Warmup:    min(max(si.warmup-1, 0), len(klines)),
+				Values:    make([]float64, len(klines)),
+				Valid:     make([]bool, len(klines)),
+			})
+		}
+		instances = append(instances, si)
+	}
+
+	for i, k := range klines {
+		result.Times[i] = k.OpenTime
+		series := 0
+		for _, si := range instances {
+			si.UpdateAt(k.OpenTime, k.High, k.Low, k.Close, k.Volume)
+			ready := si.Ready()
+			for j := range si.keys {
+				var value float64
+				if j < len(si.values) {
+					value = si.values[j]
+				}
+				if ready && !math.IsNaN(value) && !math.IsInf(value, 0) {
+					result.Series[series].Values[i] = value
+					result.Series[series].Valid[i] = true
+				}
+				series++
+			}
+		}
+	}
+	return result, nil
+}
+
+// presetSeriesKey turns a default preset key such as "macd.hist" into its
+// IndicatorValues.Map form "macdHist"; a "value" output takes the alias.
+func presetSeriesKey(key string) string {
+	alias, output, ok := strings.Cut(key, ".")
+	if !ok || output == "value" {
+		return alias
+	}
+	return alias + strings.ToUpper(output[:1]) + output[1:]
+}
@@ -0,0 +1,134 @@
+package indicators
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	specNamePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	specAliasPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Spec selects a registered indicator and its parameters, e.g. rsi(21),
+// ema(100) or bb(20,2.5). An alias such as "fast=ema(12)" names the
+// values of the instance instead of the spec itself.
+type Spec struct {
+	Alias  string    `json:"alias,omitempty"`
+	Name   string    `json:"name"`
+	Params []float64 `json:"params"` // Missing trailing parameters take their defaults
+}
+
+// ParseSpec parses a spec such as "rsi(21)", "obv" or "fast=ema(12)".
+func ParseSpec(text string) (Spec, error) {
+	text = strings.TrimSpace(text)
+	var spec Spec
+	if alias, rest, ok := strings.Cut(text, "="); ok {
+		spec.Alias = strings.TrimSpace(alias)
+		text = strings.TrimSpace(rest)
+		if !specAliasPattern.MatchString(spec.Alias) {
+			return Spec{}, fmt.Errorf("invalid indicator alias %q", spec.Alias)
+		}
+	}
+
+	name, args, hasArgs := strings.Cut(text, "(")
+	spec.Name = strings.ToLower(strings.TrimSpace(name))
+	if !specNamePattern.MatchString(spec.Name) {
+		return Spec{}, fmt.Errorf("invalid indicator spec %q", text)
+	}
+	if !hasArgs {
+		return spec, nil
+	}
+	args, ok := strings.CutSuffix(strings.TrimSpace(args), ")")
+	if !ok {
+		return Spec{}, fmt.Errorf("invalid indicator spec %q: missing ')'", text)
+	}
+	if strings.TrimSpace(args) == "" {
+		return spec, nil
+	}
+	for _, arg := range strings.Split(args, ",") {
+		value, err := strconv.ParseFloat(strings.TrimSpace(arg), 64)
+		if err != nil {
+			return Spec{}, fmt.Errorf("invalid parameter %q of indicator spec %q", strings.TrimSpace(arg), text)
+		}
+		spec.Params = append(spec.Params, value)
+	}
+	return spec, nil
+}
+
+// ParseSpecs parses a list of specs separated by commas, semicolons or
+// spaces, e.g. "rsi(21), ema(100); bb(20,2.5)".
+func ParseSpecs(text string) ([]Spec, error) {
+	var specs []Spec
+	depth, start := 0, 0
+	flush := func(end int) error {
+		if part := strings.TrimSpace(text[start:end]); part != "" {
+			spec, err := ParseSpec(part)
+			if err != nil {
+				return err
+			}
+			specs = append(specs, spec)
+		}
+		return nil
+	}
+	for i, r := range text {
+		switch {
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case depth == 0 && (r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'):
+			// "fast = ema(12)": пробелы вокруг '=' не разделяют спецификации
+			if r == ' ' && (strings.HasSuffix(strings.TrimSpace(text[start:i]), "=") || strings.HasPrefix(strings.TrimSpace(text[i:]), "=")) {
+				continue
+			}
+			if err := flush(i); err != nil {
+				return nil, err
+			}
+			start = i + 1
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in indicator specs %q", text)
+	}
+	if err := flush(len(text)); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// String formats the spec so that ParseSpec reads it back.
+func (s Spec) String() string {
+	if s.Alias != "" {
+		return s.Alias + "=" + s.canonical()
+	}
+	return s.canonical()
+}
+
+// Key names the values of the spec: its alias, or the spec itself such as
+// "bb(20,2.5)". Outputs of multi-value indicators are "<key>.<output>".
+func (s Spec) Key() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.canonical()
+}
+
+func (s Spec) canonical() string {
+	if len(s.Params) == 0 {
+		return s.Name
+	}
+	params := make([]string, len(s.Params))
+	for i, p := range s.Params {
+		// Целые параметры, например время привязки avwap, пишем без экспоненты
+		if p == math.Trunc(p) && math.Abs(p) < 1e15 {
+			params[i] = strconv.FormatFloat(p, 'f', -1, 64)
+		} else {
+			params[i] = strconv.FormatFloat(p, 'g', -1, 64)
+		}
+	}
+	return s.Name + "(" + strings.Join(params, ",") + ")"
+}
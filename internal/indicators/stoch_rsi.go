@@ -78,6 +78,9 @@ func (s *StochRSI) Signal() Signal {
 			Strength:  0.9 + (20-s.k)/20*0.1, // 0.9-1.0
 			Indicator: "StochRSI",
 			Reason:    "Strong oversold condition",
+			Value:     s.k,
+			Threshold: 20,
+			Crossed:   "below",
 		}
 	}
 	
@@ -88,6 +91,9 @@ func (s *StochRSI) Signal() Signal {
 			Strength:  0.6 + (30-s.k)/10*0.3, // 0.6-0.9
 			Indicator: "StochRSI",
 			Reason:    "Moderate oversold condition",
+			Value:     s.k,
+			Threshold: 30,
+			Crossed:   "below",
 		}
 	}
 	
@@ -98,6 +104,9 @@ func (s *StochRSI) Signal() Signal {
 			Strength:  0.3 + (40-s.k)/10*0.3, // 0.3-0.6
 			Indicator: "StochRSI",
 			Reason:    "Weak oversold condition",
+			Value:     s.k,
+			Threshold: 40,
+			Crossed:   "below",
 		}
 	}
 
@@ -108,6 +117,9 @@ func (s *StochRSI) Signal() Signal {
 			Strength:  0.9 + (s.k-80)/20*0.1, // 0.9-1.0
 			Indicator: "StochRSI",
 			Reason:    "Strong overbought condition",
+			Value:     s.k,
+			Threshold: 80,
+			Crossed:   "above",
 		}
 	}
 	
@@ -118,6 +130,9 @@ func (s *StochRSI) Signal() Signal {
 			Strength:  0.6 + (s.k-70)/10*0.3, // 0.6-0.9
 			Indicator: "StochRSI",
 			Reason:    "Moderate overbought condition",
+			Value:     s.k,
+			Threshold: 70,
+			Crossed:   "above",
 		}
 	}
 	
@@ -128,6 +143,9 @@ func (s *StochRSI) Signal() Signal {
 			Strength:  0.3 + (s.k-60)/10*0.3, // 0.3-0.6
 			Indicator: "StochRSI",
 			Reason:    "Weak overbought condition",
+			Value:     s.k,
+			Threshold: 60,
+			Crossed:   "above",
 		}
 	}
 
@@ -142,6 +160,14 @@ func (s *StochRSI) Reset() {
 	s.d = 50
 }
 
+func (s *StochRSI) clone() *StochRSI {
+	clone := *s
+	clone.rsi = s.rsi.clone()
+	clone.rsiValues = append([]float64(nil), s.rsiValues...)
+	clone.kValues = append([]float64(nil), s.kValues...)
+	return &clone
+}
+
 func minSlice(arr []float64) float64 {
 	if len(arr) == 0 {
 		return 0
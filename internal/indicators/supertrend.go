@@ -0,0 +1,143 @@
+package indicators
+
+import "math"
+
+// SuperTrend is an ATR trailing stop that flips sides when the close
+// crosses it: below the price in an uptrend, above it in a downtrend.
+type SuperTrend struct {
+	multiplier float64
+	atr        *ATR
+	upper      float64 // Final upper band
+	lower      float64 // Final lower band
+	value      float64
+	up         bool
+	flipped    bool // The trend flipped on the last candle
+	close      float64
+	count      int
+}
+
+func NewSuperTrend(period int, multiplier float64) *SuperTrend {
+	return &SuperTrend{
+		multiplier: multiplier,
+		atr:        NewATR(period),
+		up:         true,
+	}
+}
+
+// DefaultSuperTrend creates SuperTrend with the standard 10, 3.0 parameters
+func DefaultSuperTrend() *SuperTrend {
+	return NewSuperTrend(10, 3.0)
+}
+
+func (st *SuperTrend) Update(high, low, close float64) float64 {
+	atr := st.atr.Update(high, low, close)
+	mid := (high + low) / 2
+	upper := mid + st.multiplier*atr
+	lower := mid - st.multiplier*atr
+
+	st.count++
+	st.flipped = false
+	if st.count == 1 {
+		st.upper, st.lower = upper, lower
+		st.up = close >= mid
+	} else {
+		// Полосы сдвигаются только в сторону цены, пока цена их не пробила
+		if upper < st.upper || st.close > st.upper {
+			st.upper = upper
+		}
+		if lower > st.lower || st.close < st.lower {
+			st.lower = lower
+		}
+		if st.up && close < st.lower {
+			st.up, st.flipped = false, true
+		} else if !st.up && close > st.upper {
+			st.up, st.flipped = true, true
+		}
+	}
+
+	if st.up {
+		st.value = st.lower
+	} else {
+		st.value = st.upper
+	}
+	st.close = close
+	return st.value
+}
+
+func (st *SuperTrend) Value() float64 {
+	return st.value
+}
+
+// Direction returns 1 in an uptrend and -1 in a downtrend
+func (st *SuperTrend) Direction() float64 {
+	if st.up {
+		return 1
+	}
+	return -1
+}
+
+func (st *SuperTrend) Signal() Signal {
+	distance := 0.0
+	if st.close != 0 {
+		distance = math.Abs(st.close-st.value) / st.close * 100
+	}
+
+	if st.flipped {
+		if st.up {
+			return Signal{
+				Type:      "BUY",
+				Strength:  0.9,
+				Indicator: "SuperTrend",
+				Reason:    "SuperTrend flipped bullish",
+				Value:     st.close,
+				Threshold: st.value,
+				Crossed:   "above",
+			}
+		}
+		return Signal{
+			Type:      "SELL",
+			Strength:  0.9,
+			Indicator: "SuperTrend",
+			Reason:    "SuperTrend flipped bearish",
+			Value:     st.close,
+			Threshold: st.value,
+			Crossed:   "below",
+		}
+	}
+
+	// Тренд продолжается: сигнал слабее, чем дальше цена ушла от линии
+	strength := 0.5 - math.Min(distance, 2)/2*0.3 // 0.2-0.5
+	if st.up {
+		return Signal{
+			Type:      "BUY",
+			Strength:  strength,
+			Indicator: "SuperTrend",
+			Reason:    "Price above SuperTrend",
+			Value:     st.close,
+		}
+	}
+	return Signal{
+		Type:      "SELL",
+		Strength:  strength,
+		Indicator: "SuperTrend",
+		Reason:    "Price below SuperTrend",
+		Value:     st.close,
+	}
+}
+
+func (st *SuperTrend) Reset() {
+	st.atr.Reset()
+	st.upper = 0
+	st.lower = 0
+	st.value = 0
+	st.up = true
+	st.flipped = false
+	st.close = 0
+	st.count = 0
+}
+
+func (st *SuperTrend) clone() *SuperTrend {
+	clone := *st
+	clone.atr = st.atr.clone()
+	return &clone
+}
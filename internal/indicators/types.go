@@ -21,6 +21,9 @@ type Signal struct {
 	Indicator string
 	Reason    string
 	Timestamp int64
+	Value     float64 // Indicator value that triggered the signal
+	Threshold float64 // Level the value crossed
+	Crossed   string  // "above" or "below" Threshold; empty when the signal is not a threshold crossing
 }
 
 // IndicatorResult holds indicator values for a single candle
@@ -0,0 +1,153 @@
+package indicators
+
+import (
+	"math"
+	"time"
+)
+
+// VWAP is the volume-weighted average price of the typical price
+// (high+low+close)/3, accumulated over a session or from an anchor candle,
+// with volume-weighted standard deviation bands.
+type VWAP struct {
+	session int64 // Session length in ms, sessions aligned to UTC; 0 never resets
+	anchor  int64 // Candles opening before the anchor are skipped, Unix ms
+
+	sessionStart int64
+	pv           float64 // Sum of price*volume
+	pv2          float64 // Sum of price^2*volume
+	volume       float64
+	value        float64
+	stdDev       float64
+	close        float64
+	count        int // Candles in the current session
+}
+
+// NewSessionVWAP creates a VWAP that restarts every session, e.g. 24h for
+// the daily UTC session. Candles without a timestamp never start a new
+// session.
+func NewSessionVWAP(session time.Duration) *VWAP {
+	return &VWAP{session: session.Milliseconds()}
+}
+
+// DefaultVWAP creates a VWAP over the daily UTC session
+func DefaultVWAP() *VWAP {
+	return NewSessionVWAP(24 * time.Hour)
+}
+
+// NewAnchoredVWAP creates a VWAP accumulated from the candle opening at or
+// after anchor (Unix ms); anchor 0 accumulates from the first candle.
+func NewAnchoredVWAP(anchor int64) *VWAP {
+	return &VWAP{anchor: anchor}
+}
+
+// Update feeds a candle opening at timestamp (Unix ms, 0 if unknown).
+// Anchored VWAPs skip candles without a timestamp, since they cannot tell
+// whether the candle opens after the anchor.
+func (w *VWAP) Update(timestamp int64, high, low, close, volume float64) float64 {
+	if w.anchor > 0 && timestamp < w.anchor {
+		return w.value
+	}
+	if w.session > 0 && timestamp != 0 {
+		start := timestamp - timestamp%w.session
+		if start != w.sessionStart {
+			w.sessionStart = start
+			w.pv, w.pv2, w.volume, w.count = 0, 0, 0, 0
+		}
+	}
+
+	price := (high + low + close) / 3
+	w.close = close
+	w.count++
+	w.pv += price * volume
+	w.pv2 += price * price * volume
+	w.volume += volume
+
+	if w.volume <= 0 {
+		w.value = price
+		w.stdDev = 0
+		return w.value
+	}
+	w.value = w.pv / w.volume
+	w.stdDev = math.Sqrt(math.Max(w.pv2/w.volume-w.value*w.value, 0))
+	return w.value
+}
+
+func (w *VWAP) Value() float64 {
+	return w.value
+}
+
+// Ready reports whether the VWAP has a candle of the current session, or
+// one at or after the anchor.
+func (w *VWAP) Ready() bool {
+	return w.count > 0
+}
+
+// StdDev returns the volume-weighted standard deviation of the typical
+// price around the VWAP.
+func (w *VWAP) StdDev() float64 {
+	return w.stdDev
+}
+
+// Bands returns the VWAP plus and minus multiplier standard deviations
+func (w *VWAP) Bands(multiplier float64) (upper, lower float64) {
+	return w.value + multiplier*w.stdDev, w.value - multiplier*w.stdDev
+}
+
+// Deviation returns how many standard deviations price is from the VWAP
+func (w *VWAP) Deviation(price float64) float64 {
+	if w.stdDev == 0 {
+		return 0
+	}
+	return (price - w.value) / w.stdDev
+}
+
+// Signal fades closes stretched more than one standard deviation from the
+// VWAP; the further the stretch, the stronger the signal.
+func (w *VWAP) Signal() Signal {
+	// В начале сессии отклонение по нескольким свечам ничего не значит
+	if w.count < 3 {
+		return Signal{Type: "HOLD", Indicator: "VWAP"}
+	}
+	z := w.Deviation(w.close)
+
+	if z <= -1 {
+		return Signal{
+			Type:      "BUY",
+			Strength:  0.3 + math.Min(-z-1, 2)/2*0.6, // 0.3-0.9
+			Indicator: "VWAP",
+			Reason:    "Price stretched below VWAP",
+			Value:     z,
+			Threshold: -1,
+			Crossed:   "below",
+		}
+	}
+	if z >= 1 {
+		return Signal{
+			Type:      "SELL",
+			Strength:  0.3 + math.Min(z-1, 2)/2*0.6,
+			Indicator: "VWAP",
+			Reason:    "Price stretched above VWAP",
+			Value:     z,
+			Threshold: 1,
+			Crossed:   "above",
+		}
+	}
+
+	return Signal{Type: "HOLD", Indicator: "VWAP"}
+}
+
+func (w *VWAP) Reset() {
+	w.sessionStart = 0
+	w.pv = 0
+	w.pv2 = 0
+	w.volume = 0
+	w.value = 0
+	w.stdDev = 0
+	w.close = 0
+	w.count = 0
+}
+
+func (w *VWAP) clone() *VWAP {
+	clone := *w
+	return &clone
+}
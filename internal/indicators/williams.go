@@ -75,6 +75,9 @@ func (w *Williams) Signal() Signal {
 			Strength:  strength,
 			Indicator: "Williams%R",
 			Reason:    "Oversold condition",
+			Value:     w.value,
+			Threshold: -80,
+			Crossed:   "below",
 		}
 	}
 
@@ -85,6 +88,9 @@ func (w *Williams) Signal() Signal {
 			Strength:  strength,
 			Indicator: "Williams%R",
 			Reason:    "Overbought condition",
+			Value:     w.value,
+			Threshold: -20,
+			Crossed:   "above",
 		}
 	}
 
@@ -95,6 +101,9 @@ func (w *Williams) Signal() Signal {
 			Strength:  0.4,
 			Indicator: "Williams%R",
 			Reason:    "Moderate oversold",
+			Value:     w.value,
+			Threshold: -60,
+			Crossed:   "below",
 		}
 	}
 
@@ -104,6 +113,9 @@ func (w *Williams) Signal() Signal {
 			Strength:  0.4,
 			Indicator: "Williams%R",
 			Reason:    "Moderate overbought",
+			Value:     w.value,
+			Threshold: -40,
+			Crossed:   "above",
 		}
 	}
 
@@ -116,3 +128,9 @@ func (w *Williams) Reset() {
 	w.value = 0
 }
 
+func (w *Williams) clone() *Williams {
+	clone := *w
+	clone.highs = append([]float64(nil), w.highs...)
+	clone.lows = append([]float64(nil), w.lows...)
+	return &clone
+}
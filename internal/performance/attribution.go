@@ -0,0 +1,188 @@
+package performance
+
+import (
+	"sort"
+
+	"crypto-trading-bot/internal/signals"
+	"crypto-trading-bot/internal/trading"
+)
+
+// GroupStats summarizes the closed trades of one attribution group.
+type GroupStats struct {
+	Key           string  `json:"key"`
+	Trades        int     `json:"trades"`
+	Wins          int     `json:"wins"`
+	Losses        int     `json:"losses"`
+	Breakeven     int     `json:"breakeven"` // Zero PnL; neither wins nor losses
+	WinRate       float64 `json:"winRate"`   // Percent of wins and losses
+	NetPnL        float64 `json:"netPnl"`
+	AvgPnLPercent float64 `json:"avgPnlPercent"`
+	ProfitFactor  float64 `json:"profitFactor"`
+
+	grossProfit, grossLoss, pnlPercentSum float64
+}
+
+// IndicatorAttribution compares trades where an indicator agreed with the
+// trade direction against trades where it disagreed.
+type IndicatorAttribution struct {
+	Indicator   string     `json:"indicator"`
+	Aligned     GroupStats `json:"aligned"`     // Indicator pointed in the trade direction
+	Opposed     GroupStats `json:"opposed"`     // Indicator pointed against the trade
+	AvgScore    float64    `json:"avgScore"`    // Mean absolute contribution to the technical score
+	AlignedEdge float64    `json:"alignedEdge"` // Aligned avg PnL% minus avg PnL% of all attributed trades
+	scoreSum    float64
+	scoreCount  int
+}
+
+// AttributionReport answers which strategies, accounts, signal models and
+// indicators make money.
+type AttributionReport struct {
+	TotalTrades      int                    `json:"totalTrades"`
+	TradesWithSignal int                    `json:"tradesWithSignal"` // Trades whose signal was found in the store
+	ByStrategy       []GroupStats           `json:"byStrategy"`
+	ByAccount        []GroupStats           `json:"byAccount"`
+	BySymbol         []GroupStats           `json:"bySymbol"`
+	ByModel          []GroupStats           `json:"byModel"`
+	ByIndicator      []IndicatorAttribution `json:"byIndicator"`
+}
+
+// Attribute groups closed trades by strategy, account, symbol and signal
+// model, and attributes them to the indicators of their originating signal.
+// Signals are looked up by Trade.SignalID; store may be nil.
+func Attribute(trades []trading.Trade, store *signals.SignalStore) AttributionReport {
+	byStrategy := make(map[string]*GroupStats)
+	byAccount := make(map[string]*GroupStats)
+	bySymbol := make(map[string]*GroupStats)
+	byModel := make(map[string]*GroupStats)
+	byIndicator := make(map[string]*IndicatorAttribution)
+
+	report := AttributionReport{TotalTrades: len(trades)}
+	var attributedPnLPercent float64
+
+	for _, t := range trades {
+		groupFor(byStrategy, orUnknown(t.Strategy)).add(t)
+		groupFor(byAccount, orUnknown(t.Account)).add(t)
+		groupFor(bySymbol, t.Symbol).add(t)
+
+		if store == nil || t.SignalID == "" {
+			continue
+		}
+		sig := store.Get(t.SignalID)
+		if sig == nil {
+			continue
+		}
+		report.TradesWithSignal++
+		attributedPnLPercent += t.PnLPercent
+		groupFor(byModel, orUnknown(sig.Model)).add(t)
+
+		long := t.Side == "LONG" || t.Side == "BUY"
+		for _, ind := range sig.Indicators {
+			if ind.Score == 0 {
+				continue
+			}
+			attr, ok := byIndicator[ind.Indicator]
+			if !ok {
+				attr = &IndicatorAttribution{
+					Indicator: ind.Indicator,
+					Aligned:   GroupStats{Key: "aligned"},
+					Opposed:   GroupStats{Key: "opposed"},
+				}
+				byIndicator[ind.Indicator] = attr
+			}
+			if (ind.Score > 0) == long {
+				attr.Aligned.add(t)
+			} else {
+				attr.Opposed.add(t)
+			}
+			if ind.Score > 0 {
+				attr.scoreSum += ind.Score
+			} else {
+				attr.scoreSum -= ind.Score
+			}
+			attr.scoreCount++
+		}
+	}
+
+	report.ByStrategy = finishGroups(byStrategy)
+	report.ByAccount = finishGroups(byAccount)
+	report.BySymbol = finishGroups(bySymbol)
+	report.ByModel = finishGroups(byModel)
+
+	var baseline float64
+	if report.TradesWithSignal > 0 {
+		baseline = attributedPnLPercent / float64(report.TradesWithSignal)
+	}
+	report.ByIndicator = make([]IndicatorAttribution, 0, len(byIndicator))
+	for _, attr := range byIndicator {
+		attr.Aligned.finish()
+		attr.Opposed.finish()
+		if attr.scoreCount > 0 {
+			attr.AvgScore = attr.scoreSum / float64(attr.scoreCount)
+		}
+		if attr.Aligned.Trades > 0 {
+			attr.AlignedEdge = attr.Aligned.AvgPnLPercent - baseline
+		}
+		report.ByIndicator = append(report.ByIndicator, *attr)
+	}
+	sort.Slice(report.ByIndicator, func(i, j int) bool {
+		return report.ByIndicator[i].Aligned.NetPnL > report.ByIndicator[j].Aligned.NetPnL
+	})
+
+	return report
+}
+
+func groupFor(groups map[string]*GroupStats, key string) *GroupStats {
+	g, ok := groups[key]
+	if !ok {
+		g = &GroupStats{Key: key}
+		groups[key] = g
+	}
+	return g
+}
+
+func (g *GroupStats) add(t trading.Trade) {
+	g.Trades++
+	g.NetPnL += t.PnL
+	g.pnlPercentSum += t.PnLPercent
+	switch {
+	case t.PnL > 0:
+		g.Wins++
+		g.grossProfit += t.PnL
+	case t.PnL < 0:
+		g.Losses++
+		g.grossLoss -= t.PnL
+	default:
+		g.Breakeven++
+	}
+}
+
+func (g *GroupStats) finish() {
+	if g.Trades == 0 {
+		return
+	}
+	if decided := g.Wins + g.Losses; decided > 0 {
+		g.WinRate = float64(g.Wins) / float64(decided) * 100
+	}
+	g.AvgPnLPercent = g.pnlPercentSum / float64(g.Trades)
+	if g.grossLoss > 0 {
+		g.ProfitFactor = g.grossProfit / g.grossLoss
+	}
+}
+
+// finishGroups returns the groups sorted by net PnL, best first.
+func finishGroups(groups map[string]*GroupStats) []GroupStats {
+	result := make([]GroupStats, 0, len(groups))
+	for _, g := range groups {
+		g.finish()
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NetPnL > result[j].NetPnL })
+	return result
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
@@ -0,0 +1,149 @@
+package performance
+
+import (
+	"math"
+	"time"
+)
+
+// resample returns the last equity value of every period bucket.
+func resample(equity []EquitySample, period time.Duration) []float64 {
+	if len(equity) == 0 {
+		return nil
+	}
+
+	values := make([]float64, 0)
+	bucket := equity[0].Time.Truncate(period)
+	last := equity[0].Equity
+	for _, s := range equity {
+		b := s.Time.Truncate(period)
+		if b.After(bucket) {
+			values = append(values, last)
+			// Carry the last value forward through empty buckets
+			for next := bucket.Add(period); next.Before(b); next = next.Add(period) {
+				values = append(values, last)
+			}
+			bucket = b
+		}
+		last = s.Equity
+	}
+	values = append(values, last)
+	return values
+}
+
+func periodReturns(values []float64) []float64 {
+	returns := make([]float64, 0, len(values))
+	for i := 1; i < len(values); i++ {
+		if values[i-1] <= 0 {
+			continue
+		}
+		returns = append(returns, values[i]/values[i-1]-1)
+	}
+	return returns
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	var s float64
+	for _, v := range values {
+		s += v
+	}
+	return s / float64(len(values))
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	var variance float64
+	for _, v := range values {
+		variance += (v - m) * (v - m)
+	}
+	return math.Sqrt(variance / float64(len(values)-1))
+}
+
+func sharpe(returns []float64, riskFree, periodsPerYear float64) float64 {
+	sd := stdDev(returns)
+	if sd == 0 {
+		return 0
+	}
+	return (mean(returns) - riskFree) / sd * math.Sqrt(periodsPerYear)
+}
+
+// sortino uses the downside deviation below the risk-free rate.
+func sortino(returns []float64, riskFree, periodsPerYear float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var downside float64
+	for _, r := range returns {
+		if r < riskFree {
+			downside += (r - riskFree) * (r - riskFree)
+		}
+	}
+	downsideDev := math.Sqrt(downside / float64(len(returns)))
+	if downsideDev == 0 {
+		return 0
+	}
+	return (mean(returns) - riskFree) / downsideDev * math.Sqrt(periodsPerYear)
+}
+
+// drawdownStats returns the maximum drawdown and the ulcer index, both in percent.
+func drawdownStats(equity []EquitySample) (maxDrawdown, ulcer float64) {
+	if len(equity) == 0 {
+		return 0, 0
+	}
+
+	peak := equity[0].Equity
+	var sumSquares float64
+	for _, s := range equity {
+		if s.Equity > peak {
+			peak = s.Equity
+		}
+		var dd float64
+		if peak > 0 {
+			dd = (peak - s.Equity) / peak * 100
+		}
+		if dd > maxDrawdown {
+			maxDrawdown = dd
+		}
+		sumSquares += dd * dd
+	}
+	return maxDrawdown, math.Sqrt(sumSquares / float64(len(equity)))
+}
+
+// monthlyReturns compounds the equity change inside each calendar month (UTC).
+// The first month is measured from the first sample, later months from the
+// last equity of the previous month.
+func monthlyReturns(equity []EquitySample) []MonthlyReturn {
+	result := make([]MonthlyReturn, 0)
+	if len(equity) == 0 {
+		return result
+	}
+
+	base := equity[0].Equity
+	year, month := equity[0].Time.UTC().Year(), equity[0].Time.UTC().Month()
+	last := base
+
+	flush := func() {
+		if base > 0 {
+			result = append(result, MonthlyReturn{Year: year, Month: int(month), Return: (last/base - 1) * 100})
+		}
+	}
+
+	for _, s := range equity {
+		t := s.Time.UTC()
+		if t.Year() != year || t.Month() != month {
+			flush()
+			base = last
+			year, month = t.Year(), t.Month()
+		}
+		last = s.Equity
+	}
+	flush()
+
+	return result
+}
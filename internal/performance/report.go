@@ -0,0 +1,286 @@
+// Package performance computes risk-adjusted performance analytics from a
+// list of closed trades and an equity curve. The inputs are plain records,
+// so the same report is produced for live engines and for backtests.
+package performance
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"crypto-trading-bot/internal/trading"
+)
+
+// TradeRecord is the minimal description of a closed trade.
+type TradeRecord struct {
+	Symbol     string
+	PnL        float64 // Realized PnL in quote currency
+	PnLPercent float64 // Realized PnL in percent of the entry notional
+	OpenedAt   time.Time
+	ClosedAt   time.Time
+}
+
+// EquitySample is a single equity observation.
+type EquitySample struct {
+	Time   time.Time
+	Equity float64
+}
+
+// Config controls how the report is computed.
+type Config struct {
+	InitialCapital float64       // Used when the equity curve has to be rebuilt from trades
+	RiskFreeRate   float64       // Annual risk-free rate (0.04 = 4%)
+	ReturnPeriod   time.Duration // Resampling period for return series; 0 picks hourly or daily
+}
+
+// MonthlyReturn is one cell of the monthly returns table.
+type MonthlyReturn struct {
+	Year   int     `json:"year"`
+	Month  int     `json:"month"`
+	Return float64 `json:"return"` // Percent
+}
+
+// Report holds all computed performance metrics.
+type Report struct {
+	TotalTrades          int             `json:"totalTrades"`
+	WinningTrades        int             `json:"winningTrades"`
+	LosingTrades         int             `json:"losingTrades"`
+	BreakevenTrades      int             `json:"breakevenTrades"` // Zero PnL; neither wins nor losses
+	WinRate              float64         `json:"winRate"`         // Percent of winning and losing trades
+	GrossProfit          float64         `json:"grossProfit"`
+	GrossLoss            float64         `json:"grossLoss"` // Positive number
+	NetProfit            float64         `json:"netProfit"`
+	ProfitFactor         float64         `json:"profitFactor"` // Gross profit / gross loss
+	AvgWin               float64         `json:"avgWin"`
+	AvgLoss              float64         `json:"avgLoss"`     // Positive number
+	PayoffRatio          float64         `json:"payoffRatio"` // Avg win / avg loss
+	Expectancy           float64         `json:"expectancy"`  // Expected PnL per trade in quote currency
+	ExpectancyPercent    float64         `json:"expectancyPercent"`
+	MaxConsecutiveWins   int             `json:"maxConsecutiveWins"`
+	MaxConsecutiveLosses int             `json:"maxConsecutiveLosses"`
+	AvgHoldingMinutes    float64         `json:"avgHoldingMinutes"`
+	ExposureTime         float64         `json:"exposureTime"` // Percent of the period with an open position
+	TotalReturn          float64         `json:"totalReturn"`  // Percent
+	AnnualizedReturn     float64         `json:"annualizedReturn"`
+	AnnualizedVolatility float64         `json:"annualizedVolatility"`
+	SharpeRatio          float64         `json:"sharpeRatio"`
+	SortinoRatio         float64         `json:"sortinoRatio"`
+	CalmarRatio          float64         `json:"calmarRatio"`
+	MaxDrawdown          float64         `json:"maxDrawdown"` // Percent
+	UlcerIndex           float64         `json:"ulcerIndex"`
+	MonthlyReturns       []MonthlyReturn `json:"monthlyReturns"`
+	PeriodStart          int64           `json:"periodStart"` // Unix milliseconds
+	PeriodEnd            int64           `json:"periodEnd"`   // Unix milliseconds
+}
+
+// FromTrades converts engine trades into trade records.
+func FromTrades(trades []trading.Trade) []TradeRecord {
+	records := make([]TradeRecord, 0, len(trades))
+	for _, t := range trades {
+		records = append(records, TradeRecord{
+			Symbol:     t.Symbol,
+			PnL:        t.PnL,
+			PnLPercent: t.PnLPercent,
+			OpenedAt:   t.OpenedAt,
+			ClosedAt:   t.ClosedAt,
+		})
+	}
+	return records
+}
+
+// FromEquityPoints converts engine equity snapshots into equity samples.
+func FromEquityPoints(points []trading.EquityPoint) []EquitySample {
+	samples := make([]EquitySample, 0, len(points))
+	for _, p := range points {
+		samples = append(samples, EquitySample{Time: time.UnixMilli(p.Timestamp), Equity: p.Equity})
+	}
+	return samples
+}
+
+// EquityFromTrades rebuilds a stepwise equity curve from closed trades,
+// starting at initialCapital. Useful for backtests without a sampled curve.
+func EquityFromTrades(initialCapital float64, trades []TradeRecord) []EquitySample {
+	sorted := sortedByClose(trades)
+	samples := make([]EquitySample, 0, len(sorted)+1)
+
+	equity := initialCapital
+	if len(sorted) > 0 {
+		samples = append(samples, EquitySample{Time: sorted[0].OpenedAt, Equity: equity})
+	}
+	for _, t := range sorted {
+		equity += t.PnL
+		samples = append(samples, EquitySample{Time: t.ClosedAt, Equity: equity})
+	}
+	return samples
+}
+
+// Analyze computes the full report. If equity is empty the curve is rebuilt
+// from trades using Config.InitialCapital.
+func Analyze(trades []TradeRecord, equity []EquitySample, config Config) Report {
+	report := Report{MonthlyReturns: make([]MonthlyReturn, 0)}
+
+	if len(equity) == 0 && config.InitialCapital > 0 {
+		equity = EquityFromTrades(config.InitialCapital, trades)
+	}
+	equity = sortedEquity(equity)
+
+	analyzeTrades(&report, sortedByClose(trades))
+
+	if len(equity) < 2 {
+		return report
+	}
+
+	start, end := equity[0].Time, equity[len(equity)-1].Time
+	report.PeriodStart = start.UnixMilli()
+	report.PeriodEnd = end.UnixMilli()
+	report.ExposureTime = exposureTime(trades, start, end)
+
+	first, last := equity[0].Equity, equity[len(equity)-1].Equity
+	if first > 0 {
+		report.TotalReturn = (last/first - 1) * 100
+	}
+
+	years := end.Sub(start).Hours() / (24 * 365)
+	if years > 0 && first > 0 && last > 0 {
+		report.AnnualizedReturn = (math.Pow(last/first, 1/years) - 1) * 100
+	}
+
+	period := config.ReturnPeriod
+	if period <= 0 {
+		period = 24 * time.Hour
+		if end.Sub(start) < 30*24*time.Hour {
+			period = time.Hour
+		}
+	}
+	resampled := resample(equity, period)
+	periodsPerYear := float64(365*24*time.Hour) / float64(period)
+
+	returns := periodReturns(resampled)
+	riskFree := config.RiskFreeRate / periodsPerYear
+	report.AnnualizedVolatility = stdDev(returns) * math.Sqrt(periodsPerYear) * 100
+	report.SharpeRatio = sharpe(returns, riskFree, periodsPerYear)
+	report.SortinoRatio = sortino(returns, riskFree, periodsPerYear)
+
+	report.MaxDrawdown, report.UlcerIndex = drawdownStats(equity)
+	if report.MaxDrawdown > 0 {
+		report.CalmarRatio = report.AnnualizedReturn / report.MaxDrawdown
+	}
+
+	report.MonthlyReturns = monthlyReturns(equity)
+	return report
+}
+
+func analyzeTrades(report *Report, trades []TradeRecord) {
+	report.TotalTrades = len(trades)
+	if len(trades) == 0 {
+		return
+	}
+
+	var winStreak, lossStreak int
+	var holding time.Duration
+	var pnlPercentSum float64
+
+	for _, t := range trades {
+		report.NetProfit += t.PnL
+		pnlPercentSum += t.PnLPercent
+		holding += t.ClosedAt.Sub(t.OpenedAt)
+
+		// Сделка в ноль не прерывает и не продолжает серии
+		switch {
+		case t.PnL > 0:
+			report.WinningTrades++
+			report.GrossProfit += t.PnL
+			winStreak++
+			lossStreak = 0
+		case t.PnL < 0:
+			report.LosingTrades++
+			report.GrossLoss += -t.PnL
+			lossStreak++
+			winStreak = 0
+		default:
+			report.BreakevenTrades++
+		}
+
+		if winStreak > report.MaxConsecutiveWins {
+			report.MaxConsecutiveWins = winStreak
+		}
+		if lossStreak > report.MaxConsecutiveLosses {
+			report.MaxConsecutiveLosses = lossStreak
+		}
+	}
+
+	n := float64(len(trades))
+	if decided := report.WinningTrades + report.LosingTrades; decided > 0 {
+		report.WinRate = float64(report.WinningTrades) / float64(decided) * 100
+	}
+	report.Expectancy = report.NetProfit / n
+	report.ExpectancyPercent = pnlPercentSum / n
+	report.AvgHoldingMinutes = holding.Minutes() / n
+
+	if report.WinningTrades > 0 {
+		report.AvgWin = report.GrossProfit / float64(report.WinningTrades)
+	}
+	if report.LosingTrades > 0 {
+		report.AvgLoss = report.GrossLoss / float64(report.LosingTrades)
+	}
+	if report.AvgLoss > 0 {
+		report.PayoffRatio = report.AvgWin / report.AvgLoss
+	}
+	if report.GrossLoss > 0 {
+		report.ProfitFactor = report.GrossProfit / report.GrossLoss
+	}
+}
+
+// exposureTime returns the percentage of [start, end] covered by at least one
+// open trade. Overlapping trades are counted once.
+func exposureTime(trades []TradeRecord, start, end time.Time) float64 {
+	total := end.Sub(start)
+	if total <= 0 || len(trades) == 0 {
+		return 0
+	}
+
+	sorted := make([]TradeRecord, len(trades))
+	copy(sorted, trades)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].OpenedAt.Before(sorted[j].OpenedAt) })
+
+	var covered time.Duration
+	var curStart, curEnd time.Time
+	for i, t := range sorted {
+		open, close := clampTime(t.OpenedAt, start, end), clampTime(t.ClosedAt, start, end)
+		if i == 0 || open.After(curEnd) {
+			covered += curEnd.Sub(curStart)
+			curStart, curEnd = open, close
+			continue
+		}
+		if close.After(curEnd) {
+			curEnd = close
+		}
+	}
+	covered += curEnd.Sub(curStart)
+
+	return float64(covered) / float64(total) * 100
+}
+
+func clampTime(t, start, end time.Time) time.Time {
+	if t.Before(start) {
+		return start
+	}
+	if t.After(end) {
+		return end
+	}
+	return t
+}
+
+func sortedByClose(trades []TradeRecord) []TradeRecord {
+	sorted := make([]TradeRecord, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ClosedAt.Before(sorted[j].ClosedAt) })
+	return sorted
+}
+
+func sortedEquity(equity []EquitySample) []EquitySample {
+	sorted := make([]EquitySample, len(equity))
+	copy(sorted, equity)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+	return sorted
+}
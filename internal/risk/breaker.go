@@ -0,0 +1,285 @@
+package risk
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Trip reasons reported by the circuit breaker.
+const (
+	TripManual            = "manual"
+	TripDailyLoss         = "daily_loss"
+	TripDrawdown          = "drawdown"
+	TripConsecutiveLosses = "consecutive_losses"
+	TripStaleData         = "stale_data"
+	TripOrderErrors       = "order_errors"
+)
+
+// CircuitBreakerConfig holds the automatic trip thresholds. A zero value
+// disables the corresponding check. Loss limits are fractions of equity.
+type CircuitBreakerConfig struct {
+	MaxDailyLoss         float64       // Loss from start-of-day equity as a fraction (0.05 = 5%)
+	MaxDrawdown          float64       // Drawdown from peak equity as a fraction (0.2 = 20%)
+	MaxConsecutiveLosses int           // Losing trades in a row
+	StaleDataTimeout     time.Duration // Max age of market data for symbols with open positions
+	MaxOrderErrors       int           // Order errors within OrderErrorWindow
+	OrderErrorWindow     time.Duration // Window for counting order errors (default 5 minutes)
+	FlattenOnTrip        bool          // Close all positions on automatic trips
+}
+
+// DefaultCircuitBreakerConfig returns conservative default thresholds.
+func DefaultCircuitBreakerConfig() *CircuitBreakerConfig {
+	return &CircuitBreakerConfig{
+		MaxDailyLoss:         0.05,
+		MaxDrawdown:          0.2,
+		MaxConsecutiveLosses: 5,
+		StaleDataTimeout:     2 * time.Minute,
+		MaxOrderErrors:       5,
+		OrderErrorWindow:     5 * time.Minute,
+	}
+}
+
+// BreakerState is a snapshot of the circuit breaker.
+type BreakerState struct {
+	Tripped           bool   `json:"tripped"`
+	Reason            string `json:"reason"`
+	Message           string `json:"message"`
+	TrippedAt         int64  `json:"trippedAt"` // Unix milliseconds
+	Flatten           bool   `json:"flatten"`   // Positions were requested to be closed
+	ConsecutiveLosses int    `json:"consecutiveLosses"`
+	RecentOrderErrors int    `json:"recentOrderErrors"`
+	TripCount         int    `json:"tripCount"`
+}
+
+// CircuitBreaker halts new entries across every engine and strategy sharing
+// it. It trips automatically on risk limits or manually, and stays tripped
+// until Reset is called.
+type CircuitBreaker struct {
+	config      *CircuitBreakerConfig
+	state       BreakerState
+	orderErrors []time.Time
+	lastData    map[string]time.Time
+	listeners   map[string]func(BreakerState)
+	onReset     map[string]func()
+	mu          sync.RWMutex
+}
+
+// NewCircuitBreaker creates a circuit breaker; nil config uses the defaults.
+func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
+	if config == nil {
+		config = DefaultCircuitBreakerConfig()
+	}
+	if config.OrderErrorWindow <= 0 {
+		config.OrderErrorWindow = 5 * time.Minute
+	}
+	return &CircuitBreaker{
+		config:    config,
+		lastData:  make(map[string]time.Time),
+		listeners: make(map[string]func(BreakerState)),
+		onReset:   make(map[string]func()),
+	}
+}
+
+// OnTrip registers a callback invoked (in its own goroutine) whenever the
+// breaker trips. Registering again with the same key replaces the callback,
+// so a recreated engine does not leave a stale listener behind.
+func (cb *CircuitBreaker) OnTrip(key string, fn func(BreakerState)) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.listeners[key] = fn
+}
+
+// OnReset registers a callback invoked when the breaker is reset, before
+// Reset returns. Engines use it to move their daily loss and drawdown
+// references to the current equity, so the same loss does not trip the
+// breaker again on the next equity sample. A nil fn removes the callback.
+func (cb *CircuitBreaker) OnReset(key string, fn func()) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if fn == nil {
+		delete(cb.onReset, key)
+		return
+	}
+	cb.onReset[key] = fn
+}
+
+// Trip halts new entries. If flatten is true, listeners are asked to close
+// all open positions. Tripping an already tripped breaker only escalates
+// it to flatten.
+func (cb *CircuitBreaker) Trip(reason, message string, flatten bool) {
+	cb.mu.Lock()
+	if cb.state.Tripped && (cb.state.Flatten || !flatten) {
+		cb.mu.Unlock()
+		return
+	}
+	if !cb.state.Tripped {
+		cb.state.Tripped = true
+		cb.state.Reason = reason
+		cb.state.Message = message
+		cb.state.TrippedAt = time.Now().UnixMilli()
+		cb.state.TripCount++
+	}
+	cb.state.Flatten = cb.state.Flatten || flatten
+	state := cb.state
+	listeners := make([]func(BreakerState), 0, len(cb.listeners))
+	for _, fn := range cb.listeners {
+		listeners = append(listeners, fn)
+	}
+	cb.mu.Unlock()
+
+	log.Warnf("=== CIRCUIT BREAKER TRIPPED: %s ===", reason)
+	log.Warnf("%s (flatten=%v)", message, flatten)
+
+	for _, fn := range listeners {
+		go fn(state)
+	}
+}
+
+// Reset re-enables trading after a trip, clears the loss and error counters
+// and lets the OnReset callbacks rebase their equity references.
+func (cb *CircuitBreaker) Reset() {
+	cb.mu.Lock()
+	if cb.state.Tripped {
+		log.Infof("Circuit breaker reset (was tripped: %s)", cb.state.Reason)
+	}
+	tripCount := cb.state.TripCount
+	cb.state = BreakerState{TripCount: tripCount}
+	cb.orderErrors = nil
+	cb.lastData = make(map[string]time.Time)
+	callbacks := make([]func(), 0, len(cb.onReset))
+	for _, fn := range cb.onReset {
+		callbacks = append(callbacks, fn)
+	}
+	cb.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// IsTripped reports whether new entries are halted and why.
+func (cb *CircuitBreaker) IsTripped() (bool, string) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+	if !cb.state.Tripped {
+		return false, ""
+	}
+	return true, fmt.Sprintf("circuit breaker tripped (%s): %s", cb.state.Reason, cb.state.Message)
+}
+
+// State returns a snapshot of the breaker.
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	state := cb.state
+	state.RecentOrderErrors = cb.countOrderErrors(time.Now())
+	return state
+}
+
+// RecordTrade counts consecutive losing trades. A winning trade ends the
+// streak; a breakeven one (zero PnL) leaves it unchanged.
+func (cb *CircuitBreaker) RecordTrade(pnl float64) {
+	cb.mu.Lock()
+	if pnl >= 0 {
+		if pnl > 0 {
+			cb.state.ConsecutiveLosses = 0
+		}
+		cb.mu.Unlock()
+		return
+	}
+	cb.state.ConsecutiveLosses++
+	losses := cb.state.ConsecutiveLosses
+	cb.mu.Unlock()
+
+	if cb.config.MaxConsecutiveLosses > 0 && losses >= cb.config.MaxConsecutiveLosses {
+		cb.Trip(TripConsecutiveLosses, fmt.Sprintf("%d consecutive losing trades", losses), cb.config.FlattenOnTrip)
+	}
+}
+
+// CheckEquity trips on the daily loss and drawdown limits; drawdown is a
+// fraction of peak equity. account identifies the engine in the trip
+// message.
+func (cb *CircuitBreaker) CheckEquity(account string, dayStartEquity, equity, drawdown float64) {
+	if cb.config.MaxDailyLoss > 0 && dayStartEquity > 0 {
+		loss := (dayStartEquity - equity) / dayStartEquity
+		if loss >= cb.config.MaxDailyLoss {
+			cb.Trip(TripDailyLoss, fmt.Sprintf("%s: daily loss %.2f%% reached limit %.2f%%",
+				account, loss*100, cb.config.MaxDailyLoss*100), cb.config.FlattenOnTrip)
+			return
+		}
+	}
+	if cb.config.MaxDrawdown > 0 && drawdown >= cb.config.MaxDrawdown {
+		cb.Trip(TripDrawdown, fmt.Sprintf("%s: drawdown %.2f%% reached limit %.2f%%",
+			account, drawdown*100, cb.config.MaxDrawdown*100), cb.config.FlattenOnTrip)
+	}
+}
+
+// RecordMarketData marks fresh market data for a symbol.
+func (cb *CircuitBreaker) RecordMarketData(symbol string, t time.Time) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.lastData[symbol] = t
+}
+
+// CheckStaleData trips when any of the given symbols (typically those with
+// open positions) has not received data within StaleDataTimeout. Symbols
+// that never received data are ignored.
+func (cb *CircuitBreaker) CheckStaleData(symbols []string, now time.Time) {
+	if cb.config.StaleDataTimeout <= 0 {
+		return
+	}
+
+	cb.mu.RLock()
+	var stale string
+	var age time.Duration
+	for _, symbol := range symbols {
+		last, ok := cb.lastData[symbol]
+		if ok && now.Sub(last) > cb.config.StaleDataTimeout {
+			stale, age = symbol, now.Sub(last)
+			break
+		}
+	}
+	cb.mu.RUnlock()
+
+	if stale != "" {
+		cb.Trip(TripStaleData, fmt.Sprintf("no market data for %s for %v", stale, age.Round(time.Second)), cb.config.FlattenOnTrip)
+	}
+}
+
+// RecordOrderError counts a failed order and trips when too many failures
+// happen within OrderErrorWindow.
+func (cb *CircuitBreaker) RecordOrderError(err error) {
+	now := time.Now()
+
+	cb.mu.Lock()
+	recent := cb.orderErrors[:0]
+	for _, t := range cb.orderErrors {
+		if now.Sub(t) <= cb.config.OrderErrorWindow {
+			recent = append(recent, t)
+		}
+	}
+	cb.orderErrors = append(recent, now)
+	count := len(cb.orderErrors)
+	cb.mu.Unlock()
+
+	if cb.config.MaxOrderErrors > 0 && count >= cb.config.MaxOrderErrors {
+		cb.Trip(TripOrderErrors, fmt.Sprintf("%d order errors within %v, last: %v",
+			count, cb.config.OrderErrorWindow, err), cb.config.FlattenOnTrip)
+	}
+}
+
+// countOrderErrors returns the number of errors within OrderErrorWindow.
+// The caller must hold the lock.
+func (cb *CircuitBreaker) countOrderErrors(now time.Time) int {
+	count := 0
+	for _, t := range cb.orderErrors {
+		if now.Sub(t) <= cb.config.OrderErrorWindow {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,105 @@
+package risk
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DecisionJournal keeps every risk decision for review: the most recent ones
+// in memory and all of them in an append-only JSON lines file.
+type DecisionJournal struct {
+	file        *os.File
+	decisions   []RiskDecision
+	maxInMemory int
+	mu          sync.RWMutex
+}
+
+// NewDecisionJournal opens (or creates) the journal file at path. An empty
+// path keeps decisions in memory only.
+func NewDecisionJournal(path string, maxInMemory int) (*DecisionJournal, error) {
+	if maxInMemory <= 0 {
+		maxInMemory = 10000
+	}
+
+	journal := &DecisionJournal{
+		decisions:   make([]RiskDecision, 0),
+		maxInMemory: maxInMemory,
+	}
+	if path == "" {
+		return journal, nil
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create risk journal directory: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open risk journal: %w", err)
+	}
+	journal.file = file
+
+	log.Infof("Risk decision journal opened: %s", path)
+	return journal, nil
+}
+
+// Record appends a decision.
+func (j *DecisionJournal) Record(decision RiskDecision) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.decisions = append(j.decisions, decision)
+	if len(j.decisions) > j.maxInMemory {
+		j.decisions = j.decisions[len(j.decisions)-j.maxInMemory:]
+	}
+
+	if j.file == nil {
+		return
+	}
+	data, err := json.Marshal(decision)
+	if err != nil {
+		log.Errorf("Risk journal: failed to encode decision: %v", err)
+		return
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		log.Errorf("Risk journal: failed to write decision: %v", err)
+	}
+}
+
+// Query returns the most recent decisions of an account, newest first. An
+// empty account matches all; limit <= 0 returns everything in memory.
+func (j *DecisionJournal) Query(account string, limit int) []RiskDecision {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	result := make([]RiskDecision, 0)
+	for i := len(j.decisions) - 1; i >= 0; i-- {
+		if account != "" && j.decisions[i].Account != account {
+			continue
+		}
+		result = append(result, j.decisions[i])
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// Close closes the journal file.
+func (j *DecisionJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
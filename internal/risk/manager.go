@@ -18,6 +18,7 @@ type DailyStats struct {
 	MaxDailyLoss float64
 	TradesWon    int
 	TradesLost   int
+	TradesEven   int // Trades closed with zero PnL
 }
 
 func NewRiskManager(config *RiskConfig) *RiskManager {
@@ -48,22 +49,6 @@ func (rm *RiskManager) CalculatePositionSize(balance, entryPrice, stopLossPrice
 	return positionSize
 }
 
-func (rm *RiskManager) CanOpenPosition(balance, currentExposure float64) bool {
-	rm.mu.RLock()
-	defer rm.mu.RUnlock()
-
-	maxExposure := balance * rm.config.MaxPositionSize * 3
-	if currentExposure >= maxExposure {
-		return false
-	}
-
-	if rm.dailyStats.TotalPnL < -balance*0.05 {
-		return false
-	}
-
-	return true
-}
-
 func (rm *RiskManager) CalculateStopLoss(entryPrice, atr float64, side string) float64 {
 	var stopDistance float64
 
@@ -89,17 +74,20 @@ func (rm *RiskManager) CalculateTakeProfit(entryPrice, stopLoss float64, side st
 	return entryPrice - profitDistance
 }
 
-func (rm *RiskManager) UpdateDailyStats(pnl float64, won bool) {
+func (rm *RiskManager) UpdateDailyStats(pnl float64) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
 	rm.dailyStats.TotalTrades++
 	rm.dailyStats.TotalPnL += pnl
 
-	if won {
+	switch {
+	case pnl > 0:
 		rm.dailyStats.TradesWon++
-	} else {
+	case pnl < 0:
 		rm.dailyStats.TradesLost++
+	default:
+		rm.dailyStats.TradesEven++
 	}
 
 	if rm.dailyStats.TotalPnL < rm.dailyStats.MaxDailyLoss {
@@ -114,6 +102,15 @@ func (rm *RiskManager) ResetDailyStats(date string) {
 	rm.dailyStats = &DailyStats{Date: date}
 }
 
+// RestoreDailyStats replaces the daily counters, e.g. after the manager is
+// recreated with a new configuration in the middle of a trading day.
+func (rm *RiskManager) RestoreDailyStats(stats DailyStats) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.dailyStats = &stats
+}
+
 func (rm *RiskManager) GetDailyStats() DailyStats {
 	rm.mu.RLock()
 	defer rm.mu.RUnlock()
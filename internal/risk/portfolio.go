@@ -0,0 +1,459 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PortfolioLimits holds portfolio-level exposure limits as fractions of
+// equity (1.0 = 100%). A zero limit disables the check.
+type PortfolioLimits struct {
+	MaxGrossExposure     float64           `json:"maxGrossExposure"`     // Sum of |notional|
+	MaxNetExposure       float64           `json:"maxNetExposure"`       // |long - short|
+	MaxAssetExposure     float64           `json:"maxAssetExposure"`     // Per symbol
+	MaxSectorExposure    float64           `json:"maxSectorExposure"`    // Per sector
+	MaxClusterExposure   float64           `json:"maxClusterExposure"`   // Symbols correlated above CorrelationThreshold
+	CorrelationThreshold float64           `json:"correlationThreshold"` // Correlation that puts two symbols in one cluster
+	ReturnsWindow        int               `json:"returnsWindow"`        // Returns used for correlation
+	SampleInterval       time.Duration     `json:"sampleInterval"`       // Bar size of the returns series
+	Sectors              map[string]string `json:"sectors"`              // Symbol or base asset -> sector; overrides the built-in map
+}
+
+// DefaultPortfolioLimits returns limits that keep correlated altcoin
+// exposure below 60% of equity.
+func DefaultPortfolioLimits() *PortfolioLimits {
+	return &PortfolioLimits{
+		MaxGrossExposure:     1.0,
+		MaxNetExposure:       1.0,
+		MaxAssetExposure:     0.5,
+		MaxSectorExposure:    0.6,
+		MaxClusterExposure:   0.6,
+		CorrelationThreshold: 0.7,
+		ReturnsWindow:        100,
+		SampleInterval:       time.Minute,
+	}
+}
+
+// defaultSectors groups common base assets.
+var defaultSectors = map[string]string{
+	"BTC":   "store_of_value",
+	"ETH":   "smart_contract",
+	"SOL":   "smart_contract",
+	"ADA":   "smart_contract",
+	"AVAX":  "smart_contract",
+	"DOT":   "smart_contract",
+	"NEAR":  "smart_contract",
+	"TRX":   "smart_contract",
+	"ATOM":  "interoperability",
+	"LINK":  "oracle",
+	"BNB":   "exchange",
+	"OKB":   "exchange",
+	"MATIC": "layer2",
+	"POL":   "layer2",
+	"ARB":   "layer2",
+	"OP":    "layer2",
+	"DOGE":  "meme",
+	"SHIB":  "meme",
+	"PEPE":  "meme",
+	"XRP":   "payments",
+	"LTC":   "payments",
+	"UNI":   "defi",
+	"AAVE":  "defi",
+}
+
+var quoteAssets = []string{"USDT", "USDC", "FDUSD", "BUSD", "BTC", "ETH"}
+
+// Holding is a position expressed as a signed notional (long > 0, short < 0).
+type Holding struct {
+	Symbol   string  `json:"symbol"`
+	Notional float64 `json:"notional"`
+}
+
+// ExposureGroup is the exposure of a symbol, sector or correlated cluster.
+type ExposureGroup struct {
+	Key      string   `json:"key"`
+	Symbols  []string `json:"symbols"`
+	Exposure float64  `json:"exposure"` // Gross notional
+	Percent  float64  `json:"percent"`  // Percent of equity
+}
+
+// PortfolioExposure describes the current portfolio against its limits.
+type PortfolioExposure struct {
+	Equity            float64         `json:"equity"`
+	GrossExposure     float64         `json:"grossExposure"`
+	NetExposure       float64         `json:"netExposure"`
+	GrossPercent      float64         `json:"grossPercent"`
+	NetPercent        float64         `json:"netPercent"`
+	BySymbol          []ExposureGroup `json:"bySymbol"`
+	BySector          []ExposureGroup `json:"bySector"`
+	Clusters          []ExposureGroup `json:"clusters"`
+	Symbols           []string        `json:"symbols"`
+	CorrelationMatrix [][]float64     `json:"correlationMatrix"` // Rows and columns follow Symbols
+	Limits            PortfolioLimits `json:"limits"`
+}
+
+type pricePoint struct {
+	bucket int64 // Start of the sample bar, Unix seconds
+	price  float64
+}
+
+// PortfolioRisk keeps rolling price series per symbol and enforces
+// correlation-aware exposure limits before entries.
+type PortfolioRisk struct {
+	limits *PortfolioLimits
+	prices map[string][]pricePoint
+	mu     sync.RWMutex
+}
+
+// NewPortfolioRisk creates a portfolio risk checker; nil limits use the defaults.
+func NewPortfolioRisk(limits *PortfolioLimits) *PortfolioRisk {
+	if limits == nil {
+		limits = DefaultPortfolioLimits()
+	}
+	if limits.ReturnsWindow < 10 {
+		limits.ReturnsWindow = 100
+	}
+	if limits.SampleInterval <= 0 {
+		limits.SampleInterval = time.Minute
+	}
+	if limits.CorrelationThreshold <= 0 {
+		limits.CorrelationThreshold = 0.7
+	}
+	return &PortfolioRisk{
+		limits: limits,
+		prices: make(map[string][]pricePoint),
+	}
+}
+
+// Limits returns a copy of the configured limits.
+func (pr *PortfolioRisk) Limits() PortfolioLimits {
+	return *pr.limits
+}
+
+// RecordPrice records a price observation. The last price inside each
+// SampleInterval bar becomes that bar's close.
+func (pr *PortfolioRisk) RecordPrice(symbol string, t time.Time, price float64) {
+	if price <= 0 {
+		return
+	}
+	bucket := t.Truncate(pr.limits.SampleInterval).Unix()
+
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	series := pr.prices[symbol]
+	if n := len(series); n > 0 && series[n-1].bucket >= bucket {
+		if series[n-1].bucket == bucket {
+			series[n-1].price = price
+		}
+		return
+	}
+	series = append(series, pricePoint{bucket: bucket, price: price})
+	if max := pr.limits.ReturnsWindow + 1; len(series) > max*2 {
+		series = append([]pricePoint(nil), series[len(series)-max:]...)
+	}
+	pr.prices[symbol] = series
+}
+
+// LoadHistory warms up a symbol from historical bar closes. Bars with a
+// different spacing than SampleInterval are ignored, since mixing bar sizes
+// would distort the correlation estimate.
+func (pr *PortfolioRisk) LoadHistory(symbol string, closeTimes []time.Time, closes []float64) bool {
+	if len(closeTimes) < 2 || len(closeTimes) != len(closes) {
+		return false
+	}
+	spacing := closeTimes[len(closeTimes)-1].Sub(closeTimes[len(closeTimes)-2])
+	if math.Abs(float64(spacing-pr.limits.SampleInterval)) > float64(time.Second) {
+		return false
+	}
+	for i := range closes {
+		pr.RecordPrice(symbol, closeTimes[i], closes[i])
+	}
+	return true
+}
+
+// returns yields bar returns keyed by bar bucket. The caller must hold the lock.
+func (pr *PortfolioRisk) returns(symbol string) map[int64]float64 {
+	series := pr.prices[symbol]
+	if len(series) > pr.limits.ReturnsWindow+1 {
+		series = series[len(series)-pr.limits.ReturnsWindow-1:]
+	}
+
+	result := make(map[int64]float64, len(series))
+	step := int64(pr.limits.SampleInterval / time.Second)
+	for i := 1; i < len(series); i++ {
+		// Только соседние бары, пропуски в данных не превращаем в один большой return
+		if series[i].bucket-series[i-1].bucket != step || series[i-1].price <= 0 {
+			continue
+		}
+		result[series[i].bucket] = series[i].price/series[i-1].price - 1
+	}
+	return result
+}
+
+// Correlation returns the Pearson correlation of bar returns of two symbols
+// over their common bars, and false when fewer than 10 bars overlap.
+func (pr *PortfolioRisk) Correlation(a, b string) (float64, bool) {
+	if a == b {
+		return 1, true
+	}
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+	return correlation(pr.returns(a), pr.returns(b))
+}
+
+func correlation(ra, rb map[int64]float64) (float64, bool) {
+	xs := make([]float64, 0, len(ra))
+	ys := make([]float64, 0, len(ra))
+	for bucket, x := range ra {
+		if y, ok := rb[bucket]; ok {
+			xs = append(xs, x)
+			ys = append(ys, y)
+		}
+	}
+	if len(xs) < 10 {
+		return 0, false
+	}
+
+	n := float64(len(xs))
+	var mx, my float64
+	for i := range xs {
+		mx += xs[i]
+		my += ys[i]
+	}
+	mx /= n
+	my /= n
+
+	var cov, vx, vy float64
+	for i := range xs {
+		dx, dy := xs[i]-mx, ys[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0, false
+	}
+	return cov / math.Sqrt(vx*vy), true
+}
+
+// Sector returns the sector of a symbol, "other" when unknown.
+func (pr *PortfolioRisk) Sector(symbol string) string {
+	if sector, ok := pr.limits.Sectors[symbol]; ok {
+		return sector
+	}
+	base := baseAsset(symbol)
+	if sector, ok := pr.limits.Sectors[base]; ok {
+		return sector
+	}
+	if sector, ok := defaultSectors[base]; ok {
+		return sector
+	}
+	return "other"
+}
+
+func baseAsset(symbol string) string {
+	for _, quote := range quoteAssets {
+		if strings.HasSuffix(symbol, quote) && len(symbol) > len(quote) {
+			return strings.TrimSuffix(symbol, quote)
+		}
+	}
+	return symbol
+}
+
+// CheckEntry verifies that adding notional (signed) in symbol keeps the
+// portfolio within its limits. It returns nil when the entry is allowed.
+func (pr *PortfolioRisk) CheckEntry(holdings []Holding, equity float64, symbol string, notional float64) error {
+	if equity <= 0 {
+		return fmt.Errorf("portfolio limits: equity is not positive")
+	}
+
+	after := append(append([]Holding(nil), holdings...), Holding{Symbol: symbol, Notional: notional})
+	limits := pr.limits
+
+	var gross, net float64
+	bySymbol := make(map[string]float64)
+	bySector := make(map[string]float64)
+	for _, h := range after {
+		gross += math.Abs(h.Notional)
+		net += h.Notional
+		bySymbol[h.Symbol] += h.Notional
+	}
+	for sym, n := range bySymbol {
+		bySector[pr.Sector(sym)] += math.Abs(n)
+	}
+
+	if limits.MaxGrossExposure > 0 && gross > equity*limits.MaxGrossExposure {
+		return fmt.Errorf("gross exposure %.1f%% would exceed limit %.1f%%", gross/equity*100, limits.MaxGrossExposure*100)
+	}
+	if limits.MaxNetExposure > 0 && math.Abs(net) > equity*limits.MaxNetExposure {
+		return fmt.Errorf("net exposure %.1f%% would exceed limit %.1f%%", math.Abs(net)/equity*100, limits.MaxNetExposure*100)
+	}
+	if limits.MaxAssetExposure > 0 && math.Abs(bySymbol[symbol]) > equity*limits.MaxAssetExposure {
+		return fmt.Errorf("%s exposure %.1f%% would exceed per-asset limit %.1f%%",
+			symbol, math.Abs(bySymbol[symbol])/equity*100, limits.MaxAssetExposure*100)
+	}
+	sector := pr.Sector(symbol)
+	if limits.MaxSectorExposure > 0 && bySector[sector] > equity*limits.MaxSectorExposure {
+		return fmt.Errorf("sector %s exposure %.1f%% would exceed limit %.1f%%",
+			sector, bySector[sector]/equity*100, limits.MaxSectorExposure*100)
+	}
+
+	if limits.MaxClusterExposure > 0 {
+		cluster := pr.clusterOf(symbol, bySymbol)
+		var exposure float64
+		for _, sym := range cluster {
+			exposure += math.Abs(bySymbol[sym])
+		}
+		if exposure > equity*limits.MaxClusterExposure {
+			return fmt.Errorf("exposure to %s and correlated %v is %.1f%%, limit %.1f%%",
+				symbol, cluster, exposure/equity*100, limits.MaxClusterExposure*100)
+		}
+	}
+
+	return nil
+}
+
+// clusterOf returns the held symbols (including symbol itself) whose returns
+// correlate with symbol at or above the threshold.
+func (pr *PortfolioRisk) clusterOf(symbol string, held map[string]float64) []string {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	base := pr.returns(symbol)
+	cluster := []string{symbol}
+	for sym := range held {
+		if sym == symbol {
+			continue
+		}
+		if corr, ok := correlation(base, pr.returns(sym)); ok && corr >= pr.limits.CorrelationThreshold {
+			cluster = append(cluster, sym)
+		}
+	}
+	sort.Strings(cluster[1:])
+	return cluster
+}
+
+// Exposure reports the portfolio exposure, sector and cluster breakdown and
+// the correlation matrix of held symbols plus any extra symbols.
+func (pr *PortfolioRisk) Exposure(holdings []Holding, equity float64, extra ...string) PortfolioExposure {
+	report := PortfolioExposure{
+		Equity:            equity,
+		BySymbol:          make([]ExposureGroup, 0),
+		BySector:          make([]ExposureGroup, 0),
+		Clusters:          make([]ExposureGroup, 0),
+		Symbols:           make([]string, 0),
+		CorrelationMatrix: make([][]float64, 0),
+		Limits:            *pr.limits,
+	}
+
+	bySymbol := make(map[string]float64)
+	for _, h := range holdings {
+		report.GrossExposure += math.Abs(h.Notional)
+		report.NetExposure += h.Notional
+		bySymbol[h.Symbol] += h.Notional
+	}
+	if equity > 0 {
+		report.GrossPercent = report.GrossExposure / equity * 100
+		report.NetPercent = report.NetExposure / equity * 100
+	}
+
+	percent := func(v float64) float64 {
+		if equity <= 0 {
+			return 0
+		}
+		return v / equity * 100
+	}
+
+	sectors := make(map[string]*ExposureGroup)
+	for sym, n := range bySymbol {
+		report.BySymbol = append(report.BySymbol, ExposureGroup{Key: sym, Symbols: []string{sym}, Exposure: math.Abs(n), Percent: percent(math.Abs(n))})
+		sector := pr.Sector(sym)
+		g, ok := sectors[sector]
+		if !ok {
+			g = &ExposureGroup{Key: sector}
+			sectors[sector] = g
+		}
+		g.Symbols = append(g.Symbols, sym)
+		g.Exposure += math.Abs(n)
+	}
+	for _, g := range sectors {
+		g.Percent = percent(g.Exposure)
+		sort.Strings(g.Symbols)
+		report.BySector = append(report.BySector, *g)
+	}
+	for sym := range bySymbol {
+		cluster := pr.clusterOf(sym, bySymbol)
+		if len(cluster) < 2 {
+			continue
+		}
+		var exposure float64
+		for _, s := range cluster {
+			exposure += math.Abs(bySymbol[s])
+		}
+		report.Clusters = append(report.Clusters, ExposureGroup{Key: sym, Symbols: cluster, Exposure: exposure, Percent: percent(exposure)})
+	}
+	sortGroups(report.BySymbol)
+	sortGroups(report.BySector)
+	sortGroups(report.Clusters)
+
+	seen := make(map[string]bool)
+	for sym := range bySymbol {
+		seen[sym] = true
+	}
+	for _, sym := range extra {
+		seen[sym] = true
+	}
+	for sym := range seen {
+		report.Symbols = append(report.Symbols, sym)
+	}
+	sort.Strings(report.Symbols)
+
+	pr.mu.RLock()
+	series := make([]map[int64]float64, len(report.Symbols))
+	for i, sym := range report.Symbols {
+		series[i] = pr.returns(sym)
+	}
+	pr.mu.RUnlock()
+
+	for i := range report.Symbols {
+		row := make([]float64, len(report.Symbols))
+		for j := range report.Symbols {
+			if i == j {
+				row[j] = 1
+				continue
+			}
+			row[j], _ = correlation(series[i], series[j])
+		}
+		report.CorrelationMatrix = append(report.CorrelationMatrix, row)
+	}
+
+	return report
+}
+
+func sortGroups(groups []ExposureGroup) {
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Exposure > groups[j].Exposure })
+}
+
+// Series returns the sampled price history of the given symbols, for use
+// with ComputeVaR and PortfolioVaR.
+func (pr *PortfolioRisk) Series(symbols []string) []PriceSeries {
+	pr.mu.RLock()
+	defer pr.mu.RUnlock()
+
+	result := make([]PriceSeries, 0, len(symbols))
+	for _, sym := range symbols {
+		points := pr.prices[sym]
+		s := PriceSeries{Symbol: sym, Times: make([]int64, len(points)), Closes: make([]float64, len(points))}
+		for i, p := range points {
+			s.Times[i] = p.bucket
+			s.Closes[i] = p.price
+		}
+		result = append(result, s)
+	}
+	return result
+}
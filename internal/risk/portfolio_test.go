@@ -0,0 +1,184 @@
+package risk
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// patternReturns returns n deterministic bar returns of a sine wave.
+func patternReturns(n int, period, amplitude float64) []float64 {
+	returns := make([]float64, n)
+	for i := range returns {
+		returns[i] = amplitude * math.Sin(2*math.Pi*float64(i)/period)
+	}
+	return returns
+}
+
+// scaled multiplies returns by k.
+func scaled(returns []float64, k float64) []float64 {
+	result := make([]float64, len(returns))
+	for i, r := range returns {
+		result[i] = r * k
+	}
+	return result
+}
+
+// feedReturns records one-minute closes that produce the given returns.
+func feedReturns(pr *PortfolioRisk, symbol string, returns []float64) {
+	price := 100.0
+	pr.RecordPrice(symbol, testStart, price)
+	for i, r := range returns {
+		price *= 1 + r
+		pr.RecordPrice(symbol, testStart.Add(time.Duration(i+1)*time.Minute), price)
+	}
+}
+
+func newTestPortfolio(limits *PortfolioLimits) *PortfolioRisk {
+	pr := NewPortfolioRisk(limits)
+	base := patternReturns(60, 12, 0.01)
+	feedReturns(pr, "BTCUSDT", base)
+	feedReturns(pr, "ETHUSDT", scaled(base, 1.5))
+	feedReturns(pr, "SOLUSDT", scaled(base, -1))
+	feedReturns(pr, "XRPUSDT", patternReturns(60, 5, 0.01))
+	return pr
+}
+
+func TestCorrelation(t *testing.T) {
+	pr := newTestPortfolio(nil)
+	feedReturns(pr, "SHORTUSDT", patternReturns(5, 12, 0.01))
+
+	tests := []struct {
+		name   string
+		a, b   string
+		want   float64
+		wantOK bool
+	}{
+		{"same symbol", "BTCUSDT", "BTCUSDT", 1, true},
+		{"scaled returns", "BTCUSDT", "ETHUSDT", 1, true},
+		{"inverted returns", "BTCUSDT", "SOLUSDT", -1, true},
+		{"too few bars", "BTCUSDT", "SHORTUSDT", 0, false},
+		{"unknown symbol", "BTCUSDT", "ADAUSDT", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := pr.Correlation(tt.a, tt.b)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("Correlation(%s, %s) = %.4f, %v; want %.4f, %v", tt.a, tt.b, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	if corr, ok := pr.Correlation("BTCUSDT", "XRPUSDT"); !ok || math.Abs(corr) >= 0.7 {
+		t.Errorf("Correlation(BTCUSDT, XRPUSDT) = %.4f, %v; want an uncorrelated pair", corr, ok)
+	}
+}
+
+func TestCorrelationSkipsGaps(t *testing.T) {
+	pr := NewPortfolioRisk(nil)
+	pr.RecordPrice("BTCUSDT", testStart, 100)
+	pr.RecordPrice("BTCUSDT", testStart.Add(time.Minute), 101)
+	pr.RecordPrice("BTCUSDT", testStart.Add(10*time.Minute), 150)
+
+	pr.mu.RLock()
+	returns := pr.returns("BTCUSDT")
+	pr.mu.RUnlock()
+	if len(returns) != 1 {
+		t.Fatalf("returns over a data gap = %v, want only the adjacent bar", returns)
+	}
+}
+
+func TestClusterOf(t *testing.T) {
+	pr := newTestPortfolio(nil)
+
+	tests := []struct {
+		name   string
+		symbol string
+		held   []string
+		want   []string
+	}{
+		{"alone", "BTCUSDT", nil, []string{"BTCUSDT"}},
+		{"correlated pair", "BTCUSDT", []string{"ETHUSDT"}, []string{"BTCUSDT", "ETHUSDT"}},
+		{"inverse is not a cluster", "BTCUSDT", []string{"SOLUSDT"}, []string{"BTCUSDT"}},
+		{"uncorrelated", "BTCUSDT", []string{"XRPUSDT"}, []string{"BTCUSDT"}},
+		{"mixed holdings", "ETHUSDT", []string{"XRPUSDT", "BTCUSDT", "SOLUSDT"}, []string{"ETHUSDT", "BTCUSDT"}},
+		{"no history", "ADAUSDT", []string{"BTCUSDT"}, []string{"ADAUSDT"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			held := map[string]float64{tt.symbol: 100}
+			for _, sym := range tt.held {
+				held[sym] = 100
+			}
+			if got := pr.clusterOf(tt.symbol, held); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusterOf(%s) = %v, want %v", tt.symbol, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckEntry(t *testing.T) {
+	limits := &PortfolioLimits{
+		MaxGrossExposure:   1.0,
+		MaxNetExposure:     0.8,
+		MaxAssetExposure:   0.5,
+		MaxSectorExposure:  0.6,
+		MaxClusterExposure: 0.6,
+	}
+	pr := newTestPortfolio(limits)
+
+	tests := []struct {
+		name     string
+		holdings []Holding
+		equity   float64
+		symbol   string
+		notional float64
+		wantErr  string
+	}{
+		{"empty portfolio", nil, 1000, "BTCUSDT", 300, ""},
+		{"no equity", nil, 0, "BTCUSDT", 300, "equity is not positive"},
+		{"gross limit", []Holding{{"BTCUSDT", 400}, {"XRPUSDT", -400}}, 1000, "LINKUSDT", 300, "gross exposure"},
+		{"net limit", []Holding{{"BTCUSDT", 450}, {"XRPUSDT", 300}}, 1000, "LINKUSDT", 100, "net exposure"},
+		{"asset limit", []Holding{{"BTCUSDT", 400}}, 1000, "BTCUSDT", 200, "per-asset limit"},
+		{"short adds to asset exposure", []Holding{{"BTCUSDT", -400}}, 1000, "BTCUSDT", -200, "per-asset limit"},
+		{"sector limit", []Holding{{"SOLUSDT", 400}}, 1000, "ADAUSDT", 250, "sector smart_contract"},
+		{"correlated cluster", []Holding{{"BTCUSDT", 350}}, 1000, "ETHUSDT", 300, "correlated [ETHUSDT BTCUSDT]"},
+		{"uncorrelated within limits", []Holding{{"XRPUSDT", 350}}, 1000, "BTCUSDT", 300, ""},
+		{"inverse pair within limits", []Holding{{"SOLUSDT", -350}}, 1000, "BTCUSDT", 300, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pr.CheckEntry(tt.holdings, tt.equity, tt.symbol, tt.notional)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("CheckEntry() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("CheckEntry() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSector(t *testing.T) {
+	pr := NewPortfolioRisk(&PortfolioLimits{Sectors: map[string]string{"BTCUSDT": "custom", "PEPE": "frog"}})
+
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{"BTCUSDT", "custom"},
+		{"PEPEUSDT", "frog"},
+		{"ETHBTC", "smart_contract"},
+		{"DOGEUSDC", "meme"},
+		{"UNKNOWNUSDT", "other"},
+	}
+	for _, tt := range tests {
+		if got := pr.Sector(tt.symbol); got != tt.want {
+			t.Errorf("Sector(%s) = %q, want %q", tt.symbol, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// RuinInput describes the trading statistics and sizing to project.
+type RuinInput struct {
+	WinRate        float64   `json:"winRate"`        // Fraction of winning trades (0.55 = 55%)
+	PayoffRatio    float64   `json:"payoffRatio"`    // Average win divided by average loss
+	RiskPerTrade   float64   `json:"riskPerTrade"`   // Equity fraction lost on an average losing trade
+	Trades         int       `json:"trades"`         // Projection horizon in trades (default 100)
+	RuinLevel      float64   `json:"ruinLevel"`      // Equity loss counted as ruin (default 0.5 = 50%)
+	DrawdownLevels []float64 `json:"drawdownLevels"` // Drawdowns to project (default 10%, 20%, 30%)
+	RMultiples     []float64 `json:"rMultiples"`     // Optional trade results in units of the average loss, bootstrapped by Monte Carlo
+	SampleTrades   int       `json:"sampleTrades"`   // Closed trades the statistics come from
+	Simulations    int       `json:"simulations"`    // Monte Carlo paths (default 10000)
+	Seed           int64     `json:"seed"`
+}
+
+// RuinTolerance is the acceptable risk the current settings are checked
+// against. Zero values disable the corresponding check.
+type RuinTolerance struct {
+	MaxRiskPerTrade        float64 `json:"maxRiskPerTrade"`        // Max equity fraction risked per trade
+	MaxRuinProbability     float64 `json:"maxRuinProbability"`     // Max probability of ruin over the horizon
+	DrawdownLevel          float64 `json:"drawdownLevel"`          // Drawdown checked against MaxDrawdownProbability
+	MaxDrawdownProbability float64 `json:"maxDrawdownProbability"` // Max probability of reaching DrawdownLevel
+}
+
+// DefaultRuinTolerance returns a conservative tolerance.
+func DefaultRuinTolerance() RuinTolerance {
+	return RuinTolerance{
+		MaxRiskPerTrade:        0.02,
+		MaxRuinProbability:     0.01,
+		DrawdownLevel:          0.2,
+		MaxDrawdownProbability: 0.25,
+	}
+}
+
+// DrawdownProbability is the projected probability of a drawdown level.
+type DrawdownProbability struct {
+	Drawdown   float64 `json:"drawdown"`   // Fraction of equity
+	Analytic   float64 `json:"analytic"`   // Probability of losing this fraction of current equity
+	MonteCarlo float64 `json:"monteCarlo"` // Probability of a peak-to-trough drawdown of this size
+}
+
+// RuinReport projects drawdown and ruin probabilities over the next trades.
+type RuinReport struct {
+	Input               RuinInput             `json:"input"`
+	Tolerance           RuinTolerance         `json:"tolerance"`
+	Expectancy          float64               `json:"expectancy"` // Expected result per trade in R
+	KellyFraction       float64               `json:"kellyFraction"`
+	GrowthPerTrade      float64               `json:"growthPerTrade"` // Expected log growth of equity per trade
+	RuinAnalytic        float64               `json:"ruinAnalytic"`   // Probability of ruin within the horizon
+	RuinEventually      float64               `json:"ruinEventually"` // Probability of ruin over an unlimited horizon
+	RuinMonteCarlo      float64               `json:"ruinMonteCarlo"`
+	Drawdowns           []DrawdownProbability `json:"drawdowns"`
+	MedianFinalEquity   float64               `json:"medianFinalEquity"` // Final equity as a multiple of current equity
+	P5FinalEquity       float64               `json:"p5FinalEquity"`
+	MedianMaxDrawdown   float64               `json:"medianMaxDrawdown"`
+	P95MaxDrawdown      float64               `json:"p95MaxDrawdown"`
+	MaxSafeRiskPerTrade float64               `json:"maxSafeRiskPerTrade"` // Largest risk per trade within the ruin tolerance
+	ExceedsTolerance    bool                  `json:"exceedsTolerance"`
+	Warnings            []string              `json:"warnings"`
+}
+
+// ProjectRuin estimates the probability of reaching drawdown and ruin levels
+// over the next Trades trades with fixed fractional sizing. The analytic
+// estimates treat log equity as a Brownian motion with the per-trade drift
+// and variance of the win/loss distribution; Monte Carlo replays either the
+// win/loss distribution or, when given, the bootstrapped R-multiples.
+func ProjectRuin(input RuinInput, tolerance RuinTolerance) RuinReport {
+	if input.Trades <= 0 {
+		input.Trades = 100
+	}
+	if input.RuinLevel <= 0 || input.RuinLevel >= 1 {
+		input.RuinLevel = 0.5
+	}
+	if len(input.DrawdownLevels) == 0 {
+		input.DrawdownLevels = []float64{0.1, 0.2, 0.3}
+	}
+	if input.Simulations <= 0 {
+		input.Simulations = 10000
+	}
+
+	report := RuinReport{
+		Input:     input,
+		Tolerance: tolerance,
+		Drawdowns: make([]DrawdownProbability, 0, len(input.DrawdownLevels)),
+		Warnings:  make([]string, 0),
+	}
+
+	p, b, f := input.WinRate, input.PayoffRatio, input.RiskPerTrade
+	if p < 0 || p > 1 || b <= 0 || f <= 0 || f >= 1 {
+		report.Warnings = append(report.Warnings, "win rate, payoff ratio and risk per trade are required to project ruin")
+		return report
+	}
+
+	report.Expectancy = p*b - (1 - p)
+	report.KellyFraction = p - (1-p)/b
+	mu, sigma2 := logMoments(p, b, f)
+	report.GrowthPerTrade = mu
+
+	report.RuinAnalytic = firstPassage(mu, sigma2, -math.Log(1-input.RuinLevel), input.Trades)
+	report.RuinEventually = firstPassage(mu, sigma2, -math.Log(1-input.RuinLevel), 0)
+
+	sim := simulateRuin(input)
+	report.RuinMonteCarlo = sim.ruin
+	report.MedianFinalEquity = percentile(sim.finalEquity, 0.5)
+	report.P5FinalEquity = percentile(sim.finalEquity, 0.05)
+	report.MedianMaxDrawdown = percentile(sim.maxDrawdown, 0.5)
+	report.P95MaxDrawdown = percentile(sim.maxDrawdown, 0.95)
+
+	for _, level := range input.DrawdownLevels {
+		if level <= 0 || level >= 1 {
+			continue
+		}
+		report.Drawdowns = append(report.Drawdowns, DrawdownProbability{
+			Drawdown:   level,
+			Analytic:   firstPassage(mu, sigma2, -math.Log(1-level), input.Trades),
+			MonteCarlo: fractionAtLeast(sim.maxDrawdown, level),
+		})
+	}
+
+	report.MaxSafeRiskPerTrade = maxSafeRisk(p, b, input.RuinLevel, input.Trades, tolerance.MaxRuinProbability)
+	report.checkTolerance(input)
+	return report
+}
+
+// checkTolerance adds warnings for settings outside the tolerance.
+func (r *RuinReport) checkTolerance(input RuinInput) {
+	t := r.Tolerance
+	if input.SampleTrades > 0 && input.SampleTrades < 30 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("statistics from only %d trades, projection is unreliable", input.SampleTrades))
+	}
+	if r.Expectancy <= 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("negative expectancy (%.2fR per trade): ruin is certain in the long run", r.Expectancy))
+		r.ExceedsTolerance = true
+	}
+	if t.MaxRiskPerTrade > 0 && input.RiskPerTrade > t.MaxRiskPerTrade {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("risk per trade %.1f%% exceeds tolerance %.1f%%",
+			input.RiskPerTrade*100, t.MaxRiskPerTrade*100))
+		r.ExceedsTolerance = true
+	}
+	if r.KellyFraction > 0 && input.RiskPerTrade > r.KellyFraction {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("risk per trade %.1f%% exceeds the full Kelly fraction %.1f%%",
+			input.RiskPerTrade*100, r.KellyFraction*100))
+	}
+	ruin := math.Max(r.RuinAnalytic, r.RuinMonteCarlo)
+	if t.MaxRuinProbability > 0 && ruin > t.MaxRuinProbability {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("probability of losing %.0f%% within %d trades is %.1f%%, tolerance %.1f%%",
+			input.RuinLevel*100, input.Trades, ruin*100, t.MaxRuinProbability*100))
+		r.ExceedsTolerance = true
+	}
+	if t.DrawdownLevel > 0 && t.MaxDrawdownProbability > 0 {
+		mu, sigma2 := logMoments(input.WinRate, input.PayoffRatio, input.RiskPerTrade)
+		dd := firstPassage(mu, sigma2, -math.Log(1-t.DrawdownLevel), input.Trades)
+		for _, d := range r.Drawdowns {
+			if d.Drawdown == t.DrawdownLevel {
+				dd = math.Max(dd, d.MonteCarlo)
+			}
+		}
+		if dd > t.MaxDrawdownProbability {
+			r.Warnings = append(r.Warnings, fmt.Sprintf("probability of a %.0f%% drawdown within %d trades is %.1f%%, tolerance %.1f%%",
+				t.DrawdownLevel*100, input.Trades, dd*100, t.MaxDrawdownProbability*100))
+			r.ExceedsTolerance = true
+		}
+	}
+}
+
+// logMoments returns the mean and variance of the per-trade log return of
+// equity when risking f per trade with win rate p and payoff ratio b.
+func logMoments(p, b, f float64) (float64, float64) {
+	up := math.Log(1 + f*b)
+	down := math.Log(1 - f)
+	mu := p*up + (1-p)*down
+	sigma2 := p*up*up + (1-p)*down*down - mu*mu
+	return mu, sigma2
+}
+
+// firstPassage returns the probability that a Brownian motion with drift mu
+// and variance sigma2 per step falls by level within n steps (n <= 0 means
+// an unlimited horizon).
+func firstPassage(mu, sigma2, level float64, n int) float64 {
+	if level <= 0 {
+		return 1
+	}
+	if sigma2 <= 0 {
+		if mu < 0 && (n <= 0 || -mu*float64(n) >= level) {
+			return 1
+		}
+		return 0
+	}
+	if n <= 0 {
+		if mu <= 0 {
+			return 1
+		}
+		return math.Exp(-2 * mu * level / sigma2)
+	}
+
+	t := float64(n)
+	sd := math.Sqrt(sigma2 * t)
+	p := normalCDF((-level-mu*t)/sd) + math.Exp(-2*mu*level/sigma2)*normalCDF((-level+mu*t)/sd)
+	return math.Min(math.Max(p, 0), 1)
+}
+
+func normalCDF(x float64) float64 {
+	return 0.5 * math.Erfc(-x/math.Sqrt2)
+}
+
+type ruinSimulation struct {
+	ruin        float64
+	finalEquity []float64
+	maxDrawdown []float64
+}
+
+// simulateRuin replays input.Trades trades on every path. Equity stops at
+// the ruin level.
+func simulateRuin(input RuinInput) ruinSimulation {
+	rng := rand.New(rand.NewSource(input.Seed))
+	sim := ruinSimulation{
+		finalEquity: make([]float64, input.Simulations),
+		maxDrawdown: make([]float64, input.Simulations),
+	}
+	floor := 1 - input.RuinLevel
+	ruined := 0
+
+	for i := 0; i < input.Simulations; i++ {
+		equity, peak, maxDD := 1.0, 1.0, 0.0
+		for n := 0; n < input.Trades; n++ {
+			var r float64
+			if len(input.RMultiples) > 0 {
+				r = input.RMultiples[rng.Intn(len(input.RMultiples))]
+			} else if rng.Float64() < input.WinRate {
+				r = input.PayoffRatio
+			} else {
+				r = -1
+			}
+			equity *= math.Max(1+input.RiskPerTrade*r, 0)
+			if equity > peak {
+				peak = equity
+			}
+			if dd := 1 - equity/peak; dd > maxDD {
+				maxDD = dd
+			}
+			if equity <= floor {
+				ruined++
+				break
+			}
+		}
+		sim.finalEquity[i] = equity
+		sim.maxDrawdown[i] = maxDD
+	}
+
+	sim.ruin = float64(ruined) / float64(input.Simulations)
+	sort.Float64s(sim.finalEquity)
+	sort.Float64s(sim.maxDrawdown)
+	return sim
+}
+
+// maxSafeRisk finds the largest risk per trade whose analytic ruin
+// probability stays within maxRuin, by bisection.
+func maxSafeRisk(p, b, ruinLevel float64, n int, maxRuin float64) float64 {
+	if maxRuin <= 0 || p*b-(1-p) <= 0 {
+		return 0
+	}
+	level := -math.Log(1 - ruinLevel)
+	lo, hi := 0.0, 0.99
+	for i := 0; i < 60; i++ {
+		mid := (lo + hi) / 2
+		mu, sigma2 := logMoments(p, b, mid)
+		if firstPassage(mu, sigma2, level, n) <= maxRuin {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return math.Floor(lo*10000) / 10000
+}
+
+// percentile returns the q-quantile of sorted values.
+func percentile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(math.Round(q * float64(len(sorted)-1)))
+	return sorted[idx]
+}
+
+func fractionAtLeast(sorted []float64, level float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := sort.SearchFloat64s(sorted, level)
+	return float64(len(sorted)-i) / float64(len(sorted))
+}
@@ -0,0 +1,220 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+func TestProjectRuin(t *testing.T) {
+	tests := []struct {
+		name           string
+		input          RuinInput
+		wantExpectancy float64
+		wantKelly      float64
+		wantExceeds    bool
+		wantRuinAbove  float64 // RuinAnalytic and RuinMonteCarlo are at least this
+		wantRuinBelow  float64 // ... and at most this
+	}{
+		{
+			name:           "small risk with an edge",
+			input:          RuinInput{WinRate: 0.55, PayoffRatio: 1.5, RiskPerTrade: 0.01},
+			wantExpectancy: 0.375,
+			wantKelly:      0.25,
+			wantRuinBelow:  0.001,
+		},
+		{
+			name:           "risk above tolerance",
+			input:          RuinInput{WinRate: 0.55, PayoffRatio: 1.5, RiskPerTrade: 0.05},
+			wantExpectancy: 0.375,
+			wantKelly:      0.25,
+			wantExceeds:    true,
+			wantRuinBelow:  0.2,
+		},
+		{
+			name:           "oversized risk",
+			input:          RuinInput{WinRate: 0.5, PayoffRatio: 2, RiskPerTrade: 0.3},
+			wantExpectancy: 0.5,
+			wantKelly:      0.25,
+			wantExceeds:    true,
+			wantRuinAbove:  0.2,
+			wantRuinBelow:  1,
+		},
+		{
+			name:           "negative expectancy",
+			input:          RuinInput{WinRate: 0.3, PayoffRatio: 1, RiskPerTrade: 0.02},
+			wantExpectancy: -0.4,
+			wantKelly:      -0.4,
+			wantExceeds:    true,
+			wantRuinAbove:  0.1,
+			wantRuinBelow:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.input.Seed = 1
+			report := ProjectRuin(tt.input, DefaultRuinTolerance())
+
+			if math.Abs(report.Expectancy-tt.wantExpectancy) > 1e-9 || math.Abs(report.KellyFraction-tt.wantKelly) > 1e-9 {
+				t.Errorf("expectancy, kelly = %.4f, %.4f; want %.4f, %.4f",
+					report.Expectancy, report.KellyFraction, tt.wantExpectancy, tt.wantKelly)
+			}
+			if report.ExceedsTolerance != tt.wantExceeds {
+				t.Errorf("ExceedsTolerance = %v, want %v (warnings %v)", report.ExceedsTolerance, tt.wantExceeds, report.Warnings)
+			}
+			for _, ruin := range []float64{report.RuinAnalytic, report.RuinMonteCarlo} {
+				if ruin < tt.wantRuinAbove || ruin > tt.wantRuinBelow {
+					t.Errorf("ruin analytic, monte carlo = %.4f, %.4f; want within [%.4f, %.4f]",
+						report.RuinAnalytic, report.RuinMonteCarlo, tt.wantRuinAbove, tt.wantRuinBelow)
+					break
+				}
+			}
+			if report.RuinEventually < report.RuinAnalytic {
+				t.Errorf("RuinEventually %.4f < RuinAnalytic %.4f", report.RuinEventually, report.RuinAnalytic)
+			}
+			if tt.wantExpectancy < 0 && report.RuinEventually != 1 {
+				t.Errorf("RuinEventually = %.4f, want 1 for a negative edge", report.RuinEventually)
+			}
+
+			if len(report.Drawdowns) != 3 {
+				t.Fatalf("drawdowns = %v, want the 3 default levels", report.Drawdowns)
+			}
+			for i := 1; i < len(report.Drawdowns); i++ {
+				prev, cur := report.Drawdowns[i-1], report.Drawdowns[i]
+				if cur.Analytic > prev.Analytic || cur.MonteCarlo > prev.MonteCarlo {
+					t.Errorf("drawdown probabilities grow with the drawdown: %v", report.Drawdowns)
+				}
+			}
+		})
+	}
+}
+
+func TestProjectRuinDefaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        RuinInput
+		wantWarnings []string
+	}{
+		{
+			name:         "missing statistics",
+			input:        RuinInput{WinRate: 0.5},
+			wantWarnings: []string{"win rate, payoff ratio and risk per trade are required to project ruin"},
+		},
+		{
+			name:         "risk per trade of 100%",
+			input:        RuinInput{WinRate: 0.5, PayoffRatio: 2, RiskPerTrade: 1},
+			wantWarnings: []string{"win rate, payoff ratio and risk per trade are required to project ruin"},
+		},
+		{
+			name:         "few sample trades",
+			input:        RuinInput{WinRate: 0.55, PayoffRatio: 1.5, RiskPerTrade: 0.01, SampleTrades: 10},
+			wantWarnings: []string{"statistics from only 10 trades, projection is unreliable"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ProjectRuin(tt.input, DefaultRuinTolerance())
+			if report.Input.Trades != 100 || report.Input.RuinLevel != 0.5 || report.Input.Simulations != 10000 {
+				t.Errorf("defaults = %d trades, %.2f ruin level, %d simulations",
+					report.Input.Trades, report.Input.RuinLevel, report.Input.Simulations)
+			}
+			if len(report.Warnings) != len(tt.wantWarnings) {
+				t.Fatalf("warnings = %v, want %v", report.Warnings, tt.wantWarnings)
+			}
+			for i := range tt.wantWarnings {
+				if report.Warnings[i] != tt.wantWarnings[i] {
+					t.Errorf("warning %d = %q, want %q", i, report.Warnings[i], tt.wantWarnings[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRuinMonteCarloRMultiples(t *testing.T) {
+	tests := []struct {
+		name       string
+		rMultiples []float64
+		wantRuin   float64
+		wantFinal  float64
+	}{
+		// Все сделки выигрышные: просадок нет, equity растет на 1% за сделку
+		{"only wins", []float64{1}, 0, math.Pow(1.01, 100)},
+		// Все сделки убыточные: разорение после ln(0.5)/ln(0.99) ≈ 69 сделок
+		{"only losses", []float64{-1}, 1, math.Pow(0.99, 69)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ProjectRuin(RuinInput{
+				WinRate:      0.5,
+				PayoffRatio:  1,
+				RiskPerTrade: 0.01,
+				RMultiples:   tt.rMultiples,
+				Simulations:  100,
+			}, RuinTolerance{})
+			if report.RuinMonteCarlo != tt.wantRuin {
+				t.Errorf("RuinMonteCarlo = %.4f, want %.4f", report.RuinMonteCarlo, tt.wantRuin)
+			}
+			if math.Abs(report.MedianFinalEquity-tt.wantFinal) > 1e-9 {
+				t.Errorf("MedianFinalEquity = %.6f, want %.6f", report.MedianFinalEquity, tt.wantFinal)
+			}
+		})
+	}
+}
+
+func TestFirstPassage(t *testing.T) {
+	tests := []struct {
+		name   string
+		mu     float64
+		sigma2 float64
+		level  float64
+		n      int
+		want   float64
+	}{
+		{"level already reached", 0.01, 0.001, 0, 100, 1},
+		{"no variance, falling", -0.01, 0, 0.5, 100, 1},
+		{"no variance, falling too slowly", -0.001, 0, 0.5, 100, 0},
+		{"no variance, rising", 0.01, 0, 0.5, 0, 0},
+		{"unlimited horizon without drift", 0, 0.001, 0.5, 0, 1},
+		{"unlimited horizon with drift", 0.001, 0.001, 0.5, 0, math.Exp(-1)},
+		{"driftless within horizon", 0, 0.0025, 0.5, 100, 2 * normalCDF(-1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := firstPassage(tt.mu, tt.sigma2, tt.level, tt.n); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("firstPassage() = %.6f, want %.6f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxSafeRisk(t *testing.T) {
+	tests := []struct {
+		name    string
+		p, b    float64
+		maxRuin float64
+		wantMin float64
+	}{
+		{"edge", 0.55, 1.5, 0.01, 0.01},
+		{"no tolerance", 0.55, 1.5, 0, 0},
+		{"no edge", 0.4, 1, 0.01, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := maxSafeRisk(tt.p, tt.b, 0.5, 100, tt.maxRuin)
+			if got < tt.wantMin || (tt.wantMin == 0 && got != 0) {
+				t.Fatalf("maxSafeRisk() = %.4f, want at least %.4f", got, tt.wantMin)
+			}
+			if got == 0 {
+				return
+			}
+			level := -math.Log(0.5)
+			mu, sigma2 := logMoments(tt.p, tt.b, got)
+			if ruin := firstPassage(mu, sigma2, level, 100); ruin > tt.maxRuin {
+				t.Errorf("ruin at %.4f is %.4f, above %.4f", got, ruin, tt.maxRuin)
+			}
+			mu, sigma2 = logMoments(tt.p, tt.b, got+0.001)
+			if ruin := firstPassage(mu, sigma2, level, 100); ruin <= tt.maxRuin {
+				t.Errorf("ruin at %.4f is %.4f, %.4f is not the largest safe risk", got+0.001, ruin, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,474 @@
+package risk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Rule names accepted by NewRuleChain.
+const (
+	RuleCircuitBreaker = "circuit_breaker" // Reject entries while the kill switch is tripped
+	RuleSession        = "session"         // Reject entries outside the trading windows
+	RuleMinConfidence  = "min_confidence"  // Reject signal entries below the confidence threshold
+	RuleDailyTrades    = "daily_trades"    // Reject signal entries over the daily trade limit
+	RuleCooldown       = "cooldown"        // Reject signal entries during the cooldown after a trade
+	RuleMaxPositions   = "max_positions"   // Reject entries over the open position limits
+	RulePositionSize   = "position_size"   // Shrink entries to the max position value
+	RulePortfolio      = "portfolio"       // Reject entries breaching portfolio exposure limits
+	RuleVaR            = "var"             // Reject entries pushing portfolio VaR over the limit
+)
+
+// Rule actions.
+const (
+	ActionAllow  = "allow"
+	ActionReject = "reject"
+	ActionResize = "resize"
+)
+
+// DefaultRiskRules returns the rule order used when an account configures none.
+func DefaultRiskRules() []string {
+	return []string{
+		RuleCircuitBreaker,
+		RuleSession,
+		RuleMinConfidence,
+		RuleDailyTrades,
+		RuleCooldown,
+		RuleMaxPositions,
+		RulePositionSize,
+		RulePortfolio,
+		RuleVaR,
+	}
+}
+
+// OrderIntent is an order about to be placed.
+type OrderIntent struct {
+	Account    string  `json:"account"`
+	Strategy   string  `json:"strategy"`
+	Symbol     string  `json:"symbol"`
+	Side       string  `json:"side"`
+	Price      float64 `json:"price"`
+	Quantity   float64 `json:"quantity"`
+	Confidence float64 `json:"confidence"` // Signal confidence, 0 for orders without a signal
+	FromSignal bool    `json:"fromSignal"` // The order was triggered by a trading signal
+	Reduce     bool    `json:"reduce"`     // The order closes or reduces an existing position
+}
+
+// Notional returns the signed order value: positive for long entries,
+// negative for short ones.
+func (o OrderIntent) Notional() float64 {
+	notional := o.Price * o.Quantity
+	if o.Side == "SHORT" || o.Side == "SELL" {
+		return -notional
+	}
+	return notional
+}
+
+// RuleContext is the account state rules are evaluated against.
+type RuleContext struct {
+	Now               time.Time
+	Equity            float64
+	Available         float64
+	Holdings          []Holding
+	PortfolioHoldings []Holding // Holdings of every account sharing the portfolio limits
+	PortfolioEquity   float64   // Combined equity of those accounts
+	OpenPositions     int
+	StrategyPositions map[string]int // Open positions per strategy tag
+	TodayTrades       int
+	LastTradeTime     time.Time
+	SessionOpen       bool
+	SessionReason     string // Why the session is closed
+}
+
+// RuleResult is the verdict of a single rule. Quantity is the approved
+// quantity after the rule.
+type RuleResult struct {
+	Rule     string  `json:"rule"`
+	Action   string  `json:"action"`
+	Reason   string  `json:"reason"`
+	Quantity float64 `json:"quantity"`
+}
+
+// RiskRule is one step of the pre-trade check. Rules return ActionAllow,
+// ActionReject or ActionResize with a smaller positive quantity.
+type RiskRule interface {
+	Name() string
+	Check(intent OrderIntent, ctx RuleContext) RuleResult
+}
+
+// RiskDecision records the outcome of running an order through the chain.
+type RiskDecision struct {
+	Time              int64        `json:"time"` // Unix milliseconds
+	Account           string       `json:"account"`
+	Strategy          string       `json:"strategy"`
+	Symbol            string       `json:"symbol"`
+	Side              string       `json:"side"`
+	Price             float64      `json:"price"`
+	RequestedQuantity float64      `json:"requestedQuantity"`
+	Quantity          float64      `json:"quantity"` // Approved quantity, 0 when rejected
+	Reduce            bool         `json:"reduce"`
+	Action            string       `json:"action"`
+	Rule              string       `json:"rule"` // Rule that rejected or last resized the order
+	Reason            string       `json:"reason"`
+	Steps             []RuleResult `json:"steps"`
+}
+
+// RuleParams holds the limits and shared state rules are built from.
+type RuleParams struct {
+	Breaker           *CircuitBreaker
+	Portfolio         *PortfolioRisk
+	VaRLimit          *VaRLimit
+	MinConfidence     float64
+	MaxDailyTrades    int // 0 = no limit
+	Cooldown          time.Duration
+	MaxOpenPositions  int            // 0 = no limit
+	StrategyPositions map[string]int // Per-strategy open position limits
+	MaxPositionSize   float64        // Max position value as an equity fraction (0 = no cap)
+}
+
+// RuleChain runs an order through an ordered list of rules. The first
+// rejection stops the chain; a resize passes the smaller quantity on to the
+// following rules.
+type RuleChain struct {
+	rules   []RiskRule
+	journal *DecisionJournal
+}
+
+// NewRuleChain builds the named rules in order. An empty list selects
+// DefaultRiskRules. journal may be nil.
+func NewRuleChain(names []string, params RuleParams, journal *DecisionJournal) (*RuleChain, error) {
+	if len(names) == 0 {
+		names = DefaultRiskRules()
+	}
+
+	rules := make([]RiskRule, 0, len(names))
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate risk rule: %s", name)
+		}
+		seen[name] = true
+
+		rule, err := newRiskRule(name, params)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return &RuleChain{rules: rules, journal: journal}, nil
+}
+
+func newRiskRule(name string, params RuleParams) (RiskRule, error) {
+	switch name {
+	case RuleCircuitBreaker:
+		if params.Breaker == nil {
+			return nil, fmt.Errorf("%s rule requires a circuit breaker", name)
+		}
+		return &CircuitBreakerRule{Breaker: params.Breaker}, nil
+	case RuleSession:
+		return &SessionRule{}, nil
+	case RuleMinConfidence:
+		return &MinConfidenceRule{Min: params.MinConfidence}, nil
+	case RuleDailyTrades:
+		return &DailyTradesRule{Max: params.MaxDailyTrades}, nil
+	case RuleCooldown:
+		return &CooldownRule{Cooldown: params.Cooldown}, nil
+	case RuleMaxPositions:
+		return &MaxPositionsRule{Max: params.MaxOpenPositions, PerStrategy: params.StrategyPositions}, nil
+	case RulePositionSize:
+		return &PositionSizeRule{MaxPositionSize: params.MaxPositionSize}, nil
+	case RulePortfolio:
+		if params.Portfolio == nil {
+			return nil, fmt.Errorf("%s rule requires portfolio limits", name)
+		}
+		return &PortfolioRule{Portfolio: params.Portfolio}, nil
+	case RuleVaR:
+		if params.Portfolio == nil {
+			return nil, fmt.Errorf("%s rule requires portfolio price history", name)
+		}
+		return &VaRRule{Portfolio: params.Portfolio, Limit: params.VaRLimit}, nil
+	default:
+		return nil, fmt.Errorf("unknown risk rule: %s", name)
+	}
+}
+
+// Names returns the rule names in evaluation order.
+func (c *RuleChain) Names() []string {
+	names := make([]string, len(c.rules))
+	for i, rule := range c.rules {
+		names[i] = rule.Name()
+	}
+	return names
+}
+
+// Evaluate runs the intent through every rule and records the decision in
+// the journal.
+func (c *RuleChain) Evaluate(intent OrderIntent, ctx RuleContext) RiskDecision {
+	decision := RiskDecision{
+		Time:              ctx.Now.UnixMilli(),
+		Account:           intent.Account,
+		Strategy:          intent.Strategy,
+		Symbol:            intent.Symbol,
+		Side:              intent.Side,
+		Price:             intent.Price,
+		RequestedQuantity: intent.Quantity,
+		Reduce:            intent.Reduce,
+		Action:            ActionAllow,
+		Steps:             make([]RuleResult, 0, len(c.rules)),
+	}
+
+	for _, rule := range c.rules {
+		result := rule.Check(intent, ctx)
+		result.Rule = rule.Name()
+		if result.Action == ActionResize && result.Quantity <= 0 {
+			result.Action = ActionReject
+		}
+
+		switch result.Action {
+		case ActionReject:
+			result.Quantity = 0
+		case ActionResize:
+			intent.Quantity = result.Quantity
+			decision.Action = ActionResize
+			decision.Rule = result.Rule
+			decision.Reason = result.Reason
+		default:
+			result.Quantity = intent.Quantity
+		}
+		decision.Steps = append(decision.Steps, result)
+
+		if result.Action == ActionReject {
+			decision.Action = ActionReject
+			decision.Rule = result.Rule
+			decision.Reason = result.Reason
+			intent.Quantity = 0
+			break
+		}
+	}
+	decision.Quantity = intent.Quantity
+
+	switch decision.Action {
+	case ActionReject:
+		log.Warnf("Risk check [%s] %s %s %s rejected by %s: %s",
+			decision.Account, decision.Strategy, decision.Side, decision.Symbol, decision.Rule, decision.Reason)
+	case ActionResize:
+		log.Infof("Risk check [%s] %s %s %s resized %.8f -> %.8f by %s: %s",
+			decision.Account, decision.Strategy, decision.Side, decision.Symbol,
+			decision.RequestedQuantity, decision.Quantity, decision.Rule, decision.Reason)
+	default:
+		log.Debugf("Risk check [%s] %s %s %s allowed (%.8f)",
+			decision.Account, decision.Strategy, decision.Side, decision.Symbol, decision.Quantity)
+	}
+
+	if c.journal != nil {
+		c.journal.Record(decision)
+	}
+	return decision
+}
+
+func allow(reason string) RuleResult {
+	return RuleResult{Action: ActionAllow, Reason: reason}
+}
+
+func reject(format string, args ...interface{}) RuleResult {
+	return RuleResult{Action: ActionReject, Reason: fmt.Sprintf(format, args...)}
+}
+
+// Exits are never blocked: every built-in rule lets reducing orders through.
+const reduceReason = "reduces exposure"
+
+// CircuitBreakerRule rejects entries while the breaker is tripped.
+type CircuitBreakerRule struct {
+	Breaker *CircuitBreaker
+}
+
+func (r *CircuitBreakerRule) Name() string { return RuleCircuitBreaker }
+
+func (r *CircuitBreakerRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce {
+		return allow(reduceReason)
+	}
+	if tripped, reason := r.Breaker.IsTripped(); tripped {
+		return reject("%s", reason)
+	}
+	return allow("")
+}
+
+// SessionRule rejects entries outside the account's trading windows.
+type SessionRule struct{}
+
+func (r *SessionRule) Name() string { return RuleSession }
+
+func (r *SessionRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce {
+		return allow(reduceReason)
+	}
+	if !ctx.SessionOpen {
+		return reject("%s", ctx.SessionReason)
+	}
+	return allow("")
+}
+
+// MinConfidenceRule rejects signal-driven entries below the threshold. A
+// zero threshold trades at any confidence.
+type MinConfidenceRule struct {
+	Min float64
+}
+
+func (r *MinConfidenceRule) Name() string { return RuleMinConfidence }
+
+func (r *MinConfidenceRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce || !intent.FromSignal {
+		return allow("not a signal entry")
+	}
+	if r.Min > 0 && intent.Confidence < r.Min {
+		return reject("signal confidence %.2f below minimum %.2f", intent.Confidence, r.Min)
+	}
+	return allow("")
+}
+
+// DailyTradesRule limits signal-driven entries per trading day.
+type DailyTradesRule struct {
+	Max int
+}
+
+func (r *DailyTradesRule) Name() string { return RuleDailyTrades }
+
+func (r *DailyTradesRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce || !intent.FromSignal {
+		return allow("not a signal entry")
+	}
+	if r.Max > 0 && ctx.TodayTrades >= r.Max {
+		return reject("%d trades today, limit %d", ctx.TodayTrades, r.Max)
+	}
+	return allow("")
+}
+
+// CooldownRule delays signal-driven entries after the last trade.
+type CooldownRule struct {
+	Cooldown time.Duration
+}
+
+func (r *CooldownRule) Name() string { return RuleCooldown }
+
+func (r *CooldownRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce || !intent.FromSignal {
+		return allow("not a signal entry")
+	}
+	if since := ctx.Now.Sub(ctx.LastTradeTime); since < r.Cooldown {
+		return reject("cooldown: %v since last trade, required %v", since.Round(time.Second), r.Cooldown)
+	}
+	return allow("")
+}
+
+// MaxPositionsRule limits the number of open positions of the account and,
+// optionally, of individual strategies.
+type MaxPositionsRule struct {
+	Max         int
+	PerStrategy map[string]int
+}
+
+func (r *MaxPositionsRule) Name() string { return RuleMaxPositions }
+
+func (r *MaxPositionsRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce {
+		return allow(reduceReason)
+	}
+	if r.Max > 0 && ctx.OpenPositions >= r.Max {
+		return reject("max positions reached (%d/%d)", ctx.OpenPositions, r.Max)
+	}
+	if limit := r.PerStrategy[intent.Strategy]; limit > 0 && ctx.StrategyPositions[intent.Strategy] >= limit {
+		return reject("max %s positions reached (%d/%d)", intent.Strategy, ctx.StrategyPositions[intent.Strategy], limit)
+	}
+	return allow("")
+}
+
+// PositionSizeRule shrinks entries whose value exceeds MaxPositionSize of
+// equity.
+type PositionSizeRule struct {
+	MaxPositionSize float64
+}
+
+func (r *PositionSizeRule) Name() string { return RulePositionSize }
+
+func (r *PositionSizeRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce || r.MaxPositionSize <= 0 || ctx.Equity <= 0 || intent.Price <= 0 {
+		return allow("")
+	}
+	maxValue := ctx.Equity * r.MaxPositionSize
+	if intent.Price*intent.Quantity <= maxValue {
+		return allow("")
+	}
+	quantity := capQuantity(maxValue/intent.Price, SizingRequest{EntryPrice: intent.Price}, 0)
+	return RuleResult{
+		Action:   ActionResize,
+		Reason:   fmt.Sprintf("position value %.2f over %.0f%% of equity (%.2f)", intent.Price*intent.Quantity, r.MaxPositionSize*100, maxValue),
+		Quantity: quantity,
+	}
+}
+
+// PortfolioRule rejects entries breaching gross, net, asset, sector or
+// correlated cluster exposure limits.
+type PortfolioRule struct {
+	Portfolio *PortfolioRisk
+}
+
+func (r *PortfolioRule) Name() string { return RulePortfolio }
+
+func (r *PortfolioRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce {
+		return allow(reduceReason)
+	}
+	if err := r.Portfolio.CheckEntry(ctx.PortfolioHoldings, ctx.PortfolioEquity, intent.Symbol, intent.Notional()); err != nil {
+		return reject("%v", err)
+	}
+	return allow("")
+}
+
+// VaRRule rejects entries that push portfolio VaR over the limit. Until
+// enough price history is sampled the rule allows the order.
+type VaRRule struct {
+	Portfolio *PortfolioRisk
+	Limit     *VaRLimit // nil disables the rule
+}
+
+func (r *VaRRule) Name() string { return RuleVaR }
+
+func (r *VaRRule) Check(intent OrderIntent, ctx RuleContext) RuleResult {
+	if intent.Reduce {
+		return allow(reduceReason)
+	}
+	if r.Limit == nil || r.Limit.MaxVaRPercent <= 0 || ctx.PortfolioEquity <= 0 {
+		return allow("no VaR limit")
+	}
+
+	confidence := r.Limit.Confidence
+	if confidence <= 0 || confidence >= 1 {
+		confidence = 0.99
+	}
+	minBars := r.Limit.MinBars
+	if minBars <= 0 {
+		minBars = 30
+	}
+
+	holdings := append(append([]Holding(nil), ctx.PortfolioHoldings...), Holding{Symbol: intent.Symbol, Notional: intent.Notional()})
+	symbols := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		symbols = append(symbols, h.Symbol)
+	}
+	series := r.Limit.Series
+	if series == nil {
+		series = r.Portfolio.Series
+	}
+	v, ok := PortfolioVaR(holdings, series(symbols), r.Limit.Method, confidence, r.Limit.HorizonBars, minBars)
+	if !ok {
+		return allow("not enough price history")
+	}
+	if pct := v / ctx.PortfolioEquity * 100; pct > r.Limit.MaxVaRPercent {
+		return reject("portfolio VaR %.2f%% at %.0f%% would exceed %.2f%%", pct, confidence*100, r.Limit.MaxVaRPercent)
+	}
+	return allow("")
+}
@@ -0,0 +1,291 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Position sizing methods accepted in SizingConfig.Method.
+const (
+	SizingFixedNotional   = "fixed_notional"
+	SizingFixedFractional = "fixed_fractional"
+	SizingVolatility      = "volatility"
+	SizingKelly           = "kelly"
+	SizingEqualRisk       = "equal_risk"
+)
+
+// SizingRequest describes the entry to be sized.
+type SizingRequest struct {
+	Symbol     string
+	Equity     float64 // Account equity used as the sizing base
+	Available  float64 // Free cash; the resulting cost never exceeds it (0 = no limit)
+	EntryPrice float64
+	StopLoss   float64 // 0 if the entry has no stop
+	ATR        float64 // Average true range at entry, 0 if unknown
+	Volatility float64 // Realized volatility of returns per bar (0.01 = 1%), 0 if unknown
+}
+
+// PositionSizer turns an entry into a quantity. A zero result means the
+// entry should be skipped.
+type PositionSizer interface {
+	Name() string
+	Size(req SizingRequest) float64
+}
+
+// TradeRecorder is implemented by sizers that adapt to realized results.
+// Engines feed every closed trade to such sizers.
+type TradeRecorder interface {
+	RecordTrade(pnlPercent float64)
+}
+
+// SizingConfig selects and parameterizes a position sizer.
+type SizingConfig struct {
+	Method          string  `json:"method"`          // One of the Sizing* constants
+	Notional        float64 `json:"notional"`        // fixed_notional: position value in quote currency
+	RiskPerTrade    float64 `json:"riskPerTrade"`    // fixed_fractional: equity fraction lost at the stop
+	TargetRisk      float64 `json:"targetRisk"`      // volatility: equity fraction moved by one ATR / one vol unit
+	KellyFraction   float64 `json:"kellyFraction"`   // kelly: fraction of the full Kelly bet (default 0.25)
+	KellyWindow     int     `json:"kellyWindow"`     // kelly: trades in the rolling window (default 50)
+	KellyMinTrades  int     `json:"kellyMinTrades"`  // kelly: trades required before Kelly is used (default 20)
+	TotalRisk       float64 `json:"totalRisk"`       // equal_risk: equity fraction at risk across all positions
+	MaxPositions    int     `json:"maxPositions"`    // equal_risk: positions sharing TotalRisk
+	MaxPositionSize float64 `json:"maxPositionSize"` // Cap on position value as an equity fraction (0 = no cap)
+}
+
+// NewPositionSizer builds the sizer described by config.
+func NewPositionSizer(config *SizingConfig) (PositionSizer, error) {
+	if config == nil {
+		return nil, fmt.Errorf("sizing config is nil")
+	}
+
+	switch config.Method {
+	case SizingFixedNotional:
+		if config.Notional <= 0 {
+			return nil, fmt.Errorf("fixed_notional sizer requires a positive notional")
+		}
+		return &FixedNotionalSizer{Notional: config.Notional, MaxPositionSize: config.MaxPositionSize}, nil
+	case SizingFixedFractional, "":
+		if config.RiskPerTrade <= 0 {
+			return nil, fmt.Errorf("fixed_fractional sizer requires a positive riskPerTrade")
+		}
+		return &FixedFractionalSizer{RiskPerTrade: config.RiskPerTrade, MaxPositionSize: config.MaxPositionSize}, nil
+	case SizingVolatility:
+		if config.TargetRisk <= 0 {
+			return nil, fmt.Errorf("volatility sizer requires a positive targetRisk")
+		}
+		return &VolatilitySizer{TargetRisk: config.TargetRisk, MaxPositionSize: config.MaxPositionSize}, nil
+	case SizingKelly:
+		return NewKellySizer(config.KellyFraction, config.KellyWindow, config.KellyMinTrades, config.MaxPositionSize), nil
+	case SizingEqualRisk:
+		if config.TotalRisk <= 0 || config.MaxPositions <= 0 {
+			return nil, fmt.Errorf("equal_risk sizer requires positive totalRisk and maxPositions")
+		}
+		return &EqualRiskSizer{TotalRisk: config.TotalRisk, MaxPositions: config.MaxPositions, MaxPositionSize: config.MaxPositionSize}, nil
+	default:
+		return nil, fmt.Errorf("unknown sizing method: %s", config.Method)
+	}
+}
+
+// FixedNotionalSizer buys a fixed position value.
+type FixedNotionalSizer struct {
+	Notional        float64
+	MaxPositionSize float64
+}
+
+func (s *FixedNotionalSizer) Name() string { return SizingFixedNotional }
+
+func (s *FixedNotionalSizer) Size(req SizingRequest) float64 {
+	if req.EntryPrice <= 0 {
+		return 0
+	}
+	return capQuantity(s.Notional/req.EntryPrice, req, s.MaxPositionSize)
+}
+
+// FixedFractionalSizer risks a fixed fraction of equity between entry and stop.
+type FixedFractionalSizer struct {
+	RiskPerTrade    float64
+	MaxPositionSize float64
+}
+
+func (s *FixedFractionalSizer) Name() string { return SizingFixedFractional }
+
+func (s *FixedFractionalSizer) Size(req SizingRequest) float64 {
+	priceRisk := math.Abs(req.EntryPrice - req.StopLoss)
+	if req.EntryPrice <= 0 || req.StopLoss <= 0 || priceRisk == 0 {
+		return 0
+	}
+	return capQuantity(req.Equity*s.RiskPerTrade/priceRisk, req, s.MaxPositionSize)
+}
+
+// VolatilitySizer targets a fixed equity move per unit of volatility: one ATR
+// when known, otherwise one realized-volatility move, otherwise the stop
+// distance as a proxy.
+type VolatilitySizer struct {
+	TargetRisk      float64
+	MaxPositionSize float64
+}
+
+func (s *VolatilitySizer) Name() string { return SizingVolatility }
+
+func (s *VolatilitySizer) Size(req SizingRequest) float64 {
+	if req.EntryPrice <= 0 {
+		return 0
+	}
+
+	unit := req.ATR
+	if unit <= 0 && req.Volatility > 0 {
+		unit = req.Volatility * req.EntryPrice
+	}
+	if unit <= 0 && req.StopLoss > 0 {
+		unit = math.Abs(req.EntryPrice - req.StopLoss)
+	}
+	if unit <= 0 {
+		return 0
+	}
+	return capQuantity(req.Equity*s.TargetRisk/unit, req, s.MaxPositionSize)
+}
+
+// KellySizer allocates a fraction of the Kelly-optimal bet estimated from a
+// rolling window of trade results. Until enough trades are recorded, and
+// whenever the estimated edge is not positive, it falls back to a small
+// fixed allocation of one percent of equity.
+type KellySizer struct {
+	Fraction        float64
+	Window          int
+	MinTrades       int
+	MaxPositionSize float64
+
+	results []float64 // PnL percent of recent trades
+	mu      sync.RWMutex
+}
+
+// NewKellySizer creates a fractional Kelly sizer with defaults for
+// non-positive parameters.
+func NewKellySizer(fraction float64, window, minTrades int, maxPositionSize float64) *KellySizer {
+	if fraction <= 0 {
+		fraction = 0.25
+	}
+	if window <= 0 {
+		window = 50
+	}
+	if minTrades <= 0 {
+		minTrades = 20
+	}
+	return &KellySizer{
+		Fraction:        fraction,
+		Window:          window,
+		MinTrades:       minTrades,
+		MaxPositionSize: maxPositionSize,
+		results:         make([]float64, 0, window),
+	}
+}
+
+func (s *KellySizer) Name() string { return SizingKelly }
+
+// RecordTrade adds a closed trade result to the rolling window.
+func (s *KellySizer) RecordTrade(pnlPercent float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results = append(s.results, pnlPercent)
+	if len(s.results) > s.Window {
+		s.results = s.results[len(s.results)-s.Window:]
+	}
+}
+
+// KellyFraction returns the full Kelly fraction W - (1-W)/R estimated from
+// the window, where W is the win rate and R the payoff ratio. It is the
+// fraction of equity to risk per trade, 0 without enough data or edge.
+func (s *KellySizer) KellyFraction() float64 {
+	kelly, _ := s.estimate()
+	return kelly
+}
+
+// estimate returns the Kelly fraction and the average loss in percent.
+func (s *KellySizer) estimate() (kelly, avgLoss float64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.results) < s.MinTrades {
+		return 0, 0
+	}
+
+	var wins, losses int
+	var sumWin, sumLoss float64
+	for _, r := range s.results {
+		if r > 0 {
+			wins++
+			sumWin += r
+		} else {
+			losses++
+			sumLoss -= r
+		}
+	}
+	if wins == 0 || losses == 0 || sumLoss == 0 {
+		// Без убыточных сделок оценка payoff невозможна
+		return 0, 0
+	}
+
+	winRate := float64(wins) / float64(len(s.results))
+	avgLoss = sumLoss / float64(losses)
+	payoff := (sumWin / float64(wins)) / avgLoss
+	return winRate - (1-winRate)/payoff, avgLoss
+}
+
+// Size risks Fraction of the Kelly bet. The risked equity is converted into a
+// position value through the average loss of a losing trade.
+func (s *KellySizer) Size(req SizingRequest) float64 {
+	if req.EntryPrice <= 0 {
+		return 0
+	}
+
+	allocation := 0.01
+	if kelly, avgLoss := s.estimate(); kelly > 0 && avgLoss > 0 {
+		allocation = kelly * s.Fraction / (avgLoss / 100)
+	}
+	return capQuantity(req.Equity*allocation/req.EntryPrice, req, s.MaxPositionSize)
+}
+
+// EqualRiskSizer splits a total risk budget evenly across MaxPositions, so
+// every position contributes the same loss at its stop (or per ATR when the
+// entry has no stop).
+type EqualRiskSizer struct {
+	TotalRisk       float64
+	MaxPositions    int
+	MaxPositionSize float64
+}
+
+func (s *EqualRiskSizer) Name() string { return SizingEqualRisk }
+
+func (s *EqualRiskSizer) Size(req SizingRequest) float64 {
+	if req.EntryPrice <= 0 {
+		return 0
+	}
+
+	unit := math.Abs(req.EntryPrice - req.StopLoss)
+	if req.StopLoss <= 0 {
+		unit = req.ATR
+	}
+	if unit <= 0 {
+		return 0
+	}
+
+	budget := req.Equity * s.TotalRisk / float64(s.MaxPositions)
+	return capQuantity(budget/unit, req, s.MaxPositionSize)
+}
+
+// capQuantity limits a quantity by the position size cap and the available
+// cash, and rounds it down to exchange precision (1e-8).
+func capQuantity(quantity float64, req SizingRequest, maxPositionSize float64) float64 {
+	if quantity <= 0 || req.EntryPrice <= 0 {
+		return 0
+	}
+	if maxPositionSize > 0 && req.Equity > 0 {
+		quantity = math.Min(quantity, req.Equity*maxPositionSize/req.EntryPrice)
+	}
+	if req.Available > 0 {
+		quantity = math.Min(quantity, req.Available/req.EntryPrice)
+	}
+	return math.Floor(quantity*1e8) / 1e8
+}
@@ -0,0 +1,452 @@
+package risk
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// VaR methods.
+const (
+	VaRHistorical = "historical"
+	VaRParametric = "parametric"
+	VaRMonteCarlo = "monte_carlo"
+)
+
+// PriceSeries is a bar close series of one symbol. Times identify bars
+// (e.g. close time in Unix milliseconds) and are used to align symbols.
+type PriceSeries struct {
+	Symbol string
+	Times  []int64
+	Closes []float64
+}
+
+// VaRConfig controls a VaR computation.
+type VaRConfig struct {
+	ConfidenceLevels []float64 // e.g. 0.95, 0.99
+	HorizonBars      int       // Holding period in bars of the input series
+	Simulations      int       // Monte Carlo paths (default 10000)
+	Seed             int64     // Monte Carlo seed, fixed for reproducible reports
+}
+
+// VaRResult is VaR and expected shortfall for one method and confidence.
+// Losses are positive numbers.
+type VaRResult struct {
+	Method      string  `json:"method"`
+	Confidence  float64 `json:"confidence"`
+	HorizonBars int     `json:"horizonBars"`
+	VaR         float64 `json:"var"`  // Quote currency
+	CVaR        float64 `json:"cvar"` // Expected shortfall beyond VaR, quote currency
+	VaRPercent  float64 `json:"varPercent"`
+	CVaRPercent float64 `json:"cvarPercent"`
+}
+
+// VaRReport holds VaR results for the current portfolio.
+type VaRReport struct {
+	Equity        float64     `json:"equity"`
+	GrossExposure float64     `json:"grossExposure"`
+	Observations  int         `json:"observations"` // Aligned bar returns used
+	Symbols       []string    `json:"symbols"`
+	Results       []VaRResult `json:"results"`
+	Warnings      []string    `json:"warnings"`
+}
+
+// VaRLimit configures the optional pre-trade VaR check.
+type VaRLimit struct {
+	MaxVaRPercent float64 // Max VaR as a percent of equity; 0 disables the check
+	Confidence    float64 // Default 0.99
+	HorizonBars   int     // Default 1
+	Method        string  // Default historical
+	MinBars       int     // Bars required before the check applies (default 30)
+
+	// Series loads the price history of symbols. It should be the source the
+	// VaR report uses so that the check and the report agree; nil falls back
+	// to the portfolio's sampled series.
+	Series func(symbols []string) []PriceSeries
+}
+
+// ComputeVaR estimates VaR and CVaR of holdings with historical, parametric
+// (variance-covariance, normal) and Monte Carlo methods from aligned bar
+// returns of the input series.
+func ComputeVaR(holdings []Holding, series []PriceSeries, equity float64, config VaRConfig) VaRReport {
+	report := VaRReport{
+		Equity:   equity,
+		Symbols:  make([]string, 0),
+		Results:  make([]VaRResult, 0),
+		Warnings: make([]string, 0),
+	}
+	if len(config.ConfidenceLevels) == 0 {
+		config.ConfidenceLevels = []float64{0.95, 0.99}
+	}
+	if config.HorizonBars <= 0 {
+		config.HorizonBars = 1
+	}
+	if config.Simulations <= 0 {
+		config.Simulations = 10000
+	}
+
+	symbols, weights := netHoldings(holdings)
+	for _, w := range weights {
+		report.GrossExposure += math.Abs(w)
+	}
+	report.Symbols = symbols
+	if len(symbols) == 0 {
+		return report
+	}
+
+	returns, missing := alignedReturns(symbols, series)
+	for _, sym := range missing {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("no price history for %s, excluded", sym))
+	}
+	if len(returns) < 2 {
+		report.Warnings = append(report.Warnings, "not enough aligned price history")
+		return report
+	}
+	report.Observations = len(returns)
+	if len(returns) < 30 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("only %d observations, estimates are unreliable", len(returns)))
+	}
+
+	pnl := portfolioPnL(returns, weights, config.HorizonBars)
+	means, cov := moments(returns)
+	rng := rand.New(rand.NewSource(config.Seed))
+	simulated := monteCarloPnL(means, cov, weights, config.HorizonBars, config.Simulations, rng)
+
+	for _, c := range config.ConfidenceLevels {
+		if c <= 0 || c >= 1 {
+			continue
+		}
+		hVaR, hCVaR := empiricalVaR(pnl, c)
+		pVaR, pCVaR := parametricVaR(means, cov, weights, config.HorizonBars, c)
+		mVaR, mCVaR := empiricalVaR(simulated, c)
+
+		report.Results = append(report.Results,
+			newVaRResult(VaRHistorical, c, config.HorizonBars, hVaR, hCVaR, equity),
+			newVaRResult(VaRParametric, c, config.HorizonBars, pVaR, pCVaR, equity),
+			newVaRResult(VaRMonteCarlo, c, config.HorizonBars, mVaR, mCVaR, equity),
+		)
+	}
+	return report
+}
+
+// PortfolioVaR returns the VaR of holdings for a single method and
+// confidence, and false when there is not enough history.
+func PortfolioVaR(holdings []Holding, series []PriceSeries, method string, confidence float64, horizonBars, minBars int) (float64, bool) {
+	symbols, weights := netHoldings(holdings)
+	if len(symbols) == 0 {
+		return 0, true
+	}
+	if horizonBars <= 0 {
+		horizonBars = 1
+	}
+	returns, missing := alignedReturns(symbols, series)
+	if len(missing) > 0 || len(returns) < minBars || len(returns) < 2 {
+		return 0, false
+	}
+
+	switch method {
+	case VaRParametric:
+		means, cov := moments(returns)
+		v, _ := parametricVaR(means, cov, weights, horizonBars, confidence)
+		return v, true
+	case VaRMonteCarlo:
+		means, cov := moments(returns)
+		sim := monteCarloPnL(means, cov, weights, horizonBars, 5000, rand.New(rand.NewSource(1)))
+		v, _ := empiricalVaR(sim, confidence)
+		return v, true
+	default:
+		v, _ := empiricalVaR(portfolioPnL(returns, weights, horizonBars), confidence)
+		return v, true
+	}
+}
+
+func newVaRResult(method string, confidence float64, horizon int, v, cv, equity float64) VaRResult {
+	result := VaRResult{Method: method, Confidence: confidence, HorizonBars: horizon, VaR: v, CVaR: cv}
+	if equity > 0 {
+		result.VaRPercent = v / equity * 100
+		result.CVaRPercent = cv / equity * 100
+	}
+	return result
+}
+
+// netHoldings nets holdings per symbol and returns sorted symbols with
+// their signed notionals.
+func netHoldings(holdings []Holding) ([]string, []float64) {
+	net := make(map[string]float64)
+	for _, h := range holdings {
+		net[h.Symbol] += h.Notional
+	}
+	symbols := make([]string, 0, len(net))
+	for sym, n := range net {
+		if n != 0 {
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Strings(symbols)
+	weights := make([]float64, len(symbols))
+	for i, sym := range symbols {
+		weights[i] = net[sym]
+	}
+	return symbols, weights
+}
+
+// alignedReturns returns bar returns as rows of [symbol] over the bars all
+// symbols share. Symbols without history are reported as missing and get a
+// zero return. The bar spacing is the smallest step between shared bars;
+// bars further apart are a gap in the data and yield no return.
+func alignedReturns(symbols []string, series []PriceSeries) ([][]float64, []string) {
+	bySymbol := make(map[string]PriceSeries, len(series))
+	for _, s := range series {
+		bySymbol[s.Symbol] = s
+	}
+
+	missing := make([]string, 0)
+	var common map[int64]bool
+	closes := make([]map[int64]float64, len(symbols))
+	for i, sym := range symbols {
+		s, ok := bySymbol[sym]
+		if !ok || len(s.Closes) < 2 || len(s.Times) != len(s.Closes) {
+			missing = append(missing, sym)
+			continue
+		}
+		closes[i] = make(map[int64]float64, len(s.Closes))
+		times := make(map[int64]bool, len(s.Closes))
+		for j, t := range s.Times {
+			closes[i][t] = s.Closes[j]
+			times[t] = true
+		}
+		if common == nil {
+			common = times
+			continue
+		}
+		for t := range common {
+			if !times[t] {
+				delete(common, t)
+			}
+		}
+	}
+
+	times := make([]int64, 0, len(common))
+	for t := range common {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	var step int64
+	for k := 1; k < len(times); k++ {
+		if d := times[k] - times[k-1]; step == 0 || d < step {
+			step = d
+		}
+	}
+
+	rows := make([][]float64, 0, len(times))
+	for k := 1; k < len(times); k++ {
+		// Пропуск в данных не превращаем в один большой return
+		if times[k]-times[k-1] != step {
+			continue
+		}
+		row := make([]float64, len(symbols))
+		valid := true
+		for i := range symbols {
+			if closes[i] == nil {
+				continue
+			}
+			prev, cur := closes[i][times[k-1]], closes[i][times[k]]
+			if prev <= 0 {
+				valid = false
+				break
+			}
+			row[i] = cur/prev - 1
+		}
+		if valid {
+			rows = append(rows, row)
+		}
+	}
+	return rows, missing
+}
+
+// portfolioPnL returns overlapping horizon PnL observations, compounding
+// bar returns within each window.
+func portfolioPnL(returns [][]float64, weights []float64, horizon int) []float64 {
+	if horizon > len(returns) {
+		horizon = len(returns)
+	}
+	pnl := make([]float64, 0, len(returns)-horizon+1)
+	for start := 0; start+horizon <= len(returns); start++ {
+		var total float64
+		for i, w := range weights {
+			growth := 1.0
+			for k := start; k < start+horizon; k++ {
+				growth *= 1 + returns[k][i]
+			}
+			total += w * (growth - 1)
+		}
+		pnl = append(pnl, total)
+	}
+	return pnl
+}
+
+// moments returns mean bar returns and their sample covariance matrix.
+func moments(returns [][]float64) ([]float64, [][]float64) {
+	n := len(returns[0])
+	means := make([]float64, n)
+	for _, row := range returns {
+		for i, r := range row {
+			means[i] += r
+		}
+	}
+	for i := range means {
+		means[i] /= float64(len(returns))
+	}
+
+	cov := make([][]float64, n)
+	for i := range cov {
+		cov[i] = make([]float64, n)
+	}
+	for _, row := range returns {
+		for i := 0; i < n; i++ {
+			for j := i; j < n; j++ {
+				cov[i][j] += (row[i] - means[i]) * (row[j] - means[j])
+			}
+		}
+	}
+	denom := float64(len(returns) - 1)
+	for i := 0; i < n; i++ {
+		for j := i; j < n; j++ {
+			cov[i][j] /= denom
+			cov[j][i] = cov[i][j]
+		}
+	}
+	return means, cov
+}
+
+// empiricalVaR returns VaR and CVaR at confidence c from PnL observations.
+func empiricalVaR(pnl []float64, c float64) (float64, float64) {
+	if len(pnl) == 0 {
+		return 0, 0
+	}
+	sorted := make([]float64, len(pnl))
+	copy(sorted, pnl)
+	sort.Float64s(sorted)
+
+	idx := int(math.Floor((1 - c) * float64(len(sorted))))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	v := -sorted[idx]
+
+	var tail float64
+	for _, p := range sorted[:idx+1] {
+		tail += p
+	}
+	cv := -tail / float64(idx+1)
+	return math.Max(v, 0), math.Max(cv, 0)
+}
+
+// parametricVaR assumes normally distributed returns scaled by sqrt(horizon).
+func parametricVaR(means []float64, cov [][]float64, weights []float64, horizon int, c float64) (float64, float64) {
+	var mu, variance float64
+	for i, w := range weights {
+		mu += w * means[i]
+		for j, w2 := range weights {
+			variance += w * w2 * cov[i][j]
+		}
+	}
+	h := float64(horizon)
+	mu *= h
+	sigma := math.Sqrt(math.Max(variance, 0) * h)
+
+	z := normalQuantile(c)
+	v := z*sigma - mu
+	density := math.Exp(-z*z/2) / math.Sqrt(2*math.Pi)
+	cv := sigma*density/(1-c) - mu
+	return math.Max(v, 0), math.Max(cv, 0)
+}
+
+// monteCarloPnL simulates horizon PnL from a multivariate normal with the
+// sample means and covariance.
+func monteCarloPnL(means []float64, cov [][]float64, weights []float64, horizon, paths int, rng *rand.Rand) []float64 {
+	chol := cholesky(cov)
+	n := len(means)
+	h := float64(horizon)
+	sqrtH := math.Sqrt(h)
+
+	pnl := make([]float64, paths)
+	z := make([]float64, n)
+	for p := 0; p < paths; p++ {
+		for i := range z {
+			z[i] = rng.NormFloat64()
+		}
+		var total float64
+		for i := 0; i < n; i++ {
+			shock := 0.0
+			for j := 0; j <= i; j++ {
+				shock += chol[i][j] * z[j]
+			}
+			total += weights[i] * (means[i]*h + shock*sqrtH)
+		}
+		pnl[p] = total
+	}
+	return pnl
+}
+
+// cholesky returns the lower-triangular factor of a covariance matrix,
+// adding a small diagonal jitter when the matrix is not positive definite.
+func cholesky(cov [][]float64) [][]float64 {
+	n := len(cov)
+	jitter := 0.0
+	for attempt := 0; attempt < 5; attempt++ {
+		l := make([][]float64, n)
+		for i := range l {
+			l[i] = make([]float64, n)
+		}
+		ok := true
+		for i := 0; i < n && ok; i++ {
+			for j := 0; j <= i; j++ {
+				sum := cov[i][j]
+				if i == j {
+					sum += jitter
+				}
+				for k := 0; k < j; k++ {
+					sum -= l[i][k] * l[j][k]
+				}
+				if i == j {
+					if sum <= 0 {
+						ok = false
+						break
+					}
+					l[i][i] = math.Sqrt(sum)
+				} else {
+					l[i][j] = sum / l[j][j]
+				}
+			}
+		}
+		if ok {
+			return l
+		}
+		jitter = math.Max(jitter*10, 1e-12)
+	}
+
+	// Крайний случай: независимые активы с их собственными дисперсиями
+	l := make([][]float64, n)
+	for i := range l {
+		l[i] = make([]float64, n)
+		l[i][i] = math.Sqrt(math.Max(cov[i][i], 0))
+	}
+	return l
+}
+
+// normalQuantile returns the standard normal quantile of p by bisection.
+func normalQuantile(p float64) float64 {
+	lo, hi := -10.0, 10.0
+	for i := 0; i < 100; i++ {
+		mid := (lo + hi) / 2
+		if 0.5*(1+math.Erf(mid/math.Sqrt2)) < p {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
@@ -0,0 +1,232 @@
+package risk
+
+import (
+	"math"
+	"testing"
+)
+
+// seriesFromReturns builds one-minute closes (times in seconds) that
+// produce the given returns.
+func seriesFromReturns(symbol string, returns []float64) PriceSeries {
+	s := PriceSeries{Symbol: symbol, Times: []int64{0}, Closes: []float64{100}}
+	for i, r := range returns {
+		s.Times = append(s.Times, int64(i+1)*60)
+		s.Closes = append(s.Closes, s.Closes[i]*(1+r))
+	}
+	return s
+}
+
+// uniformReturns returns 100 returns evenly spread over ±4.95%, so that a
+// 1000 notional position loses 49.5, 48.5, ... on its worst bars.
+func uniformReturns() []float64 {
+	returns := make([]float64, 100)
+	for i := range returns {
+		returns[i] = (float64(i) - 49.5) / 1000
+	}
+	return returns
+}
+
+func findResult(results []VaRResult, method string, confidence float64) (VaRResult, bool) {
+	for _, r := range results {
+		if r.Method == method && r.Confidence == confidence {
+			return r, true
+		}
+	}
+	return VaRResult{}, false
+}
+
+func TestComputeVaR(t *testing.T) {
+	// Выборочное стандартное отклонение uniformReturns для позиции 1000
+	sigma := math.Sqrt(83325.0 / 99)
+	normalTail := func(z, c, h float64) (float64, float64) {
+		s := sigma * math.Sqrt(h)
+		return z * s, s * math.Exp(-z*z/2) / math.Sqrt(2*math.Pi) / (1 - c)
+	}
+	p95, pc95 := normalTail(1.6448536, 0.95, 1)
+	p99, pc99 := normalTail(2.3263479, 0.99, 1)
+	p95h4, pc95h4 := normalTail(1.6448536, 0.95, 4)
+
+	btc := seriesFromReturns("BTCUSDT", uniformReturns())
+	eth := seriesFromReturns("ETHUSDT", uniformReturns())
+
+	tests := []struct {
+		name       string
+		holdings   []Holding
+		series     []PriceSeries
+		horizon    int
+		method     string
+		confidence float64
+		wantVaR    float64
+		wantCVaR   float64
+		tolerance  float64
+	}{
+		{"historical 95%", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 1, VaRHistorical, 0.95, 44.5, 47, 1e-6},
+		{"historical 99%", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 1, VaRHistorical, 0.99, 48.5, 49, 1e-6},
+		{"historical short", []Holding{{"BTCUSDT", -1000}}, []PriceSeries{btc}, 1, VaRHistorical, 0.95, 44.5, 47, 1e-6},
+		{"historical netted holdings", []Holding{{"BTCUSDT", 600}, {"BTCUSDT", 400}}, []PriceSeries{btc}, 1, VaRHistorical, 0.95, 44.5, 47, 1e-6},
+		{"historical hedged", []Holding{{"BTCUSDT", 1000}, {"ETHUSDT", -1000}}, []PriceSeries{btc, eth}, 1, VaRHistorical, 0.95, 0, 0, 1e-6},
+		{"parametric 95%", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 1, VaRParametric, 0.95, p95, pc95, 1e-3},
+		{"parametric 99%", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 1, VaRParametric, 0.99, p99, pc99, 1e-3},
+		{"parametric horizon", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 4, VaRParametric, 0.95, p95h4, pc95h4, 1e-3},
+		{"parametric hedged", []Holding{{"BTCUSDT", 1000}, {"ETHUSDT", -1000}}, []PriceSeries{btc, eth}, 1, VaRParametric, 0.95, 0, 0, 1e-6},
+		{"monte carlo 95%", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 1, VaRMonteCarlo, 0.95, p95, pc95, 1.5},
+		{"monte carlo 99%", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 1, VaRMonteCarlo, 0.99, p99, pc99, 4},
+		{"monte carlo horizon", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 4, VaRMonteCarlo, 0.95, p95h4, pc95h4, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ComputeVaR(tt.holdings, tt.series, 10000, VaRConfig{
+				ConfidenceLevels: []float64{0.95, 0.99},
+				HorizonBars:      tt.horizon,
+				Seed:             1,
+			})
+			result, ok := findResult(report.Results, tt.method, tt.confidence)
+			if !ok {
+				t.Fatalf("no %s result at %.2f, warnings %v", tt.method, tt.confidence, report.Warnings)
+			}
+			if math.Abs(result.VaR-tt.wantVaR) > tt.tolerance || math.Abs(result.CVaR-tt.wantCVaR) > tt.tolerance {
+				t.Errorf("VaR, CVaR = %.4f, %.4f; want %.4f, %.4f", result.VaR, result.CVaR, tt.wantVaR, tt.wantCVaR)
+			}
+			if math.Abs(result.VaRPercent-result.VaR/100) > 1e-9 {
+				t.Errorf("VaRPercent = %.4f, want %.4f", result.VaRPercent, result.VaR/100)
+			}
+		})
+	}
+}
+
+func TestComputeVaRWarnings(t *testing.T) {
+	btc := seriesFromReturns("BTCUSDT", uniformReturns())
+
+	tests := []struct {
+		name             string
+		holdings         []Holding
+		series           []PriceSeries
+		wantObservations int
+		wantResults      int
+		wantWarnings     int
+	}{
+		{"no holdings", nil, []PriceSeries{btc}, 0, 0, 0},
+		{"no history", []Holding{{"BTCUSDT", 1000}}, nil, 0, 0, 2},
+		{"missing symbol is excluded", []Holding{{"BTCUSDT", 1000}, {"ETHUSDT", 500}}, []PriceSeries{btc}, 100, 6, 1},
+		{"short history", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{seriesFromReturns("BTCUSDT", uniformReturns()[:10])}, 10, 6, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := ComputeVaR(tt.holdings, tt.series, 10000, VaRConfig{Simulations: 100})
+			if report.Observations != tt.wantObservations || len(report.Results) != tt.wantResults || len(report.Warnings) != tt.wantWarnings {
+				t.Errorf("observations, results, warnings = %d, %d, %v; want %d, %d, %d warnings",
+					report.Observations, len(report.Results), report.Warnings, tt.wantObservations, tt.wantResults, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestAlignedReturns(t *testing.T) {
+	tests := []struct {
+		name     string
+		series   []PriceSeries
+		wantRows [][]float64
+	}{
+		{
+			name:     "adjacent bars",
+			series:   []PriceSeries{{Symbol: "A", Times: []int64{0, 60, 120}, Closes: []float64{100, 110, 99}}},
+			wantRows: [][]float64{{0.1}, {-0.1}},
+		},
+		{
+			name:     "gap in the data",
+			series:   []PriceSeries{{Symbol: "A", Times: []int64{0, 60, 300, 360}, Closes: []float64{100, 110, 200, 220}}},
+			wantRows: [][]float64{{0.1}, {0.1}},
+		},
+		{
+			name: "bar missing in one symbol",
+			series: []PriceSeries{
+				{Symbol: "A", Times: []int64{0, 60, 120, 180}, Closes: []float64{100, 110, 121, 242}},
+				{Symbol: "B", Times: []int64{0, 60, 180}, Closes: []float64{100, 90, 45}},
+			},
+			wantRows: [][]float64{{0.1, -0.1}},
+		},
+		{
+			name:     "non-positive close",
+			series:   []PriceSeries{{Symbol: "A", Times: []int64{0, 60, 120}, Closes: []float64{0, 100, 110}}},
+			wantRows: [][]float64{{0.1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols := make([]string, len(tt.series))
+			for i, s := range tt.series {
+				symbols[i] = s.Symbol
+			}
+			rows, missing := alignedReturns(symbols, tt.series)
+			if len(missing) != 0 {
+				t.Fatalf("missing = %v", missing)
+			}
+			if len(rows) != len(tt.wantRows) {
+				t.Fatalf("rows = %v, want %v", rows, tt.wantRows)
+			}
+			for k := range rows {
+				for i := range rows[k] {
+					if math.Abs(rows[k][i]-tt.wantRows[k][i]) > 1e-9 {
+						t.Errorf("rows = %v, want %v", rows, tt.wantRows)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPortfolioVaR(t *testing.T) {
+	btc := seriesFromReturns("BTCUSDT", uniformReturns())
+
+	tests := []struct {
+		name     string
+		holdings []Holding
+		series   []PriceSeries
+		minBars  int
+		want     float64
+		wantOK   bool
+	}{
+		{"empty portfolio", nil, nil, 30, 0, true},
+		{"enough history", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 30, 48.5, true},
+		{"not enough bars", []Holding{{"BTCUSDT", 1000}}, []PriceSeries{btc}, 200, 0, false},
+		{"missing symbol", []Holding{{"BTCUSDT", 1000}, {"ETHUSDT", 100}}, []PriceSeries{btc}, 30, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := PortfolioVaR(tt.holdings, tt.series, VaRHistorical, 0.99, 1, tt.minBars)
+			if ok != tt.wantOK || math.Abs(got-tt.want) > 1e-6 {
+				t.Errorf("PortfolioVaR() = %.4f, %v; want %.4f, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestVaRRuleSeriesSource(t *testing.T) {
+	btc := seriesFromReturns("BTCUSDT", uniformReturns())
+	loaded := func(symbols []string) []PriceSeries { return []PriceSeries{btc} }
+
+	tests := []struct {
+		name       string
+		series     func(symbols []string) []PriceSeries
+		maxPercent float64
+		want       string
+	}{
+		{"limit series within limit", loaded, 10, ActionAllow},
+		{"limit series over limit", loaded, 1, ActionReject},
+		// Портфель без истории: проверка пропускает ордер
+		{"portfolio series without history", nil, 1, ActionAllow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &VaRRule{
+				Portfolio: NewPortfolioRisk(nil),
+				Limit:     &VaRLimit{MaxVaRPercent: tt.maxPercent, Confidence: 0.99, Series: tt.series},
+			}
+			intent := OrderIntent{Symbol: "BTCUSDT", Side: "BUY", Price: 100, Quantity: 10}
+			result := rule.Check(intent, RuleContext{PortfolioEquity: 1000})
+			if result.Action != tt.want {
+				t.Errorf("Check() = %s (%s), want %s", result.Action, result.Reason, tt.want)
+			}
+		})
+	}
+}
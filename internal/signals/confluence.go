@@ -0,0 +1,331 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConfluenceTimeframe is the timeframe of consolidated multi-timeframe
+// signals.
+const ConfluenceTimeframe = "mtf"
+
+// Confluence modes accepted in ConfluenceConfig.Mode.
+const (
+	ConfluenceWeighted    = "weighted"     // Weighted vote of all timeframes
+	ConfluenceHigherTrend = "higher_trend" // Lowest timeframe trades, higher ones must not disagree
+	ConfluenceTiming      = "timing"       // Highest timeframe sets direction, lowest one times the entry
+)
+
+// ConfluenceConfig controls how a symbol's signals on several timeframes are
+// consolidated.
+type ConfluenceConfig struct {
+	Mode          string             `json:"mode"`          // One of the Confluence* constants
+	Weights       map[string]float64 `json:"weights"`       // Vote weight per timeframe; missing ones weigh by rank (1 for the lowest)
+	MinAgreement  float64            `json:"minAgreement"`  // weighted: share of weight that must agree (default 0.6)
+	RequireTrend  bool               `json:"requireTrend"`  // higher_trend: a HOLD on a higher timeframe blocks the signal
+	MaxAgeCandles float64            `json:"maxAgeCandles"` // Signals older than this many candles of their timeframe are ignored (default 2)
+}
+
+// confidenceRules describe how each confluence mode derives confidence.
+var confidenceRules = map[string]string{
+	ConfluenceWeighted:    "weighted mean of timeframe confidences signed by direction",
+	ConfluenceHigherTrend: "weighted mean confidence of agreeing timeframes",
+	ConfluenceTiming:      "mean confidence of the trend and timing timeframes",
+}
+
+// TimeframeVote is one timeframe's contribution to a consolidated signal.
+type TimeframeVote struct {
+	Timeframe  string  `json:"timeframe"`
+	Direction  string  `json:"direction"`
+	Confidence float64 `json:"confidence"`
+	Weight     float64 `json:"weight"`
+	Agrees     bool    `json:"agrees"` // Same direction as the consolidated signal
+}
+
+// Validate fills defaults and checks the configuration.
+func (c *ConfluenceConfig) Validate() error {
+	switch c.Mode {
+	case ConfluenceWeighted, ConfluenceHigherTrend, ConfluenceTiming:
+	default:
+		return fmt.Errorf("unknown confluence mode: %s", c.Mode)
+	}
+	if c.MinAgreement <= 0 {
+		c.MinAgreement = 0.6
+	}
+	if c.MinAgreement > 1 {
+		return fmt.Errorf("confluence min agreement must be within (0, 1]")
+	}
+	if c.MaxAgeCandles <= 0 {
+		c.MaxAgeCandles = 2
+	}
+	for tf, weight := range c.Weights {
+		if weight <= 0 {
+			return fmt.Errorf("confluence weight of %s must be positive", tf)
+		}
+	}
+	return nil
+}
+
+// TimeframeDuration returns the candle duration of a Binance interval such
+// as "1m", "4h" or "1d", or 0 if it is not recognized.
+func TimeframeDuration(timeframe string) time.Duration {
+	if len(timeframe) < 2 {
+		return 0
+	}
+	n, err := strconv.Atoi(timeframe[:len(timeframe)-1])
+	if err != nil || n <= 0 {
+		return 0
+	}
+	unit := time.Duration(0)
+	switch timeframe[len(timeframe)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	case 'M':
+		unit = 30 * 24 * time.Hour
+	}
+	return time.Duration(n) * unit
+}
+
+func directionSign(direction string) float64 {
+	switch direction {
+	case "LONG":
+		return 1
+	case "SHORT":
+		return -1
+	}
+	return 0
+}
+
+// Confluence consolidates the latest signal of each timeframe of a symbol
+// into one signal. Stale signals are ignored, and in the higher_trend and
+// timing modes a stale timeframe the mode relies on yields HOLD; nil is
+// returned when no timeframe has a usable signal. The per-timeframe breakdown is recorded in
+// Timeframes and in the explanation notes.
+func Confluence(symbol string, byTimeframe map[string]*Signal, config *ConfluenceConfig, now time.Time) *Signal {
+	cfg := *config
+	if err := cfg.Validate(); err != nil {
+		return nil
+	}
+
+	timeframes := make([]string, 0, len(byTimeframe))
+	for tf := range byTimeframe {
+		timeframes = append(timeframes, tf)
+	}
+	sort.Slice(timeframes, func(i, j int) bool {
+		return TimeframeDuration(timeframes[i]) < TimeframeDuration(timeframes[j])
+	})
+
+	stale := make([]string, 0)
+	present := make([]string, 0, len(timeframes))
+	staleTimeframes := make(map[string]bool)
+	votes := make([]TimeframeVote, 0, len(timeframes))
+	sigs := make([]*Signal, 0, len(timeframes))
+	for rank, tf := range timeframes {
+		sig := byTimeframe[tf]
+		if sig == nil {
+			continue
+		}
+		present = append(present, tf)
+		if d := TimeframeDuration(tf); d > 0 && now.Sub(sig.Timestamp) > time.Duration(cfg.MaxAgeCandles*float64(d)) {
+			stale = append(stale, fmt.Sprintf("%s: %s ignored, stale since %s", tf, sig.Direction, sig.Timestamp.Format(time.RFC3339)))
+			staleTimeframes[tf] = true
+			continue
+		}
+		weight, ok := cfg.Weights[tf]
+		if !ok {
+			weight = float64(rank + 1)
+		}
+		votes = append(votes, TimeframeVote{
+			Timeframe:  tf,
+			Direction:  sig.Direction,
+			Confidence: sig.Confidence,
+			Weight:     weight,
+		})
+		sigs = append(sigs, sig)
+	}
+	if len(votes) == 0 {
+		return nil
+	}
+
+	var direction, rule string
+	var confidence float64
+	missing := requiredStale(cfg.Mode, present, staleTimeframes)
+	switch {
+	case missing != "":
+		direction, rule = "HOLD", fmt.Sprintf("%s signal is stale", missing)
+	case cfg.Mode == ConfluenceWeighted:
+		direction, confidence, rule = weightedVote(votes, cfg.MinAgreement)
+	case cfg.Mode == ConfluenceHigherTrend:
+		direction, confidence, rule = higherTrend(votes, cfg.RequireTrend)
+	case cfg.Mode == ConfluenceTiming:
+		direction, confidence, rule = trendTiming(votes)
+	}
+
+	// Цена, ATR и индикаторы берутся с младшего таймфрейма, по нему же входим
+	base := sigs[0]
+	result := &Signal{
+		ID:         uuid.New().String(),
+		Symbol:     symbol,
+		Timeframe:  ConfluenceTimeframe,
+		Direction:  direction,
+		Confidence: confidence,
+		Price:      base.Price,
+		ATR:        base.ATR,
+		Timestamp:  now,
+		Model:      base.Model,
+		Indicators: append([]IndicatorScore(nil), base.Indicators...),
+		Timeframes: votes,
+		Regime:     base.Regime,
+		Explanation: &SignalExplanation{
+			Model:          base.Model,
+			DirectionRule:  fmt.Sprintf("%s confluence: %s", cfg.Mode, rule),
+			ConfidenceRule: confidenceRules[cfg.Mode],
+		},
+	}
+
+	var totalWeight float64
+	notes := make([]string, 0, len(votes)+len(stale))
+	for i, vote := range votes {
+		result.Timeframes[i].Agrees = direction != "HOLD" && vote.Direction == direction
+		result.TechnicalSignal += sigs[i].TechnicalSignal * vote.Weight
+		result.MLSignal += sigs[i].MLSignal * vote.Weight
+		result.SentimentSignal += sigs[i].SentimentSignal * vote.Weight
+		totalWeight += vote.Weight
+		notes = append(notes, fmt.Sprintf("%s: %s %.0f%% (weight %.1f)",
+			vote.Timeframe, vote.Direction, vote.Confidence*100, vote.Weight))
+	}
+	if totalWeight > 0 {
+		result.TechnicalSignal /= totalWeight
+		result.MLSignal /= totalWeight
+		result.SentimentSignal /= totalWeight
+	}
+	result.Explanation.Notes = append(notes, stale...)
+	result.Explain()
+	return result
+}
+
+// requiredStale returns the first stale timeframe the mode cannot decide
+// without, or "" if there is none. present lists the timeframes with a
+// signal, lowest first. The weighted vote simply goes without stale
+// timeframes; higher_trend needs every timeframe, timing the lowest and the
+// highest one.
+func requiredStale(mode string, present []string, stale map[string]bool) string {
+	switch mode {
+	case ConfluenceHigherTrend:
+		for _, tf := range present {
+			if stale[tf] {
+				return tf
+			}
+		}
+	case ConfluenceTiming:
+		if stale[present[0]] {
+			return present[0]
+		}
+		if last := present[len(present)-1]; stale[last] {
+			return last
+		}
+	}
+	return ""
+}
+
+// weightedVote takes the direction of the weighted confidence score when
+// enough of the weight agrees with it.
+func weightedVote(votes []TimeframeVote, minAgreement float64) (string, float64, string) {
+	var score, total float64
+	for _, v := range votes {
+		score += directionSign(v.Direction) * v.Confidence * v.Weight
+		total += v.Weight
+	}
+	if total == 0 || score == 0 {
+		return "HOLD", 0, "no directional votes"
+	}
+	score /= total
+
+	direction := "LONG"
+	if score < 0 {
+		direction = "SHORT"
+	}
+	var agreeing float64
+	for _, v := range votes {
+		if v.Direction == direction {
+			agreeing += v.Weight
+		}
+	}
+	agreement := agreeing / total
+	if agreement < minAgreement {
+		return "HOLD", 0, fmt.Sprintf("%s agreement %.0f%% below %.0f%%", direction, agreement*100, minAgreement*100)
+	}
+	return direction, math.Min(math.Abs(score), 1), fmt.Sprintf("%s agreement %.0f%%", direction, agreement*100)
+}
+
+// higherTrend trades the lowest timeframe's direction unless a higher
+// timeframe points the other way (or is flat when requireTrend is set).
+func higherTrend(votes []TimeframeVote, requireTrend bool) (string, float64, string) {
+	base := votes[0]
+	if directionSign(base.Direction) == 0 {
+		return "HOLD", 0, fmt.Sprintf("%s has no direction", base.Timeframe)
+	}
+
+	confidence, weight := base.Confidence*base.Weight, base.Weight
+	for _, v := range votes[1:] {
+		switch {
+		case v.Direction == base.Direction:
+			confidence += v.Confidence * v.Weight
+			weight += v.Weight
+		case directionSign(v.Direction) != 0:
+			return "HOLD", 0, fmt.Sprintf("%s %s opposes %s %s", v.Timeframe, v.Direction, base.Timeframe, base.Direction)
+		case requireTrend:
+			return "HOLD", 0, fmt.Sprintf("%s has no trend", v.Timeframe)
+		}
+	}
+	return base.Direction, confidence / weight, fmt.Sprintf("higher timeframes agree with %s %s", base.Timeframe, base.Direction)
+}
+
+// trendTiming takes the direction of the highest timeframe and enters only
+// when the lowest timeframe points the same way.
+func trendTiming(votes []TimeframeVote) (string, float64, string) {
+	trend, trigger := votes[len(votes)-1], votes[0]
+	if directionSign(trend.Direction) == 0 {
+		return "HOLD", 0, fmt.Sprintf("%s trend has no direction", trend.Timeframe)
+	}
+	if trigger.Direction != trend.Direction {
+		return "HOLD", 0, fmt.Sprintf("%s %s trend, waiting for %s timing", trend.Timeframe, trend.Direction, trigger.Timeframe)
+	}
+	return trend.Direction, (trend.Confidence + trigger.Confidence) / 2,
+		fmt.Sprintf("%s %s trend timed by %s", trend.Timeframe, trend.Direction, trigger.Timeframe)
+}
+
+// ParseConfluenceWeights parses "1m:1,15m:2,1h:3" into per-timeframe weights.
+func ParseConfluenceWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		tf, w, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid confluence weight %q, expected timeframe:weight", item)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(w), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid confluence weight %q: %w", item, err)
+		}
+		weights[strings.TrimSpace(tf)] = weight
+	}
+	return weights, nil
+}
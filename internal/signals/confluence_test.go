@@ -0,0 +1,303 @@
+package signals
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var confluenceNow = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// tfSignal returns a signal of the given direction and confidence issued
+// age ago.
+func tfSignal(direction string, confidence float64, age time.Duration) *Signal {
+	return &Signal{
+		Symbol:     "BTCUSDT",
+		Direction:  direction,
+		Confidence: confidence,
+		Price:      100,
+		Timestamp:  confluenceNow.Add(-age),
+	}
+}
+
+func TestConfluence(t *testing.T) {
+	fresh := time.Minute
+	stale := 3 * time.Hour
+
+	tests := []struct {
+		name           string
+		config         ConfluenceConfig
+		signals        map[string]*Signal
+		wantDirection  string
+		wantConfidence float64
+		wantRule       string
+		wantAgrees     []bool
+	}{
+		{
+			name:   "weighted unanimous",
+			config: ConfluenceConfig{Mode: ConfluenceWeighted},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.8, fresh), "15m": tfSignal("LONG", 0.8, fresh), "1h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "LONG", wantConfidence: 0.8, wantRule: "LONG agreement 100%",
+			wantAgrees: []bool{true, true, true},
+		},
+		{
+			name:   "weighted majority by rank",
+			config: ConfluenceConfig{Mode: ConfluenceWeighted},
+			signals: map[string]*Signal{
+				"1m": tfSignal("SHORT", 0.9, fresh), "15m": tfSignal("LONG", 0.6, fresh), "1h": tfSignal("LONG", 0.6, fresh),
+			},
+			wantDirection: "LONG", wantConfidence: 0.35, wantRule: "LONG agreement 83%",
+			wantAgrees: []bool{false, true, true},
+		},
+		{
+			name:   "weighted below agreement",
+			config: ConfluenceConfig{Mode: ConfluenceWeighted},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.9, fresh), "15m": tfSignal("SHORT", 0.9, fresh), "1h": tfSignal("HOLD", 0.5, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "SHORT agreement 33% below 60%",
+			wantAgrees: []bool{false, false, false},
+		},
+		{
+			name:   "weighted without direction",
+			config: ConfluenceConfig{Mode: ConfluenceWeighted},
+			signals: map[string]*Signal{
+				"1m": tfSignal("HOLD", 0.5, fresh), "1h": tfSignal("HOLD", 0.5, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "no directional votes",
+			wantAgrees: []bool{false, false},
+		},
+		{
+			name:   "weighted custom weights",
+			config: ConfluenceConfig{Mode: ConfluenceWeighted, Weights: map[string]float64{"1m": 5}},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.8, fresh), "1h": tfSignal("SHORT", 0.4, fresh),
+			},
+			wantDirection: "LONG", wantConfidence: 3.2 / 7, wantRule: "LONG agreement 71%",
+			wantAgrees: []bool{true, false},
+		},
+		{
+			name:   "weighted ignores stale timeframes",
+			config: ConfluenceConfig{Mode: ConfluenceWeighted},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.7, fresh), "1h": tfSignal("SHORT", 0.9, stale),
+			},
+			wantDirection: "LONG", wantConfidence: 0.7, wantRule: "LONG agreement 100%",
+			wantAgrees: []bool{true},
+		},
+		{
+			name:   "higher trend agrees",
+			config: ConfluenceConfig{Mode: ConfluenceHigherTrend},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, fresh), "1h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "LONG", wantConfidence: 2.2 / 3, wantRule: "higher timeframes agree with 1m LONG",
+			wantAgrees: []bool{true, true},
+		},
+		{
+			name:   "higher trend opposes",
+			config: ConfluenceConfig{Mode: ConfluenceHigherTrend},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, fresh), "1h": tfSignal("SHORT", 0.8, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "1h SHORT opposes 1m LONG",
+			wantAgrees: []bool{false, false},
+		},
+		{
+			name:   "flat higher trend allowed",
+			config: ConfluenceConfig{Mode: ConfluenceHigherTrend},
+			signals: map[string]*Signal{
+				"1m": tfSignal("SHORT", 0.6, fresh), "1h": tfSignal("HOLD", 0.8, fresh),
+			},
+			wantDirection: "SHORT", wantConfidence: 0.6, wantRule: "higher timeframes agree with 1m SHORT",
+			wantAgrees: []bool{true, false},
+		},
+		{
+			name:   "flat higher trend required",
+			config: ConfluenceConfig{Mode: ConfluenceHigherTrend, RequireTrend: true},
+			signals: map[string]*Signal{
+				"1m": tfSignal("SHORT", 0.6, fresh), "1h": tfSignal("HOLD", 0.8, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "1h has no trend",
+			wantAgrees: []bool{false, false},
+		},
+		{
+			name:   "higher trend without base direction",
+			config: ConfluenceConfig{Mode: ConfluenceHigherTrend},
+			signals: map[string]*Signal{
+				"1m": tfSignal("HOLD", 0.6, fresh), "1h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "1m has no direction",
+			wantAgrees: []bool{false, false},
+		},
+		{
+			name:   "higher trend stale",
+			config: ConfluenceConfig{Mode: ConfluenceHigherTrend},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, fresh), "1h": tfSignal("LONG", 0.8, stale),
+			},
+			wantDirection: "HOLD", wantRule: "1h signal is stale",
+			wantAgrees: []bool{false},
+		},
+		{
+			name:   "timing in trend direction",
+			config: ConfluenceConfig{Mode: ConfluenceTiming},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, fresh), "15m": tfSignal("SHORT", 0.5, fresh), "1h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "LONG", wantConfidence: 0.7, wantRule: "1h LONG trend timed by 1m",
+			wantAgrees: []bool{true, false, true},
+		},
+		{
+			name:   "timing waits for the trigger",
+			config: ConfluenceConfig{Mode: ConfluenceTiming},
+			signals: map[string]*Signal{
+				"1m": tfSignal("SHORT", 0.6, fresh), "1h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "1h LONG trend, waiting for 1m timing",
+			wantAgrees: []bool{false, false},
+		},
+		{
+			name:   "timing without trend",
+			config: ConfluenceConfig{Mode: ConfluenceTiming},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, fresh), "1h": tfSignal("HOLD", 0.8, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "1h trend has no direction",
+			wantAgrees: []bool{false, false},
+		},
+		{
+			name:   "timing ignores a stale middle timeframe",
+			config: ConfluenceConfig{Mode: ConfluenceTiming},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, fresh), "15m": tfSignal("SHORT", 0.5, stale), "4h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "LONG", wantConfidence: 0.7, wantRule: "4h LONG trend timed by 1m",
+			wantAgrees: []bool{true, true},
+		},
+		{
+			name:   "timing with a stale trigger",
+			config: ConfluenceConfig{Mode: ConfluenceTiming},
+			signals: map[string]*Signal{
+				"1m": tfSignal("LONG", 0.6, 5*time.Minute), "1h": tfSignal("LONG", 0.8, fresh),
+			},
+			wantDirection: "HOLD", wantRule: "1m signal is stale",
+			wantAgrees: []bool{false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Confluence("BTCUSDT", tt.signals, &tt.config, confluenceNow)
+			if result == nil {
+				t.Fatal("Confluence() = nil")
+			}
+			if result.Direction != tt.wantDirection || math.Abs(result.Confidence-tt.wantConfidence) > 1e-9 {
+				t.Errorf("direction, confidence = %s, %.4f; want %s, %.4f",
+					result.Direction, result.Confidence, tt.wantDirection, tt.wantConfidence)
+			}
+			if !strings.HasSuffix(result.Explanation.DirectionRule, tt.wantRule) {
+				t.Errorf("DirectionRule = %q, want a rule ending in %q", result.Explanation.DirectionRule, tt.wantRule)
+			}
+			agrees := make([]bool, len(result.Timeframes))
+			for i, vote := range result.Timeframes {
+				agrees[i] = vote.Agrees
+			}
+			if !reflect.DeepEqual(agrees, tt.wantAgrees) {
+				t.Errorf("agrees = %v, want %v", agrees, tt.wantAgrees)
+			}
+			if result.Timeframe != ConfluenceTimeframe || result.Symbol != "BTCUSDT" {
+				t.Errorf("signal = %s %s, want BTCUSDT %s", result.Symbol, result.Timeframe, ConfluenceTimeframe)
+			}
+		})
+	}
+}
+
+func TestConfluenceNoSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ConfluenceConfig
+		signals map[string]*Signal
+	}{
+		{"no timeframes", ConfluenceConfig{Mode: ConfluenceWeighted}, map[string]*Signal{}},
+		{"all stale", ConfluenceConfig{Mode: ConfluenceWeighted}, map[string]*Signal{"1m": tfSignal("LONG", 0.8, time.Hour)}},
+		{"nil signal", ConfluenceConfig{Mode: ConfluenceTiming}, map[string]*Signal{"1m": nil}},
+		{"unknown mode", ConfluenceConfig{Mode: "majority"}, map[string]*Signal{"1m": tfSignal("LONG", 0.8, 0)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := Confluence("BTCUSDT", tt.signals, &tt.config, confluenceNow); result != nil {
+				t.Errorf("Confluence() = %s %.2f, want nil", result.Direction, result.Confidence)
+			}
+		})
+	}
+}
+
+func TestConfluenceConfigValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  ConfluenceConfig
+		wantErr bool
+	}{
+		{"defaults", ConfluenceConfig{Mode: ConfluenceWeighted}, false},
+		{"unknown mode", ConfluenceConfig{Mode: "vote"}, true},
+		{"agreement above 1", ConfluenceConfig{Mode: ConfluenceWeighted, MinAgreement: 1.5}, true},
+		{"zero weight", ConfluenceConfig{Mode: ConfluenceTiming, Weights: map[string]float64{"1h": 0}}, true},
+		{"negative weight", ConfluenceConfig{Mode: ConfluenceTiming, Weights: map[string]float64{"1h": -1}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (tt.config.MinAgreement != 0.6 || tt.config.MaxAgeCandles != 2) {
+				t.Errorf("defaults = %.2f agreement, %.2f candles", tt.config.MinAgreement, tt.config.MaxAgeCandles)
+			}
+		})
+	}
+}
+
+func TestParseConfluenceWeights(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[string]float64
+		wantErr bool
+	}{
+		{"", map[string]float64{}, false},
+		{"1m:1, 15m:2,1h: 3.5", map[string]float64{"1m": 1, "15m": 2, "1h": 3.5}, false},
+		{"1m", nil, true},
+		{"1m:heavy", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseConfluenceWeights(tt.value)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("ParseConfluenceWeights(%q) = %v, %v; want %v, wantErr %v", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTimeframeDuration(t *testing.T) {
+	tests := []struct {
+		timeframe string
+		want      time.Duration
+	}{
+		{"1m", time.Minute},
+		{"15m", 15 * time.Minute},
+		{"4h", 4 * time.Hour},
+		{"1d", 24 * time.Hour},
+		{"1w", 7 * 24 * time.Hour},
+		{"1M", 30 * 24 * time.Hour},
+		{"mtf", 0},
+		{"0h", 0},
+		{"h", 0},
+	}
+	for _, tt := range tests {
+		if got := TimeframeDuration(tt.timeframe); got != tt.want {
+			t.Errorf("TimeframeDuration(%q) = %v, want %v", tt.timeframe, got, tt.want)
+		}
+	}
+}
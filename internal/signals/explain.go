@@ -0,0 +1,124 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// digestContributions is the number of strongest indicator contributions
+// listed in a digest.
+const digestContributions = 3
+
+// ThresholdCrossing is an indicator value beyond one of its signal levels.
+type ThresholdCrossing struct {
+	Indicator string  `json:"indicator"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Crossed   string  `json:"crossed"` // "above" or "below"
+	Reason    string  `json:"reason"`
+}
+
+// SignalExplanation tells why a signal has its direction and confidence.
+type SignalExplanation struct {
+	Model          string              `json:"model"`
+	DirectionRule  string              `json:"directionRule"`  // Rule that set the direction
+	ConfidenceRule string              `json:"confidenceRule"` // How the confidence was derived
+	Notes          []string            `json:"notes"`          // Additional context, e.g. the timeframe breakdown
+	Contributions  []IndicatorScore    `json:"contributions"`  // Directional indicator votes, strongest first
+	Crossings      []ThresholdCrossing `json:"crossings"`
+	Digest         string              `json:"digest"` // One-line summary for notifications
+}
+
+func (e *SignalExplanation) clone() *SignalExplanation {
+	c := *e
+	c.Notes = append([]string(nil), e.Notes...)
+	c.Contributions = append([]IndicatorScore(nil), e.Contributions...)
+	c.Crossings = append([]ThresholdCrossing(nil), e.Crossings...)
+	return &c
+}
+
+// Explain builds the structured explanation of the signal from its
+// indicator scores and fills Reasons with its human-readable lines.
+func (s *Signal) Explain() {
+	if s.Explanation == nil {
+		s.Explanation = &SignalExplanation{Model: s.Model}
+	}
+	e := s.Explanation
+
+	e.Contributions = make([]IndicatorScore, 0, len(s.Indicators))
+	e.Crossings = make([]ThresholdCrossing, 0)
+	for _, ind := range s.Indicators {
+		// Индикаторы, отключённые режимом рынка (вес 0), не голосуют
+		if ind.Type != "BUY" && ind.Type != "SELL" || ind.Weight == 0 {
+			continue
+		}
+		e.Contributions = append(e.Contributions, ind)
+		if ind.Crossed != "" {
+			e.Crossings = append(e.Crossings, ThresholdCrossing{
+				Indicator: ind.Indicator,
+				Value:     ind.Value,
+				Threshold: ind.Threshold,
+				Crossed:   ind.Crossed,
+				Reason:    ind.Reason,
+			})
+		}
+	}
+	sort.SliceStable(e.Contributions, func(i, j int) bool {
+		return math.Abs(e.Contributions[i].Score) > math.Abs(e.Contributions[j].Score)
+	})
+
+	reasons := make([]string, 0, len(e.Notes)+len(e.Contributions)+2)
+	for _, rule := range []string{e.DirectionRule, e.ConfidenceRule} {
+		if rule != "" {
+			reasons = append(reasons, rule)
+		}
+	}
+	reasons = append(reasons, e.Notes...)
+	for _, c := range e.Contributions {
+		reasons = append(reasons, describeContribution(c))
+	}
+	s.Reasons = reasons
+	e.Digest = s.Digest()
+}
+
+// describeContribution formats an indicator vote, e.g.
+// "RSI BUY +0.121: Strong oversold condition (28.40 below 30)".
+func describeContribution(c IndicatorScore) string {
+	text := fmt.Sprintf("%s %s %+.3f", c.Indicator, c.Type, c.Score)
+	if c.Reason != "" {
+		text += ": " + c.Reason
+	}
+	if c.Crossed != "" {
+		text += fmt.Sprintf(" (%.2f %s %g)", c.Value, c.Crossed, c.Threshold)
+	}
+	return text
+}
+
+// Digest returns a one-line summary of the signal for notifications.
+func (s *Signal) Digest() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %s %.0f%%", s.Symbol, s.Timeframe, s.Direction, s.Confidence*100)
+	if s.Price > 0 {
+		fmt.Fprintf(&b, " @ %g", s.Price)
+	}
+
+	if e := s.Explanation; e != nil {
+		if len(e.Contributions) > 0 {
+			parts := make([]string, 0, digestContributions)
+			for i, c := range e.Contributions {
+				if i == digestContributions {
+					parts = append(parts, fmt.Sprintf("+%d more", len(e.Contributions)-i))
+					break
+				}
+				parts = append(parts, describeContribution(c))
+			}
+			b.WriteString(" | " + strings.Join(parts, ", "))
+		}
+		if e.DirectionRule != "" {
+			b.WriteString(" | " + e.DirectionRule)
+		}
+	}
+	return b.String()
+}
@@ -1,9 +1,12 @@
 package signals
 
 import (
+	"sort"
 	"sync"
 	"time"
 
+	"crypto-trading-bot/internal/indicators"
+
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
@@ -23,26 +26,59 @@ type Signal struct {
 	Timestamp      time.Time `json:"timestamp" wails:"-"`
 	Reasons        []string  `json:"reasons"`
 	Model          string    `json:"model"`
+	Indicators     []IndicatorScore `json:"indicators"`
+	Explanation    *SignalExplanation `json:"explanation"` // Why the signal has its direction and confidence
+	Timeframes     []TimeframeVote  `json:"timeframes"` // Per-timeframe breakdown of consolidated signals
+	Outcomes       []SignalOutcome  `json:"outcomes"` // Forward returns, filled in by the OutcomeLabeler
+	Labeled        bool             `json:"labeled"`  // All horizons labeled (or no longer labelable)
+	ValidUntil     int64            `json:"validUntil"`  // Unix ms after which the signal must not be acted on (0 = no expiry)
+	DuplicateOf    string           `json:"duplicateOf"` // ID of the still valid signal with the same direction this one repeats
+	Regime         *indicators.Regime `json:"regime"`    // Market regime the signal was generated in; nil if unknown
+	StopLoss       float64          `json:"stopLoss"`    // Stop-loss price set by the signal source (0 = engine computes it)
+	TakeProfit     float64          `json:"takeProfit"`  // Take-profit price set by the signal source (0 = engine computes it)
 }
 
 type SignalHandler struct {
 	signals     map[string]*Signal
 	prices      map[string]float64
 	mu          sync.RWMutex
-	subscribers []chan *Signal
+	subscribers map[uint64]*Subscription
+	nextSubID   uint64
+	store       *SignalStore
+	ledger      *ConsumerLedger
+	ttlCandles  float64       // Validity in candles of the signal's timeframe
+	ttlFallback time.Duration // Validity of signals without a known timeframe
 }
 
 func NewSignalHandler() *SignalHandler {
 	return &SignalHandler{
 		signals:     make(map[string]*Signal),
 		prices:      make(map[string]float64),
-		subscribers: make([]chan *Signal, 0),
+		subscribers: make(map[uint64]*Subscription),
+		ledger:      NewConsumerLedger(),
+		ttlCandles:  1,
+		ttlFallback: time.Minute,
 	}
 }
 
 func (sh *SignalHandler) UpdateSignal(signal *Signal) {
+	sh.update(signal.Symbol+":"+signal.Timeframe, signal)
+}
+
+// UpdateExternalSignal records an external signal, e.g. a webhook alert,
+// under ExternalSignalKey of its symbol, so that it never replaces the
+// symbol's own signal of a timeframe nor votes in its confluence.
+func (sh *SignalHandler) UpdateExternalSignal(signal *Signal) {
+	sh.update(ExternalSignalKey(signal.Symbol), signal)
+}
+
+// ExternalSignalKey is the key external signals of symbol are kept under.
+func ExternalSignalKey(symbol string) string {
+	return "ext:" + symbol
+}
+
+func (sh *SignalHandler) update(key string, signal *Signal) {
 	sh.mu.Lock()
-	defer sh.mu.Unlock()
 
 	if signal.ID == "" {
 		signal.ID = uuid.New().String()
@@ -51,19 +87,94 @@ func (sh *SignalHandler) UpdateSignal(signal *Signal) {
 		signal.Timestamp = time.Now()
 	}
 
-	key := signal.Symbol + ":" + signal.Timeframe
+	if signal.ValidUntil == 0 {
+		signal.ValidUntil = signal.Timestamp.Add(sh.validity(signal)).UnixMilli()
+	}
+	// Сигнал того же направления, пока предыдущий действителен, - повтор:
+	// по нему не должно быть второго входа
+	if prev, ok := sh.signals[key]; ok && prev != signal && signal.DuplicateOf == "" &&
+		prev.Direction == signal.Direction && signal.Direction != "HOLD" && !prev.Expired(signal.Timestamp) {
+		signal.DuplicateOf = prev.RootID()
+	}
 	sh.signals[key] = signal
+
+	// Копию сохраняем после снятия блокировки: запись в файл не должна
+	// задерживать читателей сигналов
+	store := sh.store
+	var saved *Signal
+	if store != nil {
+		saved = copySignal(signal)
+	}
 	
 	// Log signal update for debugging
 	log.Debugf("SignalHandler: Updated signal %s -> %s:%s %s (confidence=%.2f%%)", 
 		key, signal.Symbol, signal.Timeframe, signal.Direction, signal.Confidence*100)
 
-	for _, ch := range sh.subscribers {
-		select {
-		case ch <- signal:
-		default:
+	subscribers := make([]*Subscription, 0, len(sh.subscribers))
+	for _, sub := range sh.subscribers {
+		subscribers = append(subscribers, sub)
+	}
+	sh.mu.Unlock()
+
+	if store != nil {
+		if err := store.Save(saved); err != nil {
+			log.Errorf("SignalHandler: failed to persist signal %s: %v", saved.ID, err)
 		}
 	}
+
+	// Доставляем без блокировки обработчика: подписчик с политикой block может ждать
+	for _, sub := range subscribers {
+		sub.deliver(signal)
+	}
+}
+
+// validity returns how long a signal stays actionable: ttlCandles candles of
+// its timeframe (the lowest one for consolidated signals).
+func (sh *SignalHandler) validity(signal *Signal) time.Duration {
+	timeframe := signal.Timeframe
+	if timeframe == ConfluenceTimeframe && len(signal.Timeframes) > 0 {
+		timeframe = signal.Timeframes[0].Timeframe
+	}
+	if d := TimeframeDuration(timeframe); d > 0 {
+		return time.Duration(sh.ttlCandles * float64(d))
+	}
+	return sh.ttlFallback
+}
+
+// SetValidity sets how long new signals stay actionable: candles candles of
+// their timeframe, or fallback when the timeframe is unknown.
+func (sh *SignalHandler) SetValidity(candles float64, fallback time.Duration) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	if candles > 0 {
+		sh.ttlCandles = candles
+	}
+	if fallback > 0 {
+		sh.ttlFallback = fallback
+	}
+}
+
+// Acknowledge records that consumer acted on the signal. It returns false
+// if the consumer already acted on it or on a duplicate of it.
+func (sh *SignalHandler) Acknowledge(consumer string, signal *Signal) bool {
+	return sh.ledger.Acknowledge(consumer, signal)
+}
+
+// Release withdraws consumer's acknowledgement of the signal.
+func (sh *SignalHandler) Release(consumer string, signal *Signal) {
+	sh.ledger.Release(consumer, signal)
+}
+
+// IsAcknowledged reports whether consumer already acted on the signal.
+func (sh *SignalHandler) IsAcknowledged(consumer string, signal *Signal) bool {
+	return sh.ledger.IsAcknowledged(consumer, signal)
+}
+
+// SetStore attaches a persistent store; every updated signal is saved to it.
+func (sh *SignalHandler) SetStore(store *SignalStore) {
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	sh.store = store
 }
 
 func (sh *SignalHandler) UpdatePrice(symbol string, price float64) {
@@ -103,13 +214,48 @@ func (sh *SignalHandler) GetCurrentPrice(symbol string) float64 {
 	return sh.prices[symbol]
 }
 
-func (sh *SignalHandler) Subscribe() chan *Signal {
+// Subscribe returns a filtered stream of updated signals. The caller must
+// Close the subscription when it no longer reads from it.
+func (sh *SignalHandler) Subscribe(options SubscriptionOptions) *Subscription {
+	if options.Buffer <= 0 {
+		options.Buffer = 100
+	}
+	if options.Policy != DeliveryBlock {
+		options.Policy = DeliveryDrop
+	}
+
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+
+	sh.nextSubID++
+	sub := &Subscription{
+		id:      sh.nextSubID,
+		options: options,
+		ch:      make(chan *Signal, options.Buffer),
+		done:    make(chan struct{}),
+		handler: sh,
+	}
+	sh.subscribers[sub.id] = sub
+	return sub
+}
+
+func (sh *SignalHandler) unsubscribe(id uint64) {
 	sh.mu.Lock()
 	defer sh.mu.Unlock()
+	delete(sh.subscribers, id)
+}
+
+// GetSubscriptions returns the delivery counters of all subscriptions.
+func (sh *SignalHandler) GetSubscriptions() []SubscriptionStats {
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
 
-	ch := make(chan *Signal, 100)
-	sh.subscribers = append(sh.subscribers, ch)
-	return ch
+	stats := make([]SubscriptionStats, 0, len(sh.subscribers))
+	for _, sub := range sh.subscribers {
+		stats = append(stats, sub.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
 }
 
 func (sh *SignalHandler) GetAllSignals() []*Signal {
@@ -0,0 +1,516 @@
+package signals
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"crypto-trading-bot/internal/indicators"
+
+	"github.com/google/uuid"
+)
+
+// DefaultModelName is the name of the built-in signal model.
+const DefaultModelName = "default"
+
+// Scores the direction of a signal can be taken from.
+const (
+	DirectionFromTechnical = "technical"
+	DirectionFromCombined  = "combined"
+)
+
+// MixWeights weighs the technical, ML and sentiment scores in the combined
+// score.
+type MixWeights struct {
+	Technical float64 `json:"technical"`
+	ML        float64 `json:"ml"`
+	Sentiment float64 `json:"sentiment"`
+}
+
+// ConfidenceMapping turns scores into a signal confidence:
+// max(|directionScore|*Scale, Min), raised to |combined|*CombinedScale when
+// |combined| exceeds CombinedThreshold, capped at Max.
+type ConfidenceMapping struct {
+	Scale             float64 `json:"scale"`
+	Min               float64 `json:"min"`
+	Max               float64 `json:"max"`
+	CombinedScale     float64 `json:"combinedScale"` // 0 disables the combined score boost
+	CombinedThreshold float64 `json:"combinedThreshold"`
+}
+
+// RegimeRule adapts a signal model to a market regime (one of the
+// indicators.Regime* labels).
+type RegimeRule struct {
+	Weights         map[string]float64 `json:"weights"`         // Multiplier of an indicator's weight; 0 disables the indicator
+	ConfidenceScale float64            `json:"confidenceScale"` // Multiplier of the confidence (0 = unchanged)
+	Disable         bool               `json:"disable"`         // The model only holds in this regime
+}
+
+// SignalModel describes how indicator signals and component scores are
+// turned into a trading signal. A model is identified by name and version;
+// once registered it must not be changed, a new version is registered
+// instead.
+type SignalModel struct {
+	Name               string                `json:"name"`
+	Version            string                `json:"version"`
+	Description        string                `json:"description"`
+	IndicatorWeights   map[string]float64    `json:"indicatorWeights"` // Weight per indicator name
+	DefaultWeight      float64               `json:"defaultWeight"`    // Weight of indicators missing from IndicatorWeights
+	Mix                MixWeights            `json:"mix"`
+	Confidence         ConfidenceMapping     `json:"confidence"`
+	DirectionFrom      string                `json:"directionFrom"`      // One of the DirectionFrom* constants
+	DirectionThreshold float64               `json:"directionThreshold"` // Minimum |score| for LONG/SHORT, HOLD below
+	Regimes            map[string]RegimeRule `json:"regimes,omitempty"`  // Adjustments per market regime label; several may apply at once
+}
+
+// DefaultSignalModel returns the built-in scalping model.
+func DefaultSignalModel() *SignalModel {
+	return &SignalModel{
+		Name:        DefaultModelName,
+		Version:     "1",
+		Description: "Scalping: technical analysis first, any indicator signal trades",
+		IndicatorWeights: map[string]float64{
+			"RSI":            1.2,
+			"MACD":           1.5,
+			"BollingerBands": 1.0,
+			"StochRSI":       1.1,
+			"EMA":            1.3,
+			"ADX":            1.4, // Strong trend indicator
+			"CCI":            1.1,
+			"Williams%R":     1.0,
+			"Momentum":       1.2,
+			"OBV":            0.9,
+		},
+		DefaultWeight: 1.0,
+		Mix:           MixWeights{Technical: 0.7, ML: 0.2, Sentiment: 0.1},
+		Confidence: ConfidenceMapping{
+			Scale:             5.0,
+			Min:               0.25, // Любой технический сигнал проходит MinConfidence = 0.3 после буста
+			Max:               1.0,
+			CombinedScale:     10.0,
+			CombinedThreshold: 0.01,
+		},
+		DirectionFrom:      DirectionFromTechnical,
+		DirectionThreshold: 0.0001,
+	}
+}
+
+// ID returns "name@version", the identifier recorded in every signal.
+func (m *SignalModel) ID() string {
+	return m.Name + "@" + m.Version
+}
+
+// Validate fills defaults and checks the model parameters.
+func (m *SignalModel) Validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("signal model name is required")
+	}
+	if m.Version == "" {
+		m.Version = "1"
+	}
+	if m.DirectionFrom == "" {
+		m.DirectionFrom = DirectionFromTechnical
+	}
+	if m.DirectionFrom != DirectionFromTechnical && m.DirectionFrom != DirectionFromCombined {
+		return fmt.Errorf("signal model %s: unknown directionFrom %q", m.ID(), m.DirectionFrom)
+	}
+	if m.Confidence.Max <= 0 {
+		m.Confidence.Max = 1.0
+	}
+	if m.Confidence.Scale <= 0 {
+		return fmt.Errorf("signal model %s: confidence scale must be positive", m.ID())
+	}
+	if m.Confidence.Min < 0 || m.Confidence.Min > m.Confidence.Max {
+		return fmt.Errorf("signal model %s: confidence min must be within [0, max]", m.ID())
+	}
+	if m.DefaultWeight < 0 {
+		return fmt.Errorf("signal model %s: default weight must not be negative", m.ID())
+	}
+	for name, weight := range m.IndicatorWeights {
+		if weight < 0 {
+			return fmt.Errorf("signal model %s: weight of %s must not be negative", m.ID(), name)
+		}
+	}
+	if m.Mix.Technical < 0 || m.Mix.ML < 0 || m.Mix.Sentiment < 0 {
+		return fmt.Errorf("signal model %s: mix weights must not be negative", m.ID())
+	}
+	if m.Mix.Technical+m.Mix.ML+m.Mix.Sentiment == 0 {
+		return fmt.Errorf("signal model %s: at least one mix weight is required", m.ID())
+	}
+	for label, rule := range m.Regimes {
+		if !indicators.IsRegimeLabel(label) {
+			return fmt.Errorf("signal model %s: unknown market regime %q", m.ID(), label)
+		}
+		if rule.ConfidenceScale < 0 {
+			return fmt.Errorf("signal model %s: confidence scale of regime %s must not be negative", m.ID(), label)
+		}
+		for name, multiplier := range rule.Weights {
+			if multiplier < 0 {
+				return fmt.Errorf("signal model %s: weight of %s in regime %s must not be negative", m.ID(), name, label)
+			}
+		}
+	}
+	return nil
+}
+
+// weight returns the weight of an indicator, multiplied by the rules of
+// every label of the regime (nil = no regime adjustments).
+func (m *SignalModel) weight(indicator string, regime *indicators.Regime) float64 {
+	weight, ok := m.IndicatorWeights[indicator]
+	if !ok {
+		weight = m.DefaultWeight
+	}
+	for _, rule := range m.regimeRules(regime) {
+		if multiplier, ok := rule.Weights[indicator]; ok {
+			weight *= multiplier
+		}
+	}
+	return weight
+}
+
+// regimeRules returns the rules of the labels that apply to regime, in
+// label order. A regime that is not ready has no rules.
+func (m *SignalModel) regimeRules(regime *indicators.Regime) map[string]RegimeRule {
+	if regime == nil || !regime.Ready || len(m.Regimes) == 0 {
+		return nil
+	}
+	rules := make(map[string]RegimeRule, 2)
+	for _, label := range regime.Labels() {
+		if rule, ok := m.Regimes[label]; ok {
+			rules[label] = rule
+		}
+	}
+	return rules
+}
+
+// ScoreIndicators computes the technical score together with the weighted
+// contribution of every indicator signal.
+func (m *SignalModel) ScoreIndicators(indicatorSignals []indicators.Signal) (float64, []IndicatorScore) {
+	return m.ScoreIndicatorsInRegime(indicatorSignals, nil)
+}
+
+// ScoreIndicatorsInRegime is ScoreIndicators with the indicator weights
+// adjusted by the model's rules for regime. Indicators whose weight drops
+// to 0 are listed but do not vote.
+func (m *SignalModel) ScoreIndicatorsInRegime(indicatorSignals []indicators.Signal, regime *indicators.Regime) (float64, []IndicatorScore) {
+	scores := make([]IndicatorScore, 0, len(indicatorSignals))
+	if len(indicatorSignals) == 0 {
+		return 0.0, scores
+	}
+
+	var totalScore float64
+	var totalWeight float64
+
+	for _, sig := range indicatorSignals {
+		var score float64
+		switch sig.Type {
+		case "BUY":
+			score = sig.Strength
+		case "SELL":
+			score = -sig.Strength
+		}
+		weight := m.weight(sig.Indicator, regime)
+
+		totalScore += score * weight
+		totalWeight += weight
+
+		scores = append(scores, IndicatorScore{
+			Indicator: sig.Indicator,
+			Type:      sig.Type,
+			Strength:  sig.Strength,
+			Weight:    weight,
+			Score:     score * weight,
+			Reason:    sig.Reason,
+			Value:     sig.Value,
+			Threshold: sig.Threshold,
+			Crossed:   sig.Crossed,
+		})
+	}
+
+	if totalWeight == 0 {
+		return 0.0, scores
+	}
+
+	// Нормируем вклад каждого индикатора так, чтобы сумма равнялась итоговому скору
+	for i := range scores {
+		scores[i].Score /= totalWeight
+	}
+
+	return totalScore / totalWeight, scores
+}
+
+// Combine mixes the component scores into a signal produced by this model.
+func (m *SignalModel) Combine(technicalScore, mlScore, sentimentScore float64) *Signal {
+	combinedScore := technicalScore*m.Mix.Technical +
+		mlScore*m.Mix.ML +
+		sentimentScore*m.Mix.Sentiment
+
+	directionScore := technicalScore
+	if m.DirectionFrom == DirectionFromCombined {
+		directionScore = combinedScore
+	}
+
+	scoreName := "technical score"
+	if m.DirectionFrom == DirectionFromCombined {
+		scoreName = "combined score"
+	}
+
+	direction := "HOLD"
+	confidence := 0.0
+	explanation := &SignalExplanation{
+		Model:          m.ID(),
+		DirectionRule:  fmt.Sprintf("%s %+.4f within ±%g: HOLD", scoreName, directionScore, m.DirectionThreshold),
+		ConfidenceRule: "no direction, confidence 0",
+	}
+	if math.Abs(directionScore) > m.DirectionThreshold {
+		direction = "LONG"
+		if directionScore < 0 {
+			direction = "SHORT"
+		}
+		explanation.DirectionRule = fmt.Sprintf("%s %+.4f beyond ±%g: %s", scoreName, directionScore, m.DirectionThreshold, direction)
+
+		conf := m.Confidence
+		confidence = math.Abs(directionScore) * conf.Scale
+		rule := fmt.Sprintf("|%s| %.4f × %g = %.2f", scoreName, math.Abs(directionScore), conf.Scale, confidence)
+		if confidence < conf.Min {
+			confidence = conf.Min
+			rule += fmt.Sprintf(", raised to floor %.2f", conf.Min)
+		}
+		if conf.CombinedScale > 0 && math.Abs(combinedScore) > conf.CombinedThreshold {
+			if boosted := math.Abs(combinedScore) * conf.CombinedScale; boosted > confidence {
+				confidence = boosted
+				rule += fmt.Sprintf(", raised by |combined score| %.4f × %g = %.2f", math.Abs(combinedScore), conf.CombinedScale, boosted)
+			}
+		}
+		if confidence > conf.Max {
+			confidence = conf.Max
+			rule += fmt.Sprintf(", capped at %.2f", conf.Max)
+		}
+		explanation.ConfidenceRule = rule
+	}
+
+	return &Signal{
+		ID:              uuid.New().String(),
+		Direction:       direction,
+		Confidence:      confidence,
+		TechnicalSignal: technicalScore,
+		MLSignal:        mlScore,
+		SentimentSignal: sentimentScore,
+		Timestamp:       time.Now(),
+		Model:           m.ID(),
+		Explanation:     explanation,
+	}
+}
+
+// Generate scores indicator signals and combines them with the ML and
+// sentiment scores into an explained signal for symbol and timeframe. The
+// model's regime rules are applied for regime (nil = regime unknown).
+func (m *SignalModel) Generate(symbol, timeframe string, price float64, indicatorSignals []indicators.Signal, mlScore, sentimentScore float64, regime *indicators.Regime) *Signal {
+	techScore, scores := m.ScoreIndicatorsInRegime(indicatorSignals, regime)
+	sig := m.Combine(techScore, mlScore, sentimentScore)
+	sig.Symbol = symbol
+	sig.Timeframe = timeframe
+	sig.Price = price
+	sig.Indicators = scores
+	if regime != nil {
+		r := *regime
+		sig.Regime = &r
+		m.applyRegime(sig, regime)
+	}
+	sig.Explain()
+	return sig
+}
+
+// applyRegime applies the confidence scale and disable flags of the
+// regime's rules to a combined signal and notes the adjustments.
+func (m *SignalModel) applyRegime(sig *Signal, regime *indicators.Regime) {
+	e := sig.Explanation
+	e.Notes = append(e.Notes, "regime: "+regime.String())
+
+	rules := m.regimeRules(regime)
+	for _, label := range regime.Labels() {
+		rule, ok := rules[label]
+		if !ok {
+			continue
+		}
+		names := make([]string, 0, len(rule.Weights))
+		for name := range rule.Weights {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if rule.Weights[name] == 0 {
+				e.Notes = append(e.Notes, fmt.Sprintf("%s disabled in %s regime", name, label))
+			} else {
+				e.Notes = append(e.Notes, fmt.Sprintf("%s weight ×%g in %s regime", name, rule.Weights[name], label))
+			}
+		}
+
+		if sig.Direction == "HOLD" {
+			continue
+		}
+		if rule.Disable {
+			e.DirectionRule += fmt.Sprintf("; model disabled in %s regime: HOLD", label)
+			e.ConfidenceRule = "no direction, confidence 0"
+			sig.Direction = "HOLD"
+			sig.Confidence = 0
+			continue
+		}
+		if rule.ConfidenceScale > 0 && rule.ConfidenceScale != 1 {
+			sig.Confidence = math.Min(sig.Confidence*rule.ConfidenceScale, m.Confidence.Max)
+			e.ConfidenceRule += fmt.Sprintf(", ×%g in %s regime = %.2f", rule.ConfidenceScale, label, sig.Confidence)
+		}
+	}
+}
+
+// clone returns a deep copy of the model.
+func (m *SignalModel) clone() SignalModel {
+	c := *m
+	c.IndicatorWeights = make(map[string]float64, len(m.IndicatorWeights))
+	for name, weight := range m.IndicatorWeights {
+		c.IndicatorWeights[name] = weight
+	}
+	if m.Regimes != nil {
+		c.Regimes = make(map[string]RegimeRule, len(m.Regimes))
+		for label, rule := range m.Regimes {
+			weights := make(map[string]float64, len(rule.Weights))
+			for name, multiplier := range rule.Weights {
+				weights[name] = multiplier
+			}
+			rule.Weights = weights
+			c.Regimes[label] = rule
+		}
+	}
+	return c
+}
+
+// LoadSignalModels reads signal models from a JSON file holding either a
+// single model or an array of models.
+func LoadSignalModels(path string) ([]*SignalModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signal models: %w", err)
+	}
+
+	var models []*SignalModel
+	if err := json.Unmarshal(data, &models); err != nil {
+		var model SignalModel
+		if err := json.Unmarshal(data, &model); err != nil {
+			return nil, fmt.Errorf("failed to parse signal models: %w", err)
+		}
+		models = []*SignalModel{&model}
+	}
+
+	for _, model := range models {
+		if err := model.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return models, nil
+}
+
+// ModelRegistry holds the known signal models and the active one.
+type ModelRegistry struct {
+	models map[string]*SignalModel
+	order  []string
+	active *SignalModel
+	mu     sync.RWMutex
+}
+
+// NewModelRegistry creates a registry with the default model active.
+func NewModelRegistry() *ModelRegistry {
+	defaultModel := DefaultSignalModel()
+	return &ModelRegistry{
+		models: map[string]*SignalModel{defaultModel.ID(): defaultModel},
+		order:  []string{defaultModel.ID()},
+		active: defaultModel,
+	}
+}
+
+// Register adds a model. Registering an existing name and version with
+// different parameters is an error: the version must be bumped instead, so
+// that signals recorded under it stay reproducible.
+func (r *ModelRegistry) Register(model *SignalModel) error {
+	if err := model.Validate(); err != nil {
+		return err
+	}
+	registered := model.clone()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := registered.ID()
+	if existing, ok := r.models[id]; ok {
+		if !reflect.DeepEqual(existing.clone(), registered) {
+			return fmt.Errorf("signal model %s is already registered with different parameters", id)
+		}
+		return nil
+	}
+	r.models[id] = &registered
+	r.order = append(r.order, id)
+	return nil
+}
+
+// LoadFile registers every model in a models file.
+func (r *ModelRegistry) LoadFile(path string) error {
+	models, err := LoadSignalModels(path)
+	if err != nil {
+		return err
+	}
+	for _, model := range models {
+		if err := r.Register(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetActive switches the active model. id is "name@version" or a bare
+// name, which selects the most recently registered version of that name.
+func (r *ModelRegistry) SetActive(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	model, ok := r.models[id]
+	if !ok {
+		for i := len(r.order) - 1; i >= 0; i-- {
+			if m := r.models[r.order[i]]; m.Name == id {
+				model, ok = m, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return fmt.Errorf("unknown signal model: %s", id)
+	}
+	r.active = model
+	return nil
+}
+
+// Active returns the active model. The returned model must not be modified.
+func (r *ModelRegistry) Active() *SignalModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.active
+}
+
+// List returns copies of all registered models sorted by name and
+// registration order of versions.
+func (r *ModelRegistry) List() []SignalModel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]SignalModel, 0, len(r.order))
+	for _, id := range r.order {
+		result = append(result, r.models[id].clone())
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
@@ -0,0 +1,409 @@
+package signals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"crypto-trading-bot/internal/binance"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// labelerMaxKlines is the number of most recent candles fetched per
+// symbol and timeframe. Signals older than that window can no longer be
+// labeled.
+const labelerMaxKlines = 1000
+
+// SignalOutcome is the price move a fixed number of candles after a signal.
+type SignalOutcome struct {
+	Horizon           int     `json:"horizon"`           // Candles after the signal candle
+	Close             float64 `json:"close"`             // Close of the horizon candle
+	Return            float64 `json:"return"`            // Close / signal price - 1
+	DirectionalReturn float64 `json:"directionalReturn"` // Return in the signal direction (0 for HOLD)
+	Hit               bool    `json:"hit"`               // DirectionalReturn > 0
+	CloseTime         int64   `json:"closeTime"`         // Unix ms
+}
+
+// KlineSource provides recent klines for the labeler.
+type KlineSource interface {
+	GetKlines(symbol, interval string, limit int) ([]binance.Kline, error)
+}
+
+// OutcomeLabeler periodically fills in forward returns of stored signals
+// once their horizons have elapsed.
+type OutcomeLabeler struct {
+	store    *SignalStore
+	source   KlineSource
+	horizons []int
+	stop     chan struct{}
+	mu       sync.Mutex
+}
+
+// NewOutcomeLabeler creates a labeler for store. horizons are in candles of
+// each signal's timeframe (default 1, 5, 15 and 60).
+func NewOutcomeLabeler(store *SignalStore, source KlineSource, horizons []int) *OutcomeLabeler {
+	valid := make([]int, 0, len(horizons))
+	for _, h := range horizons {
+		if h > 0 {
+			valid = append(valid, h)
+		}
+	}
+	if len(valid) == 0 {
+		valid = []int{1, 5, 15, 60}
+	}
+	sort.Ints(valid)
+
+	return &OutcomeLabeler{
+		store:    store,
+		source:   source,
+		horizons: valid,
+	}
+}
+
+// Horizons returns the labeled horizons in candles.
+func (l *OutcomeLabeler) Horizons() []int {
+	return append([]int(nil), l.horizons...)
+}
+
+// Start labels pending signals every interval until Stop is called.
+func (l *OutcomeLabeler) Start(interval time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stop != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	stop := make(chan struct{})
+	l.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				l.LabelPending()
+			}
+		}
+	}()
+}
+
+// Stop stops periodic labeling.
+func (l *OutcomeLabeler) Stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		l.stop = nil
+	}
+}
+
+// LabelPending labels every signal whose next horizon has elapsed and
+// returns the number of signals updated.
+func (l *OutcomeLabeler) LabelPending() int {
+	pending := l.store.Unlabeled()
+	if len(pending) == 0 {
+		return 0
+	}
+
+	klinesByKey := make(map[string][]binance.Kline)
+	updated := 0
+	now := time.Now().UnixMilli()
+
+	for i := range pending {
+		sig := &pending[i]
+		timeframe := labelTimeframe(sig)
+		if sig.Symbol == "" || TimeframeDuration(timeframe) == 0 || sig.Price <= 0 || (sig.Direction != "LONG" && sig.Direction != "SHORT") {
+			l.finish(sig)
+			continue
+		}
+
+		key := sig.Symbol + ":" + timeframe
+		klines, ok := klinesByKey[key]
+		if !ok {
+			var err error
+			klines, err = l.source.GetKlines(sig.Symbol, timeframe, labelerMaxKlines)
+			if err != nil {
+				log.Warnf("Signal labeler: failed to load klines for %s: %v", key, err)
+			}
+			klinesByKey[key] = klines
+		}
+		if len(klines) == 0 {
+			continue
+		}
+
+		added, done := l.label(sig, klines, now)
+		if len(added) == 0 && !done {
+			continue
+		}
+		if err := l.store.AddOutcomes(sig.ID, added, done); err != nil {
+			log.Errorf("Signal labeler: failed to store outcomes of %s: %v", sig.ID, err)
+			continue
+		}
+		updated++
+	}
+
+	if updated > 0 {
+		log.Debugf("Signal labeler: labeled %d signals", updated)
+	}
+	return updated
+}
+
+// labelTimeframe returns the timeframe whose candles measure a signal's
+// horizons: its own, or the lowest one of a consolidated signal.
+func labelTimeframe(sig *Signal) string {
+	if sig.Timeframe == ConfluenceTimeframe && len(sig.Timeframes) > 0 {
+		return sig.Timeframes[0].Timeframe
+	}
+	return sig.Timeframe
+}
+
+// label computes the outcomes of the horizons that have closed since the
+// last pass. It reports whether labeling of the signal is finished.
+func (l *OutcomeLabeler) label(sig *Signal, klines []binance.Kline, now int64) ([]SignalOutcome, bool) {
+	ts := sig.Timestamp.UnixMilli()
+	if ts < klines[0].OpenTime {
+		// Сигнал старше доступной истории свечей, разметить уже нельзя
+		return nil, true
+	}
+
+	// Свеча, в которой был сгенерирован сигнал
+	entry := sort.Search(len(klines), func(i int) bool { return klines[i].OpenTime > ts }) - 1
+
+	labeled := make(map[int]bool, len(sig.Outcomes))
+	for _, o := range sig.Outcomes {
+		labeled[o.Horizon] = true
+	}
+
+	var added []SignalOutcome
+	for _, h := range l.horizons {
+		if labeled[h] {
+			continue
+		}
+		idx := entry + h
+		if idx >= len(klines) || klines[idx].CloseTime > now {
+			break
+		}
+		added = append(added, newOutcome(sig, h, klines[idx]))
+	}
+
+	return added, len(sig.Outcomes)+len(added) >= len(l.horizons)
+}
+
+func (l *OutcomeLabeler) finish(sig *Signal) {
+	if err := l.store.AddOutcomes(sig.ID, nil, true); err != nil {
+		log.Errorf("Signal labeler: failed to store outcomes of %s: %v", sig.ID, err)
+	}
+}
+
+func newOutcome(sig *Signal, horizon int, kline binance.Kline) SignalOutcome {
+	ret := kline.Close/sig.Price - 1
+	directional := 0.0
+	switch sig.Direction {
+	case "LONG":
+		directional = ret
+	case "SHORT":
+		directional = -ret
+	}
+	return SignalOutcome{
+		Horizon:           horizon,
+		Close:             kline.Close,
+		Return:            ret,
+		DirectionalReturn: directional,
+		Hit:               directional > 0,
+		CloseTime:         kline.CloseTime,
+	}
+}
+
+// HorizonStats summarizes outcomes at one horizon.
+type HorizonStats struct {
+	Horizon   int     `json:"horizon"`
+	Count     int     `json:"count"`
+	Hits      int     `json:"hits"`
+	HitRate   float64 `json:"hitRate"`
+	AvgReturn float64 `json:"avgReturn"` // Mean directional return
+	StdDev    float64 `json:"stdDev"`
+	TStat     float64 `json:"tStat"` // AvgReturn / standard error; |t| > 2 suggests a real edge
+}
+
+// EdgeGroup holds horizon statistics of one group of signals.
+type EdgeGroup struct {
+	Key      string         `json:"key"`
+	Signals  int            `json:"signals"`
+	Horizons []HorizonStats `json:"horizons"`
+}
+
+// EdgeReport measures whether signals predict forward returns.
+type EdgeReport struct {
+	Horizons     []int       `json:"horizons"`
+	Signals      int         `json:"signals"` // Signals in the period
+	Labeled      int         `json:"labeled"` // Signals with at least one outcome
+	Overall      EdgeGroup   `json:"overall"` // LONG and SHORT signals
+	ByDirection  []EdgeGroup `json:"byDirection"`
+	ByConfidence []EdgeGroup `json:"byConfidence"`
+	ByIndicator  []EdgeGroup `json:"byIndicator"` // Each indicator judged by its own BUY/SELL vote
+	ByModel      []EdgeGroup `json:"byModel"`
+	ByRegime     []EdgeGroup `json:"byRegime"` // Market regime at signal time, e.g. "trend/high_vol"
+}
+
+type edgeAccumulator struct {
+	signals int
+	returns map[int][]float64
+}
+
+func (a *edgeAccumulator) add(horizon int, ret float64) {
+	a.returns[horizon] = append(a.returns[horizon], ret)
+}
+
+func (a *edgeAccumulator) group(key string, horizons []int) EdgeGroup {
+	g := EdgeGroup{Key: key, Signals: a.signals, Horizons: make([]HorizonStats, 0, len(horizons))}
+	for _, h := range horizons {
+		g.Horizons = append(g.Horizons, horizonStats(h, a.returns[h]))
+	}
+	return g
+}
+
+func horizonStats(horizon int, returns []float64) HorizonStats {
+	stats := HorizonStats{Horizon: horizon, Count: len(returns)}
+	if len(returns) == 0 {
+		return stats
+	}
+	var sum float64
+	for _, r := range returns {
+		sum += r
+		if r > 0 {
+			stats.Hits++
+		}
+	}
+	n := float64(len(returns))
+	stats.HitRate = float64(stats.Hits) / n
+	stats.AvgReturn = sum / n
+	if len(returns) > 1 {
+		var ss float64
+		for _, r := range returns {
+			ss += (r - stats.AvgReturn) * (r - stats.AvgReturn)
+		}
+		stats.StdDev = math.Sqrt(ss / (n - 1))
+		if stats.StdDev > 0 {
+			stats.TStat = stats.AvgReturn / (stats.StdDev / math.Sqrt(n))
+		}
+	}
+	return stats
+}
+
+// confidenceBucket returns the 0.2-wide confidence range of c.
+func confidenceBucket(c float64) string {
+	lo := math.Min(math.Floor(c*5), 4) / 5
+	return fmt.Sprintf("%.1f-%.1f", lo, lo+0.2)
+}
+
+// AnalyzeEdge computes hit rate and average directional return of labeled
+// signals by direction, confidence bucket, indicator, model and market regime.
+func AnalyzeEdge(sigs []Signal, horizons []int) EdgeReport {
+	report := EdgeReport{
+		Horizons:     horizons,
+		Signals:      len(sigs),
+		ByDirection:  make([]EdgeGroup, 0),
+		ByConfidence: make([]EdgeGroup, 0),
+		ByIndicator:  make([]EdgeGroup, 0),
+		ByModel:      make([]EdgeGroup, 0),
+		ByRegime:     make([]EdgeGroup, 0),
+	}
+
+	newAcc := func() *edgeAccumulator {
+		return &edgeAccumulator{returns: make(map[int][]float64)}
+	}
+	overall := newAcc()
+	byDirection := make(map[string]*edgeAccumulator)
+	byConfidence := make(map[string]*edgeAccumulator)
+	byIndicator := make(map[string]*edgeAccumulator)
+	byModel := make(map[string]*edgeAccumulator)
+	byRegime := make(map[string]*edgeAccumulator)
+	get := func(m map[string]*edgeAccumulator, key string) *edgeAccumulator {
+		if m[key] == nil {
+			m[key] = newAcc()
+		}
+		return m[key]
+	}
+
+	for _, sig := range sigs {
+		if len(sig.Outcomes) == 0 {
+			continue
+		}
+		report.Labeled++
+
+		// Голоса отдельных индикаторов оцениваем по их собственному направлению
+		for _, ind := range sig.Indicators {
+			sign := 0.0
+			switch ind.Type {
+			case "BUY":
+				sign = 1
+			case "SELL":
+				sign = -1
+			}
+			if sign == 0 {
+				continue
+			}
+			acc := get(byIndicator, ind.Indicator)
+			acc.signals++
+			for _, o := range sig.Outcomes {
+				acc.add(o.Horizon, sign*o.Return)
+			}
+		}
+
+		if sig.Direction != "LONG" && sig.Direction != "SHORT" {
+			continue
+		}
+		model := sig.Model
+		if model == "" {
+			model = "unknown"
+		}
+		regime := "unknown"
+		if sig.Regime != nil {
+			regime = sig.Regime.Name()
+		}
+		accs := []*edgeAccumulator{
+			overall,
+			get(byDirection, sig.Direction),
+			get(byConfidence, confidenceBucket(sig.Confidence)),
+			get(byModel, model),
+			get(byRegime, regime),
+		}
+		for _, acc := range accs {
+			acc.signals++
+			for _, o := range sig.Outcomes {
+				acc.add(o.Horizon, o.DirectionalReturn)
+			}
+		}
+	}
+
+	report.Overall = overall.group("all", horizons)
+	groups := func(m map[string]*edgeAccumulator) []EdgeGroup {
+		keys := make([]string, 0, len(m))
+		for key := range m {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		result := make([]EdgeGroup, 0, len(keys))
+		for _, key := range keys {
+			result = append(result, m[key].group(key, horizons))
+		}
+		return result
+	}
+	report.ByDirection = groups(byDirection)
+	report.ByConfidence = groups(byConfidence)
+	report.ByIndicator = groups(byIndicator)
+	report.ByModel = groups(byModel)
+	report.ByRegime = groups(byRegime)
+	return report
+}
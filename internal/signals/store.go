@@ -0,0 +1,298 @@
+package signals
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SignalStore persists every generated signal, with all component scores,
+// to an append-only JSON lines file and keeps the most recent ones in memory.
+type SignalStore struct {
+	path        string
+	file        *os.File
+	signals     []*Signal
+	byID        map[string]*Signal
+	maxInMemory int
+	mu          sync.RWMutex
+}
+
+// NewSignalStore opens (or creates) the store at path and loads the most
+// recent maxInMemory signals. An empty path keeps signals in memory only.
+func NewSignalStore(path string, maxInMemory int) (*SignalStore, error) {
+	if maxInMemory <= 0 {
+		maxInMemory = 100000
+	}
+
+	store := &SignalStore{
+		path:        path,
+		signals:     make([]*Signal, 0),
+		byID:        make(map[string]*Signal),
+		maxInMemory: maxInMemory,
+	}
+
+	if path == "" {
+		return store, nil
+	}
+
+	superseded, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	// Записи разметки и вытесненные сигналы не нужны после загрузки
+	if superseded > 0 {
+		if err := store.compact(); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create signal store directory: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open signal store: %w", err)
+	}
+	store.file = file
+
+	log.Infof("Signal store opened: %s (%d signals loaded)", path, len(store.signals))
+	return store, nil
+}
+
+// load reads the file and returns the number of records that a compacted
+// file would not contain.
+func (s *SignalStore) load() (int, error) {
+	file, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read signal store: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	superseded := 0
+	for scanner.Scan() {
+		var record outcomeRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err == nil && record.OutcomeOf != "" {
+			if existing, ok := s.byID[record.OutcomeOf]; ok {
+				existing.Outcomes = append(existing.Outcomes, record.Outcomes...)
+				existing.Labeled = existing.Labeled || record.Labeled
+			}
+			superseded++
+			continue
+		}
+
+		var sig Signal
+		if err := json.Unmarshal(scanner.Bytes(), &sig); err != nil {
+			log.Warnf("Signal store: skipping malformed record: %v", err)
+			continue
+		}
+		// Повторная запись сигнала (файлы старого формата) заменяет предыдущую
+		if existing, ok := s.byID[sig.ID]; ok {
+			*existing = sig
+			superseded++
+			continue
+		}
+		if s.add(&sig) {
+			superseded++
+		}
+	}
+	return superseded, scanner.Err()
+}
+
+// compact rewrites the file with one record per signal kept in memory,
+// dropping outcome records and signals evicted by maxInMemory.
+func (s *SignalStore) compact() error {
+	tmpPath := s.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to compact signal store: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	for _, sig := range s.signals {
+		data, err := json.Marshal(sig)
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to compact signal store: %w", err)
+		}
+		writer.Write(append(data, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact signal store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to compact signal store: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to compact signal store: %w", err)
+	}
+	log.Infof("Signal store compacted: %s (%d signals)", s.path, len(s.signals))
+	return nil
+}
+
+// add keeps a signal in memory and reports whether an older one was
+// evicted. The caller must hold the write lock.
+func (s *SignalStore) add(sig *Signal) bool {
+	s.signals = append(s.signals, sig)
+	s.byID[sig.ID] = sig
+
+	if len(s.signals) <= s.maxInMemory {
+		return false
+	}
+	evicted := s.signals[0]
+	s.signals = s.signals[1:]
+	if s.byID[evicted.ID] == evicted {
+		delete(s.byID, evicted.ID)
+	}
+	return true
+}
+
+// Save appends a copy of the signal to the store. HOLD signals have no
+// direction whose forward return could be measured, so they are stored as
+// already labeled.
+func (s *SignalStore) Save(signal *Signal) error {
+	sig := copySignal(signal)
+	if sig.Direction != "LONG" && sig.Direction != "SHORT" {
+		sig.Labeled = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.add(sig)
+	return s.write(sig)
+}
+
+// outcomeRecord is the file record of outcomes added to a stored signal;
+// on load they are appended to the signal's outcomes.
+type outcomeRecord struct {
+	OutcomeOf string          `json:"outcomeOf"`
+	Outcomes  []SignalOutcome `json:"outcomes,omitempty"`
+	Labeled   bool            `json:"labeled,omitempty"`
+}
+
+// write appends a record to the file. The caller must hold the write lock.
+func (s *SignalStore) write(record any) error {
+	if s.file == nil {
+		return nil
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = s.file.Write(append(data, '\n'))
+	return err
+}
+
+// AddOutcomes adds forward-return outcomes to a stored signal and appends
+// only the added outcomes to the file. labeled marks the signal as finished
+// for the labeler.
+func (s *SignalStore) AddOutcomes(id string, outcomes []SignalOutcome, labeled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sig, ok := s.byID[id]
+	if !ok {
+		return fmt.Errorf("signal not found: %s", id)
+	}
+	sig.Outcomes = append(sig.Outcomes, outcomes...)
+	sig.Labeled = labeled
+	return s.write(outcomeRecord{OutcomeOf: id, Outcomes: outcomes, Labeled: labeled})
+}
+
+// Unlabeled returns copies of signals still waiting for outcome labels,
+// oldest first.
+func (s *SignalStore) Unlabeled() []Signal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Signal, 0)
+	for _, sig := range s.signals {
+		if !sig.Labeled {
+			result = append(result, *copySignal(sig))
+		}
+	}
+	return result
+}
+
+func copySignal(signal *Signal) *Signal {
+	sig := *signal
+	sig.Reasons = append([]string(nil), signal.Reasons...)
+	sig.Indicators = append([]IndicatorScore(nil), signal.Indicators...)
+	sig.Timeframes = append([]TimeframeVote(nil), signal.Timeframes...)
+	sig.Outcomes = append([]SignalOutcome(nil), signal.Outcomes...)
+	if signal.Explanation != nil {
+		sig.Explanation = signal.Explanation.clone()
+	}
+	if signal.Regime != nil {
+		regime := *signal.Regime
+		sig.Regime = &regime
+	}
+	return &sig
+}
+
+// Get returns a stored signal by ID.
+func (s *SignalStore) Get(id string) *Signal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if sig, ok := s.byID[id]; ok {
+		copy := *sig
+		return &copy
+	}
+	return nil
+}
+
+// Query returns stored signals for a symbol ("" = all) within [from, to]
+// (zero = unbounded), newest first, at most limit entries (0 = no limit).
+func (s *SignalStore) Query(symbol string, from, to time.Time, limit int) []Signal {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]Signal, 0)
+	for i := len(s.signals) - 1; i >= 0; i-- {
+		sig := s.signals[i]
+		if symbol != "" && sig.Symbol != symbol {
+			continue
+		}
+		if !from.IsZero() && sig.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && sig.Timestamp.After(to) {
+			continue
+		}
+		result = append(result, *sig)
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// Close closes the underlying file.
+func (s *SignalStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
@@ -0,0 +1,150 @@
+package signals
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Delivery policies accepted in SubscriptionOptions.Policy.
+const (
+	DeliveryDrop  = "drop"  // Drop signals while the subscriber's buffer is full
+	DeliveryBlock = "block" // Wait for the subscriber, up to BlockTimeout
+)
+
+// SubscriptionFilter selects the signals delivered to a subscriber. Empty
+// fields match everything.
+type SubscriptionFilter struct {
+	Symbols       []string `json:"symbols"`
+	Timeframes    []string `json:"timeframes"`
+	Directions    []string `json:"directions"` // "LONG", "SHORT", "HOLD"
+	MinConfidence float64  `json:"minConfidence"`
+}
+
+// Matches reports whether the signal passes the filter.
+func (f SubscriptionFilter) Matches(sig *Signal) bool {
+	return matchesAny(f.Symbols, sig.Symbol) &&
+		matchesAny(f.Timeframes, sig.Timeframe) &&
+		matchesAny(f.Directions, sig.Direction) &&
+		sig.Confidence >= f.MinConfidence
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionOptions configures a subscription.
+type SubscriptionOptions struct {
+	Name         string             // Shown in subscription stats
+	Filter       SubscriptionFilter // Signals to deliver
+	Buffer       int                // Channel capacity (default 100)
+	Policy       string             // DeliveryDrop (default) or DeliveryBlock
+	BlockTimeout time.Duration      // DeliveryBlock: max wait per signal before dropping it (0 = wait until delivered or closed)
+}
+
+// SubscriptionStats reports the delivery counters of a subscription.
+type SubscriptionStats struct {
+	ID        uint64 `json:"id"`
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	Delivered int64  `json:"delivered"`
+	Dropped   int64  `json:"dropped"`
+	Buffered  int    `json:"buffered"`
+}
+
+// Subscription is a filtered stream of signals. Close it when done; the
+// channel is closed afterwards.
+type Subscription struct {
+	id        uint64
+	options   SubscriptionOptions
+	ch        chan *Signal
+	done      chan struct{}
+	handler   *SignalHandler
+	delivered atomic.Int64
+	dropped   atomic.Int64
+	closed    bool
+	closeOnce sync.Once
+	mu        sync.RWMutex // Held for reading while sending, for writing while closing the channel
+}
+
+// C returns the channel signals are delivered on.
+func (s *Subscription) C() <-chan *Signal {
+	return s.ch
+}
+
+// Dropped returns the number of signals the subscriber missed.
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Stats returns the subscription's delivery counters.
+func (s *Subscription) Stats() SubscriptionStats {
+	return SubscriptionStats{
+		ID:        s.id,
+		Name:      s.options.Name,
+		Policy:    s.options.Policy,
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Buffered:  len(s.ch),
+	}
+}
+
+// Close unsubscribes and closes the channel. It is safe to call more than
+// once.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		// Сначала будим заблокированного отправителя, потом закрываем канал
+		close(s.done)
+		s.handler.unsubscribe(s.id)
+
+		s.mu.Lock()
+		s.closed = true
+		close(s.ch)
+		s.mu.Unlock()
+	})
+}
+
+// deliver sends a matching signal according to the delivery policy.
+func (s *Subscription) deliver(sig *Signal) {
+	if !s.options.Filter.Matches(sig) {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+
+	if s.options.Policy != DeliveryBlock {
+		select {
+		case s.ch <- sig:
+			s.delivered.Add(1)
+		default:
+			s.dropped.Add(1)
+		}
+		return
+	}
+
+	var timeout <-chan time.Time
+	if s.options.BlockTimeout > 0 {
+		timer := time.NewTimer(s.options.BlockTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case s.ch <- sig:
+		s.delivered.Add(1)
+	case <-timeout:
+		s.dropped.Add(1)
+	case <-s.done:
+	}
+}
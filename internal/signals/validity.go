@@ -0,0 +1,77 @@
+package signals
+
+import (
+	"sync"
+	"time"
+)
+
+// ackRetention is how long acknowledgements are remembered. A signal chain
+// never outlives it, so older acknowledgements can be dropped.
+const ackRetention = 24 * time.Hour
+
+// Expired reports whether the signal's validity window has passed.
+func (s *Signal) Expired(now time.Time) bool {
+	return s.ValidUntil > 0 && now.UnixMilli() > s.ValidUntil
+}
+
+// RootID identifies the actionable signal a duplicate repeats; for an
+// original signal it is its own ID. Consumers acknowledge signals by it.
+func (s *Signal) RootID() string {
+	if s.DuplicateOf != "" {
+		return s.DuplicateOf
+	}
+	return s.ID
+}
+
+// ConsumerLedger remembers which signals each consumer has acted on.
+type ConsumerLedger struct {
+	acks map[string]map[string]time.Time // consumer -> root signal ID -> acknowledged at
+	mu   sync.Mutex
+}
+
+// NewConsumerLedger creates an empty ledger.
+func NewConsumerLedger() *ConsumerLedger {
+	return &ConsumerLedger{acks: make(map[string]map[string]time.Time)}
+}
+
+// Acknowledge marks the signal (and all its duplicates) as consumed by
+// consumer. It returns false if the consumer already consumed it.
+func (l *ConsumerLedger) Acknowledge(consumer string, sig *Signal) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	acks, ok := l.acks[consumer]
+	if !ok {
+		acks = make(map[string]time.Time)
+		l.acks[consumer] = acks
+	}
+	for id, at := range acks {
+		if now.Sub(at) > ackRetention {
+			delete(acks, id)
+		}
+	}
+
+	root := sig.RootID()
+	if _, done := acks[root]; done {
+		return false
+	}
+	acks[root] = now
+	return true
+}
+
+// Release withdraws the consumer's acknowledgement of the signal, e.g. when
+// the entry it claimed the signal for failed.
+func (l *ConsumerLedger) Release(consumer string, sig *Signal) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.acks[consumer], sig.RootID())
+}
+
+// IsAcknowledged reports whether consumer already consumed the signal.
+func (l *ConsumerLedger) IsAcknowledged(consumer string, sig *Signal) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, done := l.acks[consumer][sig.RootID()]
+	return done
+}
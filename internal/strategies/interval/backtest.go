@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"crypto-trading-bot/internal/binance"
+	"crypto-trading-bot/internal/performance"
+	"crypto-trading-bot/internal/risk"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -21,6 +23,7 @@ type BacktestResult struct {
 	MaxDrawdown         float64        `json:"maxDrawdown"`
 	BestSymbol          string         `json:"bestSymbol"`
 	WorstSymbol         string         `json:"worstSymbol"`
+	Performance         performance.Report `json:"performance"`
 }
 
 type Backtester struct {
@@ -83,28 +86,42 @@ func (b *Backtester) Run(symbol string, startDate, endDate time.Time) (*Backtest
 
 	var balance float64 = 10000.0 // Начальный баланс для бэктеста
 	var position *Position
+	sizer := newPositionSizer(b.config)
+	trades := make([]performance.TradeRecord, 0)
+	equity := make([]performance.EquitySample, 0, len(testKlines))
 
 	for _, candle := range testKlines {
 		price := candle.Close
+		candleTime := time.Unix(candle.CloseTime/1000, 0)
 
 		if position == nil {
 			// Проверяем покупку
 			if price <= interval.Lower*1.001 { // 0.1% допуск
 				// Открываем позицию
-				quantity := b.config.PreferredPositionPrice / price
 				stopLoss := price * (1 - b.config.StopLossPercent/100)
-
-				position = &Position{
+				quantity := sizer.Size(risk.SizingRequest{
 					Symbol:     symbol,
-					Side:       "BUY",
+					Equity:     balance,
+					Available:  balance,
 					EntryPrice: price,
-					Quantity:   quantity,
 					StopLoss:   stopLoss,
-					TakeProfit: interval.Upper,
-					OpenedAt:   time.Unix(candle.CloseTime/1000, 0),
+					Volatility: interval.Width / 100,
+				})
+				if quantity <= 0 {
+					log.Debugf("Sizer returned zero quantity at %.2f, skipping entry", price)
+				} else {
+					position = &Position{
+						Symbol:     symbol,
+						Side:       "BUY",
+						EntryPrice: price,
+						Quantity:   quantity,
+						StopLoss:   stopLoss,
+						TakeProfit: interval.Upper,
+						OpenedAt:   time.Unix(candle.CloseTime/1000, 0),
+					}
+
+					balance -= price * quantity
 				}
-
-				balance -= price * quantity
 			}
 		} else {
 			// Проверяем продажу или stop-loss
@@ -133,12 +150,31 @@ func (b *Backtester) Run(symbol string, startDate, endDate time.Time) (*Backtest
 					result.TotalProfit += profit
 				}
 
+				if recorder, ok := sizer.(risk.TradeRecorder); ok {
+					recorder.RecordTrade((price - position.EntryPrice) / position.EntryPrice * 100)
+				}
+
+				trades = append(trades, performance.TradeRecord{
+					Symbol:     symbol,
+					PnL:        profit,
+					PnLPercent: (price - position.EntryPrice) / position.EntryPrice * 100,
+					OpenedAt:   position.OpenedAt,
+					ClosedAt:   candleTime,
+				})
+
 				log.Debugf("Trade closed: %s, Entry: %.2f, Exit: %.2f, Profit: %.2f",
 					reason, position.EntryPrice, price, profit)
 
 				position = nil
 			}
 		}
+
+		// Оцениваем капитал по рынку на закрытии каждой свечи
+		markedEquity := balance
+		if position != nil {
+			markedEquity += price * position.Quantity
+		}
+		equity = append(equity, performance.EquitySample{Time: candleTime, Equity: markedEquity})
 	}
 
 	// Рассчитываем итоговые метрики
@@ -147,6 +183,9 @@ func (b *Backtester) Run(symbol string, startDate, endDate time.Time) (*Backtest
 		result.AverageDayProfit = result.TotalProfitPercent / float64(days)
 	}
 
+	result.Performance = performance.Analyze(trades, equity, performance.Config{InitialCapital: 10000.0})
+	result.MaxDrawdown = result.Performance.MaxDrawdown
+
 	log.Infof("Backtest completed: Total trades: %d, Win rate: %.2f%%, Total profit: %.2f%%",
 		result.TotalTrades,
 		float64(result.WinningTrades)/float64(result.TotalTrades)*100,
@@ -0,0 +1,73 @@
+package interval
+
+import (
+	"fmt"
+	"time"
+
+	"crypto-trading-bot/internal/indicators"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// regimeRefresh is how often the market regime of a symbol is recomputed.
+const regimeRefresh = time.Minute
+
+// regimeCandles is the number of candles the regime is computed from.
+const regimeCandles = 200
+
+// symbolRegime is the cached market regime of one symbol.
+type symbolRegime struct {
+	regime  indicators.Regime
+	updated time.Time
+}
+
+// currentRegime returns the market regime of symbol on the strategy
+// timeframe, recomputed from klines at most once per regimeRefresh.
+func (s *IntervalStrategy) currentRegime(symbol string) (indicators.Regime, error) {
+	s.mu.RLock()
+	cached := s.regimes[symbol]
+	s.mu.RUnlock()
+	if time.Since(cached.updated) < regimeRefresh {
+		return cached.regime, nil
+	}
+
+	timeframe := s.config.Timeframe
+	if timeframe == "" {
+		timeframe = "1m"
+	}
+	klines, err := s.binanceClient.GetKlines(symbol, timeframe, regimeCandles)
+	if err != nil {
+		return cached.regime, fmt.Errorf("failed to get klines for %s regime: %w", symbol, err)
+	}
+
+	set := indicators.NewIndicatorSet()
+	for _, k := range klines {
+		set.UpdateAll(k.High, k.Low, k.Close, k.Volume)
+	}
+	regime := set.Regime()
+
+	s.mu.Lock()
+	s.regimes[symbol] = symbolRegime{regime: regime, updated: time.Now()}
+	s.stats.Regimes[symbol] = regime
+	s.mu.Unlock()
+	return regime, nil
+}
+
+// regimePermitsEntry reports whether the configured regime gate allows a
+// new position in symbol. Without a gate, or when the regime cannot be
+// computed, entries are allowed.
+func (s *IntervalStrategy) regimePermitsEntry(symbol string) bool {
+	if s.config.RegimeGate == nil {
+		return true
+	}
+	regime, err := s.currentRegime(symbol)
+	if err != nil {
+		log.Warnf("Interval strategy: %v", err)
+		return true
+	}
+	ok, reason := s.config.RegimeGate.Permits(regime)
+	if !ok {
+		log.Infof("⏸ Interval entries for %s paused: %s", symbol, reason)
+	}
+	return ok
+}
@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"crypto-trading-bot/internal/binance"
+	"crypto-trading-bot/internal/indicators"
+	"crypto-trading-bot/internal/risk"
 	"crypto-trading-bot/internal/trading"
 
 	log "github.com/sirupsen/logrus"
@@ -22,10 +24,12 @@ type IntervalStrategy struct {
 	analyzer      *IntervalAnalyzer         // Price interval analyzer
 	tradingEngine *trading.TradingEngine    // Trading execution engine
 	binanceClient *binance.Client          // Binance API client
+	sizer         risk.PositionSizer       // Position sizing model
 
 	// State
 	activeIntervals   map[string]PriceInterval // Active price intervals by symbol
 	lastRecalculation time.Time                // Last interval recalculation time
+	regimes           map[string]symbolRegime  // Market regime by symbol
 
 	// Statistics
 	stats IntervalStats // Trading statistics
@@ -49,14 +53,30 @@ func NewIntervalStrategy(
 		analyzer:        analyzer,
 		tradingEngine:   tradingEngine,
 		binanceClient:   binanceClient,
+		sizer:           newPositionSizer(config),
 		activeIntervals: make(map[string]PriceInterval),
+		regimes:         make(map[string]symbolRegime),
 		stats: IntervalStats{
 			ActiveIntervals: make(map[string]PriceInterval),
+			Regimes:         make(map[string]indicators.Regime),
 		},
 		stopChan: make(chan struct{}),
 	}
 }
 
+// newPositionSizer builds the configured sizer. Without a sizing config, or
+// with an invalid one, positions are sized at PreferredPositionPrice.
+func newPositionSizer(config *IntervalConfig) risk.PositionSizer {
+	if config.Sizing != nil {
+		sizer, err := risk.NewPositionSizer(config.Sizing)
+		if err == nil {
+			return sizer
+		}
+		log.Errorf("Invalid interval sizing config, using preferred position price: %v", err)
+	}
+	return &risk.FixedNotionalSizer{Notional: config.PreferredPositionPrice, MaxPositionSize: 0}
+}
+
 // Start begins the interval strategy execution.
 // It performs initial interval calculation and starts the main trading loop
 // that checks for buy/sell signals every 5 seconds.
@@ -79,6 +99,9 @@ func (s *IntervalStrategy) Start(ctx context.Context) error {
 	}
 	log.Info("Initial recalculation completed successfully")
 
+	// Лимит позиций стратегии проверяет цепочка риск-правил движка
+	s.tradingEngine.SetStrategyPositionLimit(trading.StrategyInterval, s.config.MaxPositionsCount)
+
 	// Теперь блокируем мьютекс только для установки флага и запуска горутины
 	s.mu.Lock()
 	s.isRunning = true
@@ -109,6 +132,7 @@ func (s *IntervalStrategy) Stop() {
 	}
 	s.isRunning = false
 	s.stopChan = make(chan struct{}) // Пересоздаем для следующего запуска
+	s.tradingEngine.SetStrategyPositionLimit(trading.StrategyInterval, 0)
 	log.Info("Interval Strategy stopped")
 }
 
@@ -224,6 +248,8 @@ func (s *IntervalStrategy) checkSignals() {
 	}
 
 	currentPrice := ticker.LastPrice
+	s.tradingEngine.UpdatePrice(symbol, currentPrice)
+	s.tradingEngine.ProcessExits(symbol, currentPrice)
 	log.Infof("🔍 Checking signals for %s: Price=%.8f, Interval=[%.8f - %.8f]",
 		symbol, currentPrice, interval.Lower, interval.Upper)
 
@@ -240,7 +266,7 @@ func (s *IntervalStrategy) checkSignals() {
 	if position == nil {
 		// Нет открытой позиции - проверяем сигнал на покупку
 		log.Infof("📊 No open position for %s, checking buy signal...", symbol)
-		if s.shouldBuy(currentPrice, interval) {
+		if s.shouldBuy(currentPrice, interval) && s.regimePermitsEntry(symbol) {
 			log.Infof("🚀 BUY SIGNAL CONFIRMED! Executing buy for %s", symbol)
 			s.executeBuy(symbol, currentPrice, interval)
 		} else {
@@ -309,12 +335,9 @@ func (s *IntervalStrategy) shouldSell(price float64, interval PriceInterval, pos
 
 // Выполнение покупки
 func (s *IntervalStrategy) executeBuy(symbol string, price float64, interval PriceInterval) {
-	// Проверяем лимит позиций
+	// Лимит MaxPositionsCount проверяет цепочка риск-правил движка (общий лимит
+	// позиций движка - RISK_MAX_OPEN_POSITIONS), отказ попадает в журнал решений
 	positions := s.tradingEngine.GetPositions()
-	if len(positions) >= s.config.MaxPositionsCount {
-		log.Warnf("Max positions reached (%d/%d), skipping buy for %s", len(positions), s.config.MaxPositionsCount, symbol)
-		return
-	}
 
 	log.Infof("=== INTERVAL BUY SIGNAL ===")
 	log.Infof("Symbol: %s, Price: %.8f, Interval: [%.8f - %.8f]", symbol, price, interval.Lower, interval.Upper)
@@ -323,25 +346,6 @@ func (s *IntervalStrategy) executeBuy(symbol string, price float64, interval Pri
 	balance := s.tradingEngine.GetBalance()
 	log.Infof("Current balance: %.2f USDT", balance)
 
-	// Рассчитываем количество
-	quantity := s.config.PreferredPositionPrice / price
-	cost := price * quantity
-
-	log.Infof("Calculated quantity: %.8f, Cost: %.2f USDT", quantity, cost)
-
-	// Проверяем, достаточно ли баланса (с небольшим запасом)
-	if cost > balance*0.99 {
-		log.Warnf("Insufficient balance for buy: need %.2f USDT, have %.2f USDT", cost, balance)
-		// Попробуем уменьшить размер позиции до 90% от баланса
-		cost = balance * 0.9
-		quantity = cost / price
-		log.Infof("Reducing position size to %.2f USDT, quantity: %.8f", cost, quantity)
-		if quantity <= 0 {
-			log.Errorf("Cannot calculate valid quantity, skipping buy")
-			return
-		}
-	}
-
 	// Рассчитываем StopLoss и TakeProfit
 	stopLossPercent := s.config.StopLossPercent
 	if stopLossPercent <= 0 {
@@ -350,11 +354,33 @@ func (s *IntervalStrategy) executeBuy(symbol string, price float64, interval Pri
 	stopLoss := price * (1 - stopLossPercent/100)
 	takeProfit := interval.Upper // Берем верхнюю границу интервала как цель
 
+	// Рассчитываем количество через выбранную модель размера позиции
+	equity := balance
+	for _, pos := range positions {
+		equity += pos.EntryPrice*pos.Quantity + pos.UnrealizedPnL
+	}
+	quantity := s.sizer.Size(risk.SizingRequest{
+		Symbol:     symbol,
+		Equity:     equity,
+		Available:  balance * 0.99, // Небольшой запас баланса
+		EntryPrice: price,
+		StopLoss:   stopLoss,
+		Volatility: interval.Width / 100, // Ширина интервала как оценка волатильности
+	})
+	cost := price * quantity
+
+	log.Infof("Calculated quantity (%s): %.8f, Cost: %.2f USDT", s.sizer.Name(), quantity, cost)
+
+	if quantity <= 0 {
+		log.Errorf("Cannot calculate valid quantity, skipping buy")
+		return
+	}
+
 	log.Infof("StopLoss: %.8f (%.2f%%), TakeProfit: %.8f (%.2f%%)",
 		stopLoss, stopLossPercent, takeProfit, (takeProfit-price)/price*100)
 
 	// Открываем позицию через ExecuteMarketOrder с StopLoss и TakeProfit
-	if err := s.tradingEngine.ExecuteMarketOrder(symbol, "BUY", price, quantity, stopLoss, takeProfit); err != nil {
+	if err := s.tradingEngine.ExecuteMarketOrderAs(trading.StrategyInterval, symbol, "BUY", price, quantity, stopLoss, takeProfit); err != nil {
 		log.Errorf("Failed to execute buy: %v", err)
 		return
 	}
@@ -387,7 +413,7 @@ func (s *IntervalStrategy) executeSell(symbol string, price float64, interval Pr
 	log.Infof("Expected PnL: %.2f USDT (%.2f%%)", expectedPnL, expectedPnLPercent)
 
 	// Продаем через trading engine (StopLoss и TakeProfit не нужны для продажи)
-	if err := s.tradingEngine.ExecuteMarketOrder(symbol, "SELL", price, position.Quantity, 0, 0); err != nil {
+	if err := s.tradingEngine.ExecuteMarketOrderAs(trading.StrategyInterval, symbol, "SELL", price, position.Quantity, 0, 0); err != nil {
 		log.Errorf("Failed to execute sell: %v", err)
 		return
 	}
@@ -415,6 +441,9 @@ func (s *IntervalStrategy) executeSell(symbol string, price float64, interval Pr
 		}
 		
 		if lastTrade != nil {
+			if recorder, ok := s.sizer.(risk.TradeRecorder); ok {
+				recorder.RecordTrade(lastTrade.PnLPercent)
+			}
 			s.mu.Lock()
 			// Обновляем статистику на основе реального PnL
 			if lastTrade.PnL > 0 {
@@ -474,6 +503,10 @@ func (s *IntervalStrategy) GetStats() IntervalStats {
 	for k, v := range s.stats.ActiveIntervals {
 		statsCopy.ActiveIntervals[k] = v
 	}
+	statsCopy.Regimes = make(map[string]indicators.Regime)
+	for k, v := range s.stats.Regimes {
+		statsCopy.Regimes[k] = v
+	}
 	
 	log.Infof("📊 GetStats called: SuccessfulTrades=%d, FailedTrades=%d, TotalCrosses=%d, ActiveIntervals=%d",
 		statsCopy.SuccessfulTrades, statsCopy.FailedTrades, statsCopy.TotalCrosses, len(statsCopy.ActiveIntervals))
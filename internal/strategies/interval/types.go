@@ -1,6 +1,11 @@
 package interval
 
-import "time"
+import (
+	"time"
+
+	"crypto-trading-bot/internal/indicators"
+	"crypto-trading-bot/internal/risk"
+)
 
 // Метод анализа интервала
 type AnalysisMethod int
@@ -32,14 +37,18 @@ type IntervalConfig struct {
 
 	// Защита
 	StopLossPercent        float64        `json:"stopLossPercent"`          // Stop-loss в % (по умолчанию: 1.5)
-	MaxPositionsCount      int            `json:"maxPositionsCount"`         // Максимум одновременных позиций (по умолчанию: 3)
+	MaxPositionsCount      int            `json:"maxPositionsCount"`         // Максимум одновременных позиций стратегии (по умолчанию: 3)
 
 	// Управление капиталом
 	PreferredPositionPrice float64        `json:"preferredPositionPrice"`   // Предпочтительная сумма позиции в USDT (по умолчанию: 1000)
 	MaxPositionPrice       float64        `json:"maxPositionPrice"`          // Максимальная цена лота (по умолчанию: 5000)
+	Sizing                 *risk.SizingConfig `json:"sizing,omitempty"`      // Модель размера позиции (nil = PreferredPositionPrice)
 
 	// Обновление интервалов
 	RecalculateIntervalHours int          `json:"recalculateIntervalHours"`   // Пересчет каждые N часов (по умолчанию: 6)
+
+	// Режим рынка
+	RegimeGate             *indicators.RegimeGate `json:"regimeGate,omitempty"` // Режимы, в которых открываются позиции (nil = в любом)
 }
 
 // Ценовой интервал
@@ -86,5 +95,6 @@ type IntervalStats struct {
 	AvgHoldTime         time.Duration            `json:"avgHoldTime" wails:"-"` // Среднее время удержания
 	BestSymbol          string                   `json:"bestSymbol"`           // Лучший символ
 	LastRecalculation   time.Time                `json:"lastRecalculation" wails:"-"` // Последний пересчет
+	Regimes             map[string]indicators.Regime `json:"regimes"`          // Режим рынка по символам
 }
 
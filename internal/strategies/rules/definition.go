@@ -0,0 +1,365 @@
+// Package rules runs strategies defined declaratively in JSON or YAML:
+// entry and exit conditions are expressions over candle prices and indicator
+// values, evaluated on every closed candle into signals, so that strategies
+// can be prototyped without recompiling.
+//
+// A definition looks like
+//
+//	{
+//	  "name": "ema-cross",
+//	  "symbols": ["BTCUSDT"],
+//	  "timeframe": "15m",
+//	  "entry": {"long": "crossover(ema9, ema21) and rsi14 < 70"},
+//	  "exit": {"long": "crossunder(ema9, ema21) or rsi14 > 80"},
+//	  "stopLoss": {"atr": 2},
+//	  "takeProfit": {"percent": 3},
+//	  "sizing": {"method": "fixed_fractional", "riskPerTrade": 0.01},
+//	  "exits": [{"type": "trailing_atr", "atrMultiple": 2, "activateR": 1}]
+//	}
+//
+// Indicators with tuned periods are added with specs and read with ind():
+//
+//	"indicators": ["rsi(21)", "band=bb(20,2.5)"],
+//	"symbolIndicators": {"ETHUSDT": ["rsi(10)", "band=bb(20,3)"]},
+//	"entry": {"long": "close < ind(\"band.lower\")"}
+//
+// The same definition in YAML (a .yaml or .yml file) reads
+//
+//	name: ema-cross
+//	symbols: [BTCUSDT]
+//	timeframe: 15m
+//	indicators: ["rsi(21)", "band=bb(20,2.5)"]
+//	entry:
+//	  long: crossover(ema9, ema21) and rsi14 < 70
+//	stopLoss: {atr: 2}
+//	exits:
+//	  - type: trailing_atr
+//	    atrMultiple: 2
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"crypto-trading-bot/internal/indicators"
+	"crypto-trading-bot/internal/risk"
+	"crypto-trading-bot/internal/signals"
+	"crypto-trading-bot/internal/trading"
+)
+
+// StrategyPrefix prefixes the strategy tag and signal model of rule
+// strategies, e.g. "rules:ema-cross".
+const StrategyPrefix = "rules:"
+
+// defaultWarmup is the number of candles indicators get before rules are
+// evaluated.
+const defaultWarmup = 50
+
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Conditions holds a rule for each side; an empty rule never fires.
+type Conditions struct {
+	Long  string `json:"long"`
+	Short string `json:"short"`
+}
+
+// Level is the distance of a stop-loss or take-profit from the entry.
+type Level struct {
+	Percent float64 `json:"percent"` // Distance in percent of the entry price
+	ATR     float64 `json:"atr"`     // Distance in ATR multiples, preferred when the ATR is known
+}
+
+// price returns the level for an entry at price, 0 when the level is unset.
+func (l Level) price(direction string, entry, atr float64, beyond bool) float64 {
+	var distance float64
+	switch {
+	case l.ATR > 0 && atr > 0:
+		distance = l.ATR * atr
+	case l.Percent > 0:
+		distance = entry * l.Percent / 100
+	default:
+		return 0
+	}
+	// Стоп лонга ниже входа, тейк выше; у шорта наоборот
+	if (direction == "LONG") == beyond {
+		return entry + distance
+	}
+	return entry - distance
+}
+
+// Definition is a declarative rule strategy.
+type Definition struct {
+	Name         string                     `json:"name"` // Lower-case letters, digits, "-" and "_"
+	Description  string                     `json:"description"`
+	Symbols      []string                   `json:"symbols"`
+	Timeframe    string                     `json:"timeframe"` // Candle interval the rules run on (default "15m")
+	Entry        Conditions                 `json:"entry"`
+	Exit         Conditions                 `json:"exit"`
+	Confidence   float64                    `json:"confidence"`           // Confidence of entry signals (default 0.7)
+	StopLoss     Level                      `json:"stopLoss"`             // Unset uses the engine's ATR based stop
+	TakeProfit   Level                      `json:"takeProfit"`           // Unset uses the engine's ATR based target
+	Sizing       *risk.SizingConfig         `json:"sizing,omitempty"`     // nil uses the engine's sizing
+	Exits        []trading.ExitPolicyConfig `json:"exits,omitempty"`      // Exit policies of the positions; nil uses the engine's for the strategy tag
+	MaxPositions int                        `json:"maxPositions"`         // Open positions across symbols (0 = no limit)
+	RegimeGate   *indicators.RegimeGate     `json:"regimeGate,omitempty"` // Regimes entries are allowed in (nil = any)
+	Warmup       int                        `json:"warmup"`               // Candles before rules are evaluated (default 50)
+
+	Indicators       []string            `json:"indicators,omitempty"`       // Indicator specs added to the default preset, e.g. "rsi(21)"
+	SymbolIndicators map[string][]string `json:"symbolIndicators,omitempty"` // Per-symbol specs replacing Indicators
+}
+
+// Tag returns the strategy tag recorded on positions and signals.
+func (d *Definition) Tag() string {
+	return StrategyPrefix + d.Name
+}
+
+// Validate fills defaults and checks the definition, including that every
+// rule compiles.
+func (d *Definition) Validate() error {
+	if !namePattern.MatchString(d.Name) {
+		return fmt.Errorf("invalid strategy name %q: use lower-case letters, digits, '-' and '_'", d.Name)
+	}
+	if len(d.Symbols) == 0 {
+		return fmt.Errorf("strategy %s: at least one symbol is required", d.Name)
+	}
+	for i, symbol := range d.Symbols {
+		d.Symbols[i] = strings.ToUpper(strings.TrimSpace(symbol))
+		if d.Symbols[i] == "" {
+			return fmt.Errorf("strategy %s: empty symbol", d.Name)
+		}
+	}
+	if d.Timeframe == "" {
+		d.Timeframe = "15m"
+	}
+	if signals.TimeframeDuration(d.Timeframe) == 0 {
+		return fmt.Errorf("strategy %s: unknown timeframe %q", d.Name, d.Timeframe)
+	}
+	if strings.TrimSpace(d.Entry.Long) == "" && strings.TrimSpace(d.Entry.Short) == "" {
+		return fmt.Errorf("strategy %s: an entry rule is required", d.Name)
+	}
+	compiled, err := d.compile()
+	if err != nil {
+		return err
+	}
+	if err := d.validateIndicators(compiled); err != nil {
+		return err
+	}
+
+	if d.Confidence == 0 {
+		d.Confidence = 0.7
+	}
+	if d.Confidence < 0 || d.Confidence > 1 {
+		return fmt.Errorf("strategy %s: confidence must be within (0, 1]", d.Name)
+	}
+	for _, level := range []Level{d.StopLoss, d.TakeProfit} {
+		if level.Percent < 0 || level.ATR < 0 {
+			return fmt.Errorf("strategy %s: stop-loss and take-profit must not be negative", d.Name)
+		}
+	}
+	if d.Sizing != nil {
+		if _, err := risk.NewPositionSizer(d.Sizing); err != nil {
+			return fmt.Errorf("strategy %s: %w", d.Name, err)
+		}
+	}
+	for _, config := range d.Exits {
+		if _, err := trading.NewExitPolicy(config); err != nil {
+			return fmt.Errorf("strategy %s: %w", d.Name, err)
+		}
+	}
+	if d.MaxPositions < 0 {
+		return fmt.Errorf("strategy %s: max positions must not be negative", d.Name)
+	}
+	if d.RegimeGate != nil {
+		if err := d.RegimeGate.Validate(); err != nil {
+			return fmt.Errorf("strategy %s: %w", d.Name, err)
+		}
+	}
+	if d.Warmup <= 0 {
+		d.Warmup = defaultWarmup
+	}
+	return nil
+}
+
+// validateIndicators checks the indicator specs of every symbol and that
+// they provide every key the rules read with ind().
+func (d *Definition) validateIndicators(compiled compiledRules) error {
+	overrides := make(map[string][]string, len(d.SymbolIndicators))
+	for symbol, specs := range d.SymbolIndicators {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if !slices.Contains(d.Symbols, symbol) {
+			return fmt.Errorf("strategy %s: indicators for %s, which it does not trade", d.Name, symbol)
+		}
+		overrides[symbol] = specs
+	}
+	d.SymbolIndicators = overrides
+
+	var keys []string
+	for _, expr := range []*Expr{compiled.entryLong, compiled.entryShort, compiled.exitLong, compiled.exitShort} {
+		if expr != nil {
+			keys = append(keys, expr.Indicators()...)
+		}
+	}
+	for _, symbol := range d.Symbols {
+		specs, err := d.specsFor(symbol)
+		if err != nil {
+			return fmt.Errorf("strategy %s: %w", d.Name, err)
+		}
+		set, err := indicators.NewIndicatorSetWithSpecs(specs)
+		if err != nil {
+			return fmt.Errorf("strategy %s: %s: %w", d.Name, symbol, err)
+		}
+		available := set.Keys()
+		for _, key := range keys {
+			if !slices.Contains(available, key) {
+				return fmt.Errorf("strategy %s: %s: no indicator provides ind(%q)", d.Name, symbol, key)
+			}
+		}
+	}
+	return nil
+}
+
+// specsFor returns the parsed indicator specs of symbol.
+func (d *Definition) specsFor(symbol string) ([]indicators.Spec, error) {
+	list, ok := d.SymbolIndicators[symbol]
+	if !ok {
+		list = d.Indicators
+	}
+	specs := make([]indicators.Spec, 0, len(list))
+	for _, text := range list {
+		spec, err := indicators.ParseSpec(text)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// compiledRules are the compiled conditions of a definition; nil for empty
+// rules.
+type compiledRules struct {
+	entryLong, entryShort, exitLong, exitShort *Expr
+}
+
+// lookback returns the history the rules read.
+func (c compiledRules) lookback() int {
+	lookback := 0
+	for _, expr := range []*Expr{c.entryLong, c.entryShort, c.exitLong, c.exitShort} {
+		if expr != nil {
+			lookback = max(lookback, expr.Lookback())
+		}
+	}
+	return lookback
+}
+
+func (d *Definition) compile() (compiledRules, error) {
+	var rules compiledRules
+	for _, rule := range []struct {
+		name   string
+		source string
+		target **Expr
+	}{
+		{"entry.long", d.Entry.Long, &rules.entryLong},
+		{"entry.short", d.Entry.Short, &rules.entryShort},
+		{"exit.long", d.Exit.Long, &rules.exitLong},
+		{"exit.short", d.Exit.Short, &rules.exitShort},
+	} {
+		if strings.TrimSpace(rule.source) == "" {
+			continue
+		}
+		expr, err := Compile(rule.source)
+		if err != nil {
+			return compiledRules{}, fmt.Errorf("strategy %s: %s: %w", d.Name, rule.name, err)
+		}
+		*rule.target = expr
+	}
+	return rules, nil
+}
+
+// ParseDefinition decodes and validates a JSON definition. Unknown fields
+// are rejected, so that a misspelt option is not silently ignored.
+func ParseDefinition(data []byte) (*Definition, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var def Definition
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("failed to parse strategy definition: %w", err)
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// ParseDefinitionText decodes and validates a definition of either format:
+// JSON when the text starts with '{', YAML otherwise.
+func ParseDefinitionText(data []byte) (*Definition, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return ParseDefinition(data)
+	}
+	return ParseDefinitionYAML(data)
+}
+
+// LoadDefinition reads a definition from a file, YAML for the .yaml and
+// .yml extensions and JSON otherwise.
+func LoadDefinition(path string) (*Definition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read strategy definition: %w", err)
+	}
+	parse := ParseDefinition
+	if isYAMLFile(path) {
+		parse = ParseDefinitionYAML
+	}
+	def, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return def, nil
+}
+
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// LoadDefinitions reads every *.json, *.yaml and *.yml definition in dir,
+// sorted by name.
+func LoadDefinitions(dir string) ([]*Definition, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list strategy definitions: %w", err)
+	}
+	paths := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || (strings.ToLower(filepath.Ext(name)) != ".json" && !isYAMLFile(name)) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, name))
+	}
+
+	defs := make([]*Definition, 0, len(paths))
+	names := make(map[string]string, len(paths))
+	for _, path := range paths {
+		def, err := LoadDefinition(path)
+		if err != nil {
+			return nil, err
+		}
+		if other, ok := names[def.Name]; ok {
+			return nil, fmt.Errorf("strategy %s is defined in both %s and %s", def.Name, other, filepath.Base(path))
+		}
+		names[def.Name] = filepath.Base(path)
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs, nil
+}
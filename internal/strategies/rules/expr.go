@@ -0,0 +1,984 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"crypto-trading-bot/internal/indicators"
+)
+
+// Bar is a closed candle with the indicator values computed on it.
+type Bar struct {
+	Time   int64                      `json:"time"` // Close time, Unix ms
+	Open   float64                    `json:"open"`
+	High   float64                    `json:"high"`
+	Low    float64                    `json:"low"`
+	Close  float64                    `json:"close"`
+	Volume float64                    `json:"volume"`
+	Values indicators.IndicatorValues `json:"values"`
+}
+
+// PositionState describes the position a rule is evaluated for; Side is
+// empty when flat.
+type PositionState struct {
+	Side       string  `json:"side"` // "", "LONG" or "SHORT"
+	EntryPrice float64 `json:"entryPrice"`
+}
+
+// valueType is the static type of an expression: rules must be conditions,
+// arithmetic works on numbers.
+type valueType int
+
+const (
+	typeNumber valueType = iota
+	typeBool
+)
+
+func (t valueType) String() string {
+	if t == typeBool {
+		return "condition"
+	}
+	return "number"
+}
+
+// fields maps identifiers (lower-cased) to bar values. Indicator fields use
+// the JSON names of indicators.IndicatorValues.
+var fields = map[string]func(b *Bar) float64{
+	"open":       func(b *Bar) float64 { return b.Open },
+	"high":       func(b *Bar) float64 { return b.High },
+	"low":        func(b *Bar) float64 { return b.Low },
+	"close":      func(b *Bar) float64 { return b.Close },
+	"price":      func(b *Bar) float64 { return b.Close },
+	"volume":     func(b *Bar) float64 { return b.Volume },
+	"ema9":       func(b *Bar) float64 { return b.Values.EMA9 },
+	"ema21":      func(b *Bar) float64 { return b.Values.EMA21 },
+	"ema50":      func(b *Bar) float64 { return b.Values.EMA50 },
+	"ema200":     func(b *Bar) float64 { return b.Values.EMA200 },
+	"rsi14":      func(b *Bar) float64 { return b.Values.RSI14 },
+	"rsi7":       func(b *Bar) float64 { return b.Values.RSI7 },
+	"macdline":   func(b *Bar) float64 { return b.Values.MACDLine },
+	"macdsignal": func(b *Bar) float64 { return b.Values.MACDSignal },
+	"macdhist":   func(b *Bar) float64 { return b.Values.MACDHist },
+	"bbupper":    func(b *Bar) float64 { return b.Values.BBUpper },
+	"bbmiddle":   func(b *Bar) float64 { return b.Values.BBMiddle },
+	"bblower":    func(b *Bar) float64 { return b.Values.BBLower },
+	"bbpercentb": func(b *Bar) float64 { return b.Values.BBPercentB },
+	"atr14":      func(b *Bar) float64 { return b.Values.ATR14 },
+	"stochrsik":  func(b *Bar) float64 { return b.Values.StochRSI_K },
+	"stochrsid":  func(b *Bar) float64 { return b.Values.StochRSI_D },
+	"obv":        func(b *Bar) float64 { return b.Values.OBV },
+	"adx":        func(b *Bar) float64 { return b.Values.ADX },
+	"cci":        func(b *Bar) float64 { return b.Values.CCI },
+	"williams":   func(b *Bar) float64 { return b.Values.Williams },
+	"momentum":   func(b *Bar) float64 { return b.Values.Momentum },
+}
+
+// positionFields maps identifiers to values of the evaluated position; they
+// are 0 when flat.
+var positionFields = map[string]func(p PositionState, b *Bar) float64{
+	"entryprice": func(p PositionState, b *Bar) float64 { return p.EntryPrice },
+	"pnlpercent": func(p PositionState, b *Bar) float64 {
+		if p.Side == "" || p.EntryPrice <= 0 {
+			return 0
+		}
+		change := (b.Close - p.EntryPrice) / p.EntryPrice * 100
+		if p.Side == "SHORT" {
+			change = -change
+		}
+		return change
+	},
+}
+
+// Identifiers returns the names usable in expressions.
+func Identifiers() []string {
+	names := make([]string, 0, len(fields)+len(positionFields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	for name := range positionFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// evalContext is the state an expression is evaluated against. history is
+// ordered oldest first; offset 0 is its last bar.
+type evalContext struct {
+	history  []Bar
+	position PositionState
+}
+
+func (c *evalContext) bar(offset int) *Bar {
+	i := len(c.history) - 1 - offset
+	if i < 0 || i >= len(c.history) {
+		return nil
+	}
+	return &c.history[i]
+}
+
+// node is a compiled expression. eval returns its value offset bars before
+// the current one; conditions evaluate to 1 or 0, and NaN when the history
+// is too short.
+type node interface {
+	eval(ctx *evalContext, offset int) float64
+	kind() valueType
+	lookback() int // Bars of history needed before the current one
+}
+
+func truth(v bool) float64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+type numberNode struct{ value float64 }
+
+func (n numberNode) eval(*evalContext, int) float64 { return n.value }
+func (n numberNode) kind() valueType                { return typeNumber }
+func (n numberNode) lookback() int                  { return 0 }
+
+type boolNode struct{ value bool }
+
+func (n boolNode) eval(*evalContext, int) float64 { return truth(n.value) }
+func (n boolNode) kind() valueType                { return typeBool }
+func (n boolNode) lookback() int                  { return 0 }
+
+type fieldNode struct {
+	get func(b *Bar) float64
+}
+
+func (n fieldNode) eval(ctx *evalContext, offset int) float64 {
+	b := ctx.bar(offset)
+	if b == nil {
+		return math.NaN()
+	}
+	return n.get(b)
+}
+func (n fieldNode) kind() valueType { return typeNumber }
+func (n fieldNode) lookback() int   { return 0 }
+
+type positionNode struct {
+	get func(p PositionState, b *Bar) float64
+}
+
+func (n positionNode) eval(ctx *evalContext, offset int) float64 {
+	b := ctx.bar(offset)
+	if b == nil {
+		return math.NaN()
+	}
+	return n.get(ctx.position, b)
+}
+func (n positionNode) kind() valueType { return typeNumber }
+func (n positionNode) lookback() int   { return 0 }
+
+type regimeNode struct{ label string }
+
+func (n regimeNode) eval(ctx *evalContext, offset int) float64 {
+	b := ctx.bar(offset)
+	if b == nil {
+		return math.NaN()
+	}
+	return truth(b.Values.Regime.Is(n.label))
+}
+func (n regimeNode) kind() valueType { return typeBool }
+func (n regimeNode) lookback() int   { return 0 }
+
+// indicatorNode reads a value of the indicators added by specs, e.g.
+// ind("rsi(21)") or ind("bb(20,2.5).upper"); the preset values are
+// available by their JSON names too.
+type indicatorNode struct{ key string }
+
+func (n indicatorNode) eval(ctx *evalContext, offset int) float64 {
+	b := ctx.bar(offset)
+	if b == nil {
+		return math.NaN()
+	}
+	value, ok := b.Values.Get(n.key)
+	if !ok {
+		return math.NaN()
+	}
+	return value
+}
+func (n indicatorNode) kind() valueType { return typeNumber }
+func (n indicatorNode) lookback() int   { return 0 }
+
+type unaryNode struct {
+	op string // "-" or "not"
+	x  node
+}
+
+func (n unaryNode) eval(ctx *evalContext, offset int) float64 {
+	v := n.x.eval(ctx, offset)
+	if n.op == "-" {
+		return -v
+	}
+	if math.IsNaN(v) {
+		return v
+	}
+	return truth(v == 0)
+}
+func (n unaryNode) kind() valueType { return n.x.kind() }
+func (n unaryNode) lookback() int   { return n.x.lookback() }
+
+type binaryNode struct {
+	op   string
+	l, r node
+}
+
+func (n binaryNode) eval(ctx *evalContext, offset int) float64 {
+	l := n.l.eval(ctx, offset)
+	switch n.op {
+	case "and":
+		if l == 0 || math.IsNaN(l) {
+			return l
+		}
+		return n.r.eval(ctx, offset)
+	case "or":
+		if l == 1 {
+			return l
+		}
+		r := n.r.eval(ctx, offset)
+		if math.IsNaN(l) && r != 1 {
+			return l
+		}
+		return r
+	}
+
+	r := n.r.eval(ctx, offset)
+	if math.IsNaN(l) || math.IsNaN(r) {
+		return math.NaN()
+	}
+	switch n.op {
+	case "+":
+		return l + r
+	case "-":
+		return l - r
+	case "*":
+		return l * r
+	case "/":
+		if r == 0 {
+			return math.NaN()
+		}
+		return l / r
+	case "<":
+		return truth(l < r)
+	case "<=":
+		return truth(l <= r)
+	case ">":
+		return truth(l > r)
+	case ">=":
+		return truth(l >= r)
+	case "==":
+		return truth(l == r)
+	case "!=":
+		return truth(l != r)
+	}
+	return math.NaN()
+}
+
+func (n binaryNode) kind() valueType {
+	switch n.op {
+	case "+", "-", "*", "/":
+		return typeNumber
+	}
+	return typeBool
+}
+func (n binaryNode) lookback() int { return max(n.l.lookback(), n.r.lookback()) }
+
+// shiftNode evaluates x bars bars ago, e.g. rsi14[1] or prev(rsi14).
+type shiftNode struct {
+	x    node
+	bars int
+}
+
+func (n shiftNode) eval(ctx *evalContext, offset int) float64 { return n.x.eval(ctx, offset+n.bars) }
+func (n shiftNode) kind() valueType                           { return n.x.kind() }
+func (n shiftNode) lookback() int                             { return n.x.lookback() + n.bars }
+
+// crossNode is true on the bar where a crosses above (or below) b.
+type crossNode struct {
+	a, b node
+	up   bool
+}
+
+func (n crossNode) eval(ctx *evalContext, offset int) float64 {
+	a, b := n.a.eval(ctx, offset), n.b.eval(ctx, offset)
+	pa, pb := n.a.eval(ctx, offset+1), n.b.eval(ctx, offset+1)
+	if math.IsNaN(a) || math.IsNaN(b) || math.IsNaN(pa) || math.IsNaN(pb) {
+		return math.NaN()
+	}
+	if n.up {
+		return truth(a > b && pa <= pb)
+	}
+	return truth(a < b && pa >= pb)
+}
+func (n crossNode) kind() valueType { return typeBool }
+func (n crossNode) lookback() int   { return max(n.a.lookback(), n.b.lookback()) + 1 }
+
+// mathNode applies fn to the values of its arguments.
+type mathNode struct {
+	args []node
+	fn   func(values []float64) float64
+}
+
+func (n mathNode) eval(ctx *evalContext, offset int) float64 {
+	values := make([]float64, len(n.args))
+	for i, arg := range n.args {
+		values[i] = arg.eval(ctx, offset)
+		if math.IsNaN(values[i]) {
+			return values[i]
+		}
+	}
+	return n.fn(values)
+}
+func (n mathNode) kind() valueType { return typeNumber }
+func (n mathNode) lookback() int {
+	lookback := 0
+	for _, arg := range n.args {
+		lookback = max(lookback, arg.lookback())
+	}
+	return lookback
+}
+
+// windowNode reduces the values of x over the last bars bars, the current
+// one included.
+type windowNode struct {
+	x      node
+	bars   int
+	result valueType
+	reduce func(values []float64) float64 // values ordered oldest first
+}
+
+func (n windowNode) eval(ctx *evalContext, offset int) float64 {
+	values := make([]float64, n.bars)
+	for i := 0; i < n.bars; i++ {
+		v := n.x.eval(ctx, offset+n.bars-1-i)
+		if math.IsNaN(v) {
+			return v
+		}
+		values[i] = v
+	}
+	return n.reduce(values)
+}
+func (n windowNode) kind() valueType { return n.result }
+func (n windowNode) lookback() int   { return n.x.lookback() + n.bars - 1 }
+
+// Expr is a compiled rule condition.
+type Expr struct {
+	source      string
+	root        node
+	identifiers []string
+	indicators  []string
+}
+
+// Compile parses a rule condition such as
+//
+//	crossover(ema9, ema21) and rsi14 < 70 or regime("trend") and close > highest(high, 20)[1]
+//
+// Identifiers are the bar fields open, high, low, close (alias price) and
+// volume, the indicator values by their JSON names (ema21, rsi14, macdHist,
+// bbLower, atr14, adx, ...) and, for exit rules, entryPrice and pnlPercent.
+// x[n] is x n bars ago. Functions: crossover, crossunder, prev(x, n),
+// change(x, n), highest, lowest, avg, rising and falling over n bars, abs,
+// min, max, regime("trend"|"range"|"high_vol"|"low_vol") and ind("key"),
+// which reads an indicator added by the definition's specs, e.g.
+// ind("rsi(21)") or ind("bb(20,2.5).upper").
+func Compile(source string) (*Expr, error) {
+	tokens, err := tokenize(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, identifiers: make(map[string]bool), indicators: make(map[string]bool)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at column %d", tok.text, tok.pos+1)
+	}
+	if root.kind() != typeBool {
+		return nil, fmt.Errorf("rule %q must be a condition, not a number", source)
+	}
+
+	identifiers := make([]string, 0, len(p.identifiers))
+	for name := range p.identifiers {
+		identifiers = append(identifiers, name)
+	}
+	sort.Strings(identifiers)
+	keys := make([]string, 0, len(p.indicators))
+	for key := range p.indicators {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return &Expr{source: source, root: root, identifiers: identifiers, indicators: keys}, nil
+}
+
+// String returns the source of the expression.
+func (e *Expr) String() string { return e.source }
+
+// Indicators returns the indicator keys the expression reads with ind().
+func (e *Expr) Indicators() []string { return append([]string(nil), e.indicators...) }
+
+// Lookback returns the number of bars before the current one the
+// expression reads.
+func (e *Expr) Lookback() int { return e.root.lookback() }
+
+// Eval evaluates the condition on the last bar of history. ok is false when
+// the history is too short or a value is undefined, e.g. a division by 0.
+func (e *Expr) Eval(history []Bar, position PositionState) (value, ok bool) {
+	if len(history) <= e.Lookback() {
+		return false, false
+	}
+	v := e.root.eval(&evalContext{history: history, position: position}, 0)
+	if math.IsNaN(v) {
+		return false, false
+	}
+	return v != 0, true
+}
+
+// Values returns the identifiers the expression reads with their values on
+// the last bar, e.g. "rsi14=28.4", for explanations.
+func (e *Expr) Values(history []Bar, position PositionState) []string {
+	if len(history) == 0 {
+		return nil
+	}
+	ctx := &evalContext{history: history, position: position}
+	values := make([]string, 0, len(e.identifiers))
+	for _, name := range e.identifiers {
+		var v float64
+		if get, ok := fields[name]; ok {
+			v = fieldNode{get: get}.eval(ctx, 0)
+		} else {
+			v = positionNode{get: positionFields[name]}.eval(ctx, 0)
+		}
+		values = append(values, fmt.Sprintf("%s=%s", name, strconv.FormatFloat(v, 'g', 6, 64)))
+	}
+	for _, key := range e.indicators {
+		v := indicatorNode{key: key}.eval(ctx, 0)
+		values = append(values, fmt.Sprintf("%s=%s", key, strconv.FormatFloat(v, 'g', 6, 64)))
+	}
+	return values
+}
+
+// Tokenizer.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators lists the operators and punctuation, longest first.
+var operators = []string{"&&", "||", "<=", ">=", "==", "!=", "<", ">", "+", "-", "*", "/", "!", "(", ")", "[", "]", ","}
+
+func tokenize(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(source) && (unicode.IsDigit(rune(source[i])) || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, source[start:i], start})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(source) && (unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i])) || source[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, source[start:i], start})
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(source[i+1:], byte(c))
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at column %d", i+1)
+			}
+			tokens = append(tokens, token{tokenString, source[i+1 : i+1+end], i})
+			i += end + 2
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, token{tokenOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected character %q at column %d", c, i+1)
+			}
+		}
+	}
+	return append(tokens, token{tokenEOF, "end of rule", len(source)}), nil
+}
+
+// Parser: or > and > not > comparison > sum > term > unary > postfix.
+
+type parser struct {
+	tokens      []token
+	pos         int
+	identifiers map[string]bool
+	indicators  map[string]bool // Keys read with ind()
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// accept consumes the next token if it is one of the operators or keywords.
+func (p *parser) accept(texts ...string) (string, bool) {
+	tok := p.peek()
+	if tok.kind != tokenOp && tok.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if strings.EqualFold(tok.text, text) {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		tok := p.peek()
+		return fmt.Errorf("expected %q at column %d, got %q", text, tok.pos+1, tok.text)
+	}
+	return nil
+}
+
+func requireType(n node, want valueType, context string) error {
+	if n.kind() != want {
+		return fmt.Errorf("%s needs a %s, got a %s", context, want, n.kind())
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("or", "||"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireType(left, typeBool, "or"); err != nil {
+			return nil, err
+		}
+		if err := requireType(right, typeBool, "or"); err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "or", l: left, r: right}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("and", "&&"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireType(left, typeBool, "and"); err != nil {
+			return nil, err
+		}
+		if err := requireType(right, typeBool, "and"); err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: "and", l: left, r: right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if _, ok := p.accept("not", "!"); ok {
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireType(x, typeBool, "not"); err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "not", x: x}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("<=", ">=", "==", "!=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if op != "==" && op != "!=" || left.kind() == typeNumber || right.kind() == typeNumber {
+		if err := requireType(left, typeNumber, op); err != nil {
+			return nil, err
+		}
+		if err := requireType(right, typeNumber, op); err != nil {
+			return nil, err
+		}
+	}
+	return binaryNode{op: op, l: left, r: right}, nil
+}
+
+func (p *parser) parseSum() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireType(left, typeNumber, op); err != nil {
+			return nil, err
+		}
+		if err := requireType(right, typeNumber, op); err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) parseTerm() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireType(left, typeNumber, op); err != nil {
+			return nil, err
+		}
+		if err := requireType(right, typeNumber, op); err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, l: left, r: right}
+	}
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if _, ok := p.accept("-"); ok {
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := requireType(x, typeNumber, "-"); err != nil {
+			return nil, err
+		}
+		return unaryNode{op: "-", x: x}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.accept("["); !ok {
+			return x, nil
+		}
+		bars, err := p.parseBars()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		x = shiftNode{x: x, bars: bars}
+	}
+}
+
+// parseBars reads a bar count literal.
+func (p *parser) parseBars() (int, error) {
+	tok := p.next()
+	bars, err := strconv.Atoi(tok.text)
+	if tok.kind != tokenNumber || err != nil || bars < 0 {
+		return 0, fmt.Errorf("expected a whole number of bars at column %d, got %q", tok.pos+1, tok.text)
+	}
+	if bars > maxLookback {
+		return 0, fmt.Errorf("%d bars at column %d exceed the maximum of %d", bars, tok.pos+1, maxLookback)
+	}
+	return bars, nil
+}
+
+// maxLookback bounds the history a rule may read.
+const maxLookback = 500
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		value, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at column %d", tok.text, tok.pos+1)
+		}
+		return numberNode{value: value}, nil
+	case tokenOp:
+		if tok.text == "(" {
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return x, p.expect(")")
+		}
+	case tokenIdent:
+		name := strings.ToLower(tok.text)
+		if _, ok := p.accept("("); ok {
+			return p.parseCall(name, tok)
+		}
+		switch name {
+		case "true", "false":
+			return boolNode{value: name == "true"}, nil
+		}
+		if get, ok := fields[name]; ok {
+			p.identifiers[name] = true
+			return fieldNode{get: get}, nil
+		}
+		if get, ok := positionFields[name]; ok {
+			p.identifiers[name] = true
+			return positionNode{get: get}, nil
+		}
+		return nil, fmt.Errorf("unknown identifier %q at column %d", tok.text, tok.pos+1)
+	}
+	return nil, fmt.Errorf("unexpected %q at column %d", tok.text, tok.pos+1)
+}
+
+// parseCall parses the arguments of a function call after "(".
+func (p *parser) parseCall(name string, tok token) (node, error) {
+	if name == "regime" {
+		arg := p.next()
+		if arg.kind != tokenString || !indicators.IsRegimeLabel(arg.text) {
+			return nil, fmt.Errorf("regime() at column %d needs one of \"trend\", \"range\", \"high_vol\", \"low_vol\"", tok.pos+1)
+		}
+		return regimeNode{label: arg.text}, p.expect(")")
+	}
+	if name == "ind" {
+		arg := p.next()
+		if arg.kind != tokenString || arg.text == "" {
+			return nil, fmt.Errorf("ind() at column %d needs an indicator key such as \"rsi(21)\"", tok.pos+1)
+		}
+		p.indicators[arg.text] = true
+		return indicatorNode{key: arg.text}, p.expect(")")
+	}
+
+	var args []node
+	var bars []int
+	if _, ok := p.accept(")"); !ok {
+		for {
+			// Количество баров — всегда литерал, чтобы глубину истории можно было вычислить заранее
+			if next := p.peek(); len(args) > 0 && next.kind == tokenNumber && windowFunction(name) {
+				n, err := p.parseBars()
+				if err != nil {
+					return nil, err
+				}
+				bars = append(bars, n)
+			} else {
+				arg, err := p.parseOr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+			}
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	numbers := func(count int) error {
+		if len(args) != count {
+			return fmt.Errorf("%s() at column %d takes %d argument(s)", name, tok.pos+1, count)
+		}
+		for _, arg := range args {
+			if err := requireType(arg, typeNumber, name+"()"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	window := func(defaultBars, minBars int) (int, error) {
+		switch len(bars) {
+		case 0:
+			if defaultBars == 0 {
+				return 0, fmt.Errorf("%s() at column %d needs a number of bars", name, tok.pos+1)
+			}
+			return defaultBars, nil
+		case 1:
+			if bars[0] < minBars {
+				return 0, fmt.Errorf("%s() at column %d needs a period of at least %d", name, tok.pos+1, minBars)
+			}
+			return bars[0], nil
+		}
+		return 0, fmt.Errorf("%s() at column %d takes one number of bars", name, tok.pos+1)
+	}
+
+	switch name {
+	case "crossover", "crossunder":
+		if err := numbers(2); err != nil {
+			return nil, err
+		}
+		return crossNode{a: args[0], b: args[1], up: name == "crossover"}, nil
+	case "abs":
+		if err := numbers(1); err != nil {
+			return nil, err
+		}
+		return mathNode{args: args, fn: func(v []float64) float64 { return math.Abs(v[0]) }}, nil
+	case "min", "max":
+		if err := numbers(2); err != nil {
+			return nil, err
+		}
+		if name == "min" {
+			return mathNode{args: args, fn: func(v []float64) float64 { return math.Min(v[0], v[1]) }}, nil
+		}
+		return mathNode{args: args, fn: func(v []float64) float64 { return math.Max(v[0], v[1]) }}, nil
+	case "prev":
+		if len(args) != 1 {
+			return nil, fmt.Errorf("prev() at column %d takes one value", tok.pos+1)
+		}
+		n, err := window(1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return shiftNode{x: args[0], bars: n}, nil
+	case "change":
+		if err := numbers(1); err != nil {
+			return nil, err
+		}
+		n, err := window(1, 1)
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: "-", l: args[0], r: shiftNode{x: args[0], bars: n}}, nil
+	case "highest", "lowest", "avg":
+		if err := numbers(1); err != nil {
+			return nil, err
+		}
+		n, err := window(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		return windowNode{x: args[0], bars: n, result: typeNumber, reduce: reducers[name]}, nil
+	case "rising", "falling":
+		if err := numbers(1); err != nil {
+			return nil, err
+		}
+		n, err := window(0, 1)
+		if err != nil {
+			return nil, err
+		}
+		// rising(x, n): n подряд растущих значений, т.е. окно из n+1 баров
+		return windowNode{x: args[0], bars: n + 1, result: typeBool, reduce: reducers[name]}, nil
+	}
+	return nil, fmt.Errorf("unknown function %q at column %d", name, tok.pos+1)
+}
+
+// windowFunction reports whether the function takes a number of bars as
+// its last argument.
+func windowFunction(name string) bool {
+	switch name {
+	case "prev", "change", "highest", "lowest", "avg", "rising", "falling":
+		return true
+	}
+	return false
+}
+
+var reducers = map[string]func(values []float64) float64{
+	"highest": func(values []float64) float64 {
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Max(result, v)
+		}
+		return result
+	},
+	"lowest": func(values []float64) float64 {
+		result := values[0]
+		for _, v := range values[1:] {
+			result = math.Min(result, v)
+		}
+		return result
+	},
+	"avg": func(values []float64) float64 {
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+		return sum / float64(len(values))
+	},
+	"rising": func(values []float64) float64 {
+		for i := 1; i < len(values); i++ {
+			if values[i] <= values[i-1] {
+				return 0
+			}
+		}
+		return 1
+	},
+	"falling": func(values []float64) float64 {
+		for i := 1; i < len(values); i++ {
+			if values[i] >= values[i-1] {
+				return 0
+			}
+		}
+		return 1
+	},
+}
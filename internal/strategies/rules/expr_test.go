@@ -0,0 +1,170 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"crypto-trading-bot/internal/indicators"
+)
+
+// testHistory returns five bars, oldest first: closes 10, 11, 12, 11, 13,
+// ema9 crossing above ema21 on the last bar and a trend regime.
+func testHistory() []Bar {
+	closes := []float64{10, 11, 12, 11, 13}
+	ema9 := []float64{1, 1, 1, 1, 3}
+	history := make([]Bar, len(closes))
+	for i, c := range closes {
+		history[i] = Bar{
+			Open:   c - 0.5,
+			High:   c + 1,
+			Low:    c - 1,
+			Close:  c,
+			Volume: 100 * float64(i+1),
+			Values: indicators.IndicatorValues{
+				EMA9:   ema9[i],
+				EMA21:  2,
+				RSI14:  30 + 10*float64(i),
+				Regime: indicators.Regime{Trend: indicators.RegimeTrend, Ready: true},
+				Custom: map[string]float64{"rsi(21)": 40 + float64(i)},
+			},
+		}
+	}
+	return history
+}
+
+func TestExprEval(t *testing.T) {
+	tests := []struct {
+		source   string
+		position PositionState
+		want     bool
+		wantOK   bool
+	}{
+		{"close > 12", PositionState{}, true, true},
+		{"close > 12 and rsi14 <= 70", PositionState{}, true, true},
+		{"close > 20 or rsi14 >= 70", PositionState{}, true, true},
+		{"not (close > 12)", PositionState{}, false, true},
+		{"!true || false", PositionState{}, false, true},
+		{"PRICE == close AND Volume == 500", PositionState{}, true, true},
+		{"close - open * 2 == -12", PositionState{}, true, true},
+		{"(close - open) * 2 == 1", PositionState{}, true, true},
+		{"-close / 2 < -6", PositionState{}, true, true},
+		{"close[1] == 11 and close[4] == 10", PositionState{}, true, true},
+		{"prev(close) == 11 and prev(close, 2) == 12", PositionState{}, true, true},
+		{"change(close) == 2 and change(close, 4) == 3", PositionState{}, true, true},
+		{"crossover(ema9, ema21)", PositionState{}, true, true},
+		{"crossunder(ema9, ema21)", PositionState{}, false, true},
+		{"crossover(ema9, ema21)[1]", PositionState{}, false, true},
+		{"highest(high, 3) == 14 and lowest(low, 3) == 10", PositionState{}, true, true},
+		{"close > highest(high, 2)[1]", PositionState{}, false, true},
+		{"avg(close, 5) == 11.4", PositionState{}, true, true},
+		{"rising(rsi14, 4)", PositionState{}, true, true},
+		{"rising(close, 2)", PositionState{}, false, true},
+		{"falling(close, 1)", PositionState{}, false, true},
+		{"abs(-close) == 13 and min(close, 5) == 5 and max(close, 5) == 13", PositionState{}, true, true},
+		{"regime(\"trend\")", PositionState{}, true, true},
+		{"regime('range')", PositionState{}, false, true},
+		{"ind(\"rsi(21)\") == 44", PositionState{}, true, true},
+		{"ind(\"bb(20,2).upper\") > 0", PositionState{}, false, false},
+		{"pnlPercent == 30 and entryPrice == 10", PositionState{Side: "LONG", EntryPrice: 10}, true, true},
+		{"pnlPercent == -30", PositionState{Side: "SHORT", EntryPrice: 10}, true, true},
+		{"pnlPercent == 0", PositionState{}, true, true},
+		{"close / (open - open) > 1", PositionState{}, false, false},
+		{"close > 1 or close / 0 > 1", PositionState{}, true, true},
+		{"close[5] > 1", PositionState{}, false, false},
+		{"avg(close, 6) > 1", PositionState{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() = %v", err)
+			}
+			got, ok := expr.Eval(testHistory(), tt.position)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("Eval() = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{"close + 1", "must be a condition"},
+		{"close > ", "unexpected \"end of rule\""},
+		{"close > 1 1", "unexpected \"1\" at column 11"},
+		{"close >> 1", "unexpected \">\""},
+		{"closing > 1", "unknown identifier \"closing\""},
+		{"foo(close) > 1", "unknown function \"foo\""},
+		{"close and true", "and needs a condition, got a number"},
+		{"close > true", "> needs a number, got a condition"},
+		{"-(close > 1)", "- needs a number, got a condition"},
+		{"not close", "not needs a condition, got a number"},
+		{"close[x] > 1", "expected a whole number of bars"},
+		{"close[1.5] > 1", "expected a whole number of bars"},
+		{"close[501] > 1", "exceed the maximum of 500"},
+		{"(close > 1", "expected \")\""},
+		{"crossover(ema9) ", "crossover() at column 1 takes 2 argument(s)"},
+		{"highest(high) > 1", "highest() at column 1 needs a number of bars"},
+		{"highest(high, 0) > 1", "needs a period of at least 1"},
+		{"highest(high, 2, 3) > 1", "takes one number of bars"},
+		{"regime(\"sideways\")", "regime() at column 1 needs one of"},
+		{"ind(\"\") > 1", "ind() at column 1 needs an indicator key"},
+		{"close > \"12", "unterminated string"},
+		{"close > 1 # comment", "unexpected character '#'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Compile() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExprLookbackAndIndicators(t *testing.T) {
+	tests := []struct {
+		source         string
+		wantLookback   int
+		wantIndicators []string
+	}{
+		{"close > 1", 0, nil},
+		{"close[3] > close", 3, nil},
+		{"crossover(ema9, ema21)", 1, nil},
+		{"crossover(ema9[2], ema21)", 3, nil},
+		{"highest(high, 20)[1] < close", 20, nil},
+		{"rising(close, 3)", 3, nil},
+		{"change(close, 5) > 0 and prev(close, 7) > 0", 7, nil},
+		{"ind(\"rsi(21)\") < 30 and ind(\"band.lower\") > close", 0, []string{"band.lower", "rsi(21)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := Compile(tt.source)
+			if err != nil {
+				t.Fatalf("Compile() = %v", err)
+			}
+			if got := expr.Lookback(); got != tt.wantLookback {
+				t.Errorf("Lookback() = %d, want %d", got, tt.wantLookback)
+			}
+			if got := expr.Indicators(); !reflect.DeepEqual(got, tt.wantIndicators) {
+				t.Errorf("Indicators() = %v, want %v", got, tt.wantIndicators)
+			}
+		})
+	}
+}
+
+func TestExprValues(t *testing.T) {
+	expr, err := Compile("close > ema21 and pnlPercent > 1 and ind(\"rsi(21)\") < 50")
+	if err != nil {
+		t.Fatalf("Compile() = %v", err)
+	}
+	got := expr.Values(testHistory(), PositionState{Side: "LONG", EntryPrice: 10})
+	want := []string{"close=13", "ema21=2", "pnlpercent=30", "rsi(21)=44"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Values() = %v, want %v", got, want)
+	}
+}
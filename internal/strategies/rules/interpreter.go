@@ -0,0 +1,221 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"crypto-trading-bot/internal/binance"
+	"crypto-trading-bot/internal/indicators"
+	"crypto-trading-bot/internal/signals"
+
+	"github.com/google/uuid"
+)
+
+// Actions of a decision.
+const (
+	ActionEnterLong  = "enter_long"
+	ActionEnterShort = "enter_short"
+	ActionExitLong   = "exit_long"
+	ActionExitShort  = "exit_short"
+)
+
+// Decision is the outcome of a closed candle that requires a trade.
+type Decision struct {
+	Action string          `json:"action"` // One of the Action* constants
+	Rule   string          `json:"rule"`   // Rule that fired, e.g. "entry.long"
+	Signal *signals.Signal `json:"signal"` // LONG/SHORT for entries, HOLD for exits
+}
+
+// symbolState is the per-symbol state of an interpreter.
+type symbolState struct {
+	indicators *indicators.IndicatorSet
+	history    []Bar // Oldest first, at most Interpreter.depth bars
+	candles    int   // Closed candles seen
+	lastClose  int64 // Close time of the last candle, Unix ms
+}
+
+// Interpreter evaluates the rules of a definition on closed candles. It is
+// independent of the market data source and the engine, so the same
+// definition can be run live or over historical candles.
+type Interpreter struct {
+	def   *Definition
+	rules compiledRules
+	depth int // Bars of history kept per symbol
+
+	symbols map[string]*symbolState
+	mu      sync.Mutex
+}
+
+// NewInterpreter creates an interpreter for a definition.
+func NewInterpreter(def *Definition) (*Interpreter, error) {
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	compiled, err := def.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &Interpreter{
+		def:     def,
+		rules:   compiled,
+		depth:   compiled.lookback() + 1,
+		symbols: make(map[string]*symbolState),
+	}, nil
+}
+
+// Definition returns the definition the interpreter runs.
+func (in *Interpreter) Definition() *Definition {
+	return in.def
+}
+
+// Update feeds a closed candle to the indicators of symbol without
+// evaluating the rules, e.g. during warmup. Candles not newer than the last
+// one are ignored; it reports whether the candle was used.
+func (in *Interpreter) Update(symbol string, k binance.Kline) bool {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.update(symbol, k) != nil
+}
+
+func (in *Interpreter) update(symbol string, k binance.Kline) *symbolState {
+	state, ok := in.symbols[symbol]
+	if !ok {
+		// Спецификации проверены в Validate
+		specs, _ := in.def.specsFor(symbol)
+		set, err := indicators.NewIndicatorSetWithSpecs(specs)
+		if err != nil {
+			set = indicators.NewIndicatorSet()
+		}
+		state = &symbolState{indicators: set}
+		in.symbols[symbol] = state
+	}
+	if k.CloseTime <= state.lastClose {
+		return nil
+	}
+
+	values := state.indicators.UpdateAllAt(k.OpenTime, k.High, k.Low, k.Close, k.Volume)
+	bar := Bar{
+		Time:   k.CloseTime,
+		Open:   k.Open,
+		High:   k.High,
+		Low:    k.Low,
+		Close:  k.Close,
+		Volume: k.Volume,
+	}
+	if values != nil {
+		bar.Values = *values
+	}
+	state.history = append(state.history, bar)
+	if len(state.history) > in.depth {
+		state.history = append(state.history[:0], state.history[len(state.history)-in.depth:]...)
+	}
+	state.candles++
+	state.lastClose = k.CloseTime
+	return state
+}
+
+// OnCandle feeds a closed candle of symbol and evaluates the rules for the
+// position the strategy holds in it. It returns nil when no rule fires,
+// during warmup and for candles already seen. When both entry rules fire
+// on the same candle no entry is made.
+func (in *Interpreter) OnCandle(symbol string, k binance.Kline, position PositionState) *Decision {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	state := in.update(symbol, k)
+	if state == nil || state.candles < in.def.Warmup {
+		return nil
+	}
+	history := state.history
+
+	switch position.Side {
+	case "LONG":
+		if fired(in.rules.exitLong, history, position) {
+			return in.decide(symbol, ActionExitLong, "exit.long", in.rules.exitLong, history, position)
+		}
+		return nil
+	case "SHORT":
+		if fired(in.rules.exitShort, history, position) {
+			return in.decide(symbol, ActionExitShort, "exit.short", in.rules.exitShort, history, position)
+		}
+		return nil
+	}
+
+	long := fired(in.rules.entryLong, history, position)
+	short := fired(in.rules.entryShort, history, position)
+	if long == short {
+		return nil
+	}
+	if allowed, _ := in.def.RegimeGate.Permits(history[len(history)-1].Values.Regime); !allowed {
+		return nil
+	}
+	if long {
+		return in.decide(symbol, ActionEnterLong, "entry.long", in.rules.entryLong, history, position)
+	}
+	return in.decide(symbol, ActionEnterShort, "entry.short", in.rules.entryShort, history, position)
+}
+
+// Reset drops the candles and indicator state of all symbols.
+func (in *Interpreter) Reset() {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.symbols = make(map[string]*symbolState)
+}
+
+func fired(expr *Expr, history []Bar, position PositionState) bool {
+	if expr == nil {
+		return false
+	}
+	value, ok := expr.Eval(history, position)
+	return ok && value
+}
+
+// decide builds the decision and its signal for a rule that fired on the
+// last bar of history.
+func (in *Interpreter) decide(symbol, action, rule string, expr *Expr, history []Bar, position PositionState) *Decision {
+	bar := history[len(history)-1]
+	regime := bar.Values.Regime
+	tag := in.def.Tag()
+
+	sig := &signals.Signal{
+		ID:        uuid.New().String(),
+		Symbol:    symbol,
+		Timeframe: in.def.Timeframe,
+		Direction: "HOLD",
+		Price:     bar.Close,
+		ATR:       bar.Values.ATR14,
+		Timestamp: time.UnixMilli(bar.Time),
+		Model:     tag,
+		Regime:    &regime,
+		Reasons:   []string{fmt.Sprintf("%s: %s", rule, expr)},
+	}
+	confidenceRule := "exit rules carry no confidence"
+
+	switch action {
+	case ActionEnterLong, ActionEnterShort:
+		sig.Direction = "LONG"
+		if action == ActionEnterShort {
+			sig.Direction = "SHORT"
+		}
+		sig.Confidence = in.def.Confidence
+		sig.StopLoss = in.def.StopLoss.price(sig.Direction, bar.Close, sig.ATR, false)
+		sig.TakeProfit = in.def.TakeProfit.price(sig.Direction, bar.Close, sig.ATR, true)
+		confidenceRule = fmt.Sprintf("fixed by the strategy: %.2f", sig.Confidence)
+	}
+
+	notes := expr.Values(history, position)
+	if position.Side != "" {
+		notes = append(notes, fmt.Sprintf("position: %s from %g", position.Side, position.EntryPrice))
+	}
+	sig.Explanation = &signals.SignalExplanation{
+		Model:          tag,
+		DirectionRule:  fmt.Sprintf("%s: %s", rule, strings.TrimSpace(expr.String())),
+		ConfidenceRule: confidenceRule,
+		Notes:          notes,
+	}
+	sig.Explain()
+
+	return &Decision{Action: action, Rule: rule, Signal: sig}
+}
@@ -0,0 +1,384 @@
+package rules
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"crypto-trading-bot/internal/binance"
+	"crypto-trading-bot/internal/risk"
+	"crypto-trading-bot/internal/signals"
+	"crypto-trading-bot/internal/trading"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxWarmupKlines is the most candles requested from the REST API to warm
+// up the indicators.
+const maxWarmupKlines = 1000
+
+// Status describes a rule strategy run by a Runner.
+type Status struct {
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	Symbols      []string  `json:"symbols"`
+	Timeframe    string    `json:"timeframe"`
+	Running      bool      `json:"running"`
+	StartedAt    int64     `json:"startedAt"` // Unix ms
+	Candles      int64     `json:"candles"`   // Closed candles evaluated
+	Signals      int64     `json:"signals"`   // Rules that fired
+	Entries      int64     `json:"entries"`
+	Exits        int64     `json:"exits"`
+	Rejected     int64     `json:"rejected"` // Entries and exits the engine refused
+	LastDecision *Decision `json:"lastDecision"`
+	LastError    string    `json:"lastError"`
+}
+
+// strategy is a running rule strategy.
+type strategy struct {
+	def         *Definition
+	interpreter *Interpreter
+	sizer       risk.PositionSizer // nil uses the engine's sizing
+	status      Status
+	mu          sync.Mutex
+}
+
+// Runner runs rule strategies live: it warms up their indicators from
+// historical candles, evaluates the rules on every closed candle of the
+// kline streams and trades the decisions through a trading engine under the
+// strategy's tag.
+type Runner struct {
+	engine  *trading.TradingEngine
+	client  *binance.Client
+	ws      *binance.WSClient
+	handler *signals.SignalHandler // Records the signals of the rules; may be nil
+
+	strategies map[string]*strategy
+	streams    map[string]bool // Subscribed "SYMBOL:timeframe" kline streams
+	mu         sync.RWMutex
+}
+
+// NewRunner creates a runner trading through engine with market data from
+// client and ws.
+func NewRunner(engine *trading.TradingEngine, client *binance.Client, ws *binance.WSClient) *Runner {
+	return &Runner{
+		engine:     engine,
+		client:     client,
+		ws:         ws,
+		strategies: make(map[string]*strategy),
+		streams:    make(map[string]bool),
+	}
+}
+
+// SetSignalHandler records the signals of the rules in handler, which
+// makes them visible to its subscribers and the signal store.
+func (r *Runner) SetSignalHandler(handler *signals.SignalHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handler = handler
+}
+
+// Start validates and starts a rule strategy. A running strategy with the
+// same name is replaced.
+func (r *Runner) Start(def *Definition) error {
+	interpreter, err := NewInterpreter(def)
+	if err != nil {
+		return err
+	}
+	s := &strategy{
+		def:         def,
+		interpreter: interpreter,
+		status: Status{
+			Name:        def.Name,
+			Description: def.Description,
+			Symbols:     append([]string(nil), def.Symbols...),
+			Timeframe:   def.Timeframe,
+		},
+	}
+	if def.Sizing != nil {
+		if s.sizer, err = risk.NewPositionSizer(def.Sizing); err != nil {
+			return fmt.Errorf("strategy %s: %w", def.Name, err)
+		}
+	}
+
+	// Индикаторы прогреваем до подписки, чтобы правила работали с первой закрытой свечи
+	for _, symbol := range def.Symbols {
+		if err := r.warmup(interpreter, symbol, def.Timeframe, def.Warmup+interpreter.depth); err != nil {
+			return fmt.Errorf("strategy %s: %w", def.Name, err)
+		}
+	}
+
+	r.Stop(def.Name)
+
+	tag := def.Tag()
+	r.engine.SetStrategyPositionLimit(tag, def.MaxPositions)
+	if recorder, ok := s.sizer.(risk.TradeRecorder); ok {
+		r.engine.OnTradeEvent(tag, func(event trading.TradeEvent) {
+			if event.Type == trading.TradeEventClosed && event.Trade != nil && event.Trade.Strategy == tag {
+				recorder.RecordTrade(event.Trade.PnLPercent)
+			}
+		})
+	}
+
+	s.status.Running = true
+	s.status.StartedAt = time.Now().UnixMilli()
+	r.mu.Lock()
+	r.strategies[def.Name] = s
+	r.mu.Unlock()
+
+	for _, symbol := range def.Symbols {
+		if err := r.subscribe(symbol, def.Timeframe); err != nil {
+			r.Stop(def.Name)
+			return fmt.Errorf("strategy %s: %w", def.Name, err)
+		}
+	}
+
+	log.Infof("Rule strategy %s started on %s %s", def.Name, strings.Join(def.Symbols, ","), def.Timeframe)
+	return nil
+}
+
+// Stop stops a rule strategy. Its open positions are left open and are no
+// longer managed by the strategy.
+func (r *Runner) Stop(name string) bool {
+	r.mu.Lock()
+	s, ok := r.strategies[name]
+	delete(r.strategies, name)
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	tag := s.def.Tag()
+	r.engine.SetStrategyPositionLimit(tag, 0)
+	r.engine.OnTradeEvent(tag, nil)
+	for _, pos := range r.engine.GetPositions() {
+		if pos.Strategy == tag {
+			log.Warnf("Rule strategy %s stopped with an open %s position in %s", name, pos.Side, pos.Symbol)
+		}
+	}
+
+	s.mu.Lock()
+	s.status.Running = false
+	s.mu.Unlock()
+	log.Infof("Rule strategy %s stopped", name)
+	return true
+}
+
+// StopAll stops every rule strategy.
+func (r *Runner) StopAll() {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.strategies))
+	for name := range r.strategies {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+
+	for _, name := range names {
+		r.Stop(name)
+	}
+}
+
+// Statuses returns the status of the running strategies, sorted by name.
+func (r *Runner) Statuses() []Status {
+	r.mu.RLock()
+	strategies := make([]*strategy, 0, len(r.strategies))
+	for _, s := range r.strategies {
+		strategies = append(strategies, s)
+	}
+	r.mu.RUnlock()
+
+	statuses := make([]Status, 0, len(strategies))
+	for _, s := range strategies {
+		s.mu.Lock()
+		status := s.status
+		status.Symbols = append([]string(nil), s.status.Symbols...)
+		s.mu.Unlock()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// warmup feeds the last closed candles of symbol to the interpreter.
+func (r *Runner) warmup(interpreter *Interpreter, symbol, timeframe string, candles int) error {
+	if r.client == nil {
+		return nil
+	}
+	// Последняя свеча ответа еще не закрыта - запрашиваем на одну больше
+	limit := min(candles+1, maxWarmupKlines)
+	klines, err := r.client.GetKlines(symbol, timeframe, limit)
+	if err != nil {
+		return fmt.Errorf("failed to load %s %s klines: %w", symbol, timeframe, err)
+	}
+	now := time.Now().UnixMilli()
+	for _, k := range klines {
+		if k.CloseTime < now {
+			interpreter.Update(symbol, k)
+		}
+	}
+	return nil
+}
+
+// subscribe subscribes to the kline stream of symbol once; the stream is
+// shared by all strategies on the same symbol and timeframe and outlives
+// them, since the WebSocket client cannot unsubscribe.
+func (r *Runner) subscribe(symbol, timeframe string) error {
+	if r.ws == nil {
+		return fmt.Errorf("no market data stream")
+	}
+	key := symbol + ":" + timeframe
+
+	r.mu.Lock()
+	if r.streams[key] {
+		r.mu.Unlock()
+		return nil
+	}
+	r.streams[key] = true
+	r.mu.Unlock()
+
+	ch, err := r.ws.SubscribeKline(strings.ToLower(symbol), timeframe)
+	if err != nil {
+		r.mu.Lock()
+		delete(r.streams, key)
+		r.mu.Unlock()
+		return fmt.Errorf("failed to subscribe to %s %s klines: %w", symbol, timeframe, err)
+	}
+	go r.dispatch(symbol, timeframe, ch)
+	return nil
+}
+
+// dispatch hands the klines of a stream to the strategies trading it.
+func (r *Runner) dispatch(symbol, timeframe string, ch chan *binance.KlineWSMessage) {
+	for msg := range ch {
+		r.mu.RLock()
+		targets := make([]*strategy, 0, len(r.strategies))
+		for _, s := range r.strategies {
+			if s.def.Timeframe == timeframe && s.trades(symbol) {
+				targets = append(targets, s)
+			}
+		}
+		r.mu.RUnlock()
+
+		for _, s := range targets {
+			r.onKline(s, symbol, msg)
+		}
+	}
+
+	r.mu.Lock()
+	delete(r.streams, symbol+":"+timeframe)
+	r.mu.Unlock()
+	log.Warnf("Rule strategies lost the %s %s kline stream", symbol, timeframe)
+}
+
+func (s *strategy) trades(symbol string) bool {
+	for _, candidate := range s.def.Symbols {
+		if candidate == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// onKline manages the strategy's position on every price update and
+// evaluates the rules when the candle closes.
+func (r *Runner) onKline(s *strategy, symbol string, msg *binance.KlineWSMessage) {
+	k := binance.Kline{
+		OpenTime:  msg.Kline.StartTime,
+		Open:      parseFloat(msg.Kline.Open),
+		High:      parseFloat(msg.Kline.High),
+		Low:       parseFloat(msg.Kline.Low),
+		Close:     parseFloat(msg.Kline.Close),
+		Volume:    parseFloat(msg.Kline.Volume),
+		CloseTime: msg.Kline.CloseTime,
+	}
+	if k.Close <= 0 {
+		return
+	}
+
+	tag := s.def.Tag()
+	r.engine.UpdatePrice(symbol, k.Close)
+	position, owned := r.position(symbol, tag)
+	if owned {
+		// Движок стратегий не запускает свой цикл, поэтому стопы и политики выхода проверяем здесь
+		r.engine.ProcessExits(symbol, k.Close)
+		r.engine.ProcessStops(symbol, k.Close)
+		position, owned = r.position(symbol, tag)
+	}
+	if !msg.Kline.IsFinal {
+		return
+	}
+
+	// Позиция другой стратегии по символу: индикаторы обновляем, правила не проверяем
+	if position != nil && !owned {
+		s.interpreter.Update(symbol, k)
+		return
+	}
+
+	state := PositionState{}
+	if owned {
+		state = PositionState{Side: position.Side, EntryPrice: position.EntryPrice}
+	}
+	decision := s.interpreter.OnCandle(symbol, k, state)
+
+	s.mu.Lock()
+	s.status.Candles++
+	s.mu.Unlock()
+	if decision != nil {
+		r.execute(s, decision)
+	}
+}
+
+// position returns the open position in symbol and whether the strategy
+// tagged tag owns it.
+func (r *Runner) position(symbol, tag string) (*trading.Position, bool) {
+	for _, pos := range r.engine.GetPositions() {
+		if pos.Symbol == symbol {
+			return &pos, pos.Strategy == tag
+		}
+	}
+	return nil, false
+}
+
+// execute records the signal of a decision and trades it.
+func (r *Runner) execute(s *strategy, decision *Decision) {
+	sig := decision.Signal
+	r.mu.RLock()
+	handler := r.handler
+	r.mu.RUnlock()
+	if handler != nil {
+		handler.UpdateSignal(sig)
+	}
+
+	var err error
+	switch decision.Action {
+	case ActionEnterLong, ActionEnterShort:
+		err = r.engine.OpenPositionFor(s.def.Tag(), sig, s.sizer, s.def.Exits)
+	case ActionExitLong, ActionExitShort:
+		err = r.engine.ExitPosition(sig.Symbol, sig.Price, "Rule "+decision.Rule)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Signals++
+	s.status.LastDecision = decision
+	if err != nil {
+		s.status.Rejected++
+		s.status.LastError = err.Error()
+		log.Warnf("Rule strategy %s: %s %s not executed: %v", s.def.Name, decision.Action, sig.Symbol, err)
+		return
+	}
+	if decision.Action == ActionEnterLong || decision.Action == ActionEnterShort {
+		s.status.Entries++
+	} else {
+		s.status.Exits++
+	}
+	log.Infof("Rule strategy %s: %s", s.def.Name, sig.Digest())
+}
+
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
@@ -0,0 +1,453 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The module has no YAML dependency, so definitions in YAML are read with a
+// parser for the subset they need: block mappings and sequences, flow
+// sequences and mappings on one line, plain, single- and double-quoted
+// scalars and comments. Anchors, tags, block scalars (| and >) and multiple
+// documents are rejected. The result is decoded like a JSON definition.
+
+var yamlNumber = regexp.MustCompile(`^[-+]?(\d+(\.\d*)?|\.\d+)([eE][-+]?\d+)?$`)
+
+// ParseDefinitionYAML decodes and validates a YAML definition with the same
+// fields as its JSON form.
+func ParseDefinitionYAML(data []byte) (*Definition, error) {
+	value, err := parseYAML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse strategy definition: %w", err)
+	}
+	if _, ok := value.(map[string]any); !ok {
+		return nil, fmt.Errorf("failed to parse strategy definition: expected a mapping at the top level")
+	}
+	data, err = json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse strategy definition: %w", err)
+	}
+	return ParseDefinition(data)
+}
+
+type yamlLine struct {
+	number int // 1-based line number in the source
+	indent int
+	text   string // Without indentation and comment
+}
+
+func parseYAML(source string) (any, error) {
+	lines := make([]yamlLine, 0)
+	for i, raw := range strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in indentation", i+1)
+		}
+		if trimmed == "---" && len(lines) == 0 {
+			continue
+		}
+		if trimmed == "---" || trimmed == "..." {
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("empty document")
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.block(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return value, nil
+}
+
+// stripYAMLComment cuts a comment: a '#' outside quotes at the start of the
+// line or after whitespace.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && opensYAMLQuote(line, i):
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// opensYAMLQuote reports whether the quote at i starts a quoted scalar
+// rather than being part of a plain one, e.g. ind("bb.upper").
+func opensYAMLQuote(line string, i int) bool {
+	prev := strings.TrimRight(line[:i], " \t")
+	if prev == "" {
+		return true
+	}
+	switch prev[len(prev)-1] {
+	case ':', '[', '{', ',', '?':
+		return true
+	case '-':
+		// Только маркер элемента последовательности, а не минус внутри текста
+		return strings.Trim(prev, "- ") == ""
+	}
+	return false
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// block parses the mapping or sequence starting at the current line.
+func (p *yamlParser) block(indent int) (any, error) {
+	if isYAMLSequenceItem(p.lines[p.pos].text) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	items := make([]any, 0)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSequenceItem(line.text) {
+			break
+		}
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if rest == "" {
+			p.pos++
+			item, err := p.nested(indent, false)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		// "- key: value" начинает отображение с отступом содержимого элемента
+		if _, _, ok := splitYAMLKey(rest); ok || isYAMLSequenceItem(rest) {
+			p.lines[p.pos] = yamlLine{number: line.number, indent: indent + len(line.text) - len(rest), text: rest}
+			item, err := p.block(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			continue
+		}
+
+		item, err := parseYAMLValue(rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		p.pos++
+	}
+	return items, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	result := make(map[string]any)
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("line %d: unexpected indentation", line.number)
+		}
+		if isYAMLSequenceItem(line.text) {
+			// Последовательность на уровне ключей допустима только как его значение
+			return nil, fmt.Errorf("line %d: unexpected sequence item", line.number)
+		}
+		rawKey, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected \"key: value\"", line.number)
+		}
+		key, err := parseYAMLScalar(rawKey, line.number)
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprint(key)
+		if key == nil {
+			name = ""
+		}
+		if _, dup := result[name]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", line.number, name)
+		}
+		p.pos++
+
+		if rest == "" {
+			value, err := p.nested(indent, true)
+			if err != nil {
+				return nil, err
+			}
+			result[name] = value
+			continue
+		}
+		value, err := parseYAMLValue(rest, line.number)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+// nested parses the block value of a key or of an empty sequence item: the
+// more indented lines that follow, or null. Values of mapping keys may also
+// be sequences at the key's own indentation.
+func (p *yamlParser) nested(indent int, key bool) (any, error) {
+	if p.pos >= len(p.lines) {
+		return nil, nil
+	}
+	next := p.lines[p.pos]
+	if next.indent > indent || (key && next.indent == indent && isYAMLSequenceItem(next.text)) {
+		return p.block(next.indent)
+	}
+	return nil, nil
+}
+
+// splitYAMLKey splits "key: value" at the first colon outside quotes that is
+// followed by a space or ends the line.
+func splitYAMLKey(text string) (key, value string, ok bool) {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return "", "", false
+	}
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && i == 0:
+			quote = c
+		case c == ':' && (i+1 == len(text) || text[i+1] == ' '):
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// parseYAMLValue parses an inline value: a flow collection or a scalar.
+func parseYAMLValue(text string, line int) (any, error) {
+	switch {
+	case strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{"):
+		f := &yamlFlow{text: text, line: line}
+		value, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpaces()
+		if f.pos < len(f.text) {
+			return nil, fmt.Errorf("line %d: unexpected %q after flow collection", line, f.text[f.pos:])
+		}
+		return value, nil
+	case text == "|" || text == ">" || strings.HasPrefix(text, "|") && len(text) == 2 || strings.HasPrefix(text, ">") && len(text) == 2:
+		return nil, fmt.Errorf("line %d: block scalars are not supported, quote the value instead", line)
+	case strings.HasPrefix(text, "&") || strings.HasPrefix(text, "*") || strings.HasPrefix(text, "!"):
+		return nil, fmt.Errorf("line %d: anchors, aliases and tags are not supported", line)
+	}
+	return parseYAMLScalar(text, line)
+}
+
+// parseYAMLScalar resolves a scalar: quoted strings, null, booleans,
+// numbers, and any other plain text as a string.
+func parseYAMLScalar(text string, line int) (any, error) {
+	if len(text) >= 1 && (text[0] == '"' || text[0] == '\'') {
+		if len(text) < 2 || text[len(text)-1] != text[0] {
+			return nil, fmt.Errorf("line %d: unterminated quoted string %s", line, text)
+		}
+		if text[0] == '\'' {
+			return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+		}
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid quoted string %s", line, text)
+		}
+		return value, nil
+	}
+
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if yamlNumber.MatchString(text) {
+		value, err := strconv.ParseFloat(text, 64)
+		if err == nil {
+			return value, nil
+		}
+	}
+	return text, nil
+}
+
+// yamlFlow parses a flow collection such as ["rsi(21)", "bb(20,2.5)"] or
+// {atr: 2}. As in YAML, commas inside plain scalars end them, so specs with
+// several parameters must be quoted.
+type yamlFlow struct {
+	text string
+	pos  int
+	line int
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) value() (any, error) {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return nil, fmt.Errorf("line %d: unterminated flow collection", f.line)
+	}
+	switch f.text[f.pos] {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	}
+	return f.scalar()
+}
+
+func (f *yamlFlow) sequence() ([]any, error) {
+	f.pos++ // '['
+	items := make([]any, 0)
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == ']' {
+			f.pos++
+			return items, nil
+		}
+		item, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (map[string]any, error) {
+	f.pos++ // '{'
+	result := make(map[string]any)
+	for {
+		f.skipSpaces()
+		if f.pos < len(f.text) && f.text[f.pos] == '}' {
+			f.pos++
+			return result, nil
+		}
+		key, err := f.scalar()
+		if err != nil {
+			return nil, err
+		}
+		f.skipSpaces()
+		if f.pos >= len(f.text) || f.text[f.pos] != ':' {
+			return nil, fmt.Errorf("line %d: expected ':' in flow mapping", f.line)
+		}
+		f.pos++
+		value, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+		name := fmt.Sprint(key)
+		if key == nil {
+			name = ""
+		}
+		if _, dup := result[name]; dup {
+			return nil, fmt.Errorf("line %d: duplicate key %q", f.line, name)
+		}
+		result[name] = value
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes the ',' between items, leaving the closing bracket for
+// the caller.
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpaces()
+	if f.pos >= len(f.text) {
+		return fmt.Errorf("line %d: unterminated flow collection", f.line)
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case closing:
+		return nil
+	}
+	return fmt.Errorf("line %d: expected ',' or '%c' in flow collection", f.line, closing)
+}
+
+func (f *yamlFlow) scalar() (any, error) {
+	f.skipSpaces()
+	start := f.pos
+	if f.pos < len(f.text) && (f.text[f.pos] == '"' || f.text[f.pos] == '\'') {
+		quote := f.text[f.pos]
+		for f.pos++; f.pos < len(f.text); f.pos++ {
+			c := f.text[f.pos]
+			if quote == '"' && c == '\\' {
+				f.pos++
+				continue
+			}
+			if c != quote {
+				continue
+			}
+			// '' внутри одинарных кавычек - экранированная кавычка
+			if quote == '\'' && f.pos+1 < len(f.text) && f.text[f.pos+1] == '\'' {
+				f.pos++
+				continue
+			}
+			f.pos++
+			return parseYAMLScalar(f.text[start:f.pos], f.line)
+		}
+		return nil, fmt.Errorf("line %d: unterminated quoted string", f.line)
+	}
+
+	for f.pos < len(f.text) {
+		c := f.text[f.pos]
+		if c == ',' || c == ']' || c == '}' || c == '[' || c == '{' {
+			break
+		}
+		// В ключе плоского отображения ':' с пробелом отделяет значение
+		if c == ':' && (f.pos+1 == len(f.text) || f.text[f.pos+1] == ' ') {
+			break
+		}
+		f.pos++
+	}
+	return parseYAMLScalar(strings.TrimSpace(f.text[start:f.pos]), f.line)
+}
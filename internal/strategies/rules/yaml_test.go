@@ -0,0 +1,213 @@
+package rules
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   any
+	}{
+		{
+			name:   "scalars",
+			source: "name: ema-cross\ncount: 3\nratio: -1.5e2\nenabled: true\noff: False\nnothing: ~\nempty:\n",
+			want: map[string]any{
+				"name": "ema-cross", "count": 3.0, "ratio": -150.0, "enabled": true, "off": false, "nothing": nil, "empty": nil,
+			},
+		},
+		{
+			name:   "quoted scalars",
+			source: "a: \"1\"\nb: 'it''s'\nc: \"tab\\tquote\\\"\"\nd: '# not a comment'\n\"quoted key\": x\n",
+			want:   map[string]any{"a": "1", "b": "it's", "c": "tab\tquote\"", "d": "# not a comment", "quoted key": "x"},
+		},
+		{
+			name:   "plain scalars with quotes and colons",
+			source: "long: close < ind(\"band.lower\") and rsi14 < 30\ntime: 12:30\nurl: http://example.com\n",
+			want:   map[string]any{"long": "close < ind(\"band.lower\") and rsi14 < 30", "time": "12:30", "url": "http://example.com"},
+		},
+		{
+			name:   "comments",
+			source: "# header\n---\nname: x # trailing\nrule: close#1 # only after a space\n\n  # indented comment\n",
+			want:   map[string]any{"name": "x", "rule": "close#1"},
+		},
+		{
+			name:   "nested mappings",
+			source: "entry:\n  long: close > 1\n  short: close < 1\nstopLoss:\n  atr: 2\n",
+			want: map[string]any{
+				"entry":    map[string]any{"long": "close > 1", "short": "close < 1"},
+				"stopLoss": map[string]any{"atr": 2.0},
+			},
+		},
+		{
+			name:   "sequences",
+			source: "symbols:\n  - BTCUSDT\n  - ETHUSDT\ntimeframes:\n- 1m\n- 15m\n",
+			want: map[string]any{
+				"symbols":    []any{"BTCUSDT", "ETHUSDT"},
+				"timeframes": []any{"1m", "15m"},
+			},
+		},
+		{
+			name:   "sequence of mappings",
+			source: "exits:\n  - type: trailing_atr\n    atrMultiple: 2\n  - type: time\n    bars: 10\n  -\n    type: breakeven\n",
+			want: map[string]any{
+				"exits": []any{
+					map[string]any{"type": "trailing_atr", "atrMultiple": 2.0},
+					map[string]any{"type": "time", "bars": 10.0},
+					map[string]any{"type": "breakeven"},
+				},
+			},
+		},
+		{
+			name:   "nested sequences",
+			source: "- - a\n  - b\n- c\n",
+			want:   []any{[]any{"a", "b"}, "c"},
+		},
+		{
+			name:   "flow collections",
+			source: "symbols: [BTCUSDT, ETHUSDT]\nindicators: [\"rsi(21)\", 'bb(20,2.5)']\nstopLoss: {atr: 2, percent: 1.5}\nnested: {a: [1, {b: c}], d: {}}\nempty: []\n",
+			want: map[string]any{
+				"symbols":    []any{"BTCUSDT", "ETHUSDT"},
+				"indicators": []any{"rsi(21)", "bb(20,2.5)"},
+				"stopLoss":   map[string]any{"atr": 2.0, "percent": 1.5},
+				"nested":     map[string]any{"a": []any{1.0, map[string]any{"b": "c"}}, "d": map[string]any{}},
+				"empty":      []any{},
+			},
+		},
+		{
+			name:   "windows line endings",
+			source: "a: 1\r\nb:\r\n  c: 2\r\n",
+			want:   map[string]any{"a": 1.0, "b": map[string]any{"c": 2.0}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseYAML(tt.source)
+			if err != nil {
+				t.Fatalf("parseYAML() = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseYAML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		wantErr string
+	}{
+		{"empty document", "# nothing\n", "empty document"},
+		{"tab indentation", "a:\n\tb: 1\n", "line 2: tabs are not allowed"},
+		{"multiple documents", "a: 1\n---\nb: 2\n", "line 2: multiple documents"},
+		{"bad indentation", "a: 1\n  b: 2\n", "line 2: unexpected indentation"},
+		{"dedent below the document", "  a: 1\nb: 2\n", "line 2: unexpected indentation"},
+		{"missing colon", "a: 1\nb\n", "line 2: expected \"key: value\""},
+		{"sequence among keys", "a: 1\n- b\n", "line 2: unexpected sequence item"},
+		{"duplicate key", "a: 1\na: 2\n", "line 2: duplicate key \"a\""},
+		{"duplicate flow key", "a: {b: 1, b: 2}\n", "line 1: duplicate key \"b\""},
+		{"block scalar", "a: |\n  text\n", "line 1: block scalars are not supported"},
+		{"folded scalar", "a: >-\n  text\n", "line 1: block scalars are not supported"},
+		{"anchor", "a: &x 1\n", "line 1: anchors, aliases and tags"},
+		{"tag", "a: !!str 1\n", "line 1: anchors, aliases and tags"},
+		{"unterminated quote", "a: \"text\n", "line 1: unterminated quoted string"},
+		{"unterminated flow", "a: [1, 2\n", "line 1: unterminated flow collection"},
+		{"flow without separator", "a: {b: 1 c: 2}\n", "line 1: expected ',' or '}'"},
+		{"text after flow", "a: [1] x\n", "line 1: unexpected \"x\" after flow collection"},
+		{"flow mapping without colon", "a: {b}\n", "line 1: expected ':' in flow mapping"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseYAML(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("parseYAML() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+const testDefinitionJSON = `{
+  "name": "ema-cross",
+  "symbols": ["BTCUSDT", "ETHUSDT"],
+  "timeframe": "15m",
+  "indicators": ["rsi(21)", "band=bb(20,2.5)"],
+  "entry": {"long": "crossover(ema9, ema21) and ind(\"band.lower\") < close"},
+  "exit": {"long": "crossunder(ema9, ema21) or rsi14 > 80"},
+  "stopLoss": {"atr": 2},
+  "takeProfit": {"percent": 3},
+  "exits": [{"type": "trailing_atr", "atrMultiple": 2, "activateR": 1}]
+}`
+
+const testDefinitionYAML = `# EMA crossover
+name: ema-cross
+symbols: [BTCUSDT, ETHUSDT]
+timeframe: 15m
+indicators: ["rsi(21)", "band=bb(20,2.5)"]
+entry:
+  long: crossover(ema9, ema21) and ind("band.lower") < close
+exit:
+  long: crossunder(ema9, ema21) or rsi14 > 80
+stopLoss: {atr: 2}
+takeProfit:
+  percent: 3
+exits:
+  - type: trailing_atr
+    atrMultiple: 2
+    activateR: 1
+`
+
+func TestParseDefinitionFormats(t *testing.T) {
+	want, err := ParseDefinition([]byte(testDefinitionJSON))
+	if err != nil {
+		t.Fatalf("ParseDefinition() = %v", err)
+	}
+	wantJSON, _ := json.Marshal(want)
+
+	tests := []struct {
+		name  string
+		parse func([]byte) (*Definition, error)
+		data  string
+	}{
+		{"yaml", ParseDefinitionYAML, testDefinitionYAML},
+		{"text as json", ParseDefinitionText, "\n  " + testDefinitionJSON},
+		{"text as yaml", ParseDefinitionText, testDefinitionYAML},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := tt.parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parse = %v", err)
+			}
+			if got, _ := json.Marshal(def); string(got) != string(wantJSON) {
+				t.Errorf("definition = %s, want %s", got, wantJSON)
+			}
+		})
+	}
+}
+
+func TestParseDefinitionYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"top-level sequence", "- name: x\n", "expected a mapping at the top level"},
+		{"unknown field", strings.Replace(testDefinitionYAML, "timeframe:", "timeframes:", 1), "unknown field \"timeframes\""},
+		{"invalid yaml", "name: x\n  bad: 1\n", "line 2: unexpected indentation"},
+		{"invalid rule", strings.Replace(testDefinitionYAML, "rsi14 > 80", "rsi14 >", 1), "unexpected \"end of rule\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseDefinitionYAML([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseDefinitionYAML() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
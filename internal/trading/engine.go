@@ -3,7 +3,9 @@
 package trading
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"sync"
 	"time"
 
@@ -22,13 +24,24 @@ type TradingEngine struct {
 	orderManager  *OrderManager             // Limit order manager
 	riskManager   *risk.RiskManager         // Risk management calculator
 	signalHandler *signals.SignalHandler    // Trading signal processor
+	session       *SessionCalendar          // Trading day calendar and trading windows
+	equity        *EquityCurve              // Mark-to-market equity history
+	breaker       *risk.CircuitBreaker      // Kill switch, possibly shared with other engines
+	sizer         risk.PositionSizer        // Position sizer; nil uses RiskManager.CalculatePositionSize
+	portfolio     *risk.PortfolioRisk       // Correlation-aware exposure limits
+	rules         *risk.RuleChain           // Pre-trade risk rules every order goes through
+	strategyLimits map[string]int           // Open position limits per strategy tag
 
 	isRunning bool          // Engine running state
 	stopChan  chan struct{} // Stop signal channel
 	mu        sync.RWMutex // Mutex for thread-safe operations
+	samplerStop chan struct{} // Stop signal for the equity sampler
 
-	config *EngineConfig  // Engine configuration
-	stats  *TradingStats  // Trading statistics
+	config  *EngineConfig  // Engine configuration
+	stats   *TradingStats  // Trading statistics
+	statsMu sync.RWMutex   // Mutex protecting stats
+	dayStartEquity float64 // Equity at the start of the trading day, protected by statsMu
+	breakerPeak    float64 // Peak equity for the breaker drawdown check since its last reset, protected by statsMu
 }
 
 // EngineConfig holds configuration parameters for the trading engine.
@@ -42,13 +55,37 @@ type EngineConfig struct {
 	MinConfidence     float64 // Minimum signal confidence to trade
 	MaxDailyTrades    int     // Maximum trades per day
 	CooldownMinutes   int     // Cooldown between trades in minutes
+	Account           string  // Account tag recorded on trades (default "paper")
+	Session           *SessionConfig // Trading calendar; nil means UTC midnight rollover, no windows
+	CircuitBreaker    *risk.CircuitBreaker // Shared kill switch; nil creates a private one with default limits
+	Sizing            *risk.SizingConfig   // Position sizing model; nil means fixed fractional via RiskPerTrade
+	Portfolio         *risk.PortfolioRisk  // Portfolio exposure limits and price history; nil uses default limits
+	PortfolioHoldings func() ([]risk.Holding, float64) // Holdings and equity of all accounts sharing Portfolio; nil covers this engine only
+	VaRLimit          *risk.VaRLimit       // Optional pre-trade VaR check
+	RiskRules         []string             // Pre-trade rule names in evaluation order; empty uses risk.DefaultRiskRules
+	MaxOpenPositions  int                  // Max simultaneous open positions (0 = no limit)
+	DecisionJournal   *risk.DecisionJournal // Shared log of risk decisions; nil keeps no journal
+	ExitPolicies      map[string][]ExitPolicyConfig // Exit policies per strategy tag; nil uses DefaultExitPolicies
+	EquitySampleInterval time.Duration // Equity sampling cadence (default 1 minute)
+	EquityMaxPoints      int           // Equity samples kept before compaction (default 10000)
 }
 
+// DefaultAccount is the account tag used when EngineConfig.Account is empty.
+const DefaultAccount = "paper"
+
+// Strategy tags recorded on positions and trades for attribution.
+const (
+	StrategyAutonomous = "autonomous" // Signal-driven entries of the trading engine
+	StrategyInterval   = "interval"   // Interval strategy
+	StrategyManual     = "manual"     // Manual orders from the UI
+)
+
 // TradingStats tracks comprehensive trading performance metrics.
 type TradingStats struct {
 	TotalTrades      int       `json:"totalTrades"`
 	WinningTrades    int       `json:"winningTrades"`
 	LosingTrades     int       `json:"losingTrades"`
+	BreakevenTrades  int       `json:"breakevenTrades"` // Trades closed with zero PnL
 	TotalPnL         float64   `json:"totalPnL"`
 	TotalPnLPercent  float64   `json:"totalPnLPercent"`
 	WinRate          float64   `json:"winRate"`
@@ -61,9 +98,8 @@ type TradingStats struct {
 	DailyPnL         float64   `json:"dailyPnL"`
 	TodayTrades      int       `json:"todayTrades"`
 	LastTradeTime    time.Time `json:"lastTradeTime" wails:"-"`
-	StartTime         time.Time `json:"startTime" wails:"-"`
-
-	mu sync.RWMutex
+	StartTime        time.Time `json:"startTime" wails:"-"`
+	TradingDay       string    `json:"tradingDay"`
 }
 
 // NewTradingEngine creates a new trading engine instance with the given configuration.
@@ -77,15 +113,95 @@ func NewTradingEngine(config *EngineConfig) *TradingEngine {
 		DefaultTakeProfit: config.DefaultTakeProfit,
 	}
 
-	return &TradingEngine{
-		paperTrader:   NewPaperTrader(config.InitialBalance),
-		orderManager:  NewOrderManager(),
-		riskManager:   risk.NewRiskManager(riskConfig),
-		signalHandler: signals.NewSignalHandler(),
-		config:        config,
-		stats:         &TradingStats{StartTime: time.Now()},
-		stopChan:      make(chan struct{}),
+	session := newSessionCalendarOrDefault(config.Session)
+	riskManager := risk.NewRiskManager(riskConfig)
+	riskManager.ResetDailyStats(session.CurrentDay())
+
+	paperTrader := NewPaperTrader(config.InitialBalance)
+	account := config.Account
+	if account == "" {
+		account = DefaultAccount
+	}
+	paperTrader.SetAccount(account)
+
+	breaker := config.CircuitBreaker
+	if breaker == nil {
+		breaker = risk.NewCircuitBreaker(nil)
 	}
+
+	portfolio := config.Portfolio
+	if portfolio == nil {
+		portfolio = risk.NewPortfolioRisk(nil)
+	}
+
+	te := &TradingEngine{
+		paperTrader:    paperTrader,
+		orderManager:   NewOrderManager(),
+		riskManager:    riskManager,
+		signalHandler:  signals.NewSignalHandler(),
+		session:        session,
+		equity:         NewEquityCurve(equityMaxPoints(config)),
+		breaker:        breaker,
+		sizer:          newSizerOrDefault(config.Sizing),
+		portfolio:      portfolio,
+		config:         config,
+		stats:          &TradingStats{StartTime: time.Now(), TradingDay: session.CurrentDay()},
+		stopChan:       make(chan struct{}),
+		dayStartEquity: config.InitialBalance,
+		strategyLimits: make(map[string]int),
+	}
+	te.rules = te.newRuleChainOrDefault(config)
+
+	// Движки регистрируются по имени счета, пересозданный движок заменяет старого слушателя
+	breaker.OnTrip(account, func(state risk.BreakerState) {
+		if state.Flatten {
+			te.FlattenAll("Circuit breaker: " + state.Reason)
+		}
+	})
+	// После ручного сброса дневной убыток и просадка считаются от текущего капитала
+	breaker.OnReset(account, func() {
+		equity := te.paperTrader.GetEquity()
+		te.statsMu.Lock()
+		te.dayStartEquity = equity
+		te.breakerPeak = equity
+		te.statsMu.Unlock()
+	})
+
+	return te
+}
+
+// newSizerOrDefault builds the configured position sizer. It returns nil,
+// which selects the risk manager's fixed fractional sizing, when no sizing
+// is configured or the configuration is invalid.
+func newSizerOrDefault(config *risk.SizingConfig) risk.PositionSizer {
+	if config == nil {
+		return nil
+	}
+	sizer, err := risk.NewPositionSizer(config)
+	if err != nil {
+		log.Errorf("Invalid sizing config, falling back to fixed fractional: %v", err)
+		return nil
+	}
+	log.Infof("Position sizer: %s", sizer.Name())
+	return sizer
+}
+
+func equityMaxPoints(config *EngineConfig) int {
+	if config.EquityMaxPoints > 0 {
+		return config.EquityMaxPoints
+	}
+	return 10000
+}
+
+// newSessionCalendarOrDefault builds a session calendar, falling back to the
+// default UTC calendar when the configuration is invalid.
+func newSessionCalendarOrDefault(config *SessionConfig) *SessionCalendar {
+	session, err := NewSessionCalendar(config)
+	if err != nil {
+		log.Errorf("Invalid session config, falling back to UTC calendar: %v", err)
+		session, _ = NewSessionCalendar(DefaultSessionConfig())
+	}
+	return session
 }
 
 func (te *TradingEngine) Start() error {
@@ -100,6 +216,7 @@ func (te *TradingEngine) Start() error {
 	te.stopChan = make(chan struct{})
 
 	go te.mainLoop()
+	te.startEquitySamplerLocked()
 
 	log.Info("Trading engine started")
 	return nil
@@ -115,6 +232,7 @@ func (te *TradingEngine) Stop() {
 
 	close(te.stopChan)
 	te.isRunning = false
+	te.stopEquitySamplerLocked()
 
 	log.Info("Trading engine stopped")
 }
@@ -128,15 +246,38 @@ func (te *TradingEngine) IsRunning() bool {
 func (te *TradingEngine) UpdateConfig(newConfig *EngineConfig) {
 	te.mu.Lock()
 	defer te.mu.Unlock()
+	// Пересоздаем sizer только при смене настроек, чтобы не терять накопленную статистику (Kelly)
+	if !sameSizing(te.config.Sizing, newConfig.Sizing) {
+		te.sizer = newSizerOrDefault(newConfig.Sizing)
+	}
+	if !reflect.DeepEqual(te.config.Session, newConfig.Session) {
+		// Новый календарь продолжает текущий торговый день и историю старого
+		if session, err := NewSessionCalendar(newConfig.Session); err != nil {
+			log.Errorf("Invalid session config, keeping the current calendar: %v", err)
+		} else {
+			session.carryOver(te.session)
+			te.session = session
+		}
+	}
 	te.config = newConfig
-	// Обновляем risk manager
+	te.rules = te.newRuleChainOrDefault(newConfig)
+	// Обновляем risk manager, сохраняя дневную статистику
 	riskConfig := &risk.RiskConfig{
 		RiskPerTrade:      newConfig.RiskPerTrade,
 		MaxPositionSize:   newConfig.MaxPositionSize,
 		DefaultStopLoss:   newConfig.DefaultStopLoss,
 		DefaultTakeProfit: newConfig.DefaultTakeProfit,
 	}
+	dailyStats := te.riskManager.GetDailyStats()
 	te.riskManager = risk.NewRiskManager(riskConfig)
+	te.riskManager.RestoreDailyStats(dailyStats)
+}
+
+func sameSizing(a, b *risk.SizingConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
 }
 
 func (te *TradingEngine) GetSymbol() string {
@@ -169,22 +310,12 @@ func (te *TradingEngine) ProcessSignal(signal *signals.Signal) {
 		return
 	}
 
-	canTradeResult := te.canTrade()
-	log.Infof("ProcessSignal: canTrade() = %v", canTradeResult)
-	if !canTradeResult {
-		log.Warnf("ProcessSignal: Cannot trade - daily limit or cooldown - ABORTING")
-		return
-	}
-
-	// Если MinConfidence = 0, то торгуем при любой уверенности
-	// Иначе проверяем порог
-	if te.config.MinConfidence > 0 && signal.Confidence < te.config.MinConfidence {
-		log.Warnf("ProcessSignal: Signal confidence %.2f below minimum %.2f - ABORTING", 
-			signal.Confidence, te.config.MinConfidence)
+	if signal.Expired(time.Now()) {
+		log.Infof("ProcessSignal: signal %s expired at %s - ABORTING", signal.ID, time.UnixMilli(signal.ValidUntil).Format(time.RFC3339))
 		return
 	}
 
-	log.Infof("✅ ProcessSignal: All checks passed! Opening position...")
+	// Лимиты (уверенность, кулдаун, дневной лимит и т.д.) проверяет цепочка риск-правил в openPosition
 	log.Infof("   Symbol: %s, Direction: %s, Confidence: %.2f, Price: %.2f", 
 		signal.Symbol, signal.Direction, signal.Confidence, signal.Price)
 
@@ -202,6 +333,17 @@ func (te *TradingEngine) ProcessSignal(signal *signals.Signal) {
 	log.Infof("ProcessSignal: openPosition() returned")
 }
 
+// claimSignal acknowledges a signal for this engine's account once an entry
+// on it is approved. Every signal, including its duplicates, opens at most
+// one position; rejected entries leave it unclaimed.
+func (te *TradingEngine) claimSignal(signal *signals.Signal) bool {
+	if !te.signalHandler.Acknowledge(te.paperTrader.GetAccount(), signal) {
+		log.Debugf("Signal %s already consumed by %s, skipping entry", signal.RootID(), te.paperTrader.GetAccount())
+		return false
+	}
+	return true
+}
+
 func (te *TradingEngine) mainLoop() {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -211,6 +353,8 @@ func (te *TradingEngine) mainLoop() {
 		case <-te.stopChan:
 			return
 		case <-ticker.C:
+			te.checkRollover()
+			te.checkStaleData()
 			te.processSignals()
 			te.checkPositions()
 			te.processOrders()
@@ -229,22 +373,8 @@ func (te *TradingEngine) processSignals() {
 		return
 	}
 
-	// Проверяем, что сигнал не HOLD
-	if signal.Direction == "HOLD" {
-		return
-	}
-
-	if !te.canTrade() {
-		log.Debugf("Cannot trade: daily limit or cooldown")
-		return
-	}
-
-	// Если MinConfidence = 0, то торгуем при любой уверенности
-	// Иначе проверяем порог
-	if te.config.MinConfidence > 0 && signal.Confidence < te.config.MinConfidence {
-		log.Debugf("Signal confidence %.2f below minimum %.2f - SKIPPING TRADE", signal.Confidence, te.config.MinConfidence)
-		log.Debugf("Signal details: Direction=%s, TechnicalScore=%.4f, Price=%.2f", 
-			signal.Direction, signal.TechnicalSignal, signal.Price)
+	// Проверяем, что сигнал не HOLD и еще действителен
+	if signal.Direction == "HOLD" || signal.Expired(time.Now()) {
 		return
 	}
 
@@ -259,24 +389,40 @@ func (te *TradingEngine) processSignals() {
 	te.openPosition(signal)
 }
 
-func (te *TradingEngine) canTrade() bool {
-	te.stats.mu.RLock()
-	defer te.stats.mu.RUnlock()
-
-	if te.stats.TodayTrades >= te.config.MaxDailyTrades {
-		log.Debugf("Cannot trade: TodayTrades (%d) >= MaxDailyTrades (%d)", 
-			te.stats.TodayTrades, te.config.MaxDailyTrades)
-		return false
+// checkRollover archives the finished trading day and resets daily counters
+// when the session calendar crosses into a new day.
+func (te *TradingEngine) checkRollover() {
+	session := te.calendar()
+	previous, current, rolled := session.CheckRollover(time.Now())
+	if !rolled {
+		return
 	}
 
-	timeSinceLast := time.Since(te.stats.LastTradeTime)
-	if timeSinceLast < time.Duration(te.config.CooldownMinutes)*time.Minute {
-		log.Debugf("Cannot trade: Cooldown - time since last trade: %v, required: %d minutes", 
-			timeSinceLast, te.config.CooldownMinutes)
-		return false
+	daily := te.riskManager.GetDailyStats()
+
+	te.statsMu.Lock()
+	summary := DailySummary{
+		Date:          previous,
+		Trades:        te.stats.TodayTrades,
+		WinningTrades: daily.TradesWon,
+		LosingTrades:  daily.TradesLost,
+		BreakevenTrades: daily.TradesEven,
+		PnL:           te.stats.DailyPnL,
+		MaxDailyLoss:  daily.MaxDailyLoss,
+		EndBalance:    te.paperTrader.GetBalance(),
 	}
-
-	return true
+	te.stats.TodayTrades = 0
+	te.stats.DailyPnL = 0
+	te.stats.TradingDay = current
+	te.dayStartEquity = te.paperTrader.GetEquity()
+	te.statsMu.Unlock()
+
+	session.Archive(summary)
+	te.riskManager.ResetDailyStats(current)
+
+	log.Infof("=== TRADING DAY ROLLOVER: %s -> %s ===", previous, current)
+	log.Infof("Archived %s: Trades=%d, Won=%d, Lost=%d, PnL=%.2f USDT",
+		summary.Date, summary.Trades, summary.WinningTrades, summary.LosingTrades, summary.PnL)
 }
 
 func (te *TradingEngine) openPosition(signal *signals.Signal) {
@@ -284,11 +430,16 @@ func (te *TradingEngine) openPosition(signal *signals.Signal) {
 	log.Infof("Signal: ID=%s, Symbol=%s, Direction=%s, Confidence=%.2f, Price=%.8f", 
 		signal.ID, signal.Symbol, signal.Direction, signal.Confidence, signal.Price)
 
+	if te.signalHandler.IsAcknowledged(te.paperTrader.GetAccount(), signal) {
+		log.Debugf("Signal %s already consumed by %s, skipping entry", signal.RootID(), te.paperTrader.GetAccount())
+		return
+	}
+
 	balance := te.paperTrader.GetBalance()
 	currentPrice := signal.Price
 
 	stopLoss := te.calculateStopLoss(signal)
-	positionSize := te.riskManager.CalculatePositionSize(balance, currentPrice, stopLoss)
+	positionSize := te.positionSize(signal, balance, stopLoss)
 
 	log.Infof("Position calculation: Balance=%.2f, CurrentPrice=%.8f, StopLoss=%.8f, PositionSize=%.8f",
 		balance, currentPrice, stopLoss, positionSize)
@@ -298,6 +449,24 @@ func (te *TradingEngine) openPosition(signal *signals.Signal) {
 		return
 	}
 
+	positionSize, err := te.checkOrder(risk.OrderIntent{
+		Strategy:   StrategyAutonomous,
+		Symbol:     te.config.Symbol,
+		Side:       signal.Direction,
+		Price:      currentPrice,
+		Quantity:   positionSize,
+		Confidence: signal.Confidence,
+		FromSignal: true,
+	})
+	if err != nil {
+		log.Warnf("Entry rejected: %v", err)
+		return
+	}
+	// Сигнал считается использованным только одобренным входом
+	if !te.claimSignal(signal) {
+		return
+	}
+
 	takeProfit := te.calculateTakeProfit(signal)
 	position := &Position{
 		Symbol:     te.config.Symbol,
@@ -308,14 +477,19 @@ func (te *TradingEngine) openPosition(signal *signals.Signal) {
 		TakeProfit: takeProfit,
 		OpenedAt:   time.Now(),
 		SignalID:   signal.ID,
+		EntryATR:   signal.ATR,
+		Strategy:   StrategyAutonomous,
 	}
+	te.attachExits(position)
 
 	log.Infof("Position details: Symbol=%s, Side=%s, EntryPrice=%.8f, Quantity=%.8f, StopLoss=%.8f, TakeProfit=%.8f",
 		position.Symbol, position.Side, position.EntryPrice, position.Quantity, position.StopLoss, position.TakeProfit)
 
-	err := te.paperTrader.OpenPosition(position)
+	err = te.paperTrader.OpenPosition(position)
 	if err != nil {
 		log.Errorf("Failed to open position: %v", err)
+		te.recordOrderError(err)
+		te.signalHandler.Release(te.paperTrader.GetAccount(), signal)
 		return
 	}
 
@@ -324,72 +498,159 @@ func (te *TradingEngine) openPosition(signal *signals.Signal) {
 	log.Info("=== BOT POSITION OPENED SUCCESSFULLY ===")
 }
 
-func (te *TradingEngine) handleExistingPosition(signal *signals.Signal) {
-	position := te.paperTrader.GetPosition(te.config.Symbol)
-	if position == nil {
-		return
+// holdings returns open positions as signed notionals at the latest price.
+func (te *TradingEngine) holdings() []risk.Holding {
+	positions := te.paperTrader.GetAllPositions()
+	holdings := make([]risk.Holding, 0, len(positions))
+	for _, pos := range positions {
+		notional := te.markPrice(&pos) * pos.Quantity
+		if !isLongSide(pos.Side) {
+			notional = -notional
+		}
+		holdings = append(holdings, risk.Holding{Symbol: pos.Symbol, Notional: notional})
 	}
+	return holdings
+}
 
-	// For scalping: more aggressive reversal (lower threshold)
-	// Close position if opposite signal with moderate confidence
-	if signal.Direction != position.Side && signal.Confidence > 0.5 {
-		te.closePosition(position, "Signal reversal")
-		te.openPosition(signal)
+// GetHoldings returns open positions as signed notionals at the latest price.
+func (te *TradingEngine) GetHoldings() []risk.Holding {
+	return te.holdings()
+}
+
+// GetEquity returns cash plus the marked value of open positions.
+func (te *TradingEngine) GetEquity() float64 {
+	return te.paperTrader.GetEquity()
+}
+
+// GetPortfolioExposure returns current exposure against the portfolio limits
+// together with the correlation matrix of held and extra symbols.
+func (te *TradingEngine) GetPortfolioExposure(extra ...string) risk.PortfolioExposure {
+	return te.portfolio.Exposure(te.holdings(), te.paperTrader.GetEquity(), extra...)
+}
+
+// LoadPriceHistory warms up the portfolio returns series from bar closes.
+func (te *TradingEngine) LoadPriceHistory(symbol string, closeTimes []time.Time, closes []float64) bool {
+	return te.portfolio.LoadHistory(symbol, closeTimes, closes)
+}
+
+// positionSize sizes an entry with the configured sizer, or with the risk
+// manager's fixed fractional sizing when none is configured.
+func (te *TradingEngine) positionSize(signal *signals.Signal, balance, stopLoss float64) float64 {
+	te.mu.RLock()
+	sizer := te.sizer
+	te.mu.RUnlock()
+
+	if sizer == nil {
+		return te.riskManager.CalculatePositionSize(balance, signal.Price, stopLoss)
+	}
+	return sizer.Size(risk.SizingRequest{
+		Symbol:     signal.Symbol,
+		Equity:     te.paperTrader.GetEquity(),
+		Available:  balance,
+		EntryPrice: signal.Price,
+		StopLoss:   stopLoss,
+		ATR:        signal.ATR,
+	})
+}
+
+// recordOrderError counts a failed order execution towards the circuit
+// breaker; orders rejected by validation are not execution failures.
+func (te *TradingEngine) recordOrderError(err error) {
+	var invalid *OrderValidationError
+	if errors.As(err, &invalid) {
 		return
 	}
+	te.breaker.RecordOrderError(err)
+}
 
-	currentPrice := signal.Price
-	pnlPercent := te.calculatePnLPercent(position, currentPrice)
-
-	// For scalping: move stop to breakeven faster (0.5% instead of 1.0%)
-	if pnlPercent > 0.5 {
-		if position.Side == "LONG" && position.StopLoss < position.EntryPrice {
-			position.StopLoss = position.EntryPrice
-			log.Info("Stop loss moved to breakeven (scalping)")
-		} else if position.Side == "SHORT" && position.StopLoss > position.EntryPrice {
-			position.StopLoss = position.EntryPrice
-			log.Info("Stop loss moved to breakeven (scalping)")
-		}
+// recordClosedTrade feeds a closed trade to the circuit breaker and to
+// adaptive position sizers.
+func (te *TradingEngine) recordClosedTrade(trade *Trade) {
+	te.breaker.RecordTrade(trade.PnL)
+
+	te.mu.RLock()
+	sizer := te.sizer
+	te.mu.RUnlock()
+	if recorder, ok := sizer.(risk.TradeRecorder); ok {
+		recorder.RecordTrade(trade.PnLPercent)
 	}
-	
-	// For scalping: take profit earlier if we have small profit
-	// Close position if we have 0.3% profit and signal is weakening
-	if pnlPercent > 0.3 && signal.Confidence < 0.4 {
-		te.closePosition(position, "Quick profit taken (scalping)")
+}
+
+func (te *TradingEngine) handleExistingPosition(signal *signals.Signal) {
+	position := te.paperTrader.GetPosition(te.config.Symbol)
+	if position == nil {
 		return
 	}
+
+	// Выход определяют политики стратегии позиции (breakeven, разворот по сигналу и т.д.)
+	closed, reverse := te.applyExits(*position, ExitContext{
+		Now:    time.Now(),
+		Price:  signal.Price,
+		ATR:    signal.ATR,
+		Signal: signal,
+	})
+	if closed && reverse {
+		te.openPosition(signal)
+	}
 }
 
 func (te *TradingEngine) checkPositions() {
 	positions := te.paperTrader.GetAllPositions()
-	currentPrice := te.signalHandler.GetCurrentPrice(te.config.Symbol)
 
 	for _, pos := range positions {
+		// Каждую позицию проверяем по цене ее собственного символа
+		currentPrice := te.signalHandler.GetCurrentPrice(pos.Symbol)
+		if currentPrice <= 0 {
+			continue
+		}
+
 		if te.isStopLossHit(&pos, currentPrice) {
-			te.closePosition(&pos, "Stop loss hit")
+			te.closePositionAt(&pos, currentPrice, "Stop loss hit")
 			continue
 		}
 
 		if te.isTakeProfitHit(&pos, currentPrice) {
-			te.closePosition(&pos, "Take profit hit")
+			te.closePositionAt(&pos, currentPrice, "Take profit hit")
 			continue
 		}
+
+		te.applyExits(pos, ExitContext{Now: time.Now(), Price: currentPrice})
 	}
 }
 
-func (te *TradingEngine) closePosition(pos *Position, reason string) {
+// closePositionAt closes a position at the given price and reports whether
+// it was closed.
+func (te *TradingEngine) closePositionAt(pos *Position, currentPrice float64, reason string) bool {
+	if err := te.exitAt(pos, currentPrice, reason); err != nil {
+		log.Warnf("%v", err)
+		return false
+	}
+	return true
+}
+
+// exitAt closes a position at the given price once the risk rules approve
+// the reducing order.
+func (te *TradingEngine) exitAt(pos *Position, currentPrice float64, reason string) error {
 	log.Infof("=== BOT CLOSING POSITION ===")
 	log.Infof("Position: ID=%s, Symbol=%s, Side=%s, EntryPrice=%.8f, Quantity=%.8f", 
 		pos.ID, pos.Symbol, pos.Side, pos.EntryPrice, pos.Quantity)
 	log.Infof("Reason: %s", reason)
-
-	currentPrice := te.signalHandler.GetCurrentPrice(te.config.Symbol)
 	log.Infof("Current price: %.8f", currentPrice)
 
+	if _, err := te.checkOrder(risk.OrderIntent{
+		Strategy: pos.Strategy,
+		Symbol:   pos.Symbol,
+		Side:     pos.Side,
+		Price:    currentPrice,
+		Quantity: pos.Quantity,
+		Reduce:   true,
+	}); err != nil {
+		return fmt.Errorf("close rejected: %w", err)
+	}
+
 	trade, err := te.paperTrader.ClosePosition(pos.Symbol, currentPrice, reason)
 	if err != nil {
-		log.Errorf("Failed to close position: %v", err)
-		return
+		return fmt.Errorf("failed to close position: %w", err)
 	}
 
 	te.updateStats(trade)
@@ -397,6 +658,7 @@ func (te *TradingEngine) closePosition(pos *Position, reason string) {
 	log.Infof("=== BOT POSITION CLOSED SUCCESSFULLY ===")
 	log.Infof("Trade: ID=%s, PnL=%.2f USDT (%.2f%%), Duration=%v", 
 		trade.ID, trade.PnL, trade.PnLPercent, trade.Duration)
+	return nil
 }
 
 func (te *TradingEngine) isStopLossHit(pos *Position, price float64) bool {
@@ -414,6 +676,11 @@ func (te *TradingEngine) isTakeProfitHit(pos *Position, price float64) bool {
 }
 
 func (te *TradingEngine) calculateStopLoss(signal *signals.Signal) float64 {
+	// Уровень, заданный самим сигналом (например, внешним алертом), важнее расчётного
+	if signal.StopLoss > 0 {
+		return signal.StopLoss
+	}
+
 	atr := signal.ATR
 	if atr == 0 {
 		// For scalping, use tighter stop loss
@@ -438,6 +705,10 @@ func (te *TradingEngine) calculateStopLoss(signal *signals.Signal) float64 {
 }
 
 func (te *TradingEngine) calculateTakeProfit(signal *signals.Signal) float64 {
+	if signal.TakeProfit > 0 {
+		return signal.TakeProfit
+	}
+
 	atr := signal.ATR
 	if atr == 0 {
 		atr = signal.Price * te.config.DefaultTakeProfit / 100
@@ -468,43 +739,40 @@ func (te *TradingEngine) calculatePnLPercent(pos *Position, currentPrice float64
 }
 
 func (te *TradingEngine) updateStats(trade *Trade) {
-	te.stats.mu.Lock()
-	defer te.stats.mu.Unlock()
+	if trade != nil {
+		te.riskManager.UpdateDailyStats(trade.PnL)
+		te.recordClosedTrade(trade)
+	}
+
+	te.statsMu.Lock()
+	defer te.statsMu.Unlock()
 
 	if trade != nil {
 		te.stats.TotalTrades++
 		te.stats.TotalPnL += trade.PnL
 		te.stats.TodayTrades++
+		te.stats.DailyPnL += trade.PnL
 		te.stats.LastTradeTime = time.Now()
 
-		if trade.PnL > 0 {
+		// Сделка с нулевым PnL - безубыточная, не выигрыш и не проигрыш
+		switch {
+		case trade.PnL > 0:
 			te.stats.WinningTrades++
 			te.stats.AvgWin = (te.stats.AvgWin*float64(te.stats.WinningTrades-1) + trade.PnLPercent) / float64(te.stats.WinningTrades)
-		} else {
+		case trade.PnL < 0:
 			te.stats.LosingTrades++
 			te.stats.AvgLoss = (te.stats.AvgLoss*float64(te.stats.LosingTrades-1) + trade.PnLPercent) / float64(te.stats.LosingTrades)
+		default:
+			te.stats.BreakevenTrades++
 		}
 
-		if te.stats.TotalTrades > 0 {
-			te.stats.WinRate = float64(te.stats.WinningTrades) / float64(te.stats.TotalTrades) * 100
+		if decided := te.stats.WinningTrades + te.stats.LosingTrades; decided > 0 {
+			te.stats.WinRate = float64(te.stats.WinningTrades) / float64(decided) * 100
 		}
 	}
 
-	balance := te.paperTrader.GetBalance()
-	initialBalance := te.paperTrader.GetInitialBalance()
-
-	te.stats.TotalPnLPercent = (balance - initialBalance) / initialBalance * 100
-
-	if balance > te.stats.PeakBalance {
-		te.stats.PeakBalance = balance
-	}
-
-	if te.stats.PeakBalance > 0 {
-		te.stats.CurrentDrawdown = (te.stats.PeakBalance - balance) / te.stats.PeakBalance * 100
-		if te.stats.CurrentDrawdown > te.stats.MaxDrawdown {
-			te.stats.MaxDrawdown = te.stats.CurrentDrawdown
-		}
-	}
+	point := te.sampleEquity("fill")
+	te.applyEquityPoint(point)
 
 	if te.stats.AvgLoss != 0 && te.stats.LosingTrades > 0 {
 		totalWins := te.stats.AvgWin * float64(te.stats.WinningTrades)
@@ -515,12 +783,52 @@ func (te *TradingEngine) updateStats(trade *Trade) {
 	}
 }
 
+// applyEquityPoint updates return and drawdown statistics from a
+// mark-to-market snapshot. The caller must hold statsMu.
+func (te *TradingEngine) applyEquityPoint(point EquityPoint) {
+	initialBalance := te.paperTrader.GetInitialBalance()
+	if initialBalance > 0 {
+		te.stats.TotalPnLPercent = (point.Equity - initialBalance) / initialBalance * 100
+	}
+
+	te.stats.PeakBalance = point.PeakEquity
+	te.stats.CurrentDrawdown = point.Drawdown
+	if te.stats.CurrentDrawdown > te.stats.MaxDrawdown {
+		te.stats.MaxDrawdown = te.stats.CurrentDrawdown
+	}
+
+	// Просадка для предохранителя считается от пика после его последнего сброса
+	if point.Equity > te.breakerPeak {
+		te.breakerPeak = point.Equity
+	}
+	var drawdown float64
+	if te.breakerPeak > 0 {
+		drawdown = (te.breakerPeak - point.Equity) / te.breakerPeak
+	}
+	te.breaker.CheckEquity(te.paperTrader.GetAccount(), te.dayStartEquity, point.Equity, drawdown)
+}
+
 func (te *TradingEngine) GetStats() TradingStats {
-	te.stats.mu.RLock()
-	defer te.stats.mu.RUnlock()
+	te.checkRollover()
+
+	te.statsMu.RLock()
+	defer te.statsMu.RUnlock()
 	return *te.stats
 }
 
+// GetDailyHistory returns summaries of finished trading days.
+func (te *TradingEngine) GetDailyHistory() []DailySummary {
+	te.checkRollover()
+	return te.calendar().GetHistory()
+}
+
+// calendar returns the session calendar, which UpdateConfig may replace.
+func (te *TradingEngine) calendar() *SessionCalendar {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	return te.session
+}
+
 func (te *TradingEngine) GetBalance() float64 {
 	return te.paperTrader.GetBalance()
 }
@@ -533,6 +841,12 @@ func (te *TradingEngine) GetTradeHistory() []Trade {
 	return te.paperTrader.GetTradeHistory()
 }
 
+// GetExcursionAnalysis returns MAE/MFE statistics and the stop/target grid
+// for all closed trades.
+func (te *TradingEngine) GetExcursionAnalysis() ExcursionReport {
+	return AnalyzeExcursions(te.paperTrader.GetTradeHistory())
+}
+
 func (te *TradingEngine) SetSignalHandler(handler *signals.SignalHandler) {
 	te.signalHandler = handler
 }
@@ -541,8 +855,25 @@ func (te *TradingEngine) SetSignalHandler(handler *signals.SignalHandler) {
 func (te *TradingEngine) PlaceBuyOrder(position *Position) error {
 	// Update position PnL before opening
 	if te.paperTrader.HasOpenPosition(position.Symbol) {
-		return fmt.Errorf("position already exists for %s", position.Symbol)
+		return validationErrorf("position already exists for %s", position.Symbol)
+	}
+
+	strategy := position.Strategy
+	if strategy == "" {
+		strategy = StrategyManual
 	}
+	quantity, err := te.checkOrder(risk.OrderIntent{
+		Strategy: strategy,
+		Symbol:   position.Symbol,
+		Side:     "BUY",
+		Price:    position.EntryPrice,
+		Quantity: position.Quantity,
+	})
+	if err != nil {
+		return err
+	}
+	position.Quantity = quantity
+	te.attachExits(position)
 
 	position.Side = "BUY"
 	return te.paperTrader.OpenPosition(position)
@@ -554,17 +885,26 @@ func (te *TradingEngine) processOrders() {
 	if te.config.Symbol != "" {
 		currentPrice := te.signalHandler.GetCurrentPrice(te.config.Symbol)
 		if currentPrice > 0 {
-			_, err := te.orderManager.ProcessLimitOrders(te.config.Symbol, currentPrice, te.paperTrader)
+			filled, err := te.orderManager.ProcessLimitOrders(te.config.Symbol, currentPrice, te.paperTrader)
 			if err != nil {
 				log.Errorf("Error processing orders: %v", err)
 			}
+			if len(filled) > 0 {
+				te.recordFill()
+			}
 		}
 	}
 }
 
 // ProcessOrdersForSymbol processes limit orders for a specific symbol
 func (te *TradingEngine) ProcessOrdersForSymbol(symbol string, currentPrice float64) ([]*Order, error) {
-	return te.orderManager.ProcessLimitOrders(symbol, currentPrice, te.paperTrader)
+	te.UpdatePrice(symbol, currentPrice)
+	filled, err := te.orderManager.ProcessLimitOrders(symbol, currentPrice, te.paperTrader)
+	if len(filled) > 0 {
+		te.recordFill()
+	}
+	te.ProcessExits(symbol, currentPrice)
+	return filled, err
 }
 
 // CreateLimitOrder creates a new limit order
@@ -586,12 +926,27 @@ func (te *TradingEngine) CreateLimitOrder(symbol, side string, price, quantity f
 		log.Infof("Sell order validated: position quantity %.8f", position.Quantity)
 	}
 
+	approved, err := te.checkOrder(risk.OrderIntent{
+		Strategy: StrategyManual,
+		Symbol:   symbol,
+		Side:     side,
+		Price:    price,
+		Quantity: quantity,
+		Reduce:   side == "SELL",
+	})
+	if err != nil {
+		log.Warnf("Limit order rejected: %v", err)
+		return err
+	}
+	quantity = approved
+
 	order := &Order{
 		Symbol:   symbol,
 		Side:     side,
 		Type:     "LIMIT",
 		Price:    price,
 		Quantity: quantity,
+		Strategy: StrategyManual,
 	}
 
 	// Reserve balance for BUY orders
@@ -606,7 +961,7 @@ func (te *TradingEngine) CreateLimitOrder(symbol, side string, price, quantity f
 		log.Infof("Balance reserved: %.2f -> %.2f USDT (reserved: %.2f)", balanceBefore, balanceAfter, cost)
 	}
 
-	err := te.orderManager.CreateOrder(order)
+	err = te.orderManager.CreateOrder(order)
 	if err != nil {
 		log.Errorf("Failed to create limit order: %v", err)
 	} else {
@@ -617,22 +972,44 @@ func (te *TradingEngine) CreateLimitOrder(symbol, side string, price, quantity f
 	return err
 }
 
-// ExecuteMarketOrder executes a market order immediately
+// ExecuteMarketOrder executes a manual market order immediately
 // If stopLoss and takeProfit are provided (> 0), they will be set for the position
 func (te *TradingEngine) ExecuteMarketOrder(symbol, side string, price, quantity float64, stopLoss, takeProfit float64) error {
+	return te.ExecuteMarketOrderAs(StrategyManual, symbol, side, price, quantity, stopLoss, takeProfit)
+}
+
+// ExecuteMarketOrderAs executes a market order on behalf of the given strategy,
+// which is recorded on the resulting position and trade
+func (te *TradingEngine) ExecuteMarketOrderAs(strategy, symbol, side string, price, quantity float64, stopLoss, takeProfit float64) error {
 	log.Infof("=== EXECUTING MARKET ORDER ===")
-	log.Infof("Symbol: %s, Side: %s, Price: %.8f, Quantity: %.8f", symbol, side, price, quantity)
+	log.Infof("Symbol: %s, Side: %s, Price: %.8f, Quantity: %.8f, Strategy: %s", symbol, side, price, quantity, strategy)
 	if stopLoss > 0 {
 		log.Infof("StopLoss: %.8f, TakeProfit: %.8f", stopLoss, takeProfit)
 	}
 
+	approved, err := te.checkOrder(risk.OrderIntent{
+		Strategy: strategy,
+		Symbol:   symbol,
+		Side:     side,
+		Price:    price,
+		Quantity: quantity,
+		Reduce:   side != "BUY",
+	})
+	if err != nil {
+		log.Warnf("Market order rejected: %v", err)
+		return err
+	}
+	quantity = approved
+
 	position := &Position{
 		Symbol:     symbol,
 		Side:       side,
 		EntryPrice: price,
 		Quantity:   quantity,
 		OpenedAt:   time.Now(),
+		Strategy:   strategy,
 	}
+	te.attachExits(position)
 
 	// Устанавливаем StopLoss и TakeProfit если они указаны
 	if stopLoss > 0 {
@@ -642,7 +1019,6 @@ func (te *TradingEngine) ExecuteMarketOrder(symbol, side string, price, quantity
 		position.TakeProfit = takeProfit
 	}
 
-	var err error
 	if side == "BUY" {
 		position.Side = "BUY"
 		err = te.paperTrader.OpenPosition(position)
@@ -661,11 +1037,129 @@ func (te *TradingEngine) ExecuteMarketOrder(symbol, side string, price, quantity
 			log.Infof("Market sell order executed successfully")
 		}
 	}
+	if err == nil {
+		te.UpdatePrice(symbol, price)
+		te.recordFill()
+	} else {
+		te.recordOrderError(err)
+	}
 	log.Info("=== MARKET ORDER EXECUTION COMPLETE ===")
 
 	return err
 }
 
+// OpenPositionFor opens a position from a LONG or SHORT signal on behalf of
+// strategy. The position is sized by sizer (nil uses the engine's sizing)
+// and managed by exits (nil uses the strategy's configured policies); the
+// signal's stop-loss and take-profit levels are used when set. The entry
+// passes the engine's risk rules and consumes the signal for the account
+// only once approved.
+func (te *TradingEngine) OpenPositionFor(strategy string, signal *signals.Signal, sizer risk.PositionSizer, exits []ExitPolicyConfig) error {
+	if signal.Direction != "LONG" && signal.Direction != "SHORT" {
+		return fmt.Errorf("cannot open a position from a %s signal", signal.Direction)
+	}
+	if signal.Expired(time.Now()) {
+		return fmt.Errorf("signal %s expired", signal.ID)
+	}
+	if te.paperTrader.HasOpenPosition(signal.Symbol) {
+		return fmt.Errorf("position already exists for %s", signal.Symbol)
+	}
+	if te.signalHandler.IsAcknowledged(te.paperTrader.GetAccount(), signal) {
+		return fmt.Errorf("signal %s already consumed", signal.RootID())
+	}
+
+	balance := te.paperTrader.GetBalance()
+	stopLoss := te.calculateStopLoss(signal)
+	var quantity float64
+	if sizer != nil {
+		quantity = sizer.Size(risk.SizingRequest{
+			Symbol:     signal.Symbol,
+			Equity:     te.paperTrader.GetEquity(),
+			Available:  balance,
+			EntryPrice: signal.Price,
+			StopLoss:   stopLoss,
+			ATR:        signal.ATR,
+		})
+	} else {
+		quantity = te.positionSize(signal, balance, stopLoss)
+	}
+	if quantity <= 0 {
+		return fmt.Errorf("position size for %s is 0", signal.Symbol)
+	}
+
+	quantity, err := te.checkOrder(risk.OrderIntent{
+		Strategy:   strategy,
+		Symbol:     signal.Symbol,
+		Side:       signal.Direction,
+		Price:      signal.Price,
+		Quantity:   quantity,
+		Confidence: signal.Confidence,
+		FromSignal: true,
+	})
+	if err != nil {
+		return err
+	}
+	if !te.claimSignal(signal) {
+		return fmt.Errorf("signal %s already consumed", signal.RootID())
+	}
+
+	position := &Position{
+		Symbol:     signal.Symbol,
+		Side:       signal.Direction,
+		EntryPrice: signal.Price,
+		Quantity:   quantity,
+		StopLoss:   stopLoss,
+		TakeProfit: te.calculateTakeProfit(signal),
+		OpenedAt:   time.Now(),
+		SignalID:   signal.ID,
+		EntryATR:   signal.ATR,
+		Strategy:   strategy,
+	}
+	if exits != nil {
+		position.Exits = append([]ExitPolicyConfig{}, exits...)
+	}
+	te.attachExits(position)
+
+	if err := te.paperTrader.OpenPosition(position); err != nil {
+		te.recordOrderError(err)
+		te.signalHandler.Release(te.paperTrader.GetAccount(), signal)
+		return err
+	}
+	te.UpdatePrice(signal.Symbol, signal.Price)
+	te.recordFill()
+	te.updateStats(nil)
+	return nil
+}
+
+// ExitPosition closes the open position in symbol at price through the
+// risk rules, as a reducing order; a rejection is returned as the error.
+func (te *TradingEngine) ExitPosition(symbol string, price float64, reason string) error {
+	pos := te.paperTrader.GetPosition(symbol)
+	if pos == nil {
+		return fmt.Errorf("no position for %s", symbol)
+	}
+	return te.exitAt(pos, price, reason)
+}
+
+// ProcessStops closes the open position in symbol when price reaches its
+// stop-loss or take-profit and reports whether it was closed. Strategies
+// trading through an engine whose main loop is not running call it on
+// every price update.
+func (te *TradingEngine) ProcessStops(symbol string, price float64) bool {
+	pos := te.paperTrader.GetPosition(symbol)
+	if pos == nil || price <= 0 {
+		return false
+	}
+	long := isLongSide(pos.Side)
+	switch {
+	case pos.StopLoss > 0 && (long && price <= pos.StopLoss || !long && price >= pos.StopLoss):
+		return te.closePositionAt(pos, price, "Stop loss hit")
+	case pos.TakeProfit > 0 && (long && price >= pos.TakeProfit || !long && price <= pos.TakeProfit):
+		return te.closePositionAt(pos, price, "Take profit hit")
+	}
+	return false
+}
+
 // CancelOrder cancels an order
 func (te *TradingEngine) CancelOrder(orderID string) error {
 	log.Infof("=== CANCELLING ORDER ===")
@@ -713,7 +1207,7 @@ func (te *TradingEngine) GetAllOrders() []Order {
 func (te *TradingEngine) PlaceSellOrder(symbol string, quantity float64, price float64) error {
 	position := te.paperTrader.GetPosition(symbol)
 	if position == nil {
-		return fmt.Errorf("no position found for %s", symbol)
+		return validationErrorf("no position found for %s", symbol)
 	}
 
 	if quantity > position.Quantity {
@@ -722,31 +1216,185 @@ func (te *TradingEngine) PlaceSellOrder(symbol string, quantity float64, price f
 
 	// If selling all, close position
 	if quantity >= position.Quantity {
-		_, err := te.paperTrader.ClosePosition(symbol, price, "Manual sell")
+		trade, err := te.paperTrader.ClosePosition(symbol, price, "Manual sell")
+		if err == nil {
+			te.updateStats(trade)
+		}
 		return err
 	}
 
-	// If selling part, we need to adjust position
-	// For simplicity, we'll close the entire position and create a new one with remaining quantity
-	_, err := te.paperTrader.ClosePosition(symbol, price, "Partial sell")
+	// Частичная продажа уменьшает позицию, сохраняя ее выходы и историю
+	trade, err := te.paperTrader.ReducePosition(symbol, quantity, price, "Partial sell")
 	if err != nil {
 		return err
 	}
+	te.updateStats(trade)
+	return nil
+}
+
+
+// UpdatePrice records the latest market price for a symbol and marks any
+// open position in that symbol to market.
+func (te *TradingEngine) UpdatePrice(symbol string, price float64) {
+	if price <= 0 {
+		return
+	}
+	te.signalHandler.UpdatePrice(symbol, price)
+	te.paperTrader.UpdatePosition(symbol, price)
+	now := time.Now()
+	te.breaker.RecordMarketData(symbol, now)
+	te.portfolio.RecordPrice(symbol, now, price)
+}
+
+// checkStaleData trips the circuit breaker when a symbol with an open
+// position stops receiving prices. Only running engines check staleness,
+// since a stopped engine is not expected to receive market data.
+func (te *TradingEngine) checkStaleData() {
+	positions := te.paperTrader.GetAllPositions()
+	if len(positions) == 0 {
+		return
+	}
+	symbols := make([]string, 0, len(positions))
+	for _, pos := range positions {
+		symbols = append(symbols, pos.Symbol)
+	}
+	te.breaker.CheckStaleData(symbols, time.Now())
+}
 
-	remainingQty := position.Quantity - quantity
-	if remainingQty > 0 {
-		newPosition := &Position{
-			Symbol:     symbol,
-			Side:       position.Side,
-			EntryPrice: position.EntryPrice,
-			Quantity:   remainingQty,
-			StopLoss:   position.StopLoss,
-			TakeProfit: position.TakeProfit,
-			OpenedAt:   time.Now(),
+// FlattenAll cancels pending orders and closes every open position at the
+// latest known price.
+func (te *TradingEngine) FlattenAll(reason string) {
+	log.Warnf("=== FLATTENING ALL POSITIONS: %s ===", reason)
+
+	for _, order := range te.orderManager.GetOrders("") {
+		if err := te.CancelOrder(order.ID); err != nil {
+			log.Errorf("Failed to cancel order %s: %v", order.ID, err)
 		}
-		return te.paperTrader.OpenPosition(newPosition)
 	}
 
-	return nil
+	for _, pos := range te.paperTrader.GetAllPositions() {
+		price := te.markPrice(&pos)
+		trade, err := te.paperTrader.ClosePosition(pos.Symbol, price, reason)
+		if err != nil {
+			log.Errorf("Failed to close position %s: %v", pos.Symbol, err)
+			continue
+		}
+		te.updateStats(trade)
+	}
+
+	log.Warnf("=== FLATTEN COMPLETE ===")
+}
+
+// markPrice returns the latest known price of a position, falling back to
+// the price implied by its unrealized PnL.
+func (te *TradingEngine) markPrice(pos *Position) float64 {
+	if price := te.signalHandler.GetCurrentPrice(pos.Symbol); price > 0 {
+		return price
+	}
+	if pos.Quantity <= 0 {
+		return pos.EntryPrice
+	}
+	move := pos.UnrealizedPnL / pos.Quantity
+	if !isLongSide(pos.Side) {
+		move = -move
+	}
+	return pos.EntryPrice + move
+}
+
+// GetCircuitBreaker returns the circuit breaker used by the engine.
+func (te *TradingEngine) GetCircuitBreaker() *risk.CircuitBreaker {
+	return te.breaker
+}
+
+// StartEquitySampler starts periodic equity sampling without starting the
+// signal processing loop. It is a no-op if the sampler is already running.
+func (te *TradingEngine) StartEquitySampler() {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.startEquitySamplerLocked()
+}
+
+// StopEquitySampler stops periodic equity sampling.
+func (te *TradingEngine) StopEquitySampler() {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+	te.stopEquitySamplerLocked()
+}
+
+func (te *TradingEngine) startEquitySamplerLocked() {
+	if te.samplerStop != nil {
+		return
+	}
+
+	interval := te.config.EquitySampleInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	stop := make(chan struct{})
+	te.samplerStop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		te.recordEquity("interval")
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				te.recordEquity("interval")
+			}
+		}
+	}()
 }
 
+func (te *TradingEngine) stopEquitySamplerLocked() {
+	if te.samplerStop == nil {
+		return
+	}
+	close(te.samplerStop)
+	te.samplerStop = nil
+}
+
+// recordFill samples equity right after a fill and refreshes drawdown stats.
+func (te *TradingEngine) recordFill() {
+	te.recordEquity("fill")
+}
+
+func (te *TradingEngine) recordEquity(source string) {
+	point := te.sampleEquity(source)
+
+	te.statsMu.Lock()
+	te.applyEquityPoint(point)
+	te.statsMu.Unlock()
+}
+
+// sampleEquity marks all open positions to the latest known prices and
+// appends a snapshot to the equity curve.
+func (te *TradingEngine) sampleEquity(source string) EquityPoint {
+	var exposure, unrealizedPnL float64
+	for _, pos := range te.paperTrader.GetAllPositions() {
+		price := te.signalHandler.GetCurrentPrice(pos.Symbol)
+		if price > 0 {
+			te.paperTrader.UpdatePosition(pos.Symbol, price)
+		} else {
+			price = pos.EntryPrice
+		}
+		exposure += price * pos.Quantity
+	}
+	for _, pos := range te.paperTrader.GetAllPositions() {
+		unrealizedPnL += pos.UnrealizedPnL
+	}
+
+	balance := te.paperTrader.GetBalance()
+	equity := te.paperTrader.GetEquity()
+	return te.equity.Record(time.Now(), balance, equity, unrealizedPnL, exposure, source)
+}
+
+// GetEquityCurve returns equity snapshots within [from, to] (Unix
+// milliseconds, 0 = unbounded), downsampled to at most maxPoints entries.
+func (te *TradingEngine) GetEquityCurve(from, to int64, maxPoints int) []EquityPoint {
+	return te.equity.Query(from, to, maxPoints)
+}
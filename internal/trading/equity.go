@@ -0,0 +1,169 @@
+package trading
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// EquityPoint is a mark-to-market snapshot of the account.
+type EquityPoint struct {
+	Timestamp     int64   `json:"timestamp"`     // Unix milliseconds
+	Equity        float64 `json:"equity"`        // Cash plus marked value of open positions
+	Balance       float64 `json:"balance"`       // Free cash balance
+	UnrealizedPnL float64 `json:"unrealizedPnL"` // Unrealized PnL of open positions
+	Exposure      float64 `json:"exposure"`      // Gross notional of open positions at mark
+	ExposurePct   float64 `json:"exposurePct"`   // Exposure as a percentage of equity
+	PeakEquity    float64 `json:"peakEquity"`    // Highest equity seen so far
+	Drawdown      float64 `json:"drawdown"`      // Drawdown from peak equity in percent
+	Source        string  `json:"source"`        // "interval" or "fill"
+}
+
+// EquityCurve stores a bounded time series of equity snapshots. When the
+// buffer is full the older half is compacted to half resolution, so long
+// histories are kept at a coarser granularity instead of being dropped.
+type EquityCurve struct {
+	points      []EquityPoint
+	maxPoints   int
+	peak        float64
+	maxDrawdown float64
+	mu          sync.RWMutex
+}
+
+// NewEquityCurve creates an equity curve holding at most maxPoints samples.
+func NewEquityCurve(maxPoints int) *EquityCurve {
+	if maxPoints < 100 {
+		maxPoints = 100
+	}
+	return &EquityCurve{
+		points:    make([]EquityPoint, 0, 256),
+		maxPoints: maxPoints,
+	}
+}
+
+// Record appends a new snapshot and returns it with peak and drawdown filled in.
+func (ec *EquityCurve) Record(t time.Time, balance, equity, unrealizedPnL, exposure float64, source string) EquityPoint {
+	ec.mu.Lock()
+	defer ec.mu.Unlock()
+
+	if equity > ec.peak {
+		ec.peak = equity
+	}
+
+	point := EquityPoint{
+		Timestamp:     t.UnixMilli(),
+		Equity:        equity,
+		Balance:       balance,
+		UnrealizedPnL: unrealizedPnL,
+		Exposure:      exposure,
+		PeakEquity:    ec.peak,
+		Source:        source,
+	}
+	if equity > 0 {
+		point.ExposurePct = exposure / equity * 100
+	}
+	if ec.peak > 0 {
+		point.Drawdown = (ec.peak - equity) / ec.peak * 100
+	}
+	if point.Drawdown > ec.maxDrawdown {
+		ec.maxDrawdown = point.Drawdown
+	}
+
+	ec.points = append(ec.points, point)
+	if len(ec.points) > ec.maxPoints {
+		half := len(ec.points) / 2
+		compacted := downsampleEquity(ec.points[:half], half/2)
+		ec.points = append(compacted, ec.points[half:]...)
+	}
+
+	return point
+}
+
+// Latest returns the most recent snapshot, if any.
+func (ec *EquityCurve) Latest() (EquityPoint, bool) {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+
+	if len(ec.points) == 0 {
+		return EquityPoint{}, false
+	}
+	return ec.points[len(ec.points)-1], true
+}
+
+// MaxDrawdown returns the largest drawdown from peak equity in percent.
+func (ec *EquityCurve) MaxDrawdown() float64 {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+	return ec.maxDrawdown
+}
+
+// Points returns a copy of all stored snapshots, oldest first.
+func (ec *EquityCurve) Points() []EquityPoint {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+
+	points := make([]EquityPoint, len(ec.points))
+	copy(points, ec.points)
+	return points
+}
+
+// Query returns snapshots within [from, to] (Unix milliseconds, 0 = unbounded),
+// downsampled to at most maxPoints entries (0 = no limit).
+func (ec *EquityCurve) Query(from, to int64, maxPoints int) []EquityPoint {
+	ec.mu.RLock()
+	defer ec.mu.RUnlock()
+
+	result := make([]EquityPoint, 0)
+	for _, p := range ec.points {
+		if from > 0 && p.Timestamp < from {
+			continue
+		}
+		if to > 0 && p.Timestamp > to {
+			break
+		}
+		result = append(result, p)
+	}
+
+	if maxPoints > 0 && len(result) > maxPoints {
+		result = downsampleEquity(result, maxPoints)
+	}
+	return result
+}
+
+// downsampleEquity reduces points to at most n buckets. Each bucket is
+// represented by its deepest-drawdown snapshot, or by its last one when the
+// drawdown does not deepen inside it, so drawdown troughs survive
+// downsampling and equity and drawdown always come from the same moment.
+func downsampleEquity(points []EquityPoint, n int) []EquityPoint {
+	if n <= 0 || len(points) <= n {
+		out := make([]EquityPoint, len(points))
+		copy(out, points)
+		return out
+	}
+
+	bucketSize := int(math.Ceil(float64(len(points)) / float64(n)))
+	out := make([]EquityPoint, 0, n)
+	for start := 0; start < len(points); start += bucketSize {
+		end := start + bucketSize
+		if end > len(points) {
+			end = len(points)
+		}
+
+		point := points[end-1]
+		fill := false
+		for _, p := range points[start:end] {
+			if p.Drawdown > point.Drawdown {
+				point = p
+			}
+			if p.Source == "fill" {
+				fill = true
+			}
+		}
+		// Отметка о сделке в корзине сохраняется, даже если выбрана другая точка
+		if fill {
+			point.Source = "fill"
+		}
+		out = append(out, point)
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package trading
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Trade event types delivered to OnTradeEvent listeners.
+const (
+	TradeEventOpened  = "position.opened"
+	TradeEventReduced = "position.reduced"
+	TradeEventClosed  = "position.closed"
+)
+
+// tradeEventBuffer is the number of events queued for slow listeners before
+// new events are dropped.
+const tradeEventBuffer = 256
+
+// TradeEvent describes a change of the paper trader's positions.
+type TradeEvent struct {
+	Type     string    `json:"type"` // TradeEvent* constant
+	Account  string    `json:"account"`
+	Symbol   string    `json:"symbol"`
+	Position *Position `json:"position,omitempty"` // Opened position, or the rest of a reduced one
+	Trade    *Trade    `json:"trade,omitempty"`    // Closed part of a reduced or closed position
+	Time     time.Time `json:"time" wails:"-"`
+}
+
+// OnTradeEvent registers a callback for opened, reduced and closed
+// positions. Events are delivered in order from a single goroutine, so a
+// slow callback delays the others. Registering again with the same key
+// replaces the callback; a nil fn removes it.
+func (pt *PaperTrader) OnTradeEvent(key string, fn func(TradeEvent)) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	if fn == nil {
+		delete(pt.listeners, key)
+		if len(pt.listeners) == 0 && pt.events != nil {
+			close(pt.events)
+			pt.events = nil
+		}
+		return
+	}
+	if pt.listeners == nil {
+		pt.listeners = make(map[string]func(TradeEvent))
+	}
+	pt.listeners[key] = fn
+	if pt.events == nil {
+		pt.events = make(chan TradeEvent, tradeEventBuffer)
+		go pt.dispatchTradeEvents(pt.events)
+	}
+}
+
+// emitTradeEventLocked queues an event for the listeners. pt.mu must be
+// held, which keeps the events in the order of the position changes.
+func (pt *PaperTrader) emitTradeEventLocked(event TradeEvent) {
+	if pt.events == nil {
+		return
+	}
+	select {
+	case pt.events <- event:
+	default:
+		log.Warnf("Trade event queue full, dropping %s %s", event.Type, event.Symbol)
+	}
+}
+
+func (pt *PaperTrader) dispatchTradeEvents(events <-chan TradeEvent) {
+	for event := range events {
+		pt.mu.RLock()
+		listeners := make([]func(TradeEvent), 0, len(pt.listeners))
+		for _, fn := range pt.listeners {
+			listeners = append(listeners, fn)
+		}
+		pt.mu.RUnlock()
+
+		for _, fn := range listeners {
+			fn(event)
+		}
+	}
+}
+
+// positionEvent builds an event carrying a copy of pos.
+func positionEvent(eventType string, pos *Position, trade *Trade) TradeEvent {
+	snapshot := *pos
+	snapshot.Exits = append([]ExitPolicyConfig(nil), pos.Exits...)
+	event := TradeEvent{
+		Type:     eventType,
+		Account:  pos.Account,
+		Symbol:   pos.Symbol,
+		Position: &snapshot,
+		Time:     time.Now(),
+	}
+	if trade != nil {
+		tradeCopy := *trade
+		event.Trade = &tradeCopy
+	}
+	return event
+}
+
+// OnTradeEvent registers a callback for the positions of the engine's
+// account, see PaperTrader.OnTradeEvent.
+func (te *TradingEngine) OnTradeEvent(key string, fn func(TradeEvent)) {
+	te.paperTrader.OnTradeEvent(key, fn)
+}
@@ -0,0 +1,244 @@
+package trading
+
+import (
+	"math"
+	"sort"
+)
+
+// ExcursionGridCell is the simulated result of one stop/target combination.
+type ExcursionGridCell struct {
+	Stop       float64 `json:"stop"`
+	Target     float64 `json:"target"`
+	Expectancy float64 `json:"expectancy"` // Mean outcome per trade, in report units
+	WinRate    float64 `json:"winRate"`    // Percent
+}
+
+// ExcursionReport summarizes how far closed trades moved against and for us
+// and which stop/target distances would have performed best.
+type ExcursionReport struct {
+	Trades            int                 `json:"trades"`
+	Unit              string              `json:"unit"` // "atr" (multiples of entry ATR) or "percent"
+	WinnersMAE50      float64             `json:"winnersMae50"`
+	WinnersMAE90      float64             `json:"winnersMae90"` // 90% of winners never went further against us
+	LosersMFE50       float64             `json:"losersMfe50"`  // How far losers ran in our favour first
+	MFE50             float64             `json:"mfe50"`
+	MFE75             float64             `json:"mfe75"`
+	MFE90             float64             `json:"mfe90"`
+	AvgMAER           float64             `json:"avgMaeR"`
+	AvgMFER           float64             `json:"avgMfeR"`
+	ActualExpectancy  float64             `json:"actualExpectancy"` // Mean realized outcome, in report units
+	OptimalStop       float64             `json:"optimalStop"`
+	OptimalTarget     float64             `json:"optimalTarget"`
+	OptimalExpectancy float64             `json:"optimalExpectancy"`
+	StoppedWinners    int                 `json:"stoppedWinners"` // Winners that would have been stopped at the optimal stop
+	Grid              []ExcursionGridCell `json:"grid"`
+}
+
+// trackExcursion updates the price extremes and the MAE/MFE of an open
+// position with a new mark price.
+func (pos *Position) trackExcursion(price float64) {
+	if price <= 0 {
+		return
+	}
+	if pos.HighestPrice == 0 || price > pos.HighestPrice {
+		pos.HighestPrice = price
+	}
+	if pos.LowestPrice == 0 || price < pos.LowestPrice {
+		pos.LowestPrice = price
+	}
+
+	if isLongSide(pos.Side) {
+		pos.MAE = math.Max(pos.EntryPrice-pos.LowestPrice, 0)
+		pos.MFE = math.Max(pos.HighestPrice-pos.EntryPrice, 0)
+	} else {
+		pos.MAE = math.Max(pos.HighestPrice-pos.EntryPrice, 0)
+		pos.MFE = math.Max(pos.EntryPrice-pos.LowestPrice, 0)
+	}
+
+	risk := math.Abs(pos.EntryPrice - pos.InitialStopLoss)
+	if pos.InitialStopLoss > 0 && risk > 0 {
+		pos.MAER = pos.MAE / risk
+		pos.MFER = pos.MFE / risk
+	}
+}
+
+func isLongSide(side string) bool {
+	return side == "LONG" || side == "BUY"
+}
+
+// excursionSample is a trade expressed in report units.
+type excursionSample struct {
+	mae, mfe, result float64
+	won              bool
+}
+
+// AnalyzeExcursions studies MAE/MFE of closed trades. Trades with a known
+// entry ATR are measured in ATR multiples, so the optimal stop and target map
+// directly onto the ATR multipliers used for stop loss and take profit. When
+// fewer than half of the trades carry an ATR, percent of entry price is used.
+func AnalyzeExcursions(trades []Trade) ExcursionReport {
+	withATR := 0
+	for _, t := range trades {
+		if t.EntryATR > 0 {
+			withATR++
+		}
+	}
+
+	unit := "percent"
+	if withATR > 0 && withATR*2 >= len(trades) {
+		unit = "atr"
+	}
+
+	samples := make([]excursionSample, 0, len(trades))
+	var sumMAER, sumMFER float64
+	var countR int
+	for _, t := range trades {
+		if t.EntryPrice <= 0 {
+			continue
+		}
+
+		var scale float64
+		if unit == "atr" {
+			if t.EntryATR <= 0 {
+				continue
+			}
+			scale = t.EntryATR
+		} else {
+			scale = t.EntryPrice / 100
+		}
+
+		move := t.ExitPrice - t.EntryPrice
+		if !isLongSide(t.Side) {
+			move = -move
+		}
+
+		samples = append(samples, excursionSample{
+			mae:    t.MAE / scale,
+			mfe:    t.MFE / scale,
+			result: move / scale,
+			won:    t.PnL > 0,
+		})
+
+		if t.InitialStopLoss > 0 {
+			sumMAER += t.MAER
+			sumMFER += t.MFER
+			countR++
+		}
+	}
+
+	report := ExcursionReport{
+		Trades: len(samples),
+		Unit:   unit,
+		Grid:   make([]ExcursionGridCell, 0),
+	}
+	if len(samples) == 0 {
+		return report
+	}
+	if countR > 0 {
+		report.AvgMAER = sumMAER / float64(countR)
+		report.AvgMFER = sumMFER / float64(countR)
+	}
+
+	var winnersMAE, losersMFE, allMFE []float64
+	var actual float64
+	for _, s := range samples {
+		actual += s.result
+		allMFE = append(allMFE, s.mfe)
+		if s.won {
+			winnersMAE = append(winnersMAE, s.mae)
+		} else {
+			losersMFE = append(losersMFE, s.mfe)
+		}
+	}
+	report.ActualExpectancy = actual / float64(len(samples))
+	report.WinnersMAE50 = percentile(winnersMAE, 50)
+	report.WinnersMAE90 = percentile(winnersMAE, 90)
+	report.LosersMFE50 = percentile(losersMFE, 50)
+	report.MFE50 = percentile(allMFE, 50)
+	report.MFE75 = percentile(allMFE, 75)
+	report.MFE90 = percentile(allMFE, 90)
+
+	levels := excursionLevels(unit)
+	report.OptimalExpectancy = math.Inf(-1)
+	for _, stop := range levels {
+		for _, target := range levels {
+			cell := simulateExcursion(samples, stop, target)
+			report.Grid = append(report.Grid, cell)
+			if cell.Expectancy > report.OptimalExpectancy {
+				report.OptimalExpectancy = cell.Expectancy
+				report.OptimalStop = stop
+				report.OptimalTarget = target
+			}
+		}
+	}
+
+	for _, s := range samples {
+		if s.won && s.mae >= report.OptimalStop {
+			report.StoppedWinners++
+		}
+	}
+
+	return report
+}
+
+// simulateExcursion replays trades with a fixed stop and target. Without the
+// intrabar path it is unknown which level was touched first, so the stop is
+// assumed to be hit first whenever both were reached (a conservative choice).
+// Trades that were closed early by their own exit keep their realized result,
+// so wider stops and targets than the ones actually used are underestimated.
+func simulateExcursion(samples []excursionSample, stop, target float64) ExcursionGridCell {
+	var total float64
+	var wins int
+	for _, s := range samples {
+		outcome := s.result
+		switch {
+		case s.mae >= stop:
+			outcome = -stop
+		case s.mfe >= target:
+			outcome = target
+		}
+		total += outcome
+		if outcome > 0 {
+			wins++
+		}
+	}
+
+	n := float64(len(samples))
+	return ExcursionGridCell{
+		Stop:       stop,
+		Target:     target,
+		Expectancy: total / n,
+		WinRate:    float64(wins) / n * 100,
+	}
+}
+
+func excursionLevels(unit string) []float64 {
+	levels := make([]float64, 0, 20)
+	if unit == "atr" {
+		for v := 0.5; v <= 4.0+1e-9; v += 0.25 {
+			levels = append(levels, v)
+		}
+		return levels
+	}
+	for v := 0.25; v <= 5.0+1e-9; v += 0.25 {
+		levels = append(levels, v)
+	}
+	return levels
+}
+
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := make([]float64, len(values))
+	copy(sorted, values)
+	sort.Float64s(sorted)
+
+	idx := p / 100 * float64(len(sorted)-1)
+	lo := int(math.Floor(idx))
+	hi := int(math.Ceil(idx))
+	if lo == hi {
+		return sorted[lo]
+	}
+	return sorted[lo] + (sorted[hi]-sorted[lo])*(idx-float64(lo))
+}
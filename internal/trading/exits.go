@@ -0,0 +1,473 @@
+package trading
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"crypto-trading-bot/internal/risk"
+	"crypto-trading-bot/internal/signals"
+	log "github.com/sirupsen/logrus"
+)
+
+// Exit policy types accepted in ExitPolicyConfig.Type.
+const (
+	ExitTrailingATR     = "trailing_atr"     // Stop trails the current price by ATR multiples
+	ExitTrailingPercent = "trailing_percent" // Stop trails the best price by a percentage
+	ExitChandelier      = "chandelier"       // Stop trails the best price by ATR multiples
+	ExitBreakeven       = "breakeven"        // Stop moves to entry plus an offset once in profit
+	ExitMaxHolding      = "max_holding"      // Position is closed after a holding time
+	ExitScaleOut        = "scale_out"        // Parts of the position are closed at profit targets
+	ExitOppositeSignal  = "opposite_signal"  // Position is closed on a confident opposite signal
+	ExitWeakSignal      = "weak_signal"      // Profit is taken when the signal fades
+)
+
+// ScaleOutTarget closes Fraction of the initial quantity once the profit
+// reaches R (multiples of the initial stop distance) or, for positions
+// without a stop, Percent.
+type ScaleOutTarget struct {
+	R        float64 `json:"r"`
+	Percent  float64 `json:"percent"`
+	Fraction float64 `json:"fraction"`
+}
+
+// ExitPolicyConfig describes one exit policy. Only the fields of the
+// selected type are used.
+type ExitPolicyConfig struct {
+	Type              string           `json:"type"`
+	ATRMultiple       float64          `json:"atrMultiple"`       // trailing_atr, chandelier
+	Percent           float64          `json:"percent"`           // trailing_percent: trail distance in percent
+	ActivateR         float64          `json:"activateR"`         // Trailing starts once profit reaches this many R (0 = at once)
+	ActivatePercent   float64          `json:"activatePercent"`   // Same in percent, for positions without a stop
+	TriggerR          float64          `json:"triggerR"`          // breakeven: profit in R that arms the stop
+	TriggerPercent    float64          `json:"triggerPercent"`    // breakeven: same in percent
+	OffsetPercent     float64          `json:"offsetPercent"`     // breakeven: stop distance beyond entry, in percent
+	MaxHoldingMinutes int              `json:"maxHoldingMinutes"` // max_holding
+	Targets           []ScaleOutTarget `json:"targets"`           // scale_out
+	MinConfidence     float64          `json:"minConfidence"`     // opposite_signal: required confidence
+	Reverse           bool             `json:"reverse"`           // opposite_signal: open the opposite position
+	ProfitPercent     float64          `json:"profitPercent"`     // weak_signal: minimum profit to take
+	MaxConfidence     float64          `json:"maxConfidence"`     // weak_signal: confidence below which the signal is weak
+}
+
+// ExitContext is the market state exit policies are evaluated against.
+type ExitContext struct {
+	Now    time.Time
+	Price  float64
+	ATR    float64         // Current ATR, 0 falls back to the entry ATR
+	Signal *signals.Signal // Latest signal for the symbol, nil on plain price updates
+}
+
+// ExitDecision is the outcome of an exit policy. A zero decision keeps the
+// position unchanged.
+type ExitDecision struct {
+	StopLoss      float64 // New stop, 0 keeps the current one; stops are only tightened
+	CloseFraction float64 // Fraction of the initial quantity to close, 1 closes everything
+	ScaleOuts     int     // Scale-out targets taken after this exit
+	Reverse       bool    // Open the opposite position after a full close
+	Reason        string
+}
+
+// ExitPolicy decides how an open position is managed.
+type ExitPolicy interface {
+	Name() string
+	Evaluate(pos Position, ctx ExitContext) ExitDecision
+}
+
+// DefaultExitPolicies returns the per-strategy policies used when the
+// engine is configured without any. They reproduce the scalping rules of
+// the signal-driven strategy.
+func DefaultExitPolicies() map[string][]ExitPolicyConfig {
+	return map[string][]ExitPolicyConfig{
+		StrategyAutonomous: {
+			{Type: ExitBreakeven, TriggerPercent: 0.5},
+			{Type: ExitOppositeSignal, MinConfidence: 0.5, Reverse: true},
+			{Type: ExitWeakSignal, ProfitPercent: 0.3, MaxConfidence: 0.4},
+		},
+	}
+}
+
+// LoadExitPolicies reads per-strategy exit policies from a JSON file shaped
+// like {"autonomous": [{"type": "trailing_atr", "atrMultiple": 2}]}.
+func LoadExitPolicies(path string) (map[string][]ExitPolicyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exit policies: %w", err)
+	}
+
+	var policies map[string][]ExitPolicyConfig
+	if err := json.Unmarshal(data, &policies); err != nil {
+		return nil, fmt.Errorf("failed to parse exit policies: %w", err)
+	}
+	for strategy, configs := range policies {
+		for _, config := range configs {
+			if _, err := NewExitPolicy(config); err != nil {
+				return nil, fmt.Errorf("strategy %s: %w", strategy, err)
+			}
+		}
+	}
+	return policies, nil
+}
+
+// NewExitPolicy builds the policy described by config.
+func NewExitPolicy(config ExitPolicyConfig) (ExitPolicy, error) {
+	switch config.Type {
+	case ExitTrailingATR, ExitChandelier:
+		if config.ATRMultiple <= 0 {
+			return nil, fmt.Errorf("%s exit requires a positive atrMultiple", config.Type)
+		}
+		return &ATRTrailingExit{config: config, chandelier: config.Type == ExitChandelier}, nil
+	case ExitTrailingPercent:
+		if config.Percent <= 0 {
+			return nil, fmt.Errorf("trailing_percent exit requires a positive percent")
+		}
+		return &PercentTrailingExit{config: config}, nil
+	case ExitBreakeven:
+		if config.TriggerR <= 0 && config.TriggerPercent <= 0 {
+			return nil, fmt.Errorf("breakeven exit requires triggerR or triggerPercent")
+		}
+		return &BreakevenExit{config: config}, nil
+	case ExitMaxHolding:
+		if config.MaxHoldingMinutes <= 0 {
+			return nil, fmt.Errorf("max_holding exit requires positive maxHoldingMinutes")
+		}
+		return &MaxHoldingExit{MaxHolding: time.Duration(config.MaxHoldingMinutes) * time.Minute}, nil
+	case ExitScaleOut:
+		if len(config.Targets) == 0 {
+			return nil, fmt.Errorf("scale_out exit requires targets")
+		}
+		targets := append([]ScaleOutTarget(nil), config.Targets...)
+		for _, t := range targets {
+			if t.Fraction <= 0 || (t.R <= 0 && t.Percent <= 0) {
+				return nil, fmt.Errorf("scale_out targets require a positive fraction and r or percent")
+			}
+		}
+		sort.SliceStable(targets, func(i, j int) bool {
+			return targets[i].R+targets[i].Percent < targets[j].R+targets[j].Percent
+		})
+		return &ScaleOutExit{Targets: targets}, nil
+	case ExitOppositeSignal:
+		return &OppositeSignalExit{MinConfidence: config.MinConfidence, Reverse: config.Reverse}, nil
+	case ExitWeakSignal:
+		if config.MaxConfidence <= 0 {
+			return nil, fmt.Errorf("weak_signal exit requires a positive maxConfidence")
+		}
+		return &WeakSignalExit{ProfitPercent: config.ProfitPercent, MaxConfidence: config.MaxConfidence}, nil
+	default:
+		return nil, fmt.Errorf("unknown exit policy: %s", config.Type)
+	}
+}
+
+// riskDistance returns the initial stop distance (1R), 0 without a stop.
+func riskDistance(pos Position) float64 {
+	if pos.InitialStopLoss <= 0 {
+		return 0
+	}
+	return math.Abs(pos.EntryPrice - pos.InitialStopLoss)
+}
+
+// profitReached reports whether the open profit at price reaches r R-multiples
+// or, for positions without a stop or when r is 0, percent.
+func profitReached(pos Position, price, r, percent float64) bool {
+	move := price - pos.EntryPrice
+	if !isLongSide(pos.Side) {
+		move = -move
+	}
+	if distance := riskDistance(pos); r > 0 && distance > 0 {
+		return move >= r*distance
+	}
+	if percent > 0 && pos.EntryPrice > 0 {
+		return move/pos.EntryPrice*100 >= percent
+	}
+	return r <= 0 && percent <= 0
+}
+
+// ATRTrailingExit trails the stop by ATR multiples, either from the current
+// price or, as a chandelier exit, from the best price since entry.
+type ATRTrailingExit struct {
+	config     ExitPolicyConfig
+	chandelier bool
+}
+
+func (e *ATRTrailingExit) Name() string { return e.config.Type }
+
+func (e *ATRTrailingExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	atr := ctx.ATR
+	if atr <= 0 {
+		atr = pos.EntryATR
+	}
+	if atr <= 0 || !profitReached(pos, ctx.Price, e.config.ActivateR, e.config.ActivatePercent) {
+		return ExitDecision{}
+	}
+
+	distance := atr * e.config.ATRMultiple
+	if isLongSide(pos.Side) {
+		anchor := ctx.Price
+		if e.chandelier {
+			anchor = pos.HighestPrice
+		}
+		return ExitDecision{StopLoss: anchor - distance, Reason: e.Name()}
+	}
+	anchor := ctx.Price
+	if e.chandelier {
+		anchor = pos.LowestPrice
+	}
+	return ExitDecision{StopLoss: anchor + distance, Reason: e.Name()}
+}
+
+// PercentTrailingExit trails the stop a fixed percentage behind the best
+// price since entry.
+type PercentTrailingExit struct {
+	config ExitPolicyConfig
+}
+
+func (e *PercentTrailingExit) Name() string { return ExitTrailingPercent }
+
+func (e *PercentTrailingExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	if !profitReached(pos, ctx.Price, e.config.ActivateR, e.config.ActivatePercent) {
+		return ExitDecision{}
+	}
+	if isLongSide(pos.Side) {
+		return ExitDecision{StopLoss: pos.HighestPrice * (1 - e.config.Percent/100), Reason: e.Name()}
+	}
+	return ExitDecision{StopLoss: pos.LowestPrice * (1 + e.config.Percent/100), Reason: e.Name()}
+}
+
+// BreakevenExit moves the stop to entry plus an offset once the trigger
+// profit is reached.
+type BreakevenExit struct {
+	config ExitPolicyConfig
+}
+
+func (e *BreakevenExit) Name() string { return ExitBreakeven }
+
+func (e *BreakevenExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	if !profitReached(pos, ctx.Price, e.config.TriggerR, e.config.TriggerPercent) {
+		return ExitDecision{}
+	}
+	offset := pos.EntryPrice * e.config.OffsetPercent / 100
+	if isLongSide(pos.Side) {
+		return ExitDecision{StopLoss: pos.EntryPrice + offset, Reason: e.Name()}
+	}
+	return ExitDecision{StopLoss: pos.EntryPrice - offset, Reason: e.Name()}
+}
+
+// MaxHoldingExit closes positions held longer than MaxHolding.
+type MaxHoldingExit struct {
+	MaxHolding time.Duration
+}
+
+func (e *MaxHoldingExit) Name() string { return ExitMaxHolding }
+
+func (e *MaxHoldingExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	if pos.OpenedAt.IsZero() || ctx.Now.Sub(pos.OpenedAt) < e.MaxHolding {
+		return ExitDecision{}
+	}
+	return ExitDecision{CloseFraction: 1, Reason: fmt.Sprintf("Max holding time %v reached", e.MaxHolding)}
+}
+
+// ScaleOutExit closes parts of the position at successive profit targets.
+// Position.ScaleOuts counts the targets already taken.
+type ScaleOutExit struct {
+	Targets []ScaleOutTarget
+}
+
+func (e *ScaleOutExit) Name() string { return ExitScaleOut }
+
+func (e *ScaleOutExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	var fraction float64
+	taken := pos.ScaleOuts
+	for taken < len(e.Targets) && profitReached(pos, ctx.Price, e.Targets[taken].R, e.Targets[taken].Percent) {
+		fraction += e.Targets[taken].Fraction
+		taken++
+	}
+	if fraction == 0 {
+		return ExitDecision{}
+	}
+	return ExitDecision{
+		CloseFraction: math.Min(fraction, 1),
+		ScaleOuts:     taken,
+		Reason:        fmt.Sprintf("Scale-out target %d reached", taken),
+	}
+}
+
+// OppositeSignalExit closes the position when a signal in the other
+// direction exceeds MinConfidence.
+type OppositeSignalExit struct {
+	MinConfidence float64
+	Reverse       bool
+}
+
+func (e *OppositeSignalExit) Name() string { return ExitOppositeSignal }
+
+func (e *OppositeSignalExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	sig := ctx.Signal
+	if sig == nil || sig.Direction == "HOLD" || isLongSide(sig.Direction) == isLongSide(pos.Side) {
+		return ExitDecision{}
+	}
+	if sig.Confidence <= e.MinConfidence {
+		return ExitDecision{}
+	}
+	return ExitDecision{CloseFraction: 1, Reverse: e.Reverse, Reason: "Signal reversal"}
+}
+
+// WeakSignalExit takes profit when the signal confidence fades below
+// MaxConfidence.
+type WeakSignalExit struct {
+	ProfitPercent float64
+	MaxConfidence float64
+}
+
+func (e *WeakSignalExit) Name() string { return ExitWeakSignal }
+
+func (e *WeakSignalExit) Evaluate(pos Position, ctx ExitContext) ExitDecision {
+	if ctx.Signal == nil || ctx.Signal.Confidence >= e.MaxConfidence {
+		return ExitDecision{}
+	}
+	if !profitReached(pos, ctx.Price, 0, e.ProfitPercent) {
+		return ExitDecision{}
+	}
+	return ExitDecision{CloseFraction: 1, Reason: "Quick profit taken (signal faded)"}
+}
+
+// attachExits attaches the exit policies of the position's strategy unless
+// the position already carries its own.
+func (te *TradingEngine) attachExits(pos *Position) {
+	if pos.Exits != nil {
+		return
+	}
+	strategy := pos.Strategy
+	if strategy == "" {
+		strategy = StrategyManual
+	}
+	pos.Exits = te.exitPoliciesFor(strategy)
+}
+
+func (te *TradingEngine) exitPoliciesFor(strategy string) []ExitPolicyConfig {
+	te.mu.RLock()
+	policies := te.config.ExitPolicies
+	te.mu.RUnlock()
+
+	if policies == nil {
+		policies = DefaultExitPolicies()
+	}
+	return append([]ExitPolicyConfig(nil), policies[strategy]...)
+}
+
+// ProcessExits evaluates the exit policies of the open position in symbol
+// at a new price.
+func (te *TradingEngine) ProcessExits(symbol string, price float64) {
+	pos := te.paperTrader.GetPosition(symbol)
+	if pos == nil || price <= 0 {
+		return
+	}
+	te.applyExits(*pos, ExitContext{Now: time.Now(), Price: price})
+}
+
+// applyExits runs the position's exit policies: the tightest proposed stop
+// wins, a full close stops evaluation, and partial closes are summed. It
+// reports whether the position was closed and whether the closing policy
+// asked for a reversal.
+func (te *TradingEngine) applyExits(pos Position, ctx ExitContext) (closed, reverse bool) {
+	configs := pos.Exits
+	if configs == nil {
+		configs = te.exitPoliciesFor(pos.Strategy)
+	}
+	if len(configs) == 0 {
+		return false, false
+	}
+
+	long := isLongSide(pos.Side)
+	stop, stopReason := pos.StopLoss, ""
+	scaleOuts := pos.ScaleOuts
+	var closeFraction float64
+	var closeReason string
+
+	for _, config := range configs {
+		policy, err := NewExitPolicy(config)
+		if err != nil {
+			log.Warnf("Skipping exit policy for %s: %v", pos.Symbol, err)
+			continue
+		}
+		d := policy.Evaluate(pos, ctx)
+
+		if d.StopLoss > 0 && (stop <= 0 || (long && d.StopLoss > stop) || (!long && d.StopLoss < stop)) {
+			stop, stopReason = d.StopLoss, d.Reason
+		}
+		if d.CloseFraction >= 1 {
+			closeFraction, closeReason, reverse = 1, d.Reason, d.Reverse
+			break
+		}
+		if d.CloseFraction > 0 {
+			closeFraction += d.CloseFraction
+			closeReason = d.Reason
+			if d.ScaleOuts > scaleOuts {
+				scaleOuts = d.ScaleOuts
+			}
+		}
+	}
+
+	if stop != pos.StopLoss {
+		te.paperTrader.ModifyPosition(pos.Symbol, func(p *Position) { p.StopLoss = stop })
+		log.Infof("Stop loss for %s moved %.8f -> %.8f (%s)", pos.Symbol, pos.StopLoss, stop, stopReason)
+		pos.StopLoss = stop
+	}
+
+	if closeFraction >= 1 {
+		return te.closePositionAt(&pos, ctx.Price, closeReason), reverse
+	}
+	if closeFraction > 0 {
+		base := pos.InitialQuantity
+		if base <= 0 {
+			base = pos.Quantity
+		}
+		closed, ok := te.reducePosition(&pos, math.Min(base*closeFraction, pos.Quantity), ctx.Price, closeReason)
+		if closed {
+			return true, false
+		}
+		// Уровень частичной фиксации считается пройденным только после исполнения
+		if ok {
+			te.paperTrader.ModifyPosition(pos.Symbol, func(p *Position) { p.ScaleOuts = scaleOuts })
+		}
+	}
+
+	// Подтянутый стоп может оказаться за текущей ценой
+	if pos.StopLoss > 0 && ((long && ctx.Price <= pos.StopLoss) || (!long && ctx.Price >= pos.StopLoss)) {
+		reason := "Stop loss hit"
+		if stopReason != "" {
+			reason = fmt.Sprintf("Stop loss hit (%s)", stopReason)
+		}
+		return te.closePositionAt(&pos, ctx.Price, reason), false
+	}
+	return false, false
+}
+
+// reducePosition closes part of a position through the risk rules. It
+// reports whether the position was closed completely and whether the
+// reduction was executed at all.
+func (te *TradingEngine) reducePosition(pos *Position, quantity, price float64, reason string) (closed, ok bool) {
+	approved, err := te.checkOrder(risk.OrderIntent{
+		Strategy: pos.Strategy,
+		Symbol:   pos.Symbol,
+		Side:     pos.Side,
+		Price:    price,
+		Quantity: quantity,
+		Reduce:   true,
+	})
+	if err != nil {
+		log.Warnf("Partial close rejected: %v", err)
+		return false, false
+	}
+
+	trade, err := te.paperTrader.ReducePosition(pos.Symbol, approved, price, reason)
+	if err != nil {
+		log.Errorf("Failed to reduce position: %v", err)
+		te.recordOrderError(err)
+		return false, false
+	}
+	te.updateStats(trade)
+	return !te.paperTrader.HasOpenPosition(pos.Symbol), true
+}
@@ -20,6 +20,7 @@ type Order struct {
 	CreatedAt   time.Time `json:"createdAt" wails:"-"`
 	FilledAt    time.Time `json:"filledAt" wails:"-"`
 	CancelledAt time.Time `json:"cancelledAt" wails:"-"`
+	Strategy    string    `json:"strategy"`    // Strategy that placed the order
 }
 
 type OrderManager struct {
@@ -171,6 +172,7 @@ func (om *OrderManager) ProcessLimitOrders(symbol string, currentPrice float64,
 						EntryPrice: order.Price,
 						Quantity:   remainingQty,
 						OpenedAt:   time.Now(),
+						Strategy:   order.Strategy,
 					}
 					// Balance already reserved, just open position
 					if err := paperTrader.OpenPosition(position); err != nil {
@@ -11,6 +11,21 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// OrderValidationError rejects an order before it is executed, e.g. for
+// insufficient balance or a missing position. Unlike execution failures it
+// does not count towards the circuit breaker's order error limit.
+type OrderValidationError struct {
+	msg string
+}
+
+func (e *OrderValidationError) Error() string {
+	return e.msg
+}
+
+func validationErrorf(format string, args ...any) error {
+	return &OrderValidationError{msg: fmt.Sprintf(format, args...)}
+}
+
 // PaperTrader simulates trading without real money.
 // It tracks balance, positions, and trade history for paper trading.
 type PaperTrader struct {
@@ -18,6 +33,9 @@ type PaperTrader struct {
 	balance        float64              // Current available balance
 	positions      map[string]*Position // Open positions by symbol
 	trades         []Trade              // Trade history
+	account        string               // Account tag recorded on positions and trades
+	listeners      map[string]func(TradeEvent) // Trade event callbacks by key
+	events         chan TradeEvent      // Queue of the trade event dispatcher, nil without listeners
 	mu             sync.RWMutex         // Mutex for thread-safe operations
 }
 
@@ -32,8 +50,21 @@ type Position struct {
 	TakeProfit     float64   `json:"takeProfit"`
 	OpenedAt       time.Time `json:"openedAt" wails:"-"`
 	SignalID       string    `json:"signalId"`
+	Strategy       string    `json:"strategy"` // Originating strategy, see Strategy* constants
+	Account        string    `json:"account"`
 	UnrealizedPnL  float64   `json:"unrealizedPnL"`
 	UnrealizedPnLPct float64 `json:"unrealizedPnLPct"`
+	InitialStopLoss float64  `json:"initialStopLoss"` // Stop at entry, used as 1R
+	EntryATR       float64   `json:"entryAtr"`        // ATR at entry, 0 if unknown
+	HighestPrice   float64   `json:"highestPrice"`    // Highest mark since entry
+	LowestPrice    float64   `json:"lowestPrice"`     // Lowest mark since entry
+	MAE            float64   `json:"mae"`             // Maximum adverse excursion (price distance)
+	MFE            float64   `json:"mfe"`             // Maximum favorable excursion (price distance)
+	MAER           float64   `json:"maeR"`            // MAE in R-multiples, 0 without a stop
+	MFER           float64   `json:"mfeR"`            // MFE in R-multiples, 0 without a stop
+	InitialQuantity float64  `json:"initialQuantity"` // Quantity at entry, base for scale-out fractions
+	ScaleOuts      int       `json:"scaleOuts"`       // Scale-out targets already taken
+	Exits          []ExitPolicyConfig `json:"exits"`  // Exit policies attached at entry
 }
 
 // Trade represents a completed trade with entry/exit prices and PnL.
@@ -51,6 +82,16 @@ type Trade struct {
 	ClosedAt   time.Time     `json:"closedAt" wails:"-"`
 	Reason     string        `json:"reason"`
 	SignalID   string        `json:"signalId"`
+	Strategy   string        `json:"strategy"`
+	Account    string        `json:"account"`
+	InitialStopLoss float64  `json:"initialStopLoss"`
+	EntryATR   float64       `json:"entryAtr"`
+	MAE        float64       `json:"mae"`
+	MFE        float64       `json:"mfe"`
+	MAEPercent float64       `json:"maePercent"`
+	MFEPercent float64       `json:"mfePercent"`
+	MAER       float64       `json:"maeR"`
+	MFER       float64       `json:"mfeR"`
 }
 
 // NewPaperTrader creates a new paper trader instance with the given initial balance.
@@ -60,29 +101,59 @@ func NewPaperTrader(initialBalance float64) *PaperTrader {
 		balance:        initialBalance,
 		positions:      make(map[string]*Position),
 		trades:         make([]Trade, 0),
+		account:        DefaultAccount,
 	}
 }
 
+// SetAccount sets the account tag used for positions opened without one.
+func (pt *PaperTrader) SetAccount(account string) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+	pt.account = account
+}
+
+// GetAccount returns the account tag of the trader.
+func (pt *PaperTrader) GetAccount() string {
+	pt.mu.RLock()
+	defer pt.mu.RUnlock()
+	return pt.account
+}
+
 func (pt *PaperTrader) OpenPosition(pos *Position) error {
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
 
 	if _, exists := pt.positions[pos.Symbol]; exists {
 		log.Warnf("Position already exists for %s", pos.Symbol)
-		return fmt.Errorf("position already exists for %s", pos.Symbol)
+		return validationErrorf("position already exists for %s", pos.Symbol)
 	}
 
 	cost := pos.EntryPrice * pos.Quantity
 
 	if cost > pt.balance {
 		log.Errorf("Insufficient balance to open position: need %.2f, have %.2f", cost, pt.balance)
-		return fmt.Errorf("insufficient balance: need %.2f, have %.2f", cost, pt.balance)
+		return validationErrorf("insufficient balance: need %.2f, have %.2f", cost, pt.balance)
 	}
 
 	balanceBefore := pt.balance
 	pos.ID = uuid.New().String()
+	if pos.InitialStopLoss == 0 {
+		pos.InitialStopLoss = pos.StopLoss
+	}
+	if pos.InitialQuantity == 0 {
+		pos.InitialQuantity = pos.Quantity
+	}
+	if pos.Account == "" {
+		pos.Account = pt.account
+	}
+	if pos.Strategy == "" {
+		pos.Strategy = StrategyManual
+	}
+	pos.HighestPrice = pos.EntryPrice
+	pos.LowestPrice = pos.EntryPrice
 	pt.balance -= cost
 	pt.positions[pos.Symbol] = pos
+	pt.emitTradeEventLocked(positionEvent(TradeEventOpened, pos, nil))
 
 	log.Infof("=== POSITION OPENED ===")
 	log.Infof("Position ID: %s", pos.ID)
@@ -91,7 +162,7 @@ func (pt *PaperTrader) OpenPosition(pos *Position) error {
 	log.Infof("Cost: %.2f USDT", cost)
 	log.Infof("Stop Loss: %.8f, Take Profit: %.8f", pos.StopLoss, pos.TakeProfit)
 	log.Infof("Balance: %.2f -> %.2f USDT (change: -%.2f)", balanceBefore, pt.balance, cost)
-	log.Infof("Signal ID: %s", pos.SignalID)
+	log.Infof("Signal ID: %s, Strategy: %s, Account: %s", pos.SignalID, pos.Strategy, pos.Account)
 	log.Info("=== POSITION OPEN COMPLETE ===")
 
 	return nil
@@ -104,33 +175,12 @@ func (pt *PaperTrader) ClosePosition(symbol string, exitPrice float64, reason st
 	pos, exists := pt.positions[symbol]
 	if !exists {
 		log.Warnf("No position found for %s", symbol)
-		return nil, fmt.Errorf("no position for %s", symbol)
-	}
-
-	var pnl float64
-	if pos.Side == "LONG" || pos.Side == "BUY" {
-		pnl = (exitPrice - pos.EntryPrice) * pos.Quantity
-	} else {
-		pnl = (pos.EntryPrice - exitPrice) * pos.Quantity
+		return nil, validationErrorf("no position for %s", symbol)
 	}
 
-	pnlPercent := pnl / (pos.EntryPrice * pos.Quantity) * 100
-
-	trade := Trade{
-		ID:         uuid.New().String(),
-		Symbol:     pos.Symbol,
-		Side:       pos.Side,
-		EntryPrice: pos.EntryPrice,
-		ExitPrice:  exitPrice,
-		Quantity:   pos.Quantity,
-		PnL:        pnl,
-		PnLPercent: pnlPercent,
-		Duration:   time.Since(pos.OpenedAt),
-		OpenedAt:   pos.OpenedAt,
-		ClosedAt:   time.Now(),
-		Reason:     reason,
-		SignalID:   pos.SignalID,
-	}
+	pos.trackExcursion(exitPrice)
+	trade := pos.exitTrade(exitPrice, pos.Quantity, reason)
+	pnl, pnlPercent := trade.PnL, trade.PnLPercent
 
 	balanceBefore := pt.balance
 	// Возвращаем стоимость позиции плюс прибыль/убыток
@@ -139,6 +189,7 @@ func (pt *PaperTrader) ClosePosition(symbol string, exitPrice float64, reason st
 	pt.balance += revenue
 	delete(pt.positions, symbol)
 	pt.trades = append(pt.trades, trade)
+	pt.emitTradeEventLocked(positionEvent(TradeEventClosed, pos, &trade))
 
 	log.Infof("=== POSITION CLOSED ===")
 	log.Infof("Trade ID: %s, Position ID: %s", trade.ID, pos.ID)
@@ -156,6 +207,96 @@ func (pt *PaperTrader) ClosePosition(symbol string, exitPrice float64, reason st
 	return &trade, nil
 }
 
+// ReducePosition closes part of a position and records it as a trade. The
+// rest of the position keeps its entry, stops and excursion history.
+// Reducing by the full quantity closes the position.
+func (pt *PaperTrader) ReducePosition(symbol string, quantity, exitPrice float64, reason string) (*Trade, error) {
+	pt.mu.Lock()
+	pos, exists := pt.positions[symbol]
+	if !exists {
+		pt.mu.Unlock()
+		return nil, validationErrorf("no position for %s", symbol)
+	}
+	if quantity <= 0 {
+		pt.mu.Unlock()
+		return nil, validationErrorf("invalid quantity: %.8f", quantity)
+	}
+	if quantity >= pos.Quantity-1e-12 {
+		pt.mu.Unlock()
+		return pt.ClosePosition(symbol, exitPrice, reason)
+	}
+	defer pt.mu.Unlock()
+
+	pos.trackExcursion(exitPrice)
+	trade := pos.exitTrade(exitPrice, quantity, reason)
+
+	pt.balance += pos.EntryPrice*quantity + trade.PnL
+	pos.Quantity -= quantity
+	if isLongSide(pos.Side) {
+		pos.UnrealizedPnL = (exitPrice - pos.EntryPrice) * pos.Quantity
+	} else {
+		pos.UnrealizedPnL = (pos.EntryPrice - exitPrice) * pos.Quantity
+	}
+	pt.trades = append(pt.trades, trade)
+	pt.emitTradeEventLocked(positionEvent(TradeEventReduced, pos, &trade))
+
+	log.Infof("=== POSITION REDUCED ===")
+	log.Infof("Symbol: %s, Side: %s, Closed: %.8f @ %.8f, Remaining: %.8f", pos.Symbol, pos.Side, quantity, exitPrice, pos.Quantity)
+	log.Infof("PnL: %.2f USDT (%.2f%%), Reason: %s", trade.PnL, trade.PnLPercent, reason)
+
+	return &trade, nil
+}
+
+// ModifyPosition applies fn to the open position of symbol under the lock,
+// e.g. to move its stop. It reports whether the position exists.
+func (pt *PaperTrader) ModifyPosition(symbol string, fn func(pos *Position)) bool {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pos, exists := pt.positions[symbol]
+	if !exists {
+		return false
+	}
+	fn(pos)
+	return true
+}
+
+// exitTrade builds the trade record for closing quantity of the position.
+func (pos *Position) exitTrade(exitPrice, quantity float64, reason string) Trade {
+	var pnl float64
+	if isLongSide(pos.Side) {
+		pnl = (exitPrice - pos.EntryPrice) * quantity
+	} else {
+		pnl = (pos.EntryPrice - exitPrice) * quantity
+	}
+
+	return Trade{
+		ID:              uuid.New().String(),
+		Symbol:          pos.Symbol,
+		Side:            pos.Side,
+		EntryPrice:      pos.EntryPrice,
+		ExitPrice:       exitPrice,
+		Quantity:        quantity,
+		PnL:             pnl,
+		PnLPercent:      pnl / (pos.EntryPrice * quantity) * 100,
+		Duration:        time.Since(pos.OpenedAt),
+		OpenedAt:        pos.OpenedAt,
+		ClosedAt:        time.Now(),
+		Reason:          reason,
+		SignalID:        pos.SignalID,
+		Strategy:        pos.Strategy,
+		Account:         pos.Account,
+		InitialStopLoss: pos.InitialStopLoss,
+		EntryATR:        pos.EntryATR,
+		MAE:             pos.MAE,
+		MFE:             pos.MFE,
+		MAEPercent:      pos.MAE / pos.EntryPrice * 100,
+		MFEPercent:      pos.MFE / pos.EntryPrice * 100,
+		MAER:            pos.MAER,
+		MFER:            pos.MFER,
+	}
+}
+
 func (pt *PaperTrader) UpdatePosition(symbol string, currentPrice float64) {
 	pt.mu.Lock()
 	defer pt.mu.Unlock()
@@ -172,6 +313,7 @@ func (pt *PaperTrader) UpdatePosition(symbol string, currentPrice float64) {
 	}
 
 	pos.UnrealizedPnLPct = pos.UnrealizedPnL / (pos.EntryPrice * pos.Quantity) * 100
+	pos.trackExcursion(currentPrice)
 }
 
 func (pt *PaperTrader) HasOpenPosition(symbol string) bool {
@@ -248,7 +390,7 @@ func (pt *PaperTrader) ReserveBalance(amount float64) error {
 	defer pt.mu.Unlock()
 
 	if amount > pt.balance {
-		return fmt.Errorf("insufficient balance: need %.2f, have %.2f", amount, pt.balance)
+		return validationErrorf("insufficient balance: need %.2f, have %.2f", amount, pt.balance)
 	}
 
 	pt.balance -= amount
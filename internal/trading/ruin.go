@@ -0,0 +1,78 @@
+package trading
+
+import (
+	"math"
+
+	"crypto-trading-bot/internal/risk"
+)
+
+// ruinMinTrades is the number of closed trades below which the projection
+// falls back to neutral assumptions instead of realized statistics.
+const ruinMinTrades = 10
+
+// RuinInput builds a risk of ruin projection input from the last window
+// closed trades (0 = all). Win rate, payoff ratio and the R-multiples of
+// trades come from realized PnL; risk per trade is the configured fraction
+// for fixed fractional sizing and the realized average loss otherwise.
+func (te *TradingEngine) RuinInput(window int) risk.RuinInput {
+	trades := te.paperTrader.GetTradeHistory()
+	if window > 0 && len(trades) > window {
+		trades = trades[len(trades)-window:]
+	}
+
+	var wins, losses int
+	var sumWin, sumLoss float64
+	for _, trade := range trades {
+		if trade.PnL > 0 {
+			wins++
+			sumWin += trade.PnL
+		} else if trade.PnL < 0 {
+			losses++
+			sumLoss -= trade.PnL
+		}
+	}
+
+	input := risk.RuinInput{
+		WinRate:      0.5,
+		PayoffRatio:  1,
+		RiskPerTrade: te.configuredRiskPerTrade(),
+		SampleTrades: len(trades),
+	}
+	if len(trades) < ruinMinTrades || wins == 0 || losses == 0 {
+		return input
+	}
+
+	avgLoss := sumLoss / float64(losses)
+	input.WinRate = float64(wins) / float64(wins+losses)
+	input.PayoffRatio = (sumWin / float64(wins)) / avgLoss
+	input.RMultiples = make([]float64, 0, len(trades))
+	for _, trade := range trades {
+		input.RMultiples = append(input.RMultiples, trade.PnL/avgLoss)
+	}
+
+	if input.RiskPerTrade <= 0 {
+		if equity := te.paperTrader.GetEquity(); equity > 0 {
+			input.RiskPerTrade = math.Min(avgLoss/equity, 0.99)
+		}
+	}
+	return input
+}
+
+// configuredRiskPerTrade returns the equity fraction risked per trade by the
+// configured sizing, or 0 when the sizer does not risk a fixed fraction.
+func (te *TradingEngine) configuredRiskPerTrade() float64 {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+
+	sizing := te.config.Sizing
+	if sizing == nil {
+		return te.config.RiskPerTrade
+	}
+	if sizing.Method == risk.SizingFixedFractional {
+		if sizing.RiskPerTrade > 0 {
+			return sizing.RiskPerTrade
+		}
+		return te.config.RiskPerTrade
+	}
+	return 0
+}
@@ -0,0 +1,137 @@
+package trading
+
+import (
+	"fmt"
+	"time"
+
+	"crypto-trading-bot/internal/risk"
+	log "github.com/sirupsen/logrus"
+)
+
+// newRuleChainOrDefault builds the engine's pre-trade rule chain, falling
+// back to the default rule order when the configured one is invalid. The
+// caller must hold te.mu or be the constructor.
+func (te *TradingEngine) newRuleChainOrDefault(config *EngineConfig) *risk.RuleChain {
+	chain, err := risk.NewRuleChain(config.RiskRules, te.ruleParams(config), config.DecisionJournal)
+	if err != nil {
+		log.Errorf("Invalid risk rules %v, falling back to defaults: %v", config.RiskRules, err)
+		chain, _ = risk.NewRuleChain(nil, te.ruleParams(config), config.DecisionJournal)
+	}
+	return chain
+}
+
+func (te *TradingEngine) ruleParams(config *EngineConfig) risk.RuleParams {
+	limits := make(map[string]int, len(te.strategyLimits))
+	for strategy, limit := range te.strategyLimits {
+		limits[strategy] = limit
+	}
+	return risk.RuleParams{
+		Breaker:           te.breaker,
+		Portfolio:         te.portfolio,
+		VaRLimit:          config.VaRLimit,
+		MinConfidence:     config.MinConfidence,
+		MaxDailyTrades:    config.MaxDailyTrades,
+		Cooldown:          time.Duration(config.CooldownMinutes) * time.Minute,
+		MaxOpenPositions:  config.MaxOpenPositions,
+		StrategyPositions: limits,
+		MaxPositionSize:   config.MaxPositionSize,
+	}
+}
+
+// checkOrder runs an order through the risk rule chain and returns the
+// approved quantity, which may be smaller than requested.
+func (te *TradingEngine) checkOrder(intent risk.OrderIntent) (float64, error) {
+	te.mu.RLock()
+	rules := te.rules
+	te.mu.RUnlock()
+
+	intent.Account = te.paperTrader.GetAccount()
+	decision := rules.Evaluate(intent, te.ruleContext())
+	if decision.Action == risk.ActionReject {
+		return 0, fmt.Errorf("order rejected by %s rule: %s", decision.Rule, decision.Reason)
+	}
+	return decision.Quantity, nil
+}
+
+// ruleContext captures the account state the rules are evaluated against.
+func (te *TradingEngine) ruleContext() risk.RuleContext {
+	te.checkRollover()
+
+	now := time.Now()
+	open, reason := te.calendar().IsTradingAllowed(now)
+
+	positions := te.paperTrader.GetAllPositions()
+	byStrategy := make(map[string]int)
+	for _, pos := range positions {
+		byStrategy[pos.Strategy]++
+	}
+
+	holdings, equity := te.holdings(), te.paperTrader.GetEquity()
+	portfolioHoldings, portfolioEquity := holdings, equity
+	te.mu.RLock()
+	provider := te.config.PortfolioHoldings
+	te.mu.RUnlock()
+	if provider != nil {
+		// Лимиты портфеля общие для всех счетов, поэтому проверяем суммарные позиции
+		portfolioHoldings, portfolioEquity = provider()
+	}
+
+	te.statsMu.RLock()
+	todayTrades := te.stats.TodayTrades
+	lastTradeTime := te.stats.LastTradeTime
+	te.statsMu.RUnlock()
+
+	return risk.RuleContext{
+		Now:               now,
+		Equity:            equity,
+		Available:         te.paperTrader.GetBalance(),
+		Holdings:          holdings,
+		PortfolioHoldings: portfolioHoldings,
+		PortfolioEquity:   portfolioEquity,
+		OpenPositions:     len(positions),
+		StrategyPositions: byStrategy,
+		TodayTrades:       todayTrades,
+		LastTradeTime:     lastTradeTime,
+		SessionOpen:       open,
+		SessionReason:     reason,
+	}
+}
+
+// SetRiskRules replaces the pre-trade rule order of the engine's account.
+// An empty list restores the default order.
+func (te *TradingEngine) SetRiskRules(names []string) error {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	config := *te.config
+	config.RiskRules = append([]string(nil), names...)
+	chain, err := risk.NewRuleChain(config.RiskRules, te.ruleParams(&config), config.DecisionJournal)
+	if err != nil {
+		return err
+	}
+	te.config = &config
+	te.rules = chain
+	log.Infof("Risk rules for %s: %v", te.paperTrader.GetAccount(), chain.Names())
+	return nil
+}
+
+// GetRiskRules returns the rule names in evaluation order.
+func (te *TradingEngine) GetRiskRules() []string {
+	te.mu.RLock()
+	defer te.mu.RUnlock()
+	return te.rules.Names()
+}
+
+// SetStrategyPositionLimit limits the open positions of one strategy on
+// this engine (0 removes the limit).
+func (te *TradingEngine) SetStrategyPositionLimit(strategy string, limit int) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	if limit > 0 {
+		te.strategyLimits[strategy] = limit
+	} else {
+		delete(te.strategyLimits, strategy)
+	}
+	te.rules = te.newRuleChainOrDefault(te.config)
+}
@@ -0,0 +1,282 @@
+package trading
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// SessionConfig describes the trading calendar: the timezone used to cut
+// trading days, the hour at which a new day starts and optional trading windows.
+type SessionConfig struct {
+	Timezone        string           `json:"timezone"`        // IANA timezone, e.g. "UTC" or "Europe/Moscow"
+	RolloverHour    int              `json:"rolloverHour"`    // Local hour at which a new trading day starts (0-23)
+	AllowedHours    []HourRange      `json:"allowedHours"`    // Allowed local hours; empty means around the clock
+	AllowedWeekdays []string         `json:"allowedWeekdays"` // Allowed weekdays ("Mon", "Tuesday", ...); empty means every day
+	Blackouts       []BlackoutPeriod `json:"blackouts"`       // Periods when new entries are forbidden
+	BlackoutFile    string           `json:"blackoutFile"`    // Optional JSON file with additional blackout periods
+}
+
+// HourRange is a half-open range of local hours [StartHour, EndHour).
+// A range with StartHour > EndHour wraps around midnight (e.g. 22 -> 6).
+type HourRange struct {
+	StartHour int `json:"startHour"`
+	EndHour   int `json:"endHour"`
+}
+
+// BlackoutPeriod is a time range during which no new positions are opened.
+type BlackoutPeriod struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason"`
+}
+
+// DailySummary is the archived result of one trading day.
+type DailySummary struct {
+	Date          string  `json:"date"`
+	Trades        int     `json:"trades"`
+	WinningTrades int     `json:"winningTrades"`
+	LosingTrades  int     `json:"losingTrades"`
+	BreakevenTrades int   `json:"breakevenTrades"`
+	PnL           float64 `json:"pnl"`
+	MaxDailyLoss  float64 `json:"maxDailyLoss"`
+	EndBalance    float64 `json:"endBalance"`
+}
+
+// SessionCalendar tracks the current trading day, performs daily rollover
+// and enforces the configured trading windows.
+type SessionCalendar struct {
+	config     *SessionConfig
+	location   *time.Location
+	weekdays   map[time.Weekday]bool
+	currentDay string
+	history    []DailySummary
+	mu         sync.RWMutex
+}
+
+// DefaultSessionConfig returns a calendar that rolls over at UTC midnight
+// and allows trading at any time.
+func DefaultSessionConfig() *SessionConfig {
+	return &SessionConfig{Timezone: "UTC"}
+}
+
+// NewSessionCalendar creates a calendar for the given configuration.
+// Blackout periods from BlackoutFile are merged with the inline ones.
+func NewSessionCalendar(config *SessionConfig) (*SessionCalendar, error) {
+	if config == nil {
+		config = DefaultSessionConfig()
+	}
+
+	tz := config.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+
+	if config.RolloverHour < 0 || config.RolloverHour > 23 {
+		return nil, fmt.Errorf("invalid rollover hour: %d", config.RolloverHour)
+	}
+
+	for _, r := range config.AllowedHours {
+		if r.StartHour < 0 || r.StartHour > 23 || r.EndHour < 0 || r.EndHour > 24 {
+			return nil, fmt.Errorf("invalid hour range: %d-%d", r.StartHour, r.EndHour)
+		}
+	}
+
+	weekdays := make(map[time.Weekday]bool)
+	for _, name := range config.AllowedWeekdays {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return nil, err
+		}
+		weekdays[day] = true
+	}
+
+	for _, b := range config.Blackouts {
+		if !b.End.After(b.Start) {
+			return nil, fmt.Errorf("invalid blackout period %s - %s", b.Start, b.End)
+		}
+	}
+
+	cfg := *config
+	cfg.Blackouts = append([]BlackoutPeriod(nil), config.Blackouts...)
+	if cfg.BlackoutFile != "" {
+		blackouts, err := LoadBlackouts(cfg.BlackoutFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Blackouts = append(cfg.Blackouts, blackouts...)
+		log.Infof("Loaded %d blackout periods from %s", len(blackouts), cfg.BlackoutFile)
+	}
+
+	sc := &SessionCalendar{
+		config:   &cfg,
+		location: location,
+		weekdays: weekdays,
+		history:  make([]DailySummary, 0),
+	}
+	sc.currentDay = sc.TradingDay(time.Now())
+	return sc, nil
+}
+
+// carryOver keeps the current day and the archived summaries of the
+// calendar being replaced, so a config change neither rolls the day over
+// nor loses the history.
+func (sc *SessionCalendar) carryOver(previous *SessionCalendar) {
+	previous.mu.RLock()
+	currentDay := previous.currentDay
+	history := append([]DailySummary(nil), previous.history...)
+	previous.mu.RUnlock()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.currentDay = currentDay
+	sc.history = history
+}
+
+// LoadSessionConfig reads a SessionConfig from a JSON file.
+func LoadSessionConfig(path string) (*SessionConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session config: %w", err)
+	}
+
+	config := DefaultSessionConfig()
+	if err := json.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse session config: %w", err)
+	}
+	return config, nil
+}
+
+// LoadBlackouts reads a JSON array of blackout periods from a file.
+func LoadBlackouts(path string) ([]BlackoutPeriod, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blackout file: %w", err)
+	}
+
+	var blackouts []BlackoutPeriod
+	if err := json.Unmarshal(data, &blackouts); err != nil {
+		return nil, fmt.Errorf("failed to parse blackout file: %w", err)
+	}
+
+	for _, b := range blackouts {
+		if !b.End.After(b.Start) {
+			return nil, fmt.Errorf("invalid blackout period %s - %s", b.Start, b.End)
+		}
+	}
+	return blackouts, nil
+}
+
+// TradingDay returns the trading day (YYYY-MM-DD) the given moment belongs to.
+func (sc *SessionCalendar) TradingDay(t time.Time) string {
+	local := t.In(sc.location).Add(-time.Duration(sc.config.RolloverHour) * time.Hour)
+	return local.Format("2006-01-02")
+}
+
+// CurrentDay returns the trading day the calendar is currently in.
+func (sc *SessionCalendar) CurrentDay() string {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.currentDay
+}
+
+// CheckRollover reports whether a new trading day has started since the last
+// call. It returns the day that just ended and the new current day.
+func (sc *SessionCalendar) CheckRollover(now time.Time) (previous, current string, rolled bool) {
+	day := sc.TradingDay(now)
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	if day == sc.currentDay {
+		return sc.currentDay, day, false
+	}
+
+	previous = sc.currentDay
+	sc.currentDay = day
+	return previous, day, true
+}
+
+// Archive stores the summary of a finished trading day.
+func (sc *SessionCalendar) Archive(summary DailySummary) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.history = append(sc.history, summary)
+}
+
+// GetHistory returns archived daily summaries, oldest first.
+func (sc *SessionCalendar) GetHistory() []DailySummary {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+
+	history := make([]DailySummary, len(sc.history))
+	copy(history, sc.history)
+	return history
+}
+
+// IsTradingAllowed checks the trading windows and blackout periods.
+// When trading is not allowed the reason is returned.
+func (sc *SessionCalendar) IsTradingAllowed(now time.Time) (bool, string) {
+	local := now.In(sc.location)
+
+	if len(sc.weekdays) > 0 && !sc.weekdays[local.Weekday()] {
+		return false, fmt.Sprintf("trading not allowed on %s", local.Weekday())
+	}
+
+	if len(sc.config.AllowedHours) > 0 {
+		hour := local.Hour()
+		inWindow := false
+		for _, r := range sc.config.AllowedHours {
+			if r.contains(hour) {
+				inWindow = true
+				break
+			}
+		}
+		if !inWindow {
+			return false, fmt.Sprintf("outside trading hours (%02d:00 %s)", hour, sc.location)
+		}
+	}
+
+	for _, b := range sc.config.Blackouts {
+		if !now.Before(b.Start) && now.Before(b.End) {
+			reason := b.Reason
+			if reason == "" {
+				reason = "scheduled blackout"
+			}
+			return false, fmt.Sprintf("blackout until %s: %s", b.End.In(sc.location).Format("2006-01-02 15:04"), reason)
+		}
+	}
+
+	return true, ""
+}
+
+func (r HourRange) contains(hour int) bool {
+	if r.StartHour == r.EndHour {
+		return true
+	}
+	if r.StartHour < r.EndHour {
+		return hour >= r.StartHour && hour < r.EndHour
+	}
+	return hour >= r.StartHour || hour < r.EndHour
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if len(n) >= 3 {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if strings.HasPrefix(strings.ToLower(d.String()), n) {
+				return d, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday: %q", name)
+}
@@ -0,0 +1,228 @@
+// Package webhook receives trading alerts from external charting tools
+// (TradingView-style webhooks) over HTTP and turns them into signals.
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"crypto-trading-bot/internal/signals"
+
+	"github.com/google/uuid"
+)
+
+// Alert is the JSON body of a webhook request. With TradingView the body is
+// the alert message, e.g.
+//
+//	{"secret": "...", "symbol": "{{ticker}}", "direction": "buy",
+//	 "confidence": 0.8, "price": {{close}}, "timeframe": "{{interval}}",
+//	 "timestamp": "{{timenow}}"}
+type Alert struct {
+	Secret     string    `json:"secret"` // Shared secret, when it cannot be sent in a header
+	ID         string    `json:"id"`     // Unique alert id; the body hash is used when empty
+	Source     string    `json:"source"` // Sending tool or strategy, appended to the model tag
+	Symbol     string    `json:"symbol"`
+	Direction  string    `json:"direction"`  // LONG/SHORT/HOLD or BUY/SELL/FLAT, any case
+	Confidence float64   `json:"confidence"` // 0-1, or a percentage up to 100
+	Price      float64   `json:"price"`
+	StopLoss   float64   `json:"stopLoss"`   // Optional stop-loss price
+	TakeProfit float64   `json:"takeProfit"` // Optional take-profit price
+	Timeframe  string    `json:"timeframe"`  // "1m", "4h" or TradingView intervals like "15", "240", "D"
+	Timestamp  AlertTime `json:"timestamp"`  // Unix seconds or ms, or an RFC 3339 string
+	Comment    string    `json:"comment"`
+}
+
+// AlertTime accepts Unix seconds, Unix milliseconds or an RFC 3339 string.
+type AlertTime struct {
+	time.Time
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *AlertTime) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(data), `"`)
+	if text == "" || text == "null" {
+		t.Time = time.Time{}
+		return nil
+	}
+	if n, err := strconv.ParseFloat(text, 64); err == nil {
+		// Секунды и миллисекунды различаем по порядку величины
+		if n < 1e12 {
+			t.Time = time.UnixMilli(int64(n * 1000))
+		} else {
+			t.Time = time.UnixMilli(int64(n))
+		}
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return fmt.Errorf("invalid alert timestamp %q", text)
+	}
+	t.Time = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (t AlertTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.UnixMilli())
+}
+
+// normalizeDirection maps alert directions to signal directions.
+func normalizeDirection(direction string) (string, error) {
+	switch strings.ToUpper(strings.TrimSpace(direction)) {
+	case "LONG", "BUY":
+		return "LONG", nil
+	case "SHORT", "SELL":
+		return "SHORT", nil
+	case "HOLD", "FLAT", "NEUTRAL":
+		return "HOLD", nil
+	}
+	return "", fmt.Errorf("unknown direction %q", direction)
+}
+
+// normalizeTimeframe converts TradingView intervals ("1", "60", "240",
+// "D", "1W", "30S") into Binance ones ("1m", "1h", "4h", "1d", "1w", "30s").
+// Binance intervals are returned unchanged.
+func normalizeTimeframe(timeframe string) string {
+	timeframe = strings.TrimSpace(timeframe)
+	if timeframe == "" || signals.TimeframeDuration(timeframe) > 0 {
+		return timeframe
+	}
+	if minutes, err := strconv.Atoi(timeframe); err == nil && minutes > 0 {
+		switch {
+		case minutes%1440 == 0:
+			return strconv.Itoa(minutes/1440) + "d"
+		case minutes%60 == 0:
+			return strconv.Itoa(minutes/60) + "h"
+		default:
+			return strconv.Itoa(minutes) + "m"
+		}
+	}
+	// "D", "W", "M" без числа означают один период
+	if n := timeframe[len(timeframe)-1]; n == 'D' || n == 'W' || n == 'M' || n == 'S' {
+		count := strings.TrimSuffix(timeframe, string(n))
+		if count == "" {
+			count = "1"
+		}
+		switch n {
+		case 'D':
+			return count + "d"
+		case 'W':
+			return count + "w"
+		case 'S':
+			return count + "s"
+		}
+		return count + "M"
+	}
+	return timeframe
+}
+
+// Validate normalizes the alert and checks its fields. defaultTimeframe is
+// used when the alert has none.
+func (a *Alert) Validate(defaultTimeframe string) error {
+	a.Symbol = strings.ToUpper(strings.TrimSpace(a.Symbol))
+	// TradingView присылает тикер с биржей: "BINANCE:BTCUSDT"
+	if _, ticker, ok := strings.Cut(a.Symbol, ":"); ok {
+		a.Symbol = ticker
+	}
+	a.Symbol = strings.TrimSuffix(a.Symbol, ".P")
+	if a.Symbol == "" {
+		return fmt.Errorf("symbol is required")
+	}
+
+	direction, err := normalizeDirection(a.Direction)
+	if err != nil {
+		return err
+	}
+	a.Direction = direction
+
+	if a.Confidence > 1 && a.Confidence <= 100 {
+		a.Confidence /= 100
+	}
+	if a.Confidence < 0 || a.Confidence > 1 {
+		return fmt.Errorf("confidence must be within [0, 1] or [0, 100]")
+	}
+	if a.Price <= 0 {
+		return fmt.Errorf("price must be positive")
+	}
+	if a.StopLoss < 0 || a.TakeProfit < 0 {
+		return fmt.Errorf("stop-loss and take-profit must not be negative")
+	}
+	switch a.Direction {
+	case "LONG":
+		if a.StopLoss > 0 && a.StopLoss >= a.Price {
+			return fmt.Errorf("stop-loss %g of a LONG must be below price %g", a.StopLoss, a.Price)
+		}
+		if a.TakeProfit > 0 && a.TakeProfit <= a.Price {
+			return fmt.Errorf("take-profit %g of a LONG must be above price %g", a.TakeProfit, a.Price)
+		}
+	case "SHORT":
+		if a.StopLoss > 0 && a.StopLoss <= a.Price {
+			return fmt.Errorf("stop-loss %g of a SHORT must be above price %g", a.StopLoss, a.Price)
+		}
+		if a.TakeProfit > 0 && a.TakeProfit >= a.Price {
+			return fmt.Errorf("take-profit %g of a SHORT must be below price %g", a.TakeProfit, a.Price)
+		}
+	}
+
+	a.Timeframe = normalizeTimeframe(a.Timeframe)
+	if a.Timeframe == "" {
+		a.Timeframe = defaultTimeframe
+	}
+	if a.Timeframe != "" && signals.TimeframeDuration(a.Timeframe) == 0 {
+		return fmt.Errorf("unknown timeframe %q", a.Timeframe)
+	}
+	return nil
+}
+
+// Signal converts a validated alert into a signal tagged with model, e.g.
+// "webhook" or "webhook:tradingview" when the alert names its source.
+func (a *Alert) Signal(model string, received time.Time) *signals.Signal {
+	if a.Source != "" {
+		model += ":" + strings.ToLower(strings.TrimSpace(a.Source))
+	}
+
+	confidence := a.Confidence
+	if a.Direction == "HOLD" {
+		confidence = 0
+	}
+	sig := &signals.Signal{
+		ID:         uuid.New().String(),
+		Symbol:     a.Symbol,
+		Timeframe:  a.Timeframe,
+		Direction:  a.Direction,
+		Confidence: confidence,
+		Price:      a.Price,
+		StopLoss:   a.StopLoss,
+		TakeProfit: a.TakeProfit,
+		Timestamp:  received,
+		Model:      model,
+		Explanation: &signals.SignalExplanation{
+			Model:          model,
+			DirectionRule:  fmt.Sprintf("external alert: %s", a.Direction),
+			ConfidenceRule: fmt.Sprintf("supplied by the alert: %.2f", confidence),
+		},
+	}
+
+	notes := make([]string, 0, 4)
+	if a.ID != "" {
+		notes = append(notes, "alert id: "+a.ID)
+	}
+	if !a.Timestamp.IsZero() {
+		notes = append(notes, "alert time: "+a.Timestamp.UTC().Format(time.RFC3339))
+	}
+	if a.StopLoss > 0 || a.TakeProfit > 0 {
+		notes = append(notes, fmt.Sprintf("levels: stop-loss %g, take-profit %g", a.StopLoss, a.TakeProfit))
+	}
+	if a.Comment != "" {
+		notes = append(notes, a.Comment)
+	}
+	sig.Explanation.Notes = notes
+	sig.Explain()
+	return sig
+}
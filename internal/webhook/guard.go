@@ -0,0 +1,86 @@
+package webhook
+
+import (
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket refilled at rate tokens per second up to
+// burst tokens.
+type rateLimiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func newRateLimiter(perMinute, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// allow takes a token if one is available. Otherwise it returns false and
+// how long until the next token.
+func (l *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	return false, wait
+}
+
+// replayCache remembers alert nonces for the replay window.
+type replayCache struct {
+	window time.Duration
+	seen   map[string]time.Time
+	mu     sync.Mutex
+}
+
+func newReplayCache(window time.Duration) *replayCache {
+	return &replayCache{
+		window: window,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// remember records nonce and reports false if it was already seen within
+// the window.
+func (c *replayCache) remember(nonce string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, at := range c.seen {
+		if now.Sub(at) > c.window {
+			delete(c.seen, key)
+		}
+	}
+	if _, ok := c.seen[nonce]; ok {
+		return false
+	}
+	c.seen[nonce] = now
+	return true
+}
+
+// forget removes a nonce, so that an alert rejected after the replay check
+// can be resent.
+func (c *replayCache) forget(nonce string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.seen, nonce)
+}
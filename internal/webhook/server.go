@@ -0,0 +1,319 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"crypto-trading-bot/internal/signals"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Headers a request can authenticate with; the secret may instead be sent
+// in the "secret" field of the body.
+const (
+	SecretHeader    = "X-Webhook-Secret"
+	SignatureHeader = "X-Webhook-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+// maxBodyBytes limits the size of an alert body.
+const maxBodyBytes = 64 << 10
+
+// Config configures the webhook receiver.
+type Config struct {
+	Addr             string        `json:"addr"`             // Listen address, e.g. "127.0.0.1:8787"
+	Path             string        `json:"path"`             // Endpoint path (default "/webhook")
+	Secret           string        `json:"-"`                // Shared secret; required
+	ModelTag         string        `json:"modelTag"`         // Model recorded in signals (default "webhook")
+	DefaultTimeframe string        `json:"defaultTimeframe"` // Timeframe of alerts without one
+	RatePerMinute    int           `json:"ratePerMinute"`    // Sustained alert rate (default 30)
+	Burst            int           `json:"burst"`            // Alerts accepted at once (default 10)
+	MaxSkew          time.Duration `json:"maxSkew"`          // Accepted alert timestamp deviation (default 5m)
+	RequireTimestamp bool          `json:"requireTimestamp"` // Reject alerts without a timestamp
+}
+
+// Validate fills defaults and checks the configuration.
+func (c *Config) Validate() error {
+	if c.Secret == "" {
+		return fmt.Errorf("webhook secret is required")
+	}
+	if c.Addr == "" {
+		return fmt.Errorf("webhook listen address is required")
+	}
+	if c.Path == "" {
+		c.Path = "/webhook"
+	}
+	if c.ModelTag == "" {
+		c.ModelTag = "webhook"
+	}
+	if c.RatePerMinute <= 0 {
+		c.RatePerMinute = 30
+	}
+	if c.Burst <= 0 {
+		c.Burst = 10
+	}
+	if c.MaxSkew <= 0 {
+		c.MaxSkew = 5 * time.Minute
+	}
+	if c.DefaultTimeframe != "" && signals.TimeframeDuration(c.DefaultTimeframe) == 0 {
+		return fmt.Errorf("unknown webhook default timeframe %q", c.DefaultTimeframe)
+	}
+	return nil
+}
+
+// Sink receives the signals of accepted alerts. An error rejects the alert.
+type Sink func(signal *signals.Signal) error
+
+// Stats counts webhook requests.
+type Stats struct {
+	Running      bool           `json:"running"`
+	Addr         string         `json:"addr"`
+	Received     int64          `json:"received"`
+	Accepted     int64          `json:"accepted"`
+	Rejected     map[string]int `json:"rejected"`    // Rejections by reason: unauthorized, invalid, replay, rate_limited, sink
+	LastAlertAt  int64          `json:"lastAlertAt"` // Unix ms of the last accepted alert
+	LastSignalID string         `json:"lastSignalId"`
+	LastError    string         `json:"lastError"`
+}
+
+// Server is the HTTP receiver of external alerts.
+type Server struct {
+	config  Config
+	sink    Sink
+	limiter *rateLimiter
+	replays *replayCache
+	server  *http.Server
+
+	stats Stats
+	mu    sync.Mutex
+}
+
+// NewServer creates a webhook receiver delivering accepted alerts to sink.
+func NewServer(config Config, sink Sink) (*Server, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &Server{
+		config:  config,
+		sink:    sink,
+		limiter: newRateLimiter(config.RatePerMinute, config.Burst),
+		// Алерт принимается в окне ±MaxSkew, поэтому nonce храним 2*MaxSkew
+		replays: newReplayCache(2 * config.MaxSkew),
+		stats: Stats{
+			Addr:     config.Addr,
+			Rejected: make(map[string]int),
+		},
+	}, nil
+}
+
+// Start listens on the configured address in the background.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.server != nil {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", s.config.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.config.Addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(s.config.Path, s)
+	s.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	s.stats.Running = true
+
+	go func(server *http.Server) {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Webhook server stopped: %v", err)
+			s.mu.Lock()
+			s.stats.Running = false
+			s.stats.LastError = err.Error()
+			s.mu.Unlock()
+		}
+	}(s.server)
+
+	log.Infof("Webhook receiver listening on %s%s", s.config.Addr, s.config.Path)
+	return nil
+}
+
+// Stop shuts the server down.
+func (s *Server) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	server := s.server
+	s.server = nil
+	s.stats.Running = false
+	s.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
+
+// Stats returns a copy of the request counters.
+func (s *Server) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	stats.Rejected = make(map[string]int, len(s.stats.Rejected))
+	for reason, n := range s.stats.Rejected {
+		stats.Rejected[reason] = n
+	}
+	return stats
+}
+
+// response is the JSON body of every webhook reply.
+type response struct {
+	Status   string `json:"status"` // "accepted" or "rejected"
+	SignalID string `json:"signalId,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ServeHTTP authenticates, rate limits and de-duplicates an alert, then
+// hands its signal to the sink.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.stats.Received++
+	s.mu.Unlock()
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.reject(w, http.StatusMethodNotAllowed, "invalid", fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+
+	// Лимит действует и на неаутентифицированные запросы: он же ограничивает подбор секрета
+	now := time.Now()
+	if ok, wait := s.limiter.allow(now); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+		s.reject(w, http.StatusTooManyRequests, "rate_limited", fmt.Errorf("rate limit of %d alerts per minute exceeded", s.config.RatePerMinute))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		s.reject(w, http.StatusRequestEntityTooLarge, "invalid", fmt.Errorf("failed to read body: %w", err))
+		return
+	}
+
+	var alert Alert
+	if err := json.Unmarshal(body, &alert); err != nil {
+		// Тело без валидного JSON не может пройти и проверку секрета в теле
+		if !s.authenticated(r, body, "") {
+			s.reject(w, http.StatusUnauthorized, "unauthorized", fmt.Errorf("invalid secret or signature"))
+			return
+		}
+		s.reject(w, http.StatusBadRequest, "invalid", fmt.Errorf("invalid alert JSON: %w", err))
+		return
+	}
+	if !s.authenticated(r, body, alert.Secret) {
+		s.reject(w, http.StatusUnauthorized, "unauthorized", fmt.Errorf("invalid secret or signature"))
+		return
+	}
+
+	if alert.Timestamp.IsZero() {
+		if s.config.RequireTimestamp {
+			s.reject(w, http.StatusBadRequest, "invalid", fmt.Errorf("timestamp is required"))
+			return
+		}
+	} else if skew := now.Sub(alert.Timestamp.Time); skew > s.config.MaxSkew || skew < -s.config.MaxSkew {
+		s.reject(w, http.StatusConflict, "replay", fmt.Errorf("alert timestamp %s is outside ±%s", alert.Timestamp.UTC().Format(time.RFC3339), s.config.MaxSkew))
+		return
+	}
+
+	nonce := alert.ID
+	if nonce == "" {
+		sum := sha256.Sum256(body)
+		nonce = hex.EncodeToString(sum[:])
+	}
+	if !s.replays.remember(nonce, now) {
+		s.reject(w, http.StatusConflict, "replay", fmt.Errorf("alert %s was already received", shorten(nonce)))
+		return
+	}
+
+	if err := alert.Validate(s.config.DefaultTimeframe); err != nil {
+		s.replays.forget(nonce)
+		s.reject(w, http.StatusBadRequest, "invalid", err)
+		return
+	}
+
+	signal := alert.Signal(s.config.ModelTag, now)
+	if err := s.sink(signal); err != nil {
+		s.replays.forget(nonce)
+		s.reject(w, http.StatusUnprocessableEntity, "sink", err)
+		return
+	}
+
+	s.mu.Lock()
+	s.stats.Accepted++
+	s.stats.LastAlertAt = now.UnixMilli()
+	s.stats.LastSignalID = signal.ID
+	s.mu.Unlock()
+
+	log.Infof("Webhook alert accepted: %s", signal.Digest())
+	writeJSON(w, http.StatusOK, response{Status: "accepted", SignalID: signal.ID})
+}
+
+// authenticated checks the secret header, the HMAC signature header or the
+// secret field of the body, in constant time.
+func (s *Server) authenticated(r *http.Request, body []byte, bodySecret string) bool {
+	secret := []byte(s.config.Secret)
+	if header := r.Header.Get(SecretHeader); header != "" {
+		return subtle.ConstantTimeCompare([]byte(header), secret) == 1
+	}
+	if header := r.Header.Get(SignatureHeader); header != "" {
+		signature, err := hex.DecodeString(strings.TrimPrefix(header, "sha256="))
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(body)
+		return hmac.Equal(signature, mac.Sum(nil))
+	}
+	return bodySecret != "" && subtle.ConstantTimeCompare([]byte(bodySecret), secret) == 1
+}
+
+func (s *Server) reject(w http.ResponseWriter, status int, reason string, err error) {
+	s.mu.Lock()
+	s.stats.Rejected[reason]++
+	s.stats.LastError = err.Error()
+	s.mu.Unlock()
+
+	log.Warnf("Webhook alert rejected (%s): %v", reason, err)
+	writeJSON(w, status, response{Status: "rejected", Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func shorten(nonce string) string {
+	if len(nonce) > 16 {
+		return nonce[:16]
+	}
+	return nonce
+}